@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/harness/ff-proxy/v2/codec"
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+// boltBucket is the name of the bucket that BoltCache stores everything in
+var boltBucket = []byte("ffproxy")
+
+// BoltOption defines optional parameters for configuring a BoltCache
+type BoltOption func(b *BoltCache)
+
+// WithBoltCodec sets how values are encoded in the BoltCache. Values written with
+// any codec can still be read, just like with the KeyValCache.
+func WithBoltCodec(c codec.Codec) BoltOption {
+	return func(b *BoltCache) {
+		b.marshalFn = c.Marshal
+		b.unmarshalFn = c.Unmarshal
+	}
+}
+
+// BoltCache is a Cache that persists values to a file on disk using bbolt. It's
+// intended for Proxy's that run without redis so that they still have the last
+// config they saw if they restart while Harness SaaS is unreachable.
+type BoltCache struct {
+	db          *bolt.DB
+	marshalFn   func(v interface{}) ([]byte, error)
+	unmarshalFn func(b []byte, v interface{}) error
+}
+
+// NewBoltCache opens, or creates, the bbolt database at the given path. Only one
+// process can have the database open at a time so it errors if it can't get a
+// lock on the file within a second.
+func NewBoltCache(path string, opts ...BoltOption) (*BoltCache, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt cache at %q: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bucket in bolt cache: %w", err)
+	}
+
+	b := &BoltCache{
+		db:          db,
+		marshalFn:   jsoniter.Marshal,
+		unmarshalFn: codec.Unmarshal,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b, nil
+}
+
+// Set sets a value in the cache for a given key
+func (b *BoltCache) Set(_ context.Context, key string, value interface{}) error {
+	v, err := b.marshalFn(value)
+	if err != nil {
+		return err
+	}
+
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), v)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: BoltCache.Set failed for key: %q: %s", domain.ErrCacheInternal, key, err)
+	}
+	return nil
+}
+
+// Get gets the value for a given key
+func (b *BoltCache) Get(_ context.Context, key string, value interface{}) error {
+	var v []byte
+
+	// Values returned by bolt are only valid for the life of the transaction
+	// so we have to take a copy
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		if data := tx.Bucket(boltBucket).Get([]byte(key)); data != nil {
+			v = make([]byte, len(data))
+			copy(v, data)
+		}
+		return nil
+	})
+
+	if v == nil {
+		return fmt.Errorf("%w: key %q doesn't exist in bolt cache", domain.ErrCacheNotFound, key)
+	}
+
+	if err := b.unmarshalFn(v, value); err != nil {
+		return fmt.Errorf("%v: failed to unmarshal value to %T for key: %q", domain.ErrCacheInternal, value, key)
+	}
+	return nil
+}
+
+// Update makes BoltCache implement the Updater interface. The value is read and
+// written in a single bolt transaction so concurrent updates can't overwrite each other.
+func (b *BoltCache) Update(_ context.Context, key string, value interface{}, update func() error) error {
+	var updateErr error
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		resetValue(value)
+		// Values returned by bolt are only valid for the life of the transaction
+		// so we decode a copy in case the value holds on to the bytes
+		if data := bucket.Get([]byte(key)); data != nil {
+			v := make([]byte, len(data))
+			copy(v, data)
+			if err := b.unmarshalFn(v, value); err != nil {
+				return fmt.Errorf("failed to unmarshal value to %T: %s", value, err)
+			}
+		}
+
+		if updateErr = update(); updateErr != nil {
+			return updateErr
+		}
+
+		v, err := b.marshalFn(value)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), v)
+	})
+	if updateErr != nil {
+		return updateErr
+	}
+	if err != nil {
+		return fmt.Errorf("%w: BoltCache.Update failed for key: %q: %s", domain.ErrCacheInternal, key, err)
+	}
+	return nil
+}
+
+// Delete removes a key from the cache
+func (b *BoltCache) Delete(_ context.Context, key string) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	})
+	if err != nil {
+		return fmt.Errorf("%w: BoltCache.Delete failed for key: %q: %s", domain.ErrCacheInternal, key, err)
+	}
+	return nil
+}
+
+// Keys returns a list of keys that match the pattern
+func (b *BoltCache) Keys(_ context.Context, key string) ([]string, error) {
+	results := []string{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(k, _ []byte) error {
+			if s := string(k); globMatch(key, s) {
+				results = append(results, s)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: BoltCache.Keys failed for key pattern %q: %s", domain.ErrCacheInternal, key, err)
+	}
+	return results, nil
+}
+
+// Scan returns a map of keys that contain the given key. Like the KeyValCache
+// it only returns the keys and not their values.
+func (b *BoltCache) Scan(_ context.Context, key string) (map[string]string, error) {
+	pattern := "*" + key + "*"
+	scan := make(map[string]string)
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(k, _ []byte) error {
+			if s := string(k); globMatch(pattern, s) {
+				scan[s] = ""
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return scan, fmt.Errorf("%w: BoltCache.Scan failed for key %q: %s", domain.ErrCacheInternal, key, err)
+	}
+	return scan, nil
+}
+
+// HealthCheck checks that we can still read from the database
+func (b *BoltCache) HealthCheck(_ context.Context) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltBucket) == nil {
+			return fmt.Errorf("%w: bolt cache bucket %q doesn't exist", domain.ErrCacheInternal, boltBucket)
+		}
+		return nil
+	})
+}
+
+// Len returns the number of keys stored in the cache
+func (b *BoltCache) Len() int {
+	var n int
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(boltBucket).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+// Close closes the underlying database and releases the lock on the file
+func (b *BoltCache) Close() error {
+	return b.db.Close()
+}
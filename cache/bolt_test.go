@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/harness/ff-proxy/v2/codec"
+)
+
+func setupTestBoltCache(t *testing.T) (*BoltCache, string) {
+	path := filepath.Join(t.TempDir(), "ffproxy.db")
+
+	b, err := NewBoltCache(path)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = b.Close() })
+	return b, path
+}
+
+func TestBoltCache_PersistsAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	b, path := setupTestBoltCache(t)
+
+	require.NoError(t, b.Set(ctx, "foo", "bar"))
+	require.NoError(t, b.Close())
+
+	reopened, err := NewBoltCache(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	assert.Nil(t, reopened.HealthCheck(ctx))
+	assert.Equal(t, 1, reopened.Len())
+
+	var result string
+	require.NoError(t, reopened.Get(ctx, "foo", &result))
+	assert.Equal(t, "bar", result)
+}
+
+func TestBoltCache_Codec(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "ffproxy.db")
+
+	msgpack, err := codec.Parse("msgpack+zstd")
+	require.NoError(t, err)
+
+	b, err := NewBoltCache(path, WithBoltCodec(msgpack))
+	require.NoError(t, err)
+
+	require.NoError(t, b.Set(ctx, "foo", "bar"))
+
+	// Values are written with the codec
+	var raw []byte
+	require.NoError(t, b.db.View(func(tx *bolt.Tx) error {
+		raw = append(raw, tx.Bucket(boltBucket).Get([]byte("foo"))...)
+		return nil
+	}))
+	expected, err := msgpack.Marshal("bar")
+	require.NoError(t, err)
+	assert.Equal(t, expected, raw)
+	require.NoError(t, b.Close())
+
+	// And can still be read if the codec is changed back to json
+	reopened, err := NewBoltCache(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	var result string
+	require.NoError(t, reopened.Get(ctx, "foo", &result))
+	assert.Equal(t, "bar", result)
+}
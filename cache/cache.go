@@ -1,6 +1,12 @@
 package cache
 
-import "context"
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/harness/ff-proxy/v2/domain"
+)
 
 // Cache is the interface for a key value cache
 type Cache interface {
@@ -22,3 +28,42 @@ type Cache interface {
 	// Scan all the keys for given key
 	Scan(ctx context.Context, key string) (map[string]string, error)
 }
+
+// Updater is implemented by caches that can read, modify and write a value
+// without losing changes that are made to it at the same time, e.g. by another
+// Proxy that shares the same redis
+type Updater interface {
+	// Update gets the value for the key, calls update with it and then sets the
+	// value. The value is left zeroed if the key doesn't exist. If the value is
+	// changed by something else before it's set it's fetched and update is called
+	// again. The value isn't set if update returns an error.
+	Update(ctx context.Context, key string, value interface{}, update func() error) error
+}
+
+// Update updates the value for a key using the cache's Update method. Caches that
+// don't implement Updater get and then set the value, which can lose changes made
+// by concurrent updates.
+func Update(ctx context.Context, c Cache, key string, value interface{}, update func() error) error {
+	if u, ok := c.(Updater); ok {
+		return u.Update(ctx, key, value, update)
+	}
+
+	if err := c.Get(ctx, key, value); err != nil && !errors.Is(err, domain.ErrCacheNotFound) {
+		return err
+	}
+
+	if err := update(); err != nil {
+		return err
+	}
+	return c.Set(ctx, key, value)
+}
+
+// resetValue zeroes the value that a pointer points to so that a value decoded
+// on a previous attempt at an update doesn't leak in to the next one
+func resetValue(value interface{}) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return
+	}
+	v.Elem().Set(reflect.Zero(v.Elem().Type()))
+}
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/codec"
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+type conformanceValue struct {
+	Identifier string `json:"identifier"`
+	Version    int64  `json:"version"`
+}
+
+// conformanceCaches returns a constructor for each Cache implementation so that
+// every test gets a fresh, empty cache
+func conformanceCaches() map[string]func(t *testing.T) Cache {
+	newRedisClient := func(t *testing.T) redis.UniversalClient {
+		mr := miniredis.RunT(t)
+		return redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	}
+
+	return map[string]func(t *testing.T) Cache{
+		"MemCache": func(t *testing.T) Cache {
+			return NewMemCache()
+		},
+		"KeyValCache": func(t *testing.T) Cache {
+			return NewKeyValCache(newRedisClient(t))
+		},
+		"MemoizeCache": func(t *testing.T) Cache {
+			return NewMemoizeCache(newRedisClient(t), 1*time.Minute, 2*time.Minute, nil)
+		},
+		"KeyValCache with msgpack+zstd": func(t *testing.T) Cache {
+			c := codec.New(codec.FormatMsgpack, codec.CompressionZstd)
+			return NewKeyValCache(newRedisClient(t), WithMarshalFunc(c.Marshal), WithUnmarshalFunc(c.Unmarshal))
+		},
+		"MemoizeCache with msgpack+zstd": func(t *testing.T) Cache {
+			c := codec.New(codec.FormatMsgpack, codec.CompressionZstd)
+			return NewMemoizeCache(newRedisClient(t), 1*time.Minute, 2*time.Minute, nil, WithMarshalFunc(c.Marshal), WithUnmarshalFunc(c.Unmarshal))
+		},
+		"HashCache over KeyValCache": func(t *testing.T) Cache {
+			return NewHashCache(NewKeyValCache(newRedisClient(t)), 1*time.Minute, 2*time.Minute)
+		},
+		"HashCache over MemCache": func(t *testing.T) Cache {
+			return NewHashCache(NewMemCache(), 1*time.Minute, 2*time.Minute)
+		},
+		"NamespaceCache over KeyValCache": func(t *testing.T) Cache {
+			return NewNamespaceCache("ns", NewKeyValCache(newRedisClient(t)))
+		},
+		"BoltCache": func(t *testing.T) Cache {
+			b, _ := setupTestBoltCache(t)
+			return b
+		},
+	}
+}
+
+// TestCacheConformance runs the same set of tests against every Cache
+// implementation to make sure they all behave the same way
+func TestCacheConformance(t *testing.T) {
+	for name, newCache := range conformanceCaches() {
+		newCache := newCache
+		t.Run(name, func(t *testing.T) {
+			t.Run("Get returns ErrCacheNotFound for a key that doesn't exist", func(t *testing.T) {
+				c := newCache(t)
+
+				var v conformanceValue
+				err := c.Get(context.Background(), "foo", &v)
+				assert.True(t, errors.Is(err, domain.ErrCacheNotFound), "expected ErrCacheNotFound, got %v", err)
+			})
+
+			t.Run("Get returns the value that was Set", func(t *testing.T) {
+				ctx := context.Background()
+				c := newCache(t)
+
+				require.NoError(t, c.Set(ctx, "foo", conformanceValue{Identifier: "foo", Version: 1}))
+
+				var v conformanceValue
+				require.NoError(t, c.Get(ctx, "foo", &v))
+				assert.Equal(t, conformanceValue{Identifier: "foo", Version: 1}, v)
+			})
+
+			t.Run("Set overwrites existing values", func(t *testing.T) {
+				ctx := context.Background()
+				c := newCache(t)
+
+				// Use a feature-configs key so that the HashCache's hash layer is exercised
+				key := string(domain.NewFeatureConfigsKey("123"))
+
+				require.NoError(t, c.Set(ctx, key, []conformanceValue{{Identifier: "foo", Version: 1}}))
+
+				var v []conformanceValue
+				require.NoError(t, c.Get(ctx, key, &v))
+				assert.Equal(t, []conformanceValue{{Identifier: "foo", Version: 1}}, v)
+
+				require.NoError(t, c.Set(ctx, key, []conformanceValue{{Identifier: "foo", Version: 2}}))
+
+				require.NoError(t, c.Get(ctx, key, &v))
+				assert.Equal(t, []conformanceValue{{Identifier: "foo", Version: 2}}, v)
+			})
+
+			t.Run("Delete removes the key", func(t *testing.T) {
+				ctx := context.Background()
+				c := newCache(t)
+
+				require.NoError(t, c.Set(ctx, "foo", conformanceValue{Identifier: "foo"}))
+				require.NoError(t, c.Delete(ctx, "foo"))
+
+				var v conformanceValue
+				err := c.Get(ctx, "foo", &v)
+				assert.True(t, errors.Is(err, domain.ErrCacheNotFound), "expected ErrCacheNotFound, got %v", err)
+
+				// Deleting a key that doesn't exist isn't an error
+				assert.NoError(t, c.Delete(ctx, "bar"))
+			})
+
+			t.Run("Keys and Scan match keys the same way redis does", func(t *testing.T) {
+				ctx := context.Background()
+				c := newCache(t)
+
+				for _, k := range []string{"env-123-api-configs", "env-456-api-configs", "env-123-feature-config-foo", "auth-key-123"} {
+					require.NoError(t, c.Set(ctx, k, "value"))
+				}
+
+				keysTestCases := map[string][]string{
+					"env-*-api-configs": {"env-123-api-configs", "env-456-api-configs"},
+					"auth-key-123":      {"auth-key-123"},
+					"env-12?-*":         {"env-123-api-configs", "env-123-feature-config-foo"},
+					"env-[4]*":          {"env-456-api-configs"},
+					"*":                 {"env-123-api-configs", "env-456-api-configs", "env-123-feature-config-foo", "auth-key-123"},
+					"env-123":           {},
+				}
+
+				for pattern, expected := range keysTestCases {
+					keys, err := c.Keys(ctx, pattern)
+					require.NoError(t, err)
+					assert.ElementsMatch(t, expected, keys, "Keys(%q)", pattern)
+				}
+
+				scanTestCases := map[string]map[string]string{
+					"123":         {"env-123-api-configs": "", "env-123-feature-config-foo": "", "auth-key-123": ""},
+					"api-configs": {"env-123-api-configs": "", "env-456-api-configs": ""},
+					"foo":         {"env-123-feature-config-foo": ""},
+					"bar":         {},
+				}
+
+				for key, expected := range scanTestCases {
+					scan, err := c.Scan(ctx, key)
+					require.NoError(t, err)
+					assert.Equal(t, expected, scan, "Scan(%q)", key)
+				}
+			})
+
+			t.Run("Update creates and updates the value", func(t *testing.T) {
+				ctx := context.Background()
+				c := newCache(t)
+
+				increment := func() {
+					var v conformanceValue
+					require.NoError(t, Update(ctx, c, "foo", &v, func() error {
+						v.Identifier = "foo"
+						v.Version++
+						return nil
+					}))
+				}
+
+				increment()
+				increment()
+
+				var v conformanceValue
+				require.NoError(t, c.Get(ctx, "foo", &v))
+				assert.Equal(t, conformanceValue{Identifier: "foo", Version: 2}, v)
+			})
+
+			t.Run("Update doesn't set the value if the update errors", func(t *testing.T) {
+				ctx := context.Background()
+				c := newCache(t)
+
+				require.NoError(t, c.Set(ctx, "foo", conformanceValue{Identifier: "foo", Version: 1}))
+
+				updateErr := errors.New("update failed")
+
+				var v conformanceValue
+				err := Update(ctx, c, "foo", &v, func() error {
+					v.Version++
+					return updateErr
+				})
+				assert.True(t, errors.Is(err, updateErr), "expected update error, got %v", err)
+
+				require.NoError(t, c.Get(ctx, "foo", &v))
+				assert.Equal(t, conformanceValue{Identifier: "foo", Version: 1}, v)
+			})
+
+			t.Run("HealthCheck doesn't error", func(t *testing.T) {
+				c := newCache(t)
+				assert.NoError(t, c.HealthCheck(context.Background()))
+			})
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package cache
+
+// globMatch reports whether s matches the pattern using the same rules redis uses
+// for KEYS and SCAN MATCH patterns. It supports '*', '?', character classes like
+// '[abc]', '[^abc]' and '[a-z]', and '\' to escape special characters. Unlike
+// path.Match, '*' also matches '/'.
+func globMatch(pattern string, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// Consecutive stars behave the same as a single one
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if globMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			pattern = pattern[1:]
+			s = s[1:]
+
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			var ok bool
+			ok, pattern = matchClass(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+			s = s[1:]
+
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			pattern = pattern[1:]
+			s = s[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass checks if c matches the character class at the start of the pattern.
+// The pattern should start after the opening '[' and it returns the rest of the
+// pattern after the closing ']'.
+func matchClass(pattern string, c byte) (bool, string) {
+	negate := false
+	if len(pattern) > 0 && pattern[0] == '^' {
+		negate = true
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			if pattern[1] == c {
+				matched = true
+			}
+			pattern = pattern[2:]
+
+		case len(pattern) >= 3 && pattern[1] == '-':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			pattern = pattern[3:]
+
+		default:
+			if pattern[0] == c {
+				matched = true
+			}
+			pattern = pattern[1:]
+		}
+	}
+
+	// Skip over the closing ']', redis treats an unterminated class as if
+	// it was closed at the end of the pattern
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+
+	if negate {
+		matched = !matched
+	}
+	return matched, pattern
+}
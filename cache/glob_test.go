@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobMatch(t *testing.T) {
+	testCases := map[string]struct {
+		pattern  string
+		s        string
+		expected bool
+	}{
+		"Given I have an exact match": {
+			pattern:  "env-123-segments",
+			s:        "env-123-segments",
+			expected: true,
+		},
+		"Given I have a pattern that's a prefix of the string": {
+			pattern:  "env-123",
+			s:        "env-123-segments",
+			expected: false,
+		},
+		"Given I have a star in the middle of the pattern": {
+			pattern:  "env-*-api-configs",
+			s:        "env-123-api-configs",
+			expected: true,
+		},
+		"Given I have a star that needs to match a slash": {
+			pattern:  "*foo*",
+			s:        "a/foo/b",
+			expected: true,
+		},
+		"Given I have consecutive stars": {
+			pattern:  "env-**-segments",
+			s:        "env-123-segments",
+			expected: true,
+		},
+		"Given I have a question mark": {
+			pattern:  "env-12?",
+			s:        "env-123",
+			expected: true,
+		},
+		"Given I have a question mark and the string is too short": {
+			pattern:  "env-12?",
+			s:        "env-12",
+			expected: false,
+		},
+		"Given I have a character class that matches": {
+			pattern:  "env-[123]",
+			s:        "env-2",
+			expected: true,
+		},
+		"Given I have a negated character class that matches": {
+			pattern:  "env-[^123]",
+			s:        "env-2",
+			expected: false,
+		},
+		"Given I have a character range": {
+			pattern:  "env-[a-c]",
+			s:        "env-b",
+			expected: true,
+		},
+		"Given I have a reversed character range": {
+			pattern:  "env-[c-a]",
+			s:        "env-b",
+			expected: true,
+		},
+		"Given I have an escaped star": {
+			pattern:  `env-\*`,
+			s:        "env-123",
+			expected: false,
+		},
+		"Given I have an escaped star that matches a literal star": {
+			pattern:  `env-\*`,
+			s:        "env-*",
+			expected: true,
+		},
+		"Given I have an empty pattern": {
+			pattern:  "",
+			s:        "env-123",
+			expected: false,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, globMatch(tc.pattern, tc.s))
+		})
+	}
+}
@@ -11,6 +11,8 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	gocache "github.com/patrickmn/go-cache"
 	"golang.org/x/sync/singleflight"
+
+	"github.com/harness/ff-proxy/v2/log"
 )
 
 // HashCache ...
@@ -18,15 +20,81 @@ type HashCache struct {
 	Cache
 	localCache   internalCache
 	requestGroup *singleflight.Group
+	invalidator  Invalidator
+	latest       *latestHashes
+}
+
+// HashCacheOption defines optional parameters for configuring a HashCache
+type HashCacheOption func(hc *HashCache)
+
+// WithInvalidator makes the HashCache publish an Invalidation whenever it writes
+// or deletes a latest hash. If ListenForInvalidations is running the HashCache
+// also keeps track of the latest hashes published by other Proxies so it can
+// serve hot reads from its local cache without looking up the latest hash.
+func WithInvalidator(inv Invalidator) HashCacheOption {
+	return func(hc *HashCache) {
+		hc.invalidator = inv
+		hc.latest = newLatestHashes()
+	}
+}
+
+// NewLatestHashKey returns the key that the HashCache stores the latest hash
+// of the value for the given key under
+func NewLatestHashKey(key string) string {
+	return fmt.Sprintf("%s-latest", key)
 }
 
 // NewHashCache ...
-func NewHashCache(c Cache, defaultExpiration, cleanupInterval time.Duration) *HashCache {
-	return &HashCache{
+func NewHashCache(c Cache, defaultExpiration, cleanupInterval time.Duration, opts ...HashCacheOption) *HashCache {
+	hc := &HashCache{
 		Cache:        c,
 		localCache:   gocache.New(defaultExpiration, cleanupInterval),
 		requestGroup: &singleflight.Group{},
 	}
+
+	for _, opt := range opts {
+		opt(hc)
+	}
+	return hc
+}
+
+// ListenForInvalidations subscribes to invalidations published by other Proxies
+// and keeps track of the latest hash for each key. If the subscription fails we
+// forget every hash we know about, because we may have missed invalidations,
+// and fall back to looking up the latest hash on every read until we've
+// resubscribed. It blocks until the context is cancelled.
+func (hc *HashCache) ListenForInvalidations(ctx context.Context, logger log.Logger) {
+	if hc.invalidator == nil {
+		return
+	}
+
+	onSubscribe := func() {
+		hc.latest.reset(true)
+		logger.Info("subscribed to cache invalidations")
+	}
+
+	onInvalidation := func(inv Invalidation) {
+		if old, ok := hc.latest.get(inv.Key); ok && old != inv.Hash {
+			hc.localCache.Delete(old)
+		}
+		hc.latest.set(inv.Key, inv.Hash)
+	}
+
+	for {
+		err := hc.invalidator.Subscribe(ctx, onSubscribe, onInvalidation)
+		hc.latest.reset(false)
+
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Warn("cache invalidation subscription failed, retrying", "err", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(1 * time.Second):
+		}
+	}
 }
 
 // Set adds hash key entry for the given key
@@ -38,11 +106,11 @@ func (hc HashCache) Set(ctx context.Context, key string, value interface{}) erro
 
 	// If the key isn't a segments or feature-configs key then we're done.
 	// If it is then we'll want to carry on and set a latest hash for these keys
-	if !strings.HasSuffix(key, "segments") && !strings.HasSuffix(key, "feature-configs") {
+	if !hasLatestHash(key) {
 		return nil
 	}
 
-	latestKey := fmt.Sprintf("%s-latest", key)
+	latestKey := NewLatestHashKey(key)
 	v, err := jsoniter.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("unable to marshall config %s %v", latestKey, err)
@@ -50,10 +118,20 @@ func (hc HashCache) Set(ctx context.Context, key string, value interface{}) erro
 	latestHash := sha256.Sum256(v)
 	latestHashString := fmt.Sprintf("%x", latestHash)
 
-	return hc.Cache.Set(ctx, latestKey, latestHashString)
+	if err := hc.Cache.Set(ctx, latestKey, latestHashString); err != nil {
+		return err
+	}
+
+	return hc.publish(ctx, Invalidation{Key: key, Hash: latestHashString})
 }
 
 func (hc HashCache) Get(ctx context.Context, key string, value interface{}) error {
+	// Latest hashes don't have hashes of their own so we read them straight
+	// from the underlying cache
+	if isLatestHashKey(key) {
+		return hc.Cache.Get(ctx, key, value)
+	}
+
 	data, err, _ := hc.requestGroup.Do(key, func() (interface{}, error) {
 		return hc.get(ctx, key, value)
 	})
@@ -73,11 +151,20 @@ func (hc HashCache) Get(ctx context.Context, key string, value interface{}) erro
 
 // Get checks the local cache for the key and returns it if there.
 func (hc HashCache) get(ctx context.Context, key string, value interface{}) (interface{}, error) {
-	latestKey := fmt.Sprintf("%s-latest", key)
+	// If we're subscribed to invalidations and already know the latest hash
+	// we can skip looking it up
+	if hash, ok := hc.latest.get(key); ok {
+		if data, ok := hc.localCache.Get(hash); ok {
+			return data, nil
+		}
+	}
+
+	latestKey := NewLatestHashKey(key)
 
 	var hash string
 	err := hc.Cache.Get(ctx, latestKey, &hash)
 	if err == nil {
+		hc.latest.setIfAbsent(key, hash)
 		if data, ok := hc.localCache.Get(hash); ok {
 			return data, nil
 		}
@@ -98,7 +185,7 @@ func (hc HashCache) get(ctx context.Context, key string, value interface{}) (int
 // Delete key from local cache as well as hash entry in the redis
 func (hc HashCache) Delete(ctx context.Context, key string) error {
 
-	latestKey := fmt.Sprintf("%s-latest", key)
+	latestKey := NewLatestHashKey(key)
 	var hash string
 	err := hc.Cache.Get(ctx, latestKey, &hash)
 	if err == nil {
@@ -109,5 +196,36 @@ func (hc HashCache) Delete(ctx context.Context, key string) error {
 	if err != nil {
 		return err
 	}
-	return hc.Cache.Delete(ctx, key)
+
+	if err := hc.Cache.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	if !hasLatestHash(key) {
+		return nil
+	}
+	return hc.publish(ctx, Invalidation{Key: key})
+}
+
+// hasLatestHash returns true if the HashCache stores a latest hash for the key
+func hasLatestHash(key string) bool {
+	return strings.HasSuffix(key, "segments") || strings.HasSuffix(key, "feature-configs")
+}
+
+// isLatestHashKey returns true if the key is one that the HashCache stores a latest hash under
+func isLatestHashKey(key string) bool {
+	k, ok := strings.CutSuffix(key, "-latest")
+	return ok && hasLatestHash(k)
+}
+
+// publish sends an invalidation if the HashCache has an Invalidator
+func (hc HashCache) publish(ctx context.Context, inv Invalidation) error {
+	if hc.invalidator == nil {
+		return nil
+	}
+
+	if err := hc.invalidator.Publish(ctx, inv); err != nil {
+		return fmt.Errorf("HashCache failed to publish invalidation: %w", err)
+	}
+	return nil
 }
@@ -306,3 +306,32 @@ func TestHashCache_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestHashCache_GetLatestHash(t *testing.T) {
+	ctx := context.Background()
+
+	mc := &mCache{
+		set: func(d map[string]interface{}, key string, value interface{}) error {
+			d[key] = value
+			return nil
+		},
+	}
+	localCache := &mockLocalCache{data: make(map[string]interface{})}
+
+	hc := HashCache{
+		Cache:        mc,
+		localCache:   localCache,
+		requestGroup: &singleflight.Group{},
+	}
+
+	key := string(domain.NewSegmentsKey("123"))
+	assert.Nil(t, hc.Set(ctx, key, domain.Segment{Identifier: "foo"}))
+
+	var hash string
+	assert.Nil(t, hc.Get(ctx, NewLatestHashKey(key), &hash))
+
+	assert.Equal(t, "6f20db82fb80d9b6dec1e1ef68cf010f2b7bb6f2963d8cf2d42961eb6acbc5ec", hash)
+	assert.Equal(t, 1, mc.latestKeyHits)
+	assert.Equal(t, 0, mc.fullDocumentKeyHits)
+	assert.Equal(t, 0, localCache.getHits)
+}
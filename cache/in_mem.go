@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"reflect"
-	"strings"
 	"sync"
 
 	jsoniter "github.com/json-iterator/go"
@@ -19,10 +18,21 @@ type MemCache struct {
 	data map[string][]byte
 }
 
-// Scan all the keys for given key
-func (m MemCache) Scan(_ context.Context, _ string) (map[string]string, error) {
-	//TODO implement equivalent.
-	return map[string]string{}, nil
+// Scan returns a map of all the keys that contain the given key. Like the
+// KeyValCache it only returns the keys and not their values.
+func (m MemCache) Scan(_ context.Context, key string) (map[string]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	pattern := "*" + key + "*"
+
+	scan := make(map[string]string)
+	for k := range m.data {
+		if globMatch(pattern, k) {
+			scan[k] = ""
+		}
+	}
+	return scan, nil
 }
 
 // NewMemCache creates an initialised MemCache
@@ -60,6 +70,32 @@ func (m MemCache) Get(_ context.Context, key string, v interface{}) error {
 	return nil
 }
 
+// Update makes MemCache implement the Updater interface. The cache is locked
+// for the whole update so concurrent updates can't overwrite each other.
+func (m MemCache) Update(_ context.Context, key string, value interface{}, update func() error) error {
+	m.Lock()
+	defer m.Unlock()
+
+	resetValue(value)
+	if b, ok := m.data[key]; ok {
+		if err := jsoniter.Unmarshal(b, value); err != nil {
+			return fmt.Errorf("%v: failed to unmarshal value to %T for key: %q", domain.ErrCacheInternal, value, key)
+		}
+	}
+
+	if err := update(); err != nil {
+		return err
+	}
+
+	b, err := jsoniter.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	m.data[key] = b
+	return nil
+}
+
 // Delete removes all of the fields and their values for a given key
 func (m MemCache) Delete(_ context.Context, key string) error {
 	m.Lock()
@@ -71,15 +107,15 @@ func (m MemCache) Delete(_ context.Context, key string) error {
 
 // Keys returns a list of keys that match the pattern
 func (m MemCache) Keys(_ context.Context, key string) ([]string, error) {
-	m.Lock()
-	defer m.Unlock()
+	m.RLock()
+	defer m.RUnlock()
 
 	keys := reflect.ValueOf(m.data).MapKeys()
 
 	results := make([]string, 0, len(keys))
 	for _, k := range keys {
 		s := k.String()
-		if strings.HasPrefix(s, key) {
+		if globMatch(key, s) {
 			results = append(results, s)
 		}
 	}
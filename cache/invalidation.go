@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/redis/go-redis/v9"
+)
+
+// Invalidation is published whenever a HashCache writes or deletes the latest
+// hash for a key. Hash is empty if the key was deleted.
+type Invalidation struct {
+	Key  string `json:"key"`
+	Hash string `json:"hash"`
+}
+
+// Invalidator publishes and subscribes to Invalidations so that the HashCaches in
+// every Proxy sharing a cache can keep their local copies in sync
+type Invalidator interface {
+	// Publish sends an invalidation to every subscriber
+	Publish(ctx context.Context, inv Invalidation) error
+
+	// Subscribe calls onSubscribe once the subscription has been established and
+	// fn for each invalidation that's received. It blocks until the context is
+	// cancelled or the subscription fails.
+	Subscribe(ctx context.Context, onSubscribe func(), fn func(Invalidation)) error
+}
+
+// RedisInvalidator is an Invalidator that uses redis pub/sub
+type RedisInvalidator struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+// NewRedisInvalidator creates a RedisInvalidator that publishes and subscribes
+// to the given channel
+func NewRedisInvalidator(client redis.UniversalClient, channel string) RedisInvalidator {
+	return RedisInvalidator{client: client, channel: channel}
+}
+
+// Publish makes RedisInvalidator implement the Invalidator interface
+func (r RedisInvalidator) Publish(ctx context.Context, inv Invalidation) error {
+	b, err := jsoniter.Marshal(inv)
+	if err != nil {
+		return err
+	}
+
+	if err := r.client.Publish(ctx, r.channel, b).Err(); err != nil {
+		return fmt.Errorf("failed to publish invalidation for key %q: %w", inv.Key, err)
+	}
+	return nil
+}
+
+// Subscribe makes RedisInvalidator implement the Invalidator interface. It
+// returns as soon as there's an error receiving from redis rather than letting
+// the redis client reconnect because any invalidations published while we were
+// disconnected will have been missed.
+func (r RedisInvalidator) Subscribe(ctx context.Context, onSubscribe func(), fn func(Invalidation)) error {
+	pubsub := r.client.Subscribe(ctx, r.channel)
+	defer pubsub.Close()
+
+	// Receive doesn't return when the context is cancelled while it's waiting
+	// for a message so we close the subscription to unblock it
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = pubsub.Close()
+		case <-stop:
+		}
+	}()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind == "subscribe" {
+				onSubscribe()
+			}
+		case *redis.Message:
+			var inv Invalidation
+			if err := jsoniter.Unmarshal([]byte(m.Payload), &inv); err != nil {
+				continue
+			}
+			fn(inv)
+		}
+	}
+}
+
+// latestHashes tracks the latest hash of keys that the HashCache has learnt
+// about while subscribed to invalidations. As long as the subscription is
+// healthy these are up to date so the HashCache doesn't need to look them up.
+type latestHashes struct {
+	mu      sync.RWMutex
+	enabled bool
+	hashes  map[string]string
+}
+
+func newLatestHashes() *latestHashes {
+	return &latestHashes{hashes: map[string]string{}}
+}
+
+// get returns the latest hash for the key if we know it
+func (l *latestHashes) get(key string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if !l.enabled {
+		return "", false
+	}
+
+	hash, ok := l.hashes[key]
+	return hash, ok && hash != ""
+}
+
+// set records the hash from an invalidation, overwriting anything we already have
+func (l *latestHashes) set(key string, hash string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.enabled {
+		l.hashes[key] = hash
+	}
+}
+
+// setIfAbsent records a hash that was read from the cache. It doesn't overwrite
+// existing hashes because an invalidation for a newer hash may have been received
+// between reading the hash and calling setIfAbsent.
+func (l *latestHashes) setIfAbsent(key string, hash string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.hashes[key]; l.enabled && !ok {
+		l.hashes[key] = hash
+	}
+}
+
+// reset forgets every hash and enables or disables tracking. It's called whenever
+// the subscription starts or stops because we can't know what we missed.
+func (l *latestHashes) reset(enabled bool) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.enabled = enabled
+	l.hashes = map[string]string{}
+}
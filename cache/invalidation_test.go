@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+// latestCountingCache counts the number of times a latest hash is read
+type latestCountingCache struct {
+	Cache
+	latestGets int64
+}
+
+func (l *latestCountingCache) Get(ctx context.Context, key string, value interface{}) error {
+	if strings.HasSuffix(key, "-latest") {
+		atomic.AddInt64(&l.latestGets, 1)
+	}
+	return l.Cache.Get(ctx, key, value)
+}
+
+func (l *latestCountingCache) reset() {
+	atomic.StoreInt64(&l.latestGets, 0)
+}
+
+func TestHashCache_Invalidations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	invalidator := NewRedisInvalidator(client, "invalidations")
+
+	replicaCache := &latestCountingCache{Cache: NewKeyValCache(client)}
+
+	primary := NewHashCache(NewKeyValCache(client), 1*time.Minute, 2*time.Minute, WithInvalidator(invalidator))
+	replica := NewHashCache(replicaCache, 1*time.Minute, 2*time.Minute, WithInvalidator(invalidator))
+
+	go replica.ListenForInvalidations(ctx, log.NewNoOpLogger())
+
+	assert.Eventually(t, func() bool {
+		replica.latest.mu.RLock()
+		defer replica.latest.mu.RUnlock()
+		return replica.latest.enabled
+	}, 2*time.Second, 10*time.Millisecond)
+
+	key := string(domain.NewFeatureConfigsKey("123"))
+	waitForHash := func(t *testing.T, expected bool) string {
+		var hash string
+		assert.Eventually(t, func() bool {
+			var ok bool
+			hash, ok = replica.latest.get(key)
+			return ok == expected
+		}, 2*time.Second, 10*time.Millisecond)
+		return hash
+	}
+
+	require.NoError(t, primary.Set(ctx, key, []conformanceValue{{Identifier: "foo", Version: 1}}))
+	firstHash := waitForHash(t, true)
+
+	var v []conformanceValue
+	require.NoError(t, replica.Get(ctx, key, &v))
+	assert.Equal(t, []conformanceValue{{Identifier: "foo", Version: 1}}, v)
+
+	// Now that the value is in the replica's local cache it shouldn't need to
+	// look up the latest hash to serve it
+	replicaCache.reset()
+	require.NoError(t, replica.Get(ctx, key, &v))
+	assert.Equal(t, []conformanceValue{{Identifier: "foo", Version: 1}}, v)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&replicaCache.latestGets))
+
+	// When the primary writes a new value the replica should pick it up straight away
+	require.NoError(t, primary.Set(ctx, key, []conformanceValue{{Identifier: "foo", Version: 2}}))
+	assert.Eventually(t, func() bool {
+		hash, ok := replica.latest.get(key)
+		return ok && hash != firstHash
+	}, 2*time.Second, 10*time.Millisecond)
+
+	var updated []conformanceValue
+	require.NoError(t, replica.Get(ctx, key, &updated))
+	assert.Equal(t, []conformanceValue{{Identifier: "foo", Version: 2}}, updated)
+
+	// Deleting the key means the replica no longer knows its hash
+	require.NoError(t, primary.Delete(ctx, key))
+	waitForHash(t, false)
+}
+
+func TestHashCache_InvalidationsStopWhenSubscriptionEnds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	hc := NewHashCache(NewKeyValCache(client), 1*time.Minute, 2*time.Minute, WithInvalidator(NewRedisInvalidator(client, "invalidations")))
+
+	done := make(chan struct{})
+	go func() {
+		hc.ListenForInvalidations(ctx, log.NewNoOpLogger())
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		hc.latest.mu.RLock()
+		defer hc.latest.mu.RUnlock()
+		return hc.latest.enabled
+	}, 2*time.Second, 10*time.Millisecond)
+
+	hc.latest.set("foo", "bar")
+	_, ok := hc.latest.get("foo")
+	assert.True(t, ok)
+
+	cancel()
+	<-done
+
+	// Once we've stopped listening we can't trust the hashes we know about
+	_, ok = hc.latest.get("foo")
+	assert.False(t, ok)
+}
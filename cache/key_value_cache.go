@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/go-redis/cache/v8"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/redis/go-redis/v9"
 
+	"github.com/harness/ff-proxy/v2/codec"
 	"github.com/harness/ff-proxy/v2/domain"
 )
 
@@ -64,7 +66,7 @@ func NewKeyValCache(rc redis.UniversalClient, opts ...Options) *KeyValCache {
 		ttl:         0,
 		localCache:  nil,
 		marshalFn:   jsoniter.Marshal,
-		unmarshalFn: jsoniter.Unmarshal,
+		unmarshalFn: codec.Unmarshal,
 	}
 
 	for _, opt := range opts {
@@ -144,3 +146,61 @@ func (k *KeyValCache) Scan(ctx context.Context, key string) (map[string]string,
 	}
 	return scan, nil
 }
+
+// maxUpdateAttempts is the number of times KeyValCache.Update retries when the
+// key is changed by someone else while it's being updated
+const maxUpdateAttempts = 10
+
+// maxUpdateRetryWait is the longest KeyValCache.Update waits before retrying
+const maxUpdateRetryWait = 10 * time.Millisecond
+
+// Update makes KeyValCache implement the Updater interface. It watches the key
+// while the value is updated so the write fails, and is retried, if something
+// else changes the key in the meantime.
+func (k *KeyValCache) Update(ctx context.Context, key string, value interface{}, update func() error) error {
+	txf := func(tx *redis.Tx) error {
+		resetValue(value)
+
+		b, err := tx.Get(ctx, key).Bytes()
+		if err != nil && err != redis.Nil {
+			return fmt.Errorf("%w: KeyValCache.Update failed to get key: %q", err, key)
+		}
+
+		if err == nil {
+			if err := k.unmarshalFn(b, value); err != nil {
+				return err
+			}
+		}
+
+		if err := update(); err != nil {
+			return err
+		}
+
+		v, err := k.marshalFn(value)
+		if err != nil {
+			return fmt.Errorf("%w: KeyValCache.Update failed to marshal value", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, v, k.ttl)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < maxUpdateAttempts; i++ {
+		err := k.redisClient.Watch(ctx, txf, key)
+		if err != redis.TxFailedErr {
+			return err
+		}
+
+		// Wait a random amount of time before retrying so that Proxies updating
+		// the same key don't keep failing in lockstep
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(rand.Int63n(int64(maxUpdateRetryWait)))):
+		}
+	}
+	return fmt.Errorf("%w: KeyValCache.Update gave up after %d attempts for key: %q", redis.TxFailedErr, maxUpdateAttempts, key)
+}
@@ -3,8 +3,12 @@ package cache
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
 	"github.com/harness/ff-proxy/v2/domain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -163,3 +167,41 @@ func TestKeyValCache_Keys(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyValCache_Update(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+
+	// Each cache has its own client to act like separate Proxies sharing the same redis
+	caches := []*KeyValCache{
+		NewKeyValCache(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+		NewKeyValCache(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	}
+
+	const updatesPerCache = 20
+
+	var wg sync.WaitGroup
+	for _, c := range caches {
+		c := c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updatesPerCache; i++ {
+				var counts map[string]int
+				err := c.Update(ctx, "counts", &counts, func() error {
+					if counts == nil {
+						counts = map[string]int{}
+					}
+					counts["foo"]++
+					return nil
+				})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var counts map[string]int
+	require.NoError(t, caches[0].Get(ctx, "counts", &counts))
+	assert.Equal(t, map[string]int{"foo": len(caches) * updatesPerCache}, counts)
+}
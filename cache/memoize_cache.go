@@ -1,12 +1,13 @@
 package cache
 
 import (
+	"context"
 	"crypto/md5" //#nosec G501
 	"fmt"
 	"reflect"
 	"time"
 
-	jsoniter "github.com/json-iterator/go"
+	gocodec "github.com/go-redis/cache/v8"
 	gocache "github.com/patrickmn/go-cache"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/go-redis/v9"
@@ -35,31 +36,52 @@ type internalCache interface {
 
 type memoizeCache struct {
 	Cache
-	metrics memoizeMetrics
+	// base encodes and decodes values without memoizing them, it's used for
+	// updates so that the value being updated isn't shared with other callers
+	base        *KeyValCache
+	metrics     memoizeMetrics
+	marshalFn   gocodec.MarshalFunc
+	unmarshalFn gocodec.UnmarshalFunc
 }
 
-// NewMemoizeCache creates a memoize cache
-func NewMemoizeCache(rc redis.UniversalClient, defaultExpiration, cleanupInterval time.Duration, metrics memoizeMetrics) Cache {
-	mc := memoizeCache{}
+// NewMemoizeCache creates a memoize cache. The options are applied to the underlying
+// KeyValCache, if WithMarshalFunc or WithUnmarshalFunc are passed the memoize cache
+// uses them to encode and decode values instead of json.
+func NewMemoizeCache(rc redis.UniversalClient, defaultExpiration, cleanupInterval time.Duration, metrics memoizeMetrics, opts ...Options) Cache {
 	c := gocache.New(defaultExpiration, cleanupInterval)
 
 	if metrics == nil {
 		metrics = noOpMetrics{}
 	}
-	mc.metrics = metrics
 
-	mc.Cache = NewKeyValCache(rc,
-		WithTTL(0),
+	// Apply the options to a KeyValCache first so we pick up the same default
+	// marshal funcs it uses if none were passed
+	base := NewKeyValCache(rc, opts...)
+	mc := memoizeCache{
+		base:        base,
+		metrics:     metrics,
+		marshalFn:   base.marshalFn,
+		unmarshalFn: base.unmarshalFn,
+	}
+
+	kvOpts := append([]Options{WithTTL(0)}, opts...)
+	kvOpts = append(kvOpts,
 		WithMarshalFunc(mc.makeMarshalFunc(c)),
 		WithUnmarshalFunc(mc.makeUnmarshalFunc(c)),
 	)
+	mc.Cache = NewKeyValCache(rc, kvOpts...)
 
 	return mc
 }
 
+// Update makes memoizeCache implement the Updater interface
+func (m memoizeCache) Update(ctx context.Context, key string, value interface{}, update func() error) error {
+	return m.base.Update(ctx, key, value, update)
+}
+
 func (m memoizeCache) makeMarshalFunc(ffCache internalCache) func(interface{}) ([]byte, error) {
 	return func(i interface{}) ([]byte, error) {
-		data, err := jsoniter.Marshal(i)
+		data, err := m.marshalFn(i)
 		if err != nil {
 			return nil, err
 		}
@@ -85,7 +107,7 @@ func (m memoizeCache) makeUnmarshalFunc(ffCache internalCache) func([]byte, inte
 			val := reflect.ValueOf(i)
 			if val.Kind() != reflect.Ptr {
 				m.metrics.cacheHitWithUnmarshalInc()
-				return jsoniter.Unmarshal(bytes, &i)
+				return m.unmarshalFn(bytes, &i)
 			}
 
 			// We got a hit for the bytes in our memoize cache so can return them
@@ -104,7 +126,7 @@ func (m memoizeCache) makeUnmarshalFunc(ffCache internalCache) func([]byte, inte
 		// The raw bytes weren't in the memoize cache so we increment our cache
 		// readMiss counters and have to perform a full unmarshal
 		m.metrics.cacheMissInc()
-		err := jsoniter.Unmarshal(bytes, &i)
+		err := m.unmarshalFn(bytes, &i)
 		if err != nil {
 			return err
 		}
@@ -144,9 +144,12 @@ func TestMemoizeCache_makeUnmarshalFunc(t *testing.T) {
 		tc := tc
 
 		t.Run(desc, func(t *testing.T) {
+			kv := NewKeyValCache(nil)
 			c := memoizeCache{
-				Cache:   setupTestKeyValCache(),
-				metrics: tc.mocks.metrics,
+				Cache:       setupTestKeyValCache(),
+				metrics:     tc.mocks.metrics,
+				marshalFn:   kv.marshalFn,
+				unmarshalFn: kv.unmarshalFn,
 			}
 
 			if tc.cacheData.value != nil {
@@ -156,6 +156,18 @@ func (c MetricsCache) Delete(ctx context.Context, key string) (err error) {
 	return c.next.Delete(ctx, key)
 }
 
+// Update makes MetricsCache implement the Updater interface. It calls the decorated cache's Update
+// method and tracks it with the same counter and histogram as a Set.
+func (c MetricsCache) Update(ctx context.Context, key string, value interface{}, update func() error) (err error) {
+	start := time.Now()
+	defer func() {
+		trackHistogram(start, c.writeDuration)
+		trackCounter(c.writeCount, key, getErrorLabel(err))
+	}()
+
+	return Update(ctx, c.next, key, value, update)
+}
+
 // Keys makes MetricsCache implement the Cache interface. It calls the decorated cache's Keys method
 // and returns the results. It doesn't record any prometheus metrics.
 func (c MetricsCache) Keys(ctx context.Context, key string) ([]string, error) {
@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+// NamespaceCache is a decorator for a Cache that prefixes every key with a
+// namespace so that multiple Proxy deployments can share the same redis
+type NamespaceCache struct {
+	next      Cache
+	namespace string
+	prefix    string
+}
+
+// NewNamespaceCache creates a NamespaceCache. The namespace should be validated
+// with domain.ValidateNamespace before it's passed in.
+func NewNamespaceCache(namespace string, next Cache) NamespaceCache {
+	return NamespaceCache{
+		next:      next,
+		namespace: namespace,
+		prefix:    domain.NewNamespacedKey(namespace, ""),
+	}
+}
+
+// Set makes NamespaceCache implement the Cache interface
+func (n NamespaceCache) Set(ctx context.Context, key string, value interface{}) error {
+	return n.next.Set(ctx, domain.NewNamespacedKey(n.namespace, key), value)
+}
+
+// Get makes NamespaceCache implement the Cache interface
+func (n NamespaceCache) Get(ctx context.Context, key string, value interface{}) error {
+	return n.next.Get(ctx, domain.NewNamespacedKey(n.namespace, key), value)
+}
+
+// Delete makes NamespaceCache implement the Cache interface
+func (n NamespaceCache) Delete(ctx context.Context, key string) error {
+	return n.next.Delete(ctx, domain.NewNamespacedKey(n.namespace, key))
+}
+
+// Keys makes NamespaceCache implement the Cache interface. The namespace is
+// removed from the keys that are returned.
+func (n NamespaceCache) Keys(ctx context.Context, key string) ([]string, error) {
+	keys, err := n.next.Keys(ctx, domain.NewNamespacedKey(n.namespace, key))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]string, 0, len(keys))
+	for _, k := range keys {
+		results = append(results, strings.TrimPrefix(k, n.prefix))
+	}
+	return results, nil
+}
+
+// Update makes NamespaceCache implement the Updater interface
+func (n NamespaceCache) Update(ctx context.Context, key string, value interface{}, update func() error) error {
+	return Update(ctx, n.next, domain.NewNamespacedKey(n.namespace, key), value, update)
+}
+
+// HealthCheck makes NamespaceCache implement the Cache interface
+func (n NamespaceCache) HealthCheck(ctx context.Context) error {
+	return n.next.HealthCheck(ctx)
+}
+
+// Scan makes NamespaceCache implement the Cache interface. The decorated cache
+// matches the key anywhere in the full key so we have to filter out any keys from
+// other namespaces, or that only matched because of the namespace, ourselves.
+func (n NamespaceCache) Scan(ctx context.Context, key string) (map[string]string, error) {
+	scan, err := n.next.Scan(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := "*" + key + "*"
+
+	results := make(map[string]string, len(scan))
+	for k, v := range scan {
+		unprefixed, ok := strings.CutPrefix(k, n.prefix)
+		if !ok || !globMatch(pattern, unprefixed) {
+			continue
+		}
+		results[unprefixed] = v
+	}
+	return results, nil
+}
+
+// MigrateNamespace moves every key in redis that matches one of the patterns
+// under the namespace. The patterns should match keys that were written before
+// the namespace was configured, e.g. env-*. Keys that already exist in the
+// namespace are left alone and returned as skipped.
+func MigrateNamespace(ctx context.Context, client redis.UniversalClient, namespace string, patterns []string) (int, []string, error) {
+	return MigrateKeys(ctx, client, patterns, func(key string) string {
+		return domain.NewNamespacedKey(namespace, key)
+	})
+}
+
+// MigrateKeys moves every key in redis that matches one of the patterns to the
+// key that rename returns for it. Keys whose new key already exists are left
+// alone and returned as skipped.
+func MigrateKeys(ctx context.Context, client redis.UniversalClient, patterns []string, rename func(key string) string) (int, []string, error) {
+	keys, err := scanKeys(ctx, client, patterns)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	moved := 0
+	skipped := []string{}
+	for _, key := range keys {
+		ok, err := moveKey(ctx, client, key, rename(key))
+		if err != nil {
+			return moved, skipped, err
+		}
+
+		if !ok {
+			skipped = append(skipped, key)
+			continue
+		}
+		moved++
+	}
+
+	return moved, skipped, nil
+}
+
+// scanKeys returns all of the keys in redis that match the patterns. If we're
+// connected to a cluster we have to scan each of the masters individually.
+func scanKeys(ctx context.Context, client redis.UniversalClient, patterns []string) ([]string, error) {
+	seen := map[string]struct{}{}
+
+	scan := func(ctx context.Context, c redis.UniversalClient) error {
+		for _, pattern := range patterns {
+			iter := c.Scan(ctx, 0, pattern, 100).Iterator()
+			for iter.Next(ctx) {
+				seen[iter.Val()] = struct{}{}
+			}
+			if err := iter.Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if cc, ok := client.(*redis.ClusterClient); ok {
+		err = cc.ForEachMaster(ctx, func(ctx context.Context, c *redis.Client) error {
+			return scan(ctx, c)
+		})
+	} else {
+		err = scan(ctx, client)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// moveKey renames src to dst if dst doesn't already exist. It returns false if
+// dst already exists.
+func moveKey(ctx context.Context, client redis.UniversalClient, src string, dst string) (bool, error) {
+	if _, ok := client.(*redis.ClusterClient); !ok {
+		return client.RenameNX(ctx, src, dst).Result()
+	}
+
+	// In a cluster the two keys can hash to different slots which means we can't
+	// rename them, so instead we copy the value over and delete the original
+	dump, err := client.Dump(ctx, src).Result()
+	if err != nil {
+		return false, err
+	}
+
+	ttl, err := client.PTTL(ctx, src).Result()
+	if err != nil {
+		return false, err
+	}
+
+	// A negative TTL means the key doesn't expire which RESTORE expects as 0
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	if err := client.Restore(ctx, dst, ttl, dump).Err(); err != nil {
+		if strings.HasPrefix(err.Error(), "BUSYKEY") {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, client.Del(ctx, src).Err()
+}
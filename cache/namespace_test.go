@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceCache_IsolatesNamespaces(t *testing.T) {
+	ctx := context.Background()
+
+	mr := miniredis.RunT(t)
+	kv := NewKeyValCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+
+	staging := NewNamespaceCache("staging", kv)
+	prod := NewNamespaceCache("prod", kv)
+
+	require.NoError(t, staging.Set(ctx, "env-123-api-configs", "staging"))
+	require.NoError(t, prod.Set(ctx, "env-123-api-configs", "prod"))
+	require.NoError(t, prod.Set(ctx, "auth-key-staging", "prod"))
+
+	assert.ElementsMatch(t, []string{"staging:env-123-api-configs", "prod:env-123-api-configs", "prod:auth-key-staging"}, mr.Keys())
+
+	var v string
+	require.NoError(t, staging.Get(ctx, "env-123-api-configs", &v))
+	assert.Equal(t, "staging", v)
+
+	keys, err := staging.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"env-123-api-configs"}, keys)
+
+	// Scan shouldn't return keys from other namespaces or keys that only match
+	// because of the namespace
+	scan, err := staging.Scan(ctx, "staging")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{}, scan)
+
+	scan, err = prod.Scan(ctx, "staging")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"auth-key-staging": ""}, scan)
+
+	require.NoError(t, staging.Delete(ctx, "env-123-api-configs"))
+	require.NoError(t, prod.Get(ctx, "env-123-api-configs", &v))
+	assert.Equal(t, "prod", v)
+}
+
+func TestMigrateNamespace(t *testing.T) {
+	ctx := context.Background()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	require.NoError(t, mr.Set("env-123-api-configs", "old"))
+	require.NoError(t, mr.Set("auth-key-123", "old"))
+	require.NoError(t, mr.Set("unrelated", "old"))
+
+	// This key has already been written with the namespace so it shouldn't
+	// be overwritten by the migration
+	require.NoError(t, mr.Set("env-456-api-configs", "old"))
+	require.NoError(t, mr.Set("staging:env-456-api-configs", "new"))
+
+	moved, skipped, err := MigrateNamespace(ctx, client, "staging", []string{"env-*", "auth-key-*"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, moved)
+	assert.Equal(t, []string{"env-456-api-configs"}, skipped)
+
+	keys := mr.Keys()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"env-456-api-configs", "staging:auth-key-123", "staging:env-123-api-configs", "staging:env-456-api-configs", "unrelated"}, keys)
+
+	v, err := mr.Get("staging:env-456-api-configs")
+	require.NoError(t, err)
+	assert.Equal(t, "new", v)
+
+	// Running it again is a no-op
+	moved, _, err = MigrateNamespace(ctx, client, "staging", []string{"env-*", "auth-key-*"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, moved)
+}
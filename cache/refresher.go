@@ -36,6 +36,9 @@ type config interface {
 	// ClusterIdentifier returns the identifier of the cluster that the Config authenticated against
 	ClusterIdentifier() string
 
+	// AccountID returns the accountID for the account the Config's key belongs to
+	AccountID() string
+
 	// SetProxyConfig sets the proxyConfig member
 	SetProxyConfig(proxyConfig []domain.ProxyConfig)
 }
@@ -63,6 +66,10 @@ func NewRefresher(l log.Logger, config config, client domain.ClientService, inve
 
 // HandleMessage makes Refresher implement the MessageHandler interface
 func (s Refresher) HandleMessage(ctx context.Context, msg domain.SSEMessage) error {
+	// Set the accountID of the key in the context, this way it can be included
+	// in headers for any requests we make to Saas for the key's environments
+	ctx = context.WithValue(ctx, domain.ContextKeyAccountID, s.config.AccountID())
+
 	switch msg.Domain {
 	case domain.MsgDomainFeature:
 		return s.handleFeatureMessage(ctx, msg)
@@ -193,10 +200,8 @@ func (s Refresher) handleAddEnvironmentEvent(ctx context.Context, environments [
 			if err != nil {
 				return newAssets, err
 			}
-			for k := range newAssets {
-				if _, ok := assets[k]; !ok {
-					assets[k] = ""
-				}
+			for k, v := range newAssets {
+				assets[k] = v
 			}
 			return assets, nil
 		}); err != nil {
@@ -356,6 +361,14 @@ func (s Refresher) handleFetchFeatureEvent(ctx context.Context, env, id string)
 	return s.inventory.Patch(ctx, s.config.Key(), func(assets map[string]string) (map[string]string, error) {
 		featureConfigEntry := string(domain.NewFeatureConfigKey(env, id))
 		featureConfigsEntry := string(domain.NewFeatureConfigsKey(env))
+
+		// Keep the version in the inventory up to date so that the next resync
+		// doesn't think the flag has changed
+		for _, f := range features {
+			if f.Feature == id {
+				assets[featureConfigEntry] = domain.NewAssetVersion(f.Version)
+			}
+		}
 		return s.addItems(assets, featureConfigEntry, featureConfigsEntry)
 	})
 }
@@ -436,6 +449,12 @@ func (s Refresher) handleFetchSegmentEvent(ctx context.Context, env, id string)
 	return s.inventory.Patch(ctx, s.config.Key(), func(assets map[string]string) (map[string]string, error) {
 		segmentConfigEntry := string(domain.NewSegmentKey(env, id))
 		segmentConfigsEntry := string(domain.NewSegmentsKey(env))
+
+		for _, seg := range segments {
+			if seg.Identifier == id {
+				assets[segmentConfigEntry] = domain.NewAssetVersion(seg.Version)
+			}
+		}
 		return s.addItems(assets, segmentConfigEntry, segmentConfigsEntry)
 	})
 }
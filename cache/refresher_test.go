@@ -647,6 +647,9 @@ func (m mockConfig) Token() string {
 func (m mockConfig) ClusterIdentifier() string {
 	return "1"
 }
+func (m mockConfig) AccountID() string {
+	return "account"
+}
 
 func (m mockConfig) SetProxyConfig(proxyConfig []domain.ProxyConfig) {
 	m.setProxyConfigFn(proxyConfig)
@@ -0,0 +1,265 @@
+package cache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// snapshotVersion is the version of the snapshot archive format. It should be
+	// bumped if the layout of the archive changes in a way older Proxies can't read.
+	snapshotVersion = 1
+
+	snapshotManifestName = "manifest.json"
+)
+
+var (
+	// ErrInvalidSnapshot is returned when a snapshot archive can't be restored
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+	// SnapshotPatterns match every key that the Proxy owns. This covers auth keys,
+	// flag, segment and target config for each environment along with their latest
+	// hashes and the inventory of keys for the proxy key.
+	SnapshotPatterns = []string{
+		"auth-key-*",
+		"env-*",
+		"key-*-inventory",
+	}
+
+	// snapshotJSON is used to decode values when restoring them so that numbers
+	// keep their type rather than all becoming float64
+	snapshotJSON = jsoniter.Config{UseNumber: true}.Froze()
+)
+
+// SnapshotManifest describes the contents of a snapshot archive
+type SnapshotManifest struct {
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Entries   []SnapshotEntry `json:"entries"`
+}
+
+// SnapshotEntry is a single key in a snapshot archive. The value is stored as
+// json in the archive file named by File.
+type SnapshotEntry struct {
+	Key    string `json:"key"`
+	File   string `json:"file"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// WriteSnapshot writes every key in the cache that matches the SnapshotPatterns to
+// w as a gzipped tar archive. The first file in the archive is a manifest that
+// lists each key along with a checksum of its value.
+func WriteSnapshot(ctx context.Context, c Cache, w io.Writer) (SnapshotManifest, error) {
+	keys, err := snapshotKeys(ctx, c)
+	if err != nil {
+		return SnapshotManifest{}, err
+	}
+
+	manifest := SnapshotManifest{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Entries:   make([]SnapshotEntry, 0, len(keys)),
+	}
+	values := make([][]byte, 0, len(keys))
+
+	for i, key := range keys {
+		var v interface{}
+		if err := c.Get(ctx, key, &v); err != nil {
+			return SnapshotManifest{}, fmt.Errorf("failed to get key %q: %w", key, err)
+		}
+
+		b, err := jsoniter.Marshal(v)
+		if err != nil {
+			return SnapshotManifest{}, fmt.Errorf("failed to marshal key %q: %w", key, err)
+		}
+
+		manifest.Entries = append(manifest.Entries, SnapshotEntry{
+			Key:    key,
+			File:   fmt.Sprintf("keys/%06d.json", i),
+			Size:   len(b),
+			SHA256: fmt.Sprintf("%x", sha256.Sum256(b)),
+		})
+		values = append(values, b)
+	}
+
+	mb, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return SnapshotManifest{}, err
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	if err := writeTarFile(tw, snapshotManifestName, mb, manifest.CreatedAt); err != nil {
+		return SnapshotManifest{}, err
+	}
+
+	for i, entry := range manifest.Entries {
+		if err := writeTarFile(tw, entry.File, values[i], manifest.CreatedAt); err != nil {
+			return SnapshotManifest{}, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return SnapshotManifest{}, err
+	}
+	if err := gw.Close(); err != nil {
+		return SnapshotManifest{}, err
+	}
+	return manifest, nil
+}
+
+// RestoreSnapshot reads a snapshot archive written by WriteSnapshot and sets every
+// key from it in the cache. The whole archive is validated against the manifest
+// before anything is written so a corrupt archive doesn't partially restore.
+func RestoreSnapshot(ctx context.Context, c Cache, r io.Reader) (SnapshotManifest, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return SnapshotManifest{}, fmt.Errorf("%w: %s", ErrInvalidSnapshot, err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return SnapshotManifest{}, fmt.Errorf("%w: failed to read manifest: %s", ErrInvalidSnapshot, err)
+	}
+	if hdr.Name != snapshotManifestName {
+		return SnapshotManifest{}, fmt.Errorf("%w: expected first file to be %s, got %s", ErrInvalidSnapshot, snapshotManifestName, hdr.Name)
+	}
+
+	var manifest SnapshotManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return SnapshotManifest{}, fmt.Errorf("%w: failed to decode manifest: %s", ErrInvalidSnapshot, err)
+	}
+	if manifest.Version != snapshotVersion {
+		return SnapshotManifest{}, fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, manifest.Version)
+	}
+
+	files := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return SnapshotManifest{}, fmt.Errorf("%w: %s", ErrInvalidSnapshot, err)
+		}
+
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return SnapshotManifest{}, fmt.Errorf("%w: failed to read %s: %s", ErrInvalidSnapshot, hdr.Name, err)
+		}
+		files[hdr.Name] = b
+	}
+
+	values := make([]interface{}, 0, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		b, ok := files[entry.File]
+		if !ok {
+			return SnapshotManifest{}, fmt.Errorf("%w: missing file %s for key %q", ErrInvalidSnapshot, entry.File, entry.Key)
+		}
+
+		if sum := fmt.Sprintf("%x", sha256.Sum256(b)); sum != entry.SHA256 {
+			return SnapshotManifest{}, fmt.Errorf("%w: checksum mismatch for key %q", ErrInvalidSnapshot, entry.Key)
+		}
+
+		v, err := decodeSnapshotValue(b)
+		if err != nil {
+			return SnapshotManifest{}, fmt.Errorf("%w: failed to decode key %q: %s", ErrInvalidSnapshot, entry.Key, err)
+		}
+		values = append(values, v)
+	}
+
+	for i, entry := range manifest.Entries {
+		if err := c.Set(ctx, entry.Key, values[i]); err != nil {
+			return SnapshotManifest{}, fmt.Errorf("failed to set key %q: %w", entry.Key, err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// snapshotKeys returns the sorted, de-duplicated list of keys that match the
+// SnapshotPatterns
+func snapshotKeys(ctx context.Context, c Cache) ([]string, error) {
+	seen := map[string]struct{}{}
+	for _, pattern := range SnapshotPatterns {
+		keys, err := c.Keys(ctx, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys matching %q: %w", pattern, err)
+		}
+
+		for _, k := range keys {
+			seen[k] = struct{}{}
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// decodeSnapshotValue decodes a json value from a snapshot. Whole numbers are
+// decoded as int64 so that codecs like msgpack encode them as integers again.
+func decodeSnapshotValue(b []byte) (interface{}, error) {
+	var v interface{}
+	if err := snapshotJSON.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return normaliseNumbers(v), nil
+}
+
+func normaliseNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, vv := range t {
+			t[k] = normaliseNumbers(vv)
+		}
+		return t
+	case []interface{}:
+		for i, vv := range t {
+			t[i] = normaliseNumbers(vv)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+func writeTarFile(tw *tar.Writer, name string, b []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(b)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(tw, bytes.NewReader(b))
+	return err
+}
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/codec"
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+func seedSnapshotCache(t *testing.T, c Cache) {
+	ctx := context.Background()
+	hc := NewHashCache(c, 1*time.Minute, 2*time.Minute)
+
+	require.NoError(t, c.Set(ctx, "auth-key-123", "123"))
+	require.NoError(t, c.Set(ctx, string(domain.NewAPIConfigsKey("123")), []string{"auth-key-123"}))
+	require.NoError(t, hc.Set(ctx, string(domain.NewFeatureConfigsKey("123")), []conformanceValue{{Identifier: "foo", Version: 1}}))
+	require.NoError(t, c.Set(ctx, string(domain.NewFeatureConfigKey("123", "foo")), conformanceValue{Identifier: "foo", Version: 1}))
+	require.NoError(t, c.Set(ctx, "key-abc-inventory", map[string]string{"env-123-feature-configs": ""}))
+
+	// Keys the Proxy doesn't own shouldn't be included in the snapshot
+	require.NoError(t, c.Set(ctx, "something-else", "foo"))
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	src := NewMemCache()
+	seedSnapshotCache(t, src)
+
+	buf := &bytes.Buffer{}
+	written, err := WriteSnapshot(ctx, src, buf)
+	require.NoError(t, err)
+	assert.Len(t, written.Entries, 6)
+
+	// Restore into a redis cache that uses a different codec to make sure the
+	// values can be read back as the types the Proxy expects
+	mr := miniredis.RunT(t)
+	c := codec.New(codec.FormatMsgpack, codec.CompressionZstd)
+	dst := NewKeyValCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}), WithMarshalFunc(c.Marshal), WithUnmarshalFunc(c.Unmarshal))
+
+	restored, err := RestoreSnapshot(ctx, dst, buf)
+	require.NoError(t, err)
+	assert.Equal(t, written.Entries, restored.Entries)
+
+	srcKeys, err := snapshotKeys(ctx, src)
+	require.NoError(t, err)
+
+	dstKeys, err := dst.Keys(ctx, "*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, srcKeys, dstKeys)
+
+	var flags []conformanceValue
+	require.NoError(t, dst.Get(ctx, string(domain.NewFeatureConfigsKey("123")), &flags))
+	assert.Equal(t, []conformanceValue{{Identifier: "foo", Version: 1}}, flags)
+
+	var latest string
+	require.NoError(t, dst.Get(ctx, NewLatestHashKey(string(domain.NewFeatureConfigsKey("123"))), &latest))
+	assert.NotEmpty(t, latest)
+}
+
+func TestRestoreSnapshot_Invalid(t *testing.T) {
+	ctx := context.Background()
+
+	src := NewMemCache()
+	seedSnapshotCache(t, src)
+
+	buf := &bytes.Buffer{}
+	_, err := WriteSnapshot(ctx, src, buf)
+	require.NoError(t, err)
+	valid := buf.Bytes()
+
+	testCases := map[string]struct {
+		archive func(t *testing.T) []byte
+	}{
+		"Given I have a file that isn't gzipped": {
+			archive: func(t *testing.T) []byte {
+				return []byte("foo")
+			},
+		},
+		"Given I have an archive where a value has been modified": {
+			archive: func(t *testing.T) []byte {
+				return rewriteArchive(t, valid, func(name string, b []byte) []byte {
+					if name == "keys/000000.json" {
+						return []byte(`"env-456"`)
+					}
+					return b
+				})
+			},
+		},
+		"Given I have an archive where a value is missing": {
+			archive: func(t *testing.T) []byte {
+				return rewriteArchive(t, valid, func(name string, b []byte) []byte {
+					if name == "keys/000000.json" {
+						return nil
+					}
+					return b
+				})
+			},
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			dst := NewMemCache()
+
+			_, err := RestoreSnapshot(ctx, dst, bytes.NewReader(tc.archive(t)))
+			assert.True(t, errors.Is(err, ErrInvalidSnapshot), "expected ErrInvalidSnapshot, got %v", err)
+
+			// Nothing should be restored from an invalid archive
+			keys, err := dst.Keys(ctx, "*")
+			require.NoError(t, err)
+			assert.Empty(t, keys)
+		})
+	}
+}
+
+// rewriteArchive copies a snapshot archive passing the contents of each file through
+// fn. If fn returns nil the file is left out of the new archive.
+func rewriteArchive(t *testing.T, archive []byte, fn func(name string, b []byte) []byte) []byte {
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		b, err := io.ReadAll(tr)
+		require.NoError(t, err)
+
+		b = fn(hdr.Name, b)
+		if b == nil {
+			continue
+		}
+		require.NoError(t, writeTarFile(tw, hdr.Name, b, hdr.ModTime))
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/tracing"
+)
+
+// TracingCache is a decorator for a Cache that creates a span for each
+// operation made against the cache
+type TracingCache struct {
+	next   Cache
+	label  string
+	tracer trace.Tracer
+}
+
+// NewTracingCache creates a TracingCache. The label is included in the span names
+// so that spans from different layers of the cache can be told apart e.g. the
+// HashCache and the redis cache it wraps.
+func NewTracingCache(label string, tp trace.TracerProvider, next Cache) TracingCache {
+	return TracingCache{
+		next:   next,
+		label:  label,
+		tracer: tp.Tracer(tracing.ScopeName),
+	}
+}
+
+// Set makes TracingCache implement the Cache interface
+func (c TracingCache) Set(ctx context.Context, key string, value interface{}) (err error) {
+	ctx, span := c.start(ctx, "Set", key)
+	defer func() { c.end(span, err) }()
+
+	return c.next.Set(ctx, key, value)
+}
+
+// Get makes TracingCache implement the Cache interface
+func (c TracingCache) Get(ctx context.Context, key string, v interface{}) (err error) {
+	ctx, span := c.start(ctx, "Get", key)
+	defer func() { c.end(span, err) }()
+
+	return c.next.Get(ctx, key, v)
+}
+
+// Delete makes TracingCache implement the Cache interface
+func (c TracingCache) Delete(ctx context.Context, key string) (err error) {
+	ctx, span := c.start(ctx, "Delete", key)
+	defer func() { c.end(span, err) }()
+
+	return c.next.Delete(ctx, key)
+}
+
+// Update makes TracingCache implement the Updater interface
+func (c TracingCache) Update(ctx context.Context, key string, value interface{}, update func() error) (err error) {
+	ctx, span := c.start(ctx, "Update", key)
+	defer func() { c.end(span, err) }()
+
+	return Update(ctx, c.next, key, value, update)
+}
+
+// Keys makes TracingCache implement the Cache interface
+func (c TracingCache) Keys(ctx context.Context, key string) (keys []string, err error) {
+	ctx, span := c.start(ctx, "Keys", key)
+	defer func() { c.end(span, err) }()
+
+	return c.next.Keys(ctx, key)
+}
+
+// HealthCheck calls the decorated cache's HealthCheck method. It doesn't create
+// a span since it's called on a timer rather than as part of a request.
+func (c TracingCache) HealthCheck(ctx context.Context) error {
+	return c.next.HealthCheck(ctx)
+}
+
+// Scan makes TracingCache implement the Cache interface
+func (c TracingCache) Scan(ctx context.Context, key string) (m map[string]string, err error) {
+	ctx, span := c.start(ctx, "Scan", key)
+	defer func() { c.end(span, err) }()
+
+	return c.next.Scan(ctx, key)
+}
+
+func (c TracingCache) start(ctx context.Context, op string, key string) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, fmt.Sprintf("cache.%s.%s", c.label, op),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("cache.key", key)),
+	)
+}
+
+func (c TracingCache) end(span trace.Span, err error) {
+	// A cache miss isn't a failure so we just record it as an attribute
+	if errors.Is(err, domain.ErrCacheNotFound) {
+		span.SetAttributes(attribute.Bool("cache.hit", false))
+	} else {
+		tracing.RecordError(span, err)
+	}
+	span.End()
+}
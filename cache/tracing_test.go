@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+func TestTracingCache_Get(t *testing.T) {
+	testCases := map[string]struct {
+		err            error
+		expectedStatus codes.Code
+		expectedAttrs  []attribute.KeyValue
+	}{
+		"Given the decorated cache returns no error": {
+			err:            nil,
+			expectedStatus: codes.Unset,
+			expectedAttrs:  []attribute.KeyValue{attribute.String("cache.key", "foo")},
+		},
+		"Given the decorated cache returns a NotFound error": {
+			err:            fmt.Errorf("%w: foo", domain.ErrCacheNotFound),
+			expectedStatus: codes.Unset,
+			expectedAttrs:  []attribute.KeyValue{attribute.String("cache.key", "foo"), attribute.Bool("cache.hit", false)},
+		},
+		"Given the decorated cache returns an error": {
+			err:            errors.New("connection refused"),
+			expectedStatus: codes.Error,
+			expectedAttrs:  []attribute.KeyValue{attribute.String("cache.key", "foo")},
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+			c := NewTracingCache("redis", tp, mockCache{get: func() error { return tc.err }})
+
+			err := c.Get(context.Background(), "foo", nil)
+			assert.Equal(t, tc.err, err)
+
+			spans := exporter.GetSpans()
+			assert.Len(t, spans, 1)
+			assert.Equal(t, "cache.redis.Get", spans[0].Name)
+			assert.Equal(t, tc.expectedStatus, spans[0].Status.Code)
+			assert.ElementsMatch(t, tc.expectedAttrs, spans[0].Attributes)
+		})
+	}
+}
+
+func TestTracingCache_NestedSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	inner := NewTracingCache("redis", tp, NewMemCache())
+	outer := NewTracingCache("hash", tp, inner)
+
+	assert.Nil(t, outer.Set(context.Background(), "foo", "bar"))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+
+	// Spans are exported as they end so the inner span comes first
+	assert.Equal(t, "cache.redis.Set", spans[0].Name)
+	assert.Equal(t, "cache.hash.Set", spans[1].Name)
+	assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+}
@@ -10,11 +10,13 @@ import (
 	"github.com/golang-jwt/jwt"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/harness/ff-proxy/v2/domain"
 	clientgen "github.com/harness/ff-proxy/v2/gen/client"
 	"github.com/harness/ff-proxy/v2/log"
 	"github.com/harness/ff-proxy/v2/token"
+	"github.com/harness/ff-proxy/v2/tracing"
 )
 
 var (
@@ -53,11 +55,15 @@ type Client struct {
 	client ffClientService
 }
 
-// NewClient creates a Client
-func NewClient(l log.Logger, addr string, reg *prometheus.Registry) (Client, error) {
+// NewClient creates a Client. Requests made to the client service are traced
+// using the passed TracerProvider.
+func NewClient(l log.Logger, addr string, reg *prometheus.Registry, tp trace.TracerProvider) (Client, error) {
 	l = l.With("component", "ClientServiceClient")
 
-	client, err := clientgen.NewClientWithResponses(addr)
+	client, err := clientgen.NewClientWithResponses(
+		addr,
+		clientgen.WithHTTPClient(&http.Client{Transport: tracing.NewTransport(nil, tp)}),
+	)
 	if err != nil {
 		return Client{}, err
 	}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/harness/ff-proxy/v2/log"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/harness/ff-proxy/v2/domain"
 	clientgen "github.com/harness/ff-proxy/v2/gen/client"
@@ -106,7 +107,7 @@ func TestClientService_Authenticate(t *testing.T) {
 		tc := tc
 		t.Run(desc, func(t *testing.T) {
 			logger, _ := log.NewStructuredLogger("DEBUG")
-			clientService, _ := NewClient(logger, "localhost:8000", prometheus.NewRegistry())
+			clientService, _ := NewClient(logger, "localhost:8000", prometheus.NewRegistry(), noop.NewTracerProvider())
 			clientService.client = &tc.mockService
 
 			actual, err := clientService.Authenticate(context.Background(), "", domain.Target{})
@@ -8,7 +8,9 @@ import (
 	"github.com/harness/ff-proxy/v2/domain"
 	clientgen "github.com/harness/ff-proxy/v2/gen/client"
 	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/tracing"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // doer is a simple http client that gets passed to the generated admin client
@@ -33,27 +35,33 @@ type counter interface {
 type Client struct {
 	log    log.Logger
 	client clientgen.ClientWithResponsesInterface
-	token  func() string
+	token  func(envID string) string
+
+	// accountID returns the ID of the account that the environment belongs to
+	accountID func(envID string) string
 
 	sdkUsage         counter
 	metricsForwarded counter
 }
 
-// NewClient creates a MetricStore
-func NewClient(l log.Logger, addr string, token func() string, reg *prometheus.Registry) (Client, error) {
+// NewClient creates a MetricStore. Requests made to the metrics service are
+// traced using the passed TracerProvider and authenticated with the token that
+// the token func returns for the environment the metrics are for.
+func NewClient(l log.Logger, addr string, token func(envID string) string, accountID func(envID string) string, reg *prometheus.Registry, tp trace.TracerProvider) (Client, error) {
 	l = l.With("component", "MetricServiceClient")
 	client, err := clientgen.NewClientWithResponses(
 		addr,
-		clientgen.WithHTTPClient(doer{c: http.DefaultClient}),
+		clientgen.WithHTTPClient(doer{c: &http.Client{Transport: tracing.NewTransport(nil, tp)}}),
 	)
 	if err != nil {
 		return Client{}, err
 	}
 
 	m := Client{
-		log:    l,
-		client: client,
-		token:  token,
+		log:       l,
+		client:    client,
+		token:     token,
+		accountID: accountID,
 
 		sdkUsage: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
@@ -86,11 +94,13 @@ func (c Client) PostMetrics(ctx context.Context, envID string, metric domain.Met
 		c.trackSDKUsage(metric)
 	}()
 
+	ctx = context.WithValue(ctx, domain.ContextKeyAccountID, c.accountID(envID))
+
 	res, err := c.client.PostMetricsWithResponse(ctx, envID, &clientgen.PostMetricsParams{Cluster: &clusterIdentifier}, clientgen.PostMetricsJSONRequestBody{
 		MetricsData: metric.MetricsData,
 		TargetData:  metric.TargetData,
 	},
-		addAuthToken(c.token()),
+		addAuthToken(c.token(envID)),
 		domain.AddHarnessXHeaders(envID),
 	)
 	if err != nil {
@@ -14,13 +14,17 @@ type safeMetricsRequestMap struct {
 	*sync.RWMutex
 	detailed map[string]map[string]clientgen.MetricsData
 
+	// streamIDs are the IDs of the stream messages that the metrics for each environment came from
+	streamIDs map[string][]string
+
 	currentSize int
 }
 
 func newSafeMetricsRequestMap() *safeMetricsRequestMap {
 	return &safeMetricsRequestMap{
-		RWMutex:  &sync.RWMutex{},
-		detailed: make(map[string]map[string]clientgen.MetricsData),
+		RWMutex:   &sync.RWMutex{},
+		detailed:  make(map[string]map[string]clientgen.MetricsData),
+		streamIDs: make(map[string][]string),
 	}
 }
 
@@ -35,6 +39,7 @@ func (s *safeMetricsRequestMap) flush() {
 	defer s.Unlock()
 
 	s.detailed = map[string]map[string]clientgen.MetricsData{}
+	s.streamIDs = map[string][]string{}
 	s.currentSize = 0
 }
 
@@ -42,6 +47,10 @@ func (s *safeMetricsRequestMap) add(value domain.MetricsRequest) {
 	s.Lock()
 	defer s.Unlock()
 
+	if len(value.StreamIDs) > 0 {
+		s.streamIDs[value.EnvironmentID] = append(s.streamIDs[value.EnvironmentID], value.StreamIDs...)
+	}
+
 	current, ok := s.detailed[value.EnvironmentID]
 	if !ok {
 		// If we don't already have metrics for this environment
@@ -96,6 +105,7 @@ func (s *safeMetricsRequestMap) get() map[string]domain.MetricsRequest {
 	// marshaling to the response type
 	s.RLock()
 	cpy := s.detailed
+	streamIDs := s.streamIDs
 	s.RUnlock()
 
 	result := map[string]domain.MetricsRequest{}
@@ -112,6 +122,7 @@ func (s *safeMetricsRequestMap) get() map[string]domain.MetricsRequest {
 			Metrics: clientgen.Metrics{
 				MetricsData: domain.ToPtr(slice),
 			},
+			StreamIDs: streamIDs[envID],
 		}
 	}
 
@@ -38,10 +38,15 @@ func (m *safeTargetsMap) add(r domain.MetricsRequest) {
 	// Store metrics to send later
 	currentMetrics, ok := m.metrics[r.EnvironmentID]
 	if !ok {
+		// Copy the stream IDs so that appending to them later doesn't write
+		// to a slice that's shared with the caller
+		r.StreamIDs = append([]string(nil), r.StreamIDs...)
 		m.metrics[r.EnvironmentID] = r
 		return
 	}
 
+	currentMetrics.StreamIDs = append(currentMetrics.StreamIDs, r.StreamIDs...)
+
 	incrSize := false
 
 	if r.MetricsData != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/harness/ff-proxy/v2/domain"
@@ -23,17 +24,29 @@ type metricService interface {
 
 // Worker is a type that is used by the Primary Proxy to consume metrics
 // from ReadReplicas and forward them on to Harness Saas.
+//
+// If the subscriber supports acks, e.g. a RedisStream in a consumer group with
+// manual acks, each message is acked once the metrics in it have been posted to
+// Saas so that they're delivered again if the Primary stops before posting them.
 type Worker struct {
 	log               log.Logger
 	subscriber        domain.Subscriber
 	metricsStore      metricStore
 	metricsService    metricService
 	readConcurrency   int
-	clusterIdentifier string
+	clusterIdentifier func(envID string) string
+	acks              *pendingAcks
 }
 
-// NewWorker creates a Worker
-func NewWorker(l log.Logger, store metricStore, metricSvc metricService, sub domain.Subscriber, readConn int, clusterIdentifer string) Worker {
+// message is a message read from the metrics stream
+type message struct {
+	id   string
+	data []byte
+}
+
+// NewWorker creates a Worker. Metrics are posted to the cluster that the clusterIdentifier
+// func returns for the environment they're for.
+func NewWorker(l log.Logger, store metricStore, metricSvc metricService, sub domain.Subscriber, readConn int, clusterIdentifer func(envID string) string) Worker {
 	return Worker{
 		log:               l,
 		subscriber:        sub,
@@ -41,6 +54,7 @@ func NewWorker(l log.Logger, store metricStore, metricSvc metricService, sub dom
 		metricsService:    metricSvc,
 		readConcurrency:   readConn,
 		clusterIdentifier: clusterIdentifer,
+		acks:              newPendingAcks(),
 	}
 }
 
@@ -60,8 +74,8 @@ func (w Worker) Start(ctx context.Context) {
 
 // subscribe starts a single thread that subcribes to a redis stream and writes
 // events coming off the stream to a channel.
-func (w Worker) subscribe(ctx context.Context) <-chan []byte {
-	out := make(chan []byte)
+func (w Worker) subscribe(ctx context.Context) <-chan message {
+	out := make(chan message)
 
 	go func() {
 		defer close(out)
@@ -78,13 +92,14 @@ func (w Worker) subscribe(ctx context.Context) <-chan []byte {
 				s, ok := v.(string)
 				if !ok {
 					w.log.Warn("unexpected message format received", "stream", SDKMetricsStream, "type", reflect.TypeOf(v))
+					w.ack(ctx, latestID)
 					return nil
 				}
 
 				select {
 				case <-ctx.Done():
 					return ctx.Err()
-				case out <- []byte(s):
+				case out <- message{id: latestID, data: []byte(s)}:
 				}
 
 				return nil
@@ -107,28 +122,49 @@ func (w Worker) subscribe(ctx context.Context) <-chan []byte {
 	return out
 }
 
-func (w Worker) handleMetrics(ctx context.Context, metrics <-chan []byte) {
+func (w Worker) handleMetrics(ctx context.Context, metrics <-chan message) {
 	for {
 		select {
 		case <-ctx.Done():
 			w.log.Info("")
 			return
-		case b, ok := <-metrics:
+		case msg, ok := <-metrics:
 			if !ok {
 				w.log.Info("")
 				return
 			}
 
 			mr := domain.MetricsRequest{
-				Size: len(b),
+				Size: len(msg.data),
 			}
-			if err := jsoniter.Unmarshal(b, &mr); err != nil {
+			if err := jsoniter.Unmarshal(msg.data, &mr); err != nil {
+				// The message won't unmarshal any better if it's delivered again
 				w.log.Warn("failed to unmarshal metrics message", "stream", SDKMetricsStream, "err", err)
+				w.ack(ctx, msg.id)
 				continue
 			}
 
+			// The queue stores the evaluation and target metrics separately so the message
+			// is acked once both of them have been posted
+			parts := 0
+			if mr.MetricsData != nil {
+				parts++
+			}
+			if mr.TargetData != nil {
+				parts++
+			}
+
+			if parts == 0 || msg.id == "" {
+				w.ack(ctx, msg.id)
+			} else {
+				mr.StreamIDs = []string{msg.id}
+				w.acks.add(msg.id, parts)
+			}
+
 			if err := w.metricsStore.StoreMetrics(ctx, mr); err != nil {
+				// Leave the message unacked so that it's delivered again
 				w.log.Warn("failed to store metrics received from read replica", "stream", SDKMetricsStream, "err", err)
+				w.acks.forget(mr.StreamIDs...)
 				continue
 			}
 		}
@@ -139,9 +175,84 @@ func (w Worker) postMetrics(ctx context.Context) {
 	for metrics := range w.metricsStore.Listen(ctx) {
 
 		for envID, metric := range metrics {
-			if err := w.metricsService.PostMetrics(ctx, envID, metric, w.clusterIdentifier); err != nil {
-				w.log.Error("sending metrics failed", "environment", envID, "cluster_identifier", w.clusterIdentifier, "error", err)
+			clusterIdentifier := w.clusterIdentifier(envID)
+			if err := w.metricsService.PostMetrics(ctx, envID, metric, clusterIdentifier); err != nil {
+				w.log.Error("sending metrics failed", "environment", envID, "cluster_identifier", clusterIdentifier, "error", err)
+
+				// The messages the metrics came from are left unacked so that they're delivered again
+				w.acks.forget(metric.StreamIDs...)
+				continue
 			}
+
+			w.ack(ctx, w.acks.done(metric.StreamIDs...)...)
 		}
 	}
 }
+
+// ack acks messages on the metrics stream if the subscriber supports acks
+func (w Worker) ack(ctx context.Context, ids ...string) {
+	a, ok := w.subscriber.(domain.Acker)
+	if !ok || len(ids) == 0 {
+		return
+	}
+
+	if err := a.Ack(context.WithoutCancel(ctx), SDKMetricsStream, ids...); err != nil {
+		w.log.Error("failed to ack metrics messages", "stream", SDKMetricsStream, "err", err)
+	}
+}
+
+// pendingAcks keeps track of how many parts of each message are still waiting to be posted
+type pendingAcks struct {
+	*sync.Mutex
+	parts map[string]int
+}
+
+func newPendingAcks() *pendingAcks {
+	return &pendingAcks{
+		Mutex: &sync.Mutex{},
+		parts: map[string]int{},
+	}
+}
+
+// add records that a message has been split in to parts that each need to be posted
+func (p *pendingAcks) add(id string, parts int) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.parts[id] = parts
+}
+
+// done records that a part of each message has been posted and returns the IDs of
+// the messages that have had all of their parts posted
+func (p *pendingAcks) done(ids ...string) []string {
+	p.Lock()
+	defer p.Unlock()
+
+	completed := []string{}
+	for _, id := range ids {
+		remaining, ok := p.parts[id]
+		if !ok {
+			continue
+		}
+
+		if remaining > 1 {
+			p.parts[id] = remaining - 1
+			continue
+		}
+
+		delete(p.parts, id)
+		completed = append(completed, id)
+	}
+	return completed
+}
+
+// forget stops tracking messages that won't be acked so that they can be tracked
+// again when they're delivered again
+func (p *pendingAcks) forget(ids ...string) {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, id := range ids {
+		delete(p.parts, id)
+	}
+}
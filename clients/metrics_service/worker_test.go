@@ -170,7 +170,7 @@ func TestWorker_Start(t *testing.T) {
 			defer cancel()
 
 			queue := NewQueue(ctx, log.NoOpLogger{}, 5*time.Second)
-			w := NewWorker(log.NoOpLogger{}, queue, tc.mocks.metricService, tc.mocks.redisStream, 1, "1")
+			w := NewWorker(log.NoOpLogger{}, queue, tc.mocks.metricService, tc.mocks.redisStream, 1, func(string) string { return "1" })
 
 			w.Start(ctx)
 
@@ -565,3 +565,116 @@ func generateData(size int) []domain.MetricsRequest {
 	}
 	return data
 }
+
+type mockAckingStream struct {
+	*sync.Mutex
+	ids    []string
+	events []string
+	acked  []string
+}
+
+func (m *mockAckingStream) Sub(ctx context.Context, channel string, id string, messageFn domain.HandleMessageFn) error {
+	m.Lock()
+	if len(m.events) == 0 {
+		m.Unlock()
+		<-ctx.Done()
+		return context.Canceled
+	}
+
+	msgID, event := m.ids[0], m.events[0]
+	m.ids, m.events = m.ids[1:], m.events[1:]
+	m.Unlock()
+
+	return messageFn(msgID, event)
+}
+
+func (m *mockAckingStream) Ack(ctx context.Context, channel string, ids ...string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.acked = append(m.acked, ids...)
+	return nil
+}
+
+func (m *mockAckingStream) getAcked() []string {
+	m.Lock()
+	defer m.Unlock()
+
+	return append([]string{}, m.acked...)
+}
+
+type mockFailingMetricsService struct {
+	*sync.Mutex
+	failEnvs map[string]bool
+	posted   []domain.MetricsRequest
+}
+
+func (m *mockFailingMetricsService) PostMetrics(ctx context.Context, envID string, r domain.MetricsRequest, clusterIdentifier string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.failEnvs[envID] {
+		return fmt.Errorf("failed to post metrics for %s", envID)
+	}
+	m.posted = append(m.posted, r)
+	return nil
+}
+
+func (m *mockFailingMetricsService) postedCount() int {
+	m.Lock()
+	defer m.Unlock()
+
+	return len(m.posted)
+}
+
+// TestWorker_AcksAfterPosting checks that messages are only acked once all of the metrics
+// in them have been posted to Saas
+func TestWorker_AcksAfterPosting(t *testing.T) {
+	evaluationsAndTargets := domain.MetricsRequest{
+		EnvironmentID: "123",
+		Metrics: clientgen.Metrics{
+			MetricsData: &[]clientgen.MetricsData{{Count: 1, MetricsType: "Server", Timestamp: 111}},
+			TargetData:  &[]clientgen.TargetData{{Identifier: "Foo", Name: "Bar"}},
+		},
+	}
+
+	evaluationsOnly := domain.MetricsRequest{
+		EnvironmentID: "456",
+		Metrics: clientgen.Metrics{
+			MetricsData: &[]clientgen.MetricsData{{Count: 1, MetricsType: "Server", Timestamp: 111}},
+		},
+	}
+
+	failingEnv := domain.MetricsRequest{
+		EnvironmentID: "789",
+		Metrics: clientgen.Metrics{
+			MetricsData: &[]clientgen.MetricsData{{Count: 1, MetricsType: "Server", Timestamp: 111}},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	subscriber := &mockAckingStream{
+		Mutex:  &sync.Mutex{},
+		ids:    []string{"1-0", "2-0", "3-0", "4-0"},
+		events: append(mustMarshalToString(evaluationsAndTargets, evaluationsOnly, failingEnv), "not json"),
+	}
+	metricService := &mockFailingMetricsService{Mutex: &sync.Mutex{}, failEnvs: map[string]bool{"789": true}}
+
+	queue := NewQueue(ctx, log.NoOpLogger{}, 100*time.Millisecond)
+	w := NewWorker(log.NoOpLogger{}, queue, metricService, subscriber, 1, func(string) string { return "1" })
+	w.Start(ctx)
+
+	// The evaluations and targets for 123 are posted separately, plus the evaluations for 456
+	assert.Eventually(t, func() bool {
+		return metricService.postedCount() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Give the worker a chance to ack anything it shouldn't have before we check
+	time.Sleep(200 * time.Millisecond)
+
+	// Messages that couldn't be unmarshaled are acked straight away, the metrics for 789
+	// failed to post so that message is left to be delivered again
+	assert.ElementsMatch(t, []string{"1-0", "2-0", "4-0"}, subscriber.getAcked())
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/harness/ff-proxy/v2/cache"
+	metricsservice "github.com/harness/ff-proxy/v2/clients/metrics_service"
+	"github.com/harness/ff-proxy/v2/codec"
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/middleware"
+	"github.com/harness/ff-proxy/v2/stream"
+)
+
+// commands are one off tasks that can be run instead of starting the Proxy e.g.
+//
+//	ff-proxy migrate-namespace --redis-address localhost:6379 --redis-namespace staging
+//
+// Any flags or environment variables that the Proxy uses are available to commands.
+// Commands are passed any arguments left over after the flags have been parsed.
+var commands = map[string]func(ctx context.Context, logger log.Logger, args []string) error{
+	"migrate-namespace": migrateNamespace,
+	"snapshot":          snapshot,
+	"restore":           restore,
+	"config":            configCommand,
+}
+
+// runCommand runs the named command and returns the code the process should exit with
+func runCommand(logger log.Logger, name string, args []string) int {
+	cmd, ok := commands[name]
+	if !ok {
+		logger.Error("unknown command", "command", name)
+		return 1
+	}
+
+	// Flags that come after the command name won't have been parsed yet
+	if err := flag.CommandLine.Parse(args); err != nil {
+		logger.Error("failed to parse flags", "command", name, "err", err)
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := cmd(ctx, logger, flag.Args()); err != nil {
+		logger.Error("command failed", "command", name, "err", err)
+		return 1
+	}
+	return 0
+}
+
+// migrateNamespace moves the keys and streams that a Proxy wrote to redis before
+// it had a namespace configured under the namespace in --redis-namespace
+func migrateNamespace(ctx context.Context, logger log.Logger, _ []string) error {
+	if redisAddress == "" {
+		return errors.New("migrate-namespace requires a redis address")
+	}
+	if redisNamespace == "" {
+		return errors.New("migrate-namespace requires a redis namespace")
+	}
+	if err := domain.ValidateNamespace(redisNamespace); err != nil {
+		return err
+	}
+
+	redisClient := newRedisClient(redisAddress, logger)
+	defer redisClient.Close()
+
+	patterns := []string{
+		"env-*",
+		"auth-key-*",
+		"key-*-inventory",
+		streamHealthKey,
+		sseStreamTopic,
+		controlEventsTopic,
+		metricsservice.SDKMetricsStream,
+		stream.NewDeadLetterStream(metricsservice.SDKMetricsStream),
+		middleware.RateLimitKeyPattern,
+	}
+
+	moved, skipped, err := cache.MigrateNamespace(ctx, redisClient, redisNamespace, patterns)
+	if err != nil {
+		return err
+	}
+
+	// The namespace goes inside the hash tag of the event history keys so they
+	// can't be moved with the others
+	historyMoved, historySkipped, err := cache.MigrateKeys(ctx, redisClient, []string{stream.EventHistoryKeyPattern}, func(key string) string {
+		return stream.NamespaceEventHistoryKey(redisNamespace, key)
+	})
+	if err != nil {
+		return err
+	}
+	moved += historyMoved
+	skipped = append(skipped, historySkipped...)
+
+	for _, key := range skipped {
+		logger.Warn("skipped key because it already exists in the namespace", "key", key, "namespace", redisNamespace)
+	}
+	logger.Info("migrated keys to namespace", "namespace", redisNamespace, "moved", moved, "skipped", len(skipped))
+	return nil
+}
+
+// snapshot writes every key the Proxy owns in its cache to an archive e.g.
+//
+//	ff-proxy snapshot --redis-address localhost:6379 ffproxy-snapshot.tar.gz
+func snapshot(ctx context.Context, logger log.Logger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: ff-proxy snapshot [flags] <file>")
+	}
+
+	c, closeFn, err := newCommandCache(logger)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	manifest, err := cache.WriteSnapshot(ctx, c, f)
+	if err != nil {
+		// Don't leave a partial archive behind that could be mistaken for a good one
+		_ = os.Remove(args[0])
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	logger.Info("wrote snapshot", "file", args[0], "keys", len(manifest.Entries))
+	return nil
+}
+
+// restore loads every key from a snapshot archive into the Proxy's cache e.g.
+//
+//	ff-proxy restore --redis-address localhost:6379 ffproxy-snapshot.tar.gz
+func restore(ctx context.Context, logger log.Logger, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: ff-proxy restore [flags] <file>")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c, closeFn, err := newCommandCache(logger)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	manifest, err := cache.RestoreSnapshot(ctx, c, f)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("restored snapshot", "file", args[0], "keys", len(manifest.Entries), "created-at", manifest.CreatedAt)
+	return nil
+}
+
+// newCommandCache creates the cache that commands read and write to. Like the
+// Proxy this is redis if a redis address is configured, otherwise it's the
+// persistent cache at the cache path.
+func newCommandCache(logger log.Logger) (cache.Cache, func(), error) {
+	c, err := codec.Parse(cacheCodec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if redisAddress != "" {
+		redisClient := newRedisClient(redisAddress, logger)
+		kv := cache.NewKeyValCache(redisClient, cache.WithMarshalFunc(c.Marshal), cache.WithUnmarshalFunc(c.Unmarshal))
+		return cache.NewNamespaceCache(redisNamespace, kv), func() { _ = redisClient.Close() }, nil
+	}
+
+	if cachePath != "" {
+		boltCache, err := cache.NewBoltCache(cachePath, cache.WithBoltCodec(c))
+		if err != nil {
+			return nil, nil, err
+		}
+		return boltCache, func() { _ = boltCache.Close() }, nil
+	}
+
+	return nil, nil, errors.New("a redis address or cache path is required")
+}
+
+// configCommand runs the config subcommands e.g.
+//
+//	ff-proxy config validate --config-file proxy.yaml
+//
+// validate prints the config the Proxy would run with and fails if it's invalid
+func configCommand(_ context.Context, _ log.Logger, args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return errors.New("usage: ff-proxy config validate [flags]")
+	}
+
+	// Flags that come after validate, including the config file, won't have
+	// been parsed or loaded yet
+	if err := flag.CommandLine.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := loadSettings(flag.CommandLine, envToFlag); err != nil {
+		return err
+	}
+
+	b, err := yaml.Marshal(effectiveSettings(flag.CommandLine))
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(b))
+
+	return validateSettings()
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	stdlog "log"
@@ -15,6 +16,10 @@ import (
 	_ "net/http/pprof" //nolint:gosec
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"gopkg.in/cenkalti/backoff.v1"
 
 	"github.com/harness/ff-proxy/v2/domain"
@@ -35,12 +40,19 @@ import (
 	"cloud.google.com/go/profiler"
 
 	"github.com/harness/ff-proxy/v2/cache"
+	"github.com/harness/ff-proxy/v2/codec"
 	"github.com/harness/ff-proxy/v2/config"
+	"github.com/harness/ff-proxy/v2/config/local"
+	"github.com/harness/ff-proxy/v2/config/remote"
+	"github.com/harness/ff-proxy/v2/export"
 	"github.com/harness/ff-proxy/v2/hash"
 	"github.com/harness/ff-proxy/v2/log"
 	"github.com/harness/ff-proxy/v2/middleware"
+	"github.com/harness/ff-proxy/v2/overrides"
 	proxyservice "github.com/harness/ff-proxy/v2/proxy-service"
 	"github.com/harness/ff-proxy/v2/repository"
+	"github.com/harness/ff-proxy/v2/signing"
+	"github.com/harness/ff-proxy/v2/tracing"
 	"github.com/harness/ff-proxy/v2/transport"
 )
 
@@ -54,17 +66,32 @@ var (
 	authSecret            string
 	metricPostDuration    int
 	heartbeatInterval     int
+	resyncInterval        int
 	generateOfflineConfig bool
 	readReplica           bool
 	forwardTargets        bool
 
+	// Config file
+	configFile string
+
+	// Export Config
+	exportFormat        string
+	exportInterval      int
+	exportSnapshotsKeep int
+	exportSigningKey    string
+
 	// Cache Config
-	offline       bool
-	configDir     string
-	redisAddress  string
-	redisPassword string
-	redisDB       int
-	redisPoolSize int
+	offline             bool
+	configDir           string
+	configWatchInterval int
+	configPublicKey     string
+	redisAddress        string
+	redisPassword       string
+	redisDB             int
+	redisPoolSize       int
+	cachePath           string
+	redisNamespace      string
+	cacheCodec          string
 
 	// Server Config
 	port           int
@@ -72,6 +99,21 @@ var (
 	tlsCert        string
 	tlsKey         string
 	prometheusPort int
+	pushpinEnabled bool
+	grpcPort       int
+	eventHistory   int
+
+	// Rate limiting
+	rateLimitAuth        string
+	rateLimitPolling     string
+	rateLimitEvaluations string
+	rateLimitMetrics     string
+	rateLimitKey         string
+
+	// Admin API
+	adminToken           string
+	flagOverridesEnabled bool
+	flagOverridesFile    string
 
 	// Dev/Debugging
 	bypassAuth         bool
@@ -79,14 +121,30 @@ var (
 	gcpProfilerEnabled bool
 	pprofEnabled       bool
 
+	// Tracing
+	tracingEnabled     bool
+	tracingSampleRatio float64
+
 	// RedisStreams
 	metricsStreamMaxLen          int64
 	metricsStreamReadConcurrency int
+	metricsStreamConsumerGroup   string
 
 	// Beta features - will be short-lived and then become default behaviour in future releases
 	andRules bool
 )
 
+// Keys and stream topics the Proxy uses in redis, these are prefixed with the
+// redis namespace if one is configured
+const (
+	streamHealthKey    = "ffproxy_saas_stream_health"
+	sseStreamTopic     = "proxy:sse_events"
+	controlEventsTopic = "proxy:primary_to_replica_control_events"
+
+	// cacheInvalidationsTopic is a pub/sub channel rather than a stream
+	cacheInvalidationsTopic = "proxy:cache_invalidations"
+)
+
 // Environment Variables
 const (
 	// Service Config
@@ -96,17 +154,32 @@ const (
 	authSecretEnv            = "AUTH_SECRET"
 	metricPostDurationEnv    = "METRIC_POST_DURATION"
 	heartbeatIntervalEnv     = "HEARTBEAT_INTERVAL"
+	resyncIntervalEnv        = "RESYNC_INTERVAL"
 	generateOfflineConfigEnv = "GENERATE_OFFLINE_CONFIG"
 	readReplicaEnv           = "READ_REPLICA"
 	forwardTargetsEnv        = "FORWARD_TARGETS"
 
+	// Config file
+	configFileEnv = "CONFIG_FILE"
+
+	// Export Config
+	exportFormatEnv        = "EXPORT_FORMAT"
+	exportIntervalEnv      = "EXPORT_INTERVAL"
+	exportSnapshotsKeepEnv = "EXPORT_SNAPSHOTS_KEEP"
+	exportSigningKeyEnv    = "EXPORT_SIGNING_KEY"
+
 	// Cache Config
-	offlineEnv       = "OFFLINE"
-	configDirEnv     = "CONFIG_DIR"
-	redisAddrEnv     = "REDIS_ADDRESS"
-	redisPasswordEnv = "REDIS_PASSWORD"
-	redisDBEnv       = "REDIS_DB"
-	redisPoolSizeEnv = "REDIS_POOL_SIZE"
+	offlineEnv             = "OFFLINE"
+	configDirEnv           = "CONFIG_DIR"
+	configWatchIntervalEnv = "CONFIG_WATCH_INTERVAL"
+	configPublicKeyEnv     = "CONFIG_PUBLIC_KEY"
+	redisAddrEnv           = "REDIS_ADDRESS"
+	redisPasswordEnv       = "REDIS_PASSWORD"
+	redisDBEnv             = "REDIS_DB"
+	redisPoolSizeEnv       = "REDIS_POOL_SIZE"
+	cachePathEnv           = "CACHE_PATH"
+	redisNamespaceEnv      = "REDIS_NAMESPACE"
+	cacheCodecEnv          = "CACHE_CODEC"
 
 	// Server Config
 	portEnv           = "PORT"
@@ -114,6 +187,21 @@ const (
 	tlsCertEnv        = "TLS_CERT"
 	tlsKeyEnv         = "TLS_KEY"
 	prometheusPortEnv = "PROMETHEUS_PORT"
+	pushpinEnabledEnv = "PUSHPIN_ENABLED"
+	grpcPortEnv       = "GRPC_PORT"
+	eventHistoryEnv   = "SDK_EVENT_HISTORY"
+
+	// Rate limiting
+	rateLimitAuthEnv        = "RATE_LIMIT_AUTH"
+	rateLimitPollingEnv     = "RATE_LIMIT_POLLING"
+	rateLimitEvaluationsEnv = "RATE_LIMIT_EVALUATIONS"
+	rateLimitMetricsEnv     = "RATE_LIMIT_METRICS"
+	rateLimitKeyEnv         = "RATE_LIMIT_KEY"
+
+	// Admin API
+	adminTokenEnv           = "ADMIN_TOKEN" //nolint:gosec
+	flagOverridesEnabledEnv = "FLAG_OVERRIDES_ENABLED"
+	flagOverridesFileEnv    = "FLAG_OVERRIDES_FILE"
 
 	// Dev/Debugging
 	bypassAuthEnv         = "BYPASS_AUTH" //nolint:gosec
@@ -121,9 +209,14 @@ const (
 	gcpProfilerEnabledEnv = "GCP_PROFILER_ENABLED"
 	pprofEnabledEnv       = "PPROF"
 
+	// Tracing
+	tracingEnabledEnv     = "TRACING_ENABLED"
+	tracingSampleRatioEnv = "TRACING_SAMPLE_RATIO"
+
 	// RedisStreams
 	metricsStreamMaxLenEnv          = "METRICS_STREAM_MAX_LEN"
 	metricsStreamReadConcurrencyEnv = "METRIC_STREAM_READ_CONCURRENCY"
+	metricsStreamConsumerGroupEnv   = "METRICS_STREAM_CONSUMER_GROUP"
 
 	// Beta features - will be short-lived and then become default behaviour in future releases
 	andRulesEnv = "AND_RULES"
@@ -138,17 +231,32 @@ const (
 	authSecretFlag            = "auth-secret"
 	metricPostDurationFlag    = "metric-post-duration"
 	heartbeatIntervalFlag     = "heartbeat-interval"
+	resyncIntervalFlag        = "resync-interval"
 	generateOfflineConfigFlag = "generate-offline-config"
 	readReplicaFlag           = "readReplica"
 	forwardTargetsFlag        = "forward-targets"
 
+	// Config file
+	configFileFlag = "config-file"
+
+	// Export Config
+	exportFormatFlag        = "export-format"
+	exportIntervalFlag      = "export-interval"
+	exportSnapshotsKeepFlag = "export-snapshots-keep"
+	exportSigningKeyFlag    = "export-signing-key"
+
 	// Cache Config
-	configDirFlag     = "config-dir"
-	offlineFlag       = "offline"
-	redisAddressFlag  = "redis-address"
-	redisPasswordFlag = "redis-password"
-	redisDBFlag       = "redis-db"
-	redisPoolSizeFlag = "redis-pool-size"
+	configDirFlag           = "config-dir"
+	configWatchIntervalFlag = "config-watch-interval"
+	configPublicKeyFlag     = "config-public-key"
+	offlineFlag             = "offline"
+	redisAddressFlag        = "redis-address"
+	redisPasswordFlag       = "redis-password"
+	redisDBFlag             = "redis-db"
+	redisPoolSizeFlag       = "redis-pool-size"
+	cachePathFlag           = "cache-path"
+	redisNamespaceFlag      = "redis-namespace"
+	cacheCodecFlag          = "cache-codec"
 
 	// Server Config
 	portFlag           = "port"
@@ -156,6 +264,21 @@ const (
 	tlsCertFlag        = "tls-cert"
 	tlsKeyFlag         = "tls-key"
 	prometheusPortFlag = "prometheus-port"
+	pushpinEnabledFlag = "pushpin-enabled"
+	grpcPortFlag       = "grpc-port"
+	eventHistoryFlag   = "sdk-event-history"
+
+	// Rate limiting
+	rateLimitAuthFlag        = "rate-limit-auth"
+	rateLimitPollingFlag     = "rate-limit-polling"
+	rateLimitEvaluationsFlag = "rate-limit-evaluations"
+	rateLimitMetricsFlag     = "rate-limit-metrics"
+	rateLimitKeyFlag         = "rate-limit-key"
+
+	// Admin API
+	adminTokenFlag           = "admin-token"
+	flagOverridesEnabledFlag = "flag-overrides-enabled"
+	flagOverridesFileFlag    = "flag-overrides-file"
 
 	// Dev/Debugging
 	bypassAuthFlag         = "bypass-auth"
@@ -163,34 +286,110 @@ const (
 	pprofEnabledFlag       = "pprof"
 	gcpProfilerEnabledFlag = "gcp-profiler-enabled"
 
+	// Tracing
+	tracingEnabledFlag     = "tracing-enabled"
+	tracingSampleRatioFlag = "tracing-sample-ratio"
+
 	// RedisStreams
 	metricsStreamMaxLenFlag         = "metrics-stream-max-len"
 	metricStreamReadConcurrencyFlag = "metrics-stream-read-concurrency"
+	metricsStreamConsumerGroupFlag  = "metrics-stream-consumer-group"
 
 	// Beta features - will be short-lived and then become default behaviour in future releases
 	andRulesFlag = "and-rules"
 )
 
+// envToFlag maps the environment variables that can be used to configure the
+// Proxy on to the flags that they set
+var envToFlag = map[string]string{
+	bypassAuthEnv:                   bypassAuthFlag,
+	logLevelEnv:                     logLevelFlag,
+	offlineEnv:                      offlineFlag,
+	clientServiceEnv:                clientServiceFlag,
+	metricServiceEnv:                metricServiceFlag,
+	authSecretEnv:                   authSecretFlag,
+	redisAddrEnv:                    redisAddressFlag,
+	redisPasswordEnv:                redisPasswordFlag,
+	redisDBEnv:                      redisDBFlag,
+	redisPoolSizeEnv:                redisPoolSizeFlag,
+	cachePathEnv:                    cachePathFlag,
+	redisNamespaceEnv:               redisNamespaceFlag,
+	cacheCodecEnv:                   cacheCodecFlag,
+	metricPostDurationEnv:           metricPostDurationFlag,
+	heartbeatIntervalEnv:            heartbeatIntervalFlag,
+	resyncIntervalEnv:               resyncIntervalFlag,
+	pprofEnabledEnv:                 pprofEnabledFlag,
+	generateOfflineConfigEnv:        generateOfflineConfigFlag,
+	configDirEnv:                    configDirFlag,
+	configWatchIntervalEnv:          configWatchIntervalFlag,
+	exportFormatEnv:                 exportFormatFlag,
+	exportIntervalEnv:               exportIntervalFlag,
+	exportSnapshotsKeepEnv:          exportSnapshotsKeepFlag,
+	exportSigningKeyEnv:             exportSigningKeyFlag,
+	configPublicKeyEnv:              configPublicKeyFlag,
+	portEnv:                         portFlag,
+	tlsEnabledEnv:                   tlsEnabledFlag,
+	andRulesEnv:                     andRulesFlag,
+	tlsCertEnv:                      tlsCertFlag,
+	tlsKeyEnv:                       tlsKeyFlag,
+	prometheusPortEnv:               prometheusPortFlag,
+	pushpinEnabledEnv:               pushpinEnabledFlag,
+	grpcPortEnv:                     grpcPortFlag,
+	eventHistoryEnv:                 eventHistoryFlag,
+	rateLimitAuthEnv:                rateLimitAuthFlag,
+	rateLimitPollingEnv:             rateLimitPollingFlag,
+	rateLimitEvaluationsEnv:         rateLimitEvaluationsFlag,
+	rateLimitMetricsEnv:             rateLimitMetricsFlag,
+	rateLimitKeyEnv:                 rateLimitKeyFlag,
+	adminTokenEnv:                   adminTokenFlag,
+	flagOverridesEnabledEnv:         flagOverridesEnabledFlag,
+	flagOverridesFileEnv:            flagOverridesFileFlag,
+	gcpProfilerEnabledEnv:           gcpProfilerEnabledFlag,
+	tracingEnabledEnv:               tracingEnabledFlag,
+	tracingSampleRatioEnv:           tracingSampleRatioFlag,
+	proxyKeyEnv:                     proxyKeyFlag,
+	readReplicaEnv:                  readReplicaFlag,
+	metricsStreamMaxLenEnv:          metricsStreamMaxLenFlag,
+	metricsStreamReadConcurrencyEnv: metricStreamReadConcurrencyFlag,
+	metricsStreamConsumerGroupEnv:   metricsStreamConsumerGroupFlag,
+	forwardTargetsEnv:               forwardTargetsFlag,
+}
+
 // nolint:gochecknoinits
 func init() {
 	// Service Config
-	flag.StringVar(&proxyKey, proxyKeyFlag, "", "The ProxyKey you want to configure your Proxy to use")
+	flag.StringVar(&proxyKey, proxyKeyFlag, "", "The ProxyKey you want to configure your Proxy to use, multiple keys can be passed as a comma separated list")
 	flag.StringVar(&clientService, clientServiceFlag, "https://config.ff.harness.io/api/1.0", "the url of the ff client service")
 	flag.StringVar(&metricService, metricServiceFlag, "https://events.ff.harness.io/api/1.0", "the url of the ff metric service")
 	flag.StringVar(&authSecret, authSecretFlag, "secret", "the secret used for signing auth tokens")
 	flag.IntVar(&metricPostDuration, metricPostDurationFlag, 60, "How often in seconds the proxy posts metrics to Harness. Set to 0 to disable.")
 	flag.IntVar(&heartbeatInterval, heartbeatIntervalFlag, 60, "How often in seconds the proxy polls pings it's health function. Set to 0 to disable.")
+	flag.IntVar(&resyncInterval, resyncIntervalFlag, 0, "How often in seconds the proxy fetches all of its config from Harness SaaS and notifies SDKs of any changes, this keeps SDK streams open while the stream to Harness SaaS is down. Set to 0 to disable.")
 	flag.BoolVar(&generateOfflineConfig, generateOfflineConfigFlag, false, "if true the proxy will produce offline config in the /config directory then terminate")
 	flag.BoolVar(&readReplica, readReplicaFlag, false, "if true the Proxy will operate as a read replica that only reads from the cache and doesn't fetch new data from Harness SaaS")
 	flag.BoolVar(&forwardTargets, forwardTargetsFlag, false, "determines if the Proxy forwards targets to Saas during the auth flow")
 
+	// Config file
+	flag.StringVar(&configFile, configFileFlag, "", "path to a YAML file with settings for the Proxy, settings in the file are overridden by environment variables and flags")
+
+	// Export Config
+	flag.StringVar(&exportFormat, exportFormatFlag, string(export.FormatJSON), "the format offline config is exported in, one of json, yaml, zip or tar.gz")
+	flag.IntVar(&exportInterval, exportIntervalFlag, 0, "How often in seconds the proxy writes a snapshot of its config to the config dir while it's running. Set to 0 to disable.")
+	flag.IntVar(&exportSnapshotsKeep, exportSnapshotsKeepFlag, 5, "the number of config snapshots to keep in the config dir, older snapshots are removed. Set to 0 to keep every snapshot.")
+	flag.StringVar(&exportSigningKey, exportSigningKeyFlag, "", "Optional. Path to a PEM encoded ed25519 private key that exported config is signed with")
+
 	// Cache Config
 	flag.BoolVar(&offline, offlineFlag, false, "enables side loading of data from config dir")
 	flag.StringVar(&configDir, configDirFlag, "/config", "specify a custom path to search for the offline config directory. Defaults to /config")
+	flag.IntVar(&configWatchInterval, configWatchIntervalFlag, 10, "How often in seconds the proxy checks the offline config directory for changes when running in offline mode. Set to 0 to disable.")
+	flag.StringVar(&configPublicKey, configPublicKeyFlag, "", "Optional. Path to a PEM encoded ed25519 public key, when set offline config is only loaded if it's been signed by the matching private key")
 	flag.StringVar(&redisAddress, redisAddressFlag, "", "Redis host:port address")
 	flag.StringVar(&redisPassword, redisPasswordFlag, "", "Optional. Redis password")
 	flag.IntVar(&redisDB, redisDBFlag, 0, "Database to be selected after connecting to the server.")
 	flag.IntVar(&redisPoolSize, redisPoolSizeFlag, 10, "sets the redi connection pool size, to this value multipled by the number of CPU available. E.g if this value is 10 and you've 2 CPU the connection pool size will be 20")
+	flag.StringVar(&redisNamespace, redisNamespaceFlag, "", "Optional. Namespace that's prefixed to every key and stream the Proxy uses in redis so that multiple Proxies can share the same redis")
+	flag.StringVar(&cacheCodec, cacheCodecFlag, "json", "Optional. How values are encoded in redis or the persistent cache, one of json, json+zstd, msgpack or msgpack+zstd")
+	flag.StringVar(&cachePath, cachePathFlag, "", "path to a file the Proxy persists its cache to when it isn't using redis, if this isn't set the cache is only held in memory")
 
 	// Server Config
 	flag.IntVar(&port, portFlag, 8000, "port the relay proxy service is exposed on, default's to 8000")
@@ -198,6 +397,21 @@ func init() {
 	flag.StringVar(&tlsCert, tlsCertFlag, "", "Path to tls cert file. Required if tls enabled is true.")
 	flag.StringVar(&tlsKey, tlsKeyFlag, "", "Path to tls key file. Required if tls enabled is true.")
 	flag.IntVar(&prometheusPort, prometheusPortFlag, 8000, "port that the prometheus metrics are exposed on, defaults to 8000")
+	flag.BoolVar(&pushpinEnabled, pushpinEnabledFlag, true, "if true the proxy will hand off SDK streams to a Pushpin instance running alongside it rather than serving them itself")
+	flag.IntVar(&grpcPort, grpcPortFlag, 0, "port the gRPC server is exposed on, the gRPC server is disabled if this isn't set")
+	flag.IntVar(&eventHistory, eventHistoryFlag, 100, "the number of events sent to SDKs that are kept for each environment so they can be replayed when an SDK reconnects. Set to 0 to disable.")
+
+	// Rate limiting
+	flag.StringVar(&rateLimitAuth, rateLimitAuthFlag, "", "rate limit for auth requests in the format <requests>/<period> e.g. 100/1m, requests aren't limited if this isn't set")
+	flag.StringVar(&rateLimitPolling, rateLimitPollingFlag, "", "rate limit for feature-configs and target-segments requests in the format <requests>/<period> e.g. 100/1m, requests aren't limited if this isn't set")
+	flag.StringVar(&rateLimitEvaluations, rateLimitEvaluationsFlag, "", "rate limit for evaluations requests in the format <requests>/<period> e.g. 100/1m, requests aren't limited if this isn't set")
+	flag.StringVar(&rateLimitMetrics, rateLimitMetricsFlag, "", "rate limit for metrics requests in the format <requests>/<period> e.g. 100/1m, requests aren't limited if this isn't set")
+	flag.StringVar(&rateLimitKey, rateLimitKeyFlag, string(middleware.RateLimitKeyAPIKey), "what rate limits are applied to, valid options are api-key & environment")
+
+	// Admin API
+	flag.StringVar(&adminToken, adminTokenFlag, "", "token used to authenticate requests to the admin API, the admin API is disabled if this isn't set")
+	flag.BoolVar(&flagOverridesEnabled, flagOverridesEnabledFlag, false, "applies flag overrides that are set through the admin API or the overrides file on top of the config from Harness SaaS")
+	flag.StringVar(&flagOverridesFile, flagOverridesFileFlag, "", "Optional. Path to a JSON file of flag overrides that are set when the proxy starts")
 
 	// Dev/Debugging
 	flag.BoolVar(&bypassAuth, bypassAuthFlag, false, "bypasses authentication")
@@ -205,44 +419,23 @@ func init() {
 	flag.BoolVar(&pprofEnabled, pprofEnabledFlag, false, "enables pprof on port 6060")
 	flag.BoolVar(&gcpProfilerEnabled, gcpProfilerEnabledFlag, false, "Enables gcp cloud profiler")
 
+	// Tracing
+	flag.BoolVar(&tracingEnabled, tracingEnabledFlag, false, "if true the proxy will export OpenTelemetry traces using OTLP, the exporter is configured using the standard OTEL_EXPORTER_OTLP_* env vars")
+	flag.Float64Var(&tracingSampleRatio, tracingSampleRatioFlag, 1, "the fraction of requests that are traced, between 0 and 1")
+
 	// RedisStreams
 	flag.Int64Var(&metricsStreamMaxLen, metricsStreamMaxLenFlag, 1000, "Sets the max length of the redis stream that replicas use to send metrics to the Primary")
 	flag.IntVar(&metricsStreamReadConcurrency, metricStreamReadConcurrencyFlag, 10, "Controls the number of threads running in the Primary that listen for metrics data being sent by replicas")
+	flag.StringVar(&metricsStreamConsumerGroup, metricsStreamConsumerGroupFlag, "", "If set Primaries read metrics sent by replicas as part of this redis consumer group so that several Primaries can share them without sending any of them to Saas twice")
 
 	// Beta features - will be short-lived and then become default behaviour in future releases
 	flag.BoolVar(&andRules, andRulesFlag, false, "if true the proxy will enable the AND rule functionality for target groups")
 
-	loadFlagsFromEnv(map[string]string{
-		bypassAuthEnv:                   bypassAuthFlag,
-		logLevelEnv:                     logLevelFlag,
-		offlineEnv:                      offlineFlag,
-		clientServiceEnv:                clientServiceFlag,
-		metricServiceEnv:                metricServiceFlag,
-		authSecretEnv:                   authSecretFlag,
-		redisAddrEnv:                    redisAddressFlag,
-		redisPasswordEnv:                redisPasswordFlag,
-		redisDBEnv:                      redisDBFlag,
-		redisPoolSizeEnv:                redisPoolSizeFlag,
-		metricPostDurationEnv:           metricPostDurationFlag,
-		heartbeatIntervalEnv:            heartbeatIntervalFlag,
-		pprofEnabledEnv:                 pprofEnabledFlag,
-		generateOfflineConfigEnv:        generateOfflineConfigFlag,
-		configDirEnv:                    configDirFlag,
-		portEnv:                         portFlag,
-		tlsEnabledEnv:                   tlsEnabledFlag,
-		andRulesEnv:                     andRulesFlag,
-		tlsCertEnv:                      tlsCertFlag,
-		tlsKeyEnv:                       tlsKeyFlag,
-		prometheusPortEnv:               prometheusPortFlag,
-		gcpProfilerEnabledEnv:           gcpProfilerEnabledFlag,
-		proxyKeyEnv:                     proxyKeyFlag,
-		readReplicaEnv:                  readReplicaFlag,
-		metricsStreamMaxLenEnv:          metricsStreamMaxLenFlag,
-		metricsStreamReadConcurrencyEnv: metricStreamReadConcurrencyFlag,
-		forwardTargetsEnv:               forwardTargetsFlag,
-	})
-
 	flag.Parse()
+
+	if err := loadSettings(flag.CommandLine, envToFlag); err != nil {
+		stdlog.Fatalf("failed to load config: %s", err)
+	}
 }
 
 //nolint:gocognit,cyclop,maintidx,gocyclo
@@ -255,6 +448,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := domain.ValidateNamespace(redisNamespace); err != nil {
+		logger.Error("invalid redis namespace", "namespace", redisNamespace, "err", err)
+		os.Exit(1)
+	}
+
+	// If we've been passed a command we run it and exit instead of starting the Proxy
+	if cmd := flag.Arg(0); cmd != "" {
+		os.Exit(runCommand(logger, cmd, flag.Args()[1:]))
+	}
+
 	if pprofEnabled {
 		go func() {
 			//nolint:gosec
@@ -281,14 +484,16 @@ func main() {
 		}
 	}
 
-	// we currently don't require any config to run in offline mode
-	requiredFlags := map[string]interface{}{}
-	if !offline && !readReplica {
-		requiredFlags = map[string]interface{}{
-			proxyKeyEnv: proxyKey,
+	if err := validateSettings(); err != nil {
+		var errs settingErrors
+		if errors.As(err, &errs) {
+			for _, e := range errs {
+				logger.Error("invalid config", "setting", e.Setting, "err", e.Reason)
+			}
 		}
+		logger.Error("the Proxy can't start with invalid config, run ff-proxy config validate to check it")
+		os.Exit(1)
 	}
-	validateFlags(requiredFlags)
 
 	// Setup cancelation
 	sigc := make(chan os.Signal, 1)
@@ -302,22 +507,58 @@ func main() {
 	promReg := prometheus.NewRegistry()
 	promReg.MustRegister(collectors.NewGoCollector())
 
-	logger.Info("service config", "version", build.Version, "pprof", pprofEnabled, "log-level", logLevel, "bypass-auth", bypassAuth, "offline", offline, "port", port, "redis-addr", redisAddress, "redis-db", redisDB, "heartbeat-interval", fmt.Sprintf("%ds", heartbeatInterval), "config-dir", configDir, "tls-enabled", tlsEnabled, "tls-cert", tlsCert, "tls-key", tlsKey, "read-replica", readReplica, "client-service", clientService, "metrics-service", metricService, "prometheus-port", prometheusPort, "and-rules", andRules)
+	logger.Info("service config", "version", build.Version, "pprof", pprofEnabled, "log-level", logLevel, "bypass-auth", bypassAuth, "offline", offline, "port", port, "redis-addr", redisAddress, "redis-db", redisDB, "redis-namespace", redisNamespace, "cache-codec", cacheCodec, "cache-path", cachePath, "heartbeat-interval", fmt.Sprintf("%ds", heartbeatInterval), "resync-interval", fmt.Sprintf("%ds", resyncInterval), "config-dir", configDir, "config-watch-interval", fmt.Sprintf("%ds", configWatchInterval), "tls-enabled", tlsEnabled, "tls-cert", tlsCert, "tls-key", tlsKey, "read-replica", readReplica, "client-service", clientService, "metrics-service", metricService, "prometheus-port", prometheusPort, "and-rules", andRules, "pushpin-enabled", pushpinEnabled, "grpc-port", grpcPort, "sdk-event-history", eventHistory, "metrics-stream-consumer-group", metricsStreamConsumerGroup, "tracing-enabled", tracingEnabled, "tracing-sample-ratio", tracingSampleRatio, "admin-api-enabled", adminToken != "", "flag-overrides-enabled", flagOverridesEnabled)
+
+	// If tracing is disabled we still decorate everything but with a noop provider
+	// so we don't have to check if it's enabled everywhere
+	var tp trace.TracerProvider = noop.NewTracerProvider()
+	if tracingEnabled {
+		sdkTP, err := tracing.NewTracerProvider(ctx, tracingSampleRatio)
+		if err != nil {
+			logger.Error("failed to create tracer provider", "err", err)
+			os.Exit(1)
+		}
+		defer func() {
+			if err := sdkTP.Shutdown(context.Background()); err != nil {
+				logger.Error("failed to flush traces during shutdown", "err", err)
+			}
+		}()
+
+		tp = sdkTP
+	}
 
 	// Create cache
 	// if we're just generating the offline config we should only use in memory mode for now
 	// when we move to a pattern of allowing periodic config dumps to disk we can remove this requirement
 
 	var redisClient redis.UniversalClient
-	var hashCache *cache.HashCache
+	var hashCache cache.Cache
+
+	c, err := codec.Parse(cacheCodec)
+	if err != nil {
+		logger.Error("invalid cache codec", "codec", cacheCodec, "err", err)
+		os.Exit(1)
+	}
 
 	if redisAddress != "" && !generateOfflineConfig { //nolint:nestif
 		redisClient = newRedisClient(redisAddress, logger)
 
+		codecOpts := []cache.Options{cache.WithMarshalFunc(c.Marshal), cache.WithUnmarshalFunc(c.Unmarshal)}
+
 		mcMetrics := cache.NewMemoizeMetrics("proxy", promReg)
-		mcCache := cache.NewMemoizeCache(redisClient, 1*time.Minute, 2*time.Minute, mcMetrics)
-		sdkCache = cache.NewMetricsCache("redis", promReg, mcCache)
-		hashCache = cache.NewHashCache(cache.NewKeyValCache(redisClient), 10*time.Minute, 12*time.Minute)
+		mcCache := cache.NewNamespaceCache(redisNamespace, cache.NewMemoizeCache(redisClient, 1*time.Minute, 2*time.Minute, mcMetrics, codecOpts...))
+		sdkCache = cache.NewMetricsCache("redis", promReg, cache.NewTracingCache("redis", tp, mcCache))
+		// Every Proxy sharing the redis is told when the HashCache writes a new hash
+		// so that they can serve hot reads without looking up the latest hash
+		invalidator := cache.NewRedisInvalidator(redisClient, domain.NewNamespacedKey(redisNamespace, cacheInvalidationsTopic))
+		hc := cache.NewHashCache(
+			cache.NewTracingCache("redis", tp, cache.NewNamespaceCache(redisNamespace, cache.NewKeyValCache(redisClient, codecOpts...))),
+			10*time.Minute, 12*time.Minute,
+			cache.WithInvalidator(invalidator),
+		)
+		go hc.ListenForInvalidations(ctx, logger)
+
+		hashCache = cache.NewTracingCache("hash", tp, hc)
 
 		err = sdkCache.HealthCheck(ctx)
 		if err != nil {
@@ -325,42 +566,115 @@ func main() {
 			os.Exit(1)
 		}
 
+	} else if cachePath != "" && !generateOfflineConfig {
+		// The bolt cache is opened before we fetch config so that if we can't
+		// reach Harness SaaS we can still serve the config we had before restarting
+		boltCache, err := cache.NewBoltCache(cachePath, cache.WithBoltCodec(c))
+		if err != nil {
+			logger.Error("failed to open persistent cache", "path", cachePath, "err", err)
+			os.Exit(1)
+		}
+		defer boltCache.Close()
+		logger.Info("loaded persistent cache", "path", cachePath, "keys", boltCache.Len())
+
+		sdkCache = cache.NewMetricsCache("bolt", promReg, cache.NewTracingCache("bolt", tp, boltCache))
+		hashCache = cache.NewTracingCache("hash", tp,
+			cache.NewHashCache(cache.NewTracingCache("bolt", tp, boltCache), 10*time.Minute, 12*time.Minute),
+		)
 	} else {
 		logger.Info("initialising default memcache")
-		sdkCache = cache.NewMetricsCache("in_mem", promReg, cache.NewMemCache())
+		memCache := cache.NewMemCache()
+		sdkCache = cache.NewMetricsCache("in_mem", promReg, cache.NewTracingCache("in_mem", tp, memCache))
+		hashCache = cache.NewTracingCache("hash", tp,
+			cache.NewHashCache(cache.NewTracingCache("in_mem", tp, memCache), 10*time.Minute, 12*time.Minute),
+		)
 	}
 
-	clientSvc, err := clientservice.NewClient(logger, clientService, promReg)
+	clientSvc, err := clientservice.NewClient(logger, clientService, promReg, tp)
 	if err != nil {
 		logger.Error("failed to create client for the feature flags client service", "err", err)
 		os.Exit(1)
 	}
 
-	const (
-		streamHealthKey = "ffproxy_saas_stream_health"
-	)
-
 	var (
 		messageHandler domain.MessageHandler
 
-		gpc = gripcontrol.NewGripPubControl([]map[string]interface{}{
-			{
-				"control_uri": "http://localhost:5561",
-			},
-		})
-
-		keyvalCache      = cache.NewKeyValCache(redisClient)
+		keyvalCache      = cache.NewNamespaceCache(redisNamespace, cache.NewKeyValCache(redisClient))
 		sHealth          = stream.NewHealth(logger, streamHealthKey, keyvalCache, readReplica)
 		streamHealth     = stream.NewStreamHealthMetrics(sHealth, promReg)
 		connectedStreams = domain.NewSafeMap()
 
-		getConnectedStreams = func() map[string]interface{} {
+		// envHealth tracks the environments whose key the primary has lost its Saas
+		// stream for when it's configured with more than one key
+		envHealth = stream.NewEnvironmentHealth()
+
+		// getStreamsToClose returns the SDK streams to close when the Saas stream disconnects.
+		// If we're resyncing SDKs are sent events for changes that are fetched while the
+		// Saas stream is down so there's no need to close their streams.
+		getStreamsToClose = func() map[string]interface{} {
+			if resyncInterval > 0 {
+				return map[string]interface{}{}
+			}
 			return connectedStreams.Get()
 		}
 
-		pushpinStream domain.Stream = stream.NewPushpin(gpc)
-		redisStream   domain.Stream = stream.NewRedisStream(redisClient)
+		redisStream domain.Stream = stream.NewNamespaceStream(redisNamespace, stream.NewRedisStream(redisClient))
+
+		// sdkStream is what we publish events to for them to be sent on to SDKs, and
+		// sdkStreamCloser is used to close any open SDK streams if we lose our connection
+		// to SaaS. By default, the Proxy holds SDK streams open itself but this can be
+		// handed off to Pushpin for backwards compatibility.
+		sdkStream       domain.Stream
+		sdkStreamCloser domain.Closer
+		broadcaster     *stream.Broadcaster
+	)
+
+	// sdkEventHistory keeps the events that are sent to SDKs so that they can be replayed
+	// to SDKs that reconnect. If we've got redis the history is shared with the other
+	// Proxies so SDKs can reconnect to any of them. Events are recorded by the Proxy they
+	// come into, which gives them the ID that every Proxy sends to SDKs.
+	var (
+		sdkEventHistory domain.EventHistory
+		eventRecorder   remote.EventRecorder
 	)
+	if eventHistory > 0 {
+		if redisClient != nil {
+			sdkEventHistory = stream.NewRedisEventHistory(redisClient, redisNamespace, int64(eventHistory))
+		} else {
+			sdkEventHistory = stream.NewMemoryEventHistory(eventHistory)
+		}
+		eventRecorder = stream.NewEventRecorder(logger, sdkEventHistory, domain.NoOpMessageHandler{})
+	}
+
+	recordEvents := func(next domain.MessageHandler) domain.MessageHandler {
+		if sdkEventHistory == nil {
+			return next
+		}
+		return stream.NewEventRecorder(logger, sdkEventHistory, next)
+	}
+
+	if pushpinEnabled {
+		gpc := gripcontrol.NewGripPubControl([]map[string]interface{}{
+			{
+				"control_uri": "http://localhost:5561",
+			},
+		})
+
+		pushpin := stream.NewPushpin(gpc)
+		sdkStream, sdkStreamCloser = pushpin, pushpin
+
+		// gRPC subscribers are always served by the Proxy itself so if the gRPC server
+		// is enabled we need to publish events to Pushpin and the broadcaster
+		if grpcPort != 0 {
+			broadcaster = stream.NewBroadcaster(logger, stream.WithEventHistory(sdkEventHistory))
+
+			fanout := stream.NewFanout(pushpin, broadcaster)
+			sdkStream, sdkStreamCloser = fanout, fanout
+		}
+	} else {
+		broadcaster = stream.NewBroadcaster(logger, stream.WithEventHistory(sdkEventHistory))
+		sdkStream, sdkStreamCloser = broadcaster, broadcaster
+	}
 
 	// If we're running as the primary we kick off a routine to make sure that cached status matches
 	// the in memory status.
@@ -378,83 +692,186 @@ func main() {
 		go getStreamStatusForReplica(ctx, keyvalCache, logger, streamHealth, streamHealthKey)
 	}
 
-	// Get the underlying type from the pushpinStream which is currently the
-	// Stream interface. We can now pass the underlying Pushpin type that has
-	// the Close method to our OnDisconnect handlers.
-	pushpin, ok := pushpinStream.(stream.Pushpin)
-	if !ok {
-		logger.Error("failed to get underlying type from pushpinStream")
-		os.Exit(1)
-	}
-
-	const (
-		sseStreamTopic     = "proxy:sse_events"
-		controlEventsTopic = "proxy:primary_to_replica_control_events"
-	)
-
 	// Configure prometheus labels depending on if we're running as a replica or primary
 	if readReplica {
 		redisStream = stream.NewPrometheusStream("ff_proxy_replica_sse_consumer", redisStream, promReg)
-		pushpinStream = stream.NewPrometheusStream("ff_proxy_replica_to_sdk_sse_producer", pushpinStream, promReg)
+		sdkStream = stream.NewPrometheusStream("ff_proxy_replica_to_sdk_sse_producer", sdkStream, promReg)
 	} else {
 		redisStream = stream.NewPrometheusStream("ff_proxy_primary_to_replica_sse_producer", redisStream, promReg)
-		pushpinStream = stream.NewPrometheusStream("ff_proxy_primary_to_sdk_sse_producer", pushpinStream, promReg)
+		sdkStream = stream.NewPrometheusStream("ff_proxy_primary_to_sdk_sse_producer", sdkStream, promReg)
 	}
 
 	readReplicaSSEStream := stream.NewStream(
 		logger,
 		sseStreamTopic,
 		redisStream,
-		stream.NewForwarder(logger, pushpinStream, domain.NoOpMessageHandler{}),
+		stream.NewForwarder(logger, sdkStream, domain.NoOpMessageHandler{}),
 		stream.WithOnDisconnect(stream.ReadReplicaSSEStreamOnDisconnect(logger, sseStreamTopic)),
 		stream.WithBackoff(backoff.NewConstantBackOff(1*time.Minute)),
+		stream.WithTracerProvider(tp),
 	)
 
 	primaryToReplicaControlStream := stream.NewStream(
 		logger,
 		controlEventsTopic,
 		redisStream,
-		domain.NewReadReplicaMessageHandler(logger, streamHealth, getConnectedStreams, pushpin),
+		domain.NewReadReplicaMessageHandler(logger, streamHealth, getStreamsToClose, sdkStreamCloser, domain.WithEnvironmentHealth(envHealth)),
 		stream.WithOnDisconnect(stream.ReadReplicaSSEStreamOnDisconnect(logger, controlEventsTopic)),
 		stream.WithBackoff(backoff.NewConstantBackOff(1*time.Minute)),
+		stream.WithTracerProvider(tp),
 	)
 
-	if !readReplica {
-		s := stream.NewStatusWorker(streamHealth, primaryToReplicaControlStream, logger)
-		go s.Start(ctx)
+	// Create repos. Flag overrides are stored in the cache so that every Proxy
+	// sharing it applies them to the flags it serves.
+	overrideRepo := repository.NewFlagOverrideRepo(sdkCache)
+	flagRepoOpts := []func(f *repository.FeatureFlagRepo){}
+	if flagOverridesEnabled {
+		flagRepoOpts = append(flagRepoOpts, repository.WithFlagOverrides(overrideRepo))
 	}
 
-	// Create repos
 	targetRepo := repository.NewTargetRepo(sdkCache, logger)
-	flagRepo := repository.NewFeatureFlagRepo(hashCache)
+	flagRepo := repository.NewFeatureFlagRepo(hashCache, flagRepoOpts...)
 	segmentRepo := repository.NewSegmentRepo(hashCache)
 	authRepo := repository.NewAuthRepo(sdkCache)
 	inventoryRepo := repository.NewInventoryRepo(sdkCache, logger)
 
-	// Create config that we'll use to populate our repos
-	conf, err := config.NewConfig(offline, configDir, proxyKey, clientSvc, readReplicaSSEStream)
-	if err != nil {
-		logger.Error("failed to load config", "err", err)
+	// Create config that we'll use to populate our repos. Offline config also
+	// includes targets so that attribute based rules can be evaluated.
+	localOpts := []func(c *local.Config){local.WithTargetRepo(targetRepo)}
+	if configPublicKey != "" {
+		publicKey, err := signing.LoadPublicKey(configPublicKey)
+		if err != nil {
+			logger.Error("failed to load config public key", "err", err)
+			os.Exit(1)
+		}
+		localOpts = append(localOpts, local.WithPublicKey(publicKey))
+	}
 
+	// Each proxy key gets its own config that shares the repos with the others. Local
+	// config isn't tied to a key and read replicas don't fetch config so if we're
+	// running offline or without a key we only need one config.
+	proxyKeys := parseProxyKeys()
+	if offline || len(proxyKeys) == 0 {
+		proxyKeys = []string{proxyKey}
 	}
 
-	reloadConfig := func() error {
-		return conf.FetchAndPopulate(ctx, inventoryRepo, authRepo, flagRepo, segmentRepo)
+	confs := make([]config.Config, 0, len(proxyKeys))
+	remoteConfs := remote.Configs{}
+	for _, key := range proxyKeys {
+		conf, err := config.NewConfig(offline, configDir, key, clientSvc, readReplicaSSEStream, sdkStream, eventRecorder, localOpts...)
+		if err != nil {
+			logger.Error("failed to load config", "err", err)
+			os.Exit(1)
+		}
+
+		confs = append(confs, conf)
+		if remoteConf, ok := conf.(*remote.Config); ok {
+			remoteConfs = append(remoteConfs, remoteConf)
+		}
 	}
 
-	// If we're running as a Primary we'll need to fetch the config and populate the cache
-	var configStatus domain.ConfigStatus
+	// keyHealth tracks the health of the Saas stream for each key
+	keyHealth := stream.NewKeyHealth(streamHealth, proxyKeys)
+
 	if !readReplica {
-		if err := conf.FetchAndPopulate(ctx, inventoryRepo, authRepo, flagRepo, segmentRepo); err != nil {
-			logger.Error("failed to populate repos with config", "err", err)
-			configStatus = domain.NewConfigStatus(domain.ConfigStateFailedToSync)
-		} else {
-			configStatus = domain.NewConfigStatus(domain.ConfigStateSynced)
+		statusOpts := []func(s *stream.StatusWorker){}
+		if len(remoteConfs) > 1 {
+			statusOpts = append(statusOpts, stream.WithKeyHealth(keyHealth, func(key string) []string {
+				for _, conf := range remoteConfs {
+					if conf.Key() == key {
+						return conf.Environments()
+					}
+				}
+				return nil
+			}))
+		}
+
+		s := stream.NewStatusWorker(streamHealth, primaryToReplicaControlStream, logger, statusOpts...)
+		go s.Start(ctx)
+	}
+
+	// Neither primaries or read replicas are ready to serve SDK requests until the
+	// cache has been populated with their config
+	readiness := health.NewReadiness(logger, false)
+
+	// If we're running as a Primary we'll need to fetch the config for each key and
+	// populate the cache. The Proxy's config is only synced if every key's config is.
+	configStatus := domain.NewConfigStatus(domain.ConfigStateSynced)
+	keyConfigStatuses := make([]domain.ConfigStatus, len(confs))
+	if !readReplica {
+		unpopulated := []config.Config{}
+		for i, conf := range confs {
+			if err := conf.FetchAndPopulate(ctx, inventoryRepo, authRepo, flagRepo, segmentRepo); err != nil {
+				logger.Error("failed to populate repos with config", "key", token.MaskRight(conf.Key()), "err", err)
+				keyConfigStatuses[i] = domain.NewConfigStatus(domain.ConfigStateFailedToSync)
+				configStatus = keyConfigStatuses[i]
+				unpopulated = append(unpopulated, conf)
+				continue
+			}
+			keyConfigStatuses[i] = domain.NewConfigStatus(domain.ConfigStateSynced)
+		}
+
+		// If we couldn't populate the cache we keep trying until we can, unless
+		// the cache already has the config from before we restarted e.g. because
+		// it's persisted to disk, in which case we can serve that in the meantime
+		if len(unpopulated) == 0 {
+			readiness.SetReady()
+		} else if !generateOfflineConfig {
+			cachePopulated := health.NewCacheReadinessCheck(inventoryRepo, func(context.Context, string) error { return nil })
+
+			go readiness.WaitUntilReady(ctx, 5*time.Second, func(ctx context.Context) error {
+				var lastErr error
+				failed := []config.Config{}
+				for _, conf := range unpopulated {
+					if err := conf.FetchAndPopulate(ctx, inventoryRepo, authRepo, flagRepo, segmentRepo); err != nil {
+						lastErr = err
+						failed = append(failed, conf)
+					}
+				}
+
+				unpopulated = failed
+				if len(unpopulated) == 0 {
+					return nil
+				}
+
+				if err := cachePopulated(ctx); err == nil {
+					return nil
+				}
+				return fmt.Errorf("failed to populate the cache with the config for %d keys: %s", len(unpopulated), lastErr)
+			})
 		}
+	}
 
-		// Set the accountID in the context, this way it can be included in headers
-		// for any requests the Proxy makes to Saas
-		ctx = context.WithValue(ctx, domain.ContextKeyAccountID, conf.AccountID())
+	// The api keys are read from the AuthRepo each time config is exported so
+	// that snapshots pick up keys that are added while the Proxy is running
+	exportFmt, _ := export.ParseFormat(exportFormat)
+	exportOpts := []func(s *export.Service){export.WithFormat(exportFmt)}
+	if exportSigningKey != "" {
+		signingKey, err := signing.LoadPrivateKey(exportSigningKey)
+		if err != nil {
+			logger.Error("failed to load export signing key", "err", err)
+			os.Exit(1)
+		}
+		exportOpts = append(exportOpts, export.WithSigningKey(signingKey))
+	}
+	exportService := export.NewService(logger, flagRepo, targetRepo, segmentRepo, authRepo, nil, configDir, exportOpts...)
+
+	// If we're only generating offline config we're done once it's been written
+	if generateOfflineConfig {
+		if configStatus.State != domain.ConfigStateSynced {
+			logger.Error("failed to generate offline config because the Proxy couldn't fetch its config")
+			os.Exit(1)
+		}
+		if err := exportService.Persist(ctx); err != nil {
+			logger.Error("failed to generate offline config", "dir", configDir, "err", err)
+			os.Exit(1)
+		}
+		logger.Info("generated offline config", "dir", configDir, "format", exportFmt)
+		os.Exit(0)
+	}
+
+	if exportInterval > 0 {
+		go exportService.ScheduleSnapshots(ctx, time.Duration(exportInterval)*time.Second, exportSnapshotsKeep)
 	}
 
 	// If we're running as a read replica then we want to subscribe to two streams
@@ -465,48 +882,135 @@ func main() {
 	// 2. The Redis stream that the primary sends control messages on e.g. stream disconnects
 	//   - The replica subscribes to this stream and when it gets a stream disconnect message
 	//     it closes any open streams with SDKs to force them to poll for changes
+	//
+	// Read replicas aren't ready to serve SDK requests until the primary has populated
+	// the cache so we wait for everything in the key inventory to exist and load it
+	// into the HashCache's local cache before we mark the replica as ready.
 	if readReplica {
 		configStatus = domain.NewConfigStatus(domain.ConfigStateReadReplica)
 		primaryToReplicaControlStream.Subscribe(ctx)
 		readReplicaSSEStream.Subscribe(ctx)
+
+		warm := func(ctx context.Context, envID string) error {
+			if _, err := flagRepo.Get(ctx, envID); err != nil && !errors.Is(err, domain.ErrCacheNotFound) {
+				return err
+			}
+			if _, err := segmentRepo.Get(ctx, envID); err != nil && !errors.Is(err, domain.ErrCacheNotFound) {
+				return err
+			}
+			return nil
+		}
+		go readiness.WaitUntilReady(ctx, 5*time.Second, health.NewCacheReadinessCheck(inventoryRepo, warm))
+	} else if offline {
+
+		// If we're running in offline mode there's no Saas stream to subscribe to,
+		// instead we watch the config directory and send events to connected SDKs
+		// for any flags or segments that change when it's reloaded
+		messageHandler = recordEvents(stream.NewForwarder(logger, sdkStream, domain.NoOpMessageHandler{}))
+
+		localConf, ok := confs[0].(local.Config)
+		if ok && configWatchInterval > 0 {
+			watcher, err := local.NewWatcher(logger, configDir, localConf, authRepo, flagRepo, segmentRepo, messageHandler, local.WithWatchInterval(time.Duration(configWatchInterval)*time.Second))
+			if err != nil {
+				logger.Error("failed to watch config directory for changes", "err", err)
+			} else {
+				go watcher.Watch(ctx)
+			}
+		}
 	} else {
 
-		// If we're running as a Primary Proxy then we do the following
+		// If we're running as a Primary Proxy then we do the following for each key
 		//
 		// 1. Subscribe to the Saas SSE stream
 		// 2. Refresh the cache when we receive an SSE event
 		// 3. Forward events we receive on the Saas SSE Stream to read replica Proxy's
 		// 4. Forward events from the Saas SSE stream on to connected SDKs
-		cacheRefresher := cache.NewRefresher(logger, conf, clientSvc, inventoryRepo, authRepo, flagRepo, segmentRepo)
-		redisForwarder := stream.NewForwarder(logger, redisStream, cacheRefresher, stream.WithStreamName(sseStreamTopic))
-		messageHandler = stream.NewForwarder(logger, pushpinStream, redisForwarder)
-
 		pollingStatus := stream.NewPollingStatusMetric(promReg)
 
-		streamURL := fmt.Sprintf("%s/stream?cluster=%s", clientService, conf.ClusterIdentifier())
-		sseClient := stream.NewSSEClient(
-			logger,
-			streamURL,
-			proxyKey,
-			conf.Token(),
-			conf.AccountID(),
-			stream.SaasStreamOnConnect(logger, streamHealth, reloadConfig, primaryToReplicaControlStream, pollingStatus),
-			stream.SaasStreamOnDisconnect(logger, streamHealth, pushpin, primaryToReplicaControlStream, getConnectedStreams, reloadConfig, pollingStatus),
-		)
+		var saasStreamMetrics *stream.PrometheusStream
+		for _, conf := range remoteConfs {
+			conf := conf
+			keyStreamHealth := keyHealth.ForKey(conf.Key())
+			keyEnvironments := environmentsForKey(conf, len(remoteConfs) > 1)
+
+			reloadConfig := func() error {
+				return conf.FetchAndPopulate(ctx, inventoryRepo, authRepo, flagRepo, segmentRepo)
+			}
+
+			cacheRefresher := cache.NewRefresher(logger, conf, clientSvc, inventoryRepo, authRepo, flagRepo, segmentRepo)
+			redisForwarder := stream.NewForwarder(logger, redisStream, cacheRefresher, stream.WithStreamName(sseStreamTopic))
+			messageHandler = recordEvents(stream.NewForwarder(logger, sdkStream, redisForwarder))
+
+			streamURL := fmt.Sprintf("%s/stream?cluster=%s", clientService, conf.ClusterIdentifier())
+			sseClient := stream.NewSSEClient(
+				logger,
+				streamURL,
+				conf.Key(),
+				conf.Token(),
+				conf.AccountID(),
+				stream.SaasStreamOnConnect(logger, keyStreamHealth, reloadConfig, primaryToReplicaControlStream, keyEnvironments, pollingStatus),
+				stream.SaasStreamOnDisconnect(logger, keyStreamHealth, sdkStreamCloser, primaryToReplicaControlStream, keyEnvironments, streamsForKey(conf, getStreamsToClose), reloadConfig, pollingStatus),
+			)
+
+			if saasStreamMetrics == nil {
+				saasStreamMetrics = stream.NewPrometheusStream("ff_proxy_saas_to_primary_sse_consumer", sseClient, promReg)
+			}
 
-		saasStream := stream.NewStream(
-			logger,
-			"*",
-			stream.NewPrometheusStream("ff_proxy_saas_to_primary_sse_consumer", sseClient, promReg),
-			messageHandler,
+			saasStream := stream.NewStream(
+				logger,
+				"*",
+				saasStreamMetrics.Decorate(sseClient),
+				messageHandler,
+				stream.WithTracerProvider(tp),
+			)
+			saasStream.Subscribe(ctx)
+
+			if resyncInterval > 0 {
+				go conf.Resync(ctx, logger, time.Duration(resyncInterval)*time.Second, inventoryRepo, authRepo, flagRepo, segmentRepo)
+			}
+		}
+	}
+
+	// Override changes are sent to our SDKs and to read replicas, which forward them on
+	// to their own SDKs. Read replicas can't send events to the primary's SDKs so they
+	// only serve the overrides and overrides can only be changed on the primary.
+	var overrideService *overrides.Service
+	if flagOverridesEnabled {
+		var (
+			overrideHandler domain.MessageHandler = domain.NoOpMessageHandler{}
+			overrideOpts                          = []func(s *overrides.Service){}
 		)
-		saasStream.Subscribe(ctx)
+		switch {
+		case readReplica:
+			overrideOpts = append(overrideOpts, overrides.WithReadOnly())
+		case offline:
+			overrideHandler = stream.NewForwarder(logger, sdkStream, domain.NoOpMessageHandler{})
+		default:
+			replicaForwarder := stream.NewForwarder(logger, redisStream, domain.NoOpMessageHandler{}, stream.WithStreamName(sseStreamTopic))
+			overrideHandler = stream.NewForwarder(logger, sdkStream, replicaForwarder)
+		}
+
+		s := overrides.NewService(logger, overrideRepo, flagRepo, authRepo, recordEvents(overrideHandler), overrideOpts...)
+		overrideService = &s
+
+		// Only one Proxy loads the overrides file and expires overrides so that
+		// SDKs don't get duplicate events
+		if !readReplica {
+			if flagOverridesFile != "" {
+				if err := overrideService.LoadFile(ctx, flagOverridesFile); err != nil {
+					logger.Error("failed to load flag overrides", "file", flagOverridesFile, "err", err)
+					os.Exit(1)
+				}
+			}
+			go overrideService.ScheduleExpiry(ctx, 10*time.Second)
+		}
 	}
 
 	metricsEnabled := metricPostDuration != 0 && !offline
 	metricStore := newMetricStore(ctx, logger, readReplica, redisClient, promReg, metricsStreamMaxLen, metricPostDuration)
 
-	ms, err := metricsservice.NewClient(logger, metricService, conf.Token, promReg)
+	// Metrics are sent to Saas with the token, account and cluster of the key that the environment belongs to
+	ms, err := metricsservice.NewClient(logger, metricService, remoteConfs.Token, remoteConfs.AccountID, promReg, tp)
 	if err != nil {
 		logger.Error("failed to create client for the feature flags metric service", "err", err)
 		os.Exit(1)
@@ -516,15 +1020,28 @@ func main() {
 	// sent by read replicas and sends them on to Saas. Only bother to start
 	// worker if sending metrics is actually enabled.
 	if !readReplica && metricsEnabled {
-		metricsStreamConsumer := stream.NewPrometheusStream("ff_proxy_primary_metrics_stream_consumer", stream.NewRedisStream(redisClient), promReg)
+		metricsStreamConsumer := stream.NewPrometheusStream("ff_proxy_primary_metrics_stream_consumer", stream.NewNamespaceStream(redisNamespace, newMetricsStreamConsumer(logger, redisClient, time.Duration(metricPostDuration)*time.Second)), promReg)
 		store, _ := metricStore.(metricsservice.Queue)
-		worker := metricsservice.NewWorker(logger, store, ms, metricsStreamConsumer, metricsStreamReadConcurrency, conf.ClusterIdentifier())
+		worker := metricsservice.NewWorker(logger, store, ms, metricsStreamConsumer, metricsStreamReadConcurrency, remoteConfs.ClusterIdentifier)
 		worker.Start(ctx)
 	}
 
 	apiKeyHasher := hash.NewSha256()
+
+	// apiKeyExists is used to only rate limit auth requests by their api key if it's one of ours
+	apiKeyExists := func(ctx context.Context, hashedAPIKey string) bool {
+		_, ok, err := authRepo.Get(ctx, domain.NewAuthAPIKey(hashedAPIKey))
+		return err == nil && ok
+	}
 	tokenSource := token.NewSource(logger, authRepo, apiKeyHasher, []byte(authSecret))
-	proxyHealth := health.NewProxyHealth(logger, configStatus, streamHealth.Status, cacheHealthCheck)
+	// If the Proxy has more than one key we report the health of each of them too
+	healthOpts := []func(p *health.ProxyHealth){}
+	if len(remoteConfs) > 1 && !readReplica {
+		for i, conf := range remoteConfs {
+			healthOpts = append(healthOpts, health.WithProxyKeyHealth(conf.Key(), keyConfigStatuses[i], keyHealth.ForKey(conf.Key()).Status))
+		}
+	}
+	proxyHealth := health.NewProxyHealth(logger, configStatus, streamHealth.Status, cacheHealthCheck, healthOpts...)
 	proxyHealth.PollCacheHealth(ctx, 1*time.Minute)
 
 	// Setup service and middleware
@@ -540,13 +1057,37 @@ func main() {
 		Offline:       offline,
 		Hasher:        apiKeyHasher,
 		Health:        proxyHealth.Health,
-		HealthySaasStream: func() bool {
+		HealthySaasStream: func(envID string) bool {
+			// In offline mode there's no Saas stream, any events for connected
+			// SDKs come from the config watcher if it's enabled
+			if offline {
+				return true
+			}
+
+			// If we're resyncing then SDKs are sent events for changes that are
+			// fetched while the Saas stream is down
+			if resyncInterval > 0 {
+				return true
+			}
+
 			streamStatus, err := streamHealth.Status(ctx)
 			if err != nil {
 				logger.Error("failed to check status of saas -> proxy stream health", "err", err)
 				return false
 			}
-			return streamStatus.State == domain.StreamStateConnected
+			if streamStatus.State != domain.StreamStateConnected {
+				return false
+			}
+
+			// If the Proxy has more than one key we only refuse streams for the
+			// environments that belong to a key whose Saas stream is down
+			if readReplica {
+				return envHealth.Healthy(envID)
+			}
+			if conf, ok := remoteConfs.ForEnvironment(envID); ok && len(remoteConfs) > 1 && conf.HasEnvironment(envID) {
+				return keyHealth.Status(conf.Key()).State == domain.StreamStateConnected
+			}
+			return true
 		},
 		SDKStreamConnected: func(envID string) {
 			connectedStreams.Set(envID, "")
@@ -556,14 +1097,61 @@ func main() {
 	})
 
 	// Configure endpoints and server
-	endpoints := transport.NewEndpoints(service)
-	server := transport.NewHTTPServer(port, endpoints, logger, tlsEnabled, tlsCert, tlsKey)
+	endpoints := transport.NewEndpoints(proxyservice.NewTracingService(service, tp))
+	serverOpts := []transport.HTTPServerOption{transport.WithReadiness(readiness)}
+	if !pushpinEnabled {
+		serverOpts = append(serverOpts, transport.WithSSEServer(broadcaster))
+	} else if sdkEventHistory != nil {
+		serverOpts = append(serverOpts, transport.WithEventHistory(sdkEventHistory))
+	}
+
+	if adminToken != "" {
+		adminKeys := make([]string, 0, len(confs))
+		for _, conf := range confs {
+			adminKeys = append(adminKeys, conf.Key())
+		}
+
+		adminConfig := proxyservice.AdminConfig{
+			Logger:           logger,
+			AuthRepo:         authRepo,
+			FeatureRepo:      flagRepo,
+			SegmentRepo:      segmentRepo,
+			InventoryRepo:    inventoryRepo,
+			ProxyKeys:        adminKeys,
+			ConnectedStreams: connectedStreams.Get,
+			Health:           proxyHealth.Health,
+		}
+		if overrideService != nil {
+			adminConfig.Overrides = overrideService
+		}
+
+		adminService := proxyservice.NewAdminService(adminConfig)
+		adminEndpoints := transport.NewAdminEndpoints(adminService)
+		serverOpts = append(serverOpts, transport.WithAdminEndpoints(adminEndpoints, middleware.NewEchoAdminAuthMiddleware(adminToken)))
+	}
+
+	rateLimits, err := parseRateLimits()
+	if err != nil {
+		logger.Error("invalid rate limit config", "err", err)
+		os.Exit(1)
+	}
+
+	// If we've got redis we store the rate limit counters there so that limits
+	// are shared by all of the Proxy instances
+	var rateLimiter middleware.RateLimiter = middleware.NewMemoryRateLimiter()
+	if redisClient != nil {
+		rateLimiter = middleware.NewRedisRateLimiter(redisClient, middleware.WithRateLimitNamespace(redisNamespace))
+	}
+
+	server := transport.NewHTTPServer(port, endpoints, logger, tlsEnabled, tlsCert, tlsKey, serverOpts...)
 	server.Use(
 		middleware.AllowQuerySemicolons(),
 		middleware.NewCorsMiddleware(),
 		middleware.NewEchoRequestIDMiddleware(),
+		middleware.NewEchoTracingMiddleware(tp),
 		middleware.NewEchoLoggingMiddleware(logger),
 		middleware.NewEchoAuthMiddleware(logger, authRepo, []byte(authSecret), bypassAuth),
+		middleware.NewEchoRateLimitMiddleware(logger, rateLimiter, rateLimits, middleware.RateLimitKey(rateLimitKey), apiKeyExists),
 		middleware.NewPrometheusMiddleware(promReg),
 	)
 
@@ -595,6 +1183,10 @@ func main() {
 
 	}()
 
+	if grpcPort != 0 {
+		runGRPCServer(ctx, grpcPort, endpoints, authRepo, broadcaster, logger)
+	}
+
 	protocol := "http"
 	if tlsEnabled {
 		protocol = "https"
@@ -606,43 +1198,112 @@ func main() {
 	}
 }
 
-// checks the health of the connected cache instance
-func cacheHealthCheck(ctx context.Context) error {
-	return sdkCache.HealthCheck(ctx)
+// parseProxyKeys splits the comma separated list of keys the Proxy has been configured with
+func parseProxyKeys() []string {
+	keys := []string{}
+	for _, key := range strings.Split(proxyKey, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
 }
 
-func loadFlagsFromEnv(envToFlag map[string]string) {
-	for k, v := range envToFlag {
-		val := os.Getenv(k)
-		if val == "" {
-			continue
+// environmentsForKey returns a func that returns the environments that belong to the
+// key. If the Proxy only has one key its Saas stream is for all of the environments
+// so it returns no environments.
+func environmentsForKey(conf *remote.Config, multipleKeys bool) func() []string {
+	return func() []string {
+		if !multipleKeys {
+			return nil
 		}
-		os.Args = append(os.Args, fmt.Sprintf("--%s=%s", v, val))
+		return conf.Environments()
 	}
 }
 
-func validateFlags(flags map[string]interface{}) {
-	unset := []string{}
-	for k, v := range flags {
-		switch t := v.(type) {
-		case string:
-			if t == "" {
-				unset = append(unset, k)
-			}
-		case int:
-			if t == 0 {
-				unset = append(unset, k)
-			}
-		case []string:
-			if len(t) == 0 {
-				unset = append(unset, k)
+// streamsForKey returns a func that returns the SDK streams for the environments
+// that belong to the key out of the streams returned by the streams func
+func streamsForKey(conf *remote.Config, streams func() map[string]interface{}) func() map[string]interface{} {
+	return func() map[string]interface{} {
+		keyStreams := map[string]interface{}{}
+		for envID, v := range streams() {
+			if conf.HasEnvironment(envID) {
+				keyStreams[envID] = v
 			}
 		}
+		return keyStreams
 	}
+}
 
-	if len(unset) > 0 {
-		stdlog.Fatalf("The following configuration values are required: %v ", unset)
+// parseRateLimits parses the rate limits for each class of route
+func parseRateLimits() (map[middleware.RouteClass]middleware.RateLimit, error) {
+	if rateLimitKey != string(middleware.RateLimitKeyAPIKey) && rateLimitKey != string(middleware.RateLimitKeyEnvironment) {
+		return nil, fmt.Errorf("invalid %s %q, valid options are %s & %s", rateLimitKeyEnv, rateLimitKey, middleware.RateLimitKeyAPIKey, middleware.RateLimitKeyEnvironment)
+	}
+
+	limits := map[middleware.RouteClass]middleware.RateLimit{}
+	for class, s := range map[middleware.RouteClass]string{
+		middleware.RouteClassAuth:        rateLimitAuth,
+		middleware.RouteClassPolling:     rateLimitPolling,
+		middleware.RouteClassEvaluations: rateLimitEvaluations,
+		middleware.RouteClassMetrics:     rateLimitMetrics,
+	} {
+		limit, err := middleware.ParseRateLimit(s)
+		if err != nil {
+			return nil, err
+		}
+		limits[class] = limit
 	}
+	return limits, nil
+}
+
+func runGRPCServer(ctx context.Context, port int, endpoints *transport.Endpoints, authRepo repository.AuthRepo, broadcaster *stream.Broadcaster, logger log.Logger) {
+	unaryRequestID, streamRequestID := middleware.NewGRPCRequestIDInterceptors()
+	unaryLogging, streamLogging := middleware.NewGRPCLoggingInterceptors(logger)
+	unaryAuth, streamAuth := middleware.NewGRPCAuthInterceptors(logger, authRepo, []byte(authSecret), bypassAuth)
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			unaryRequestID,
+			unaryLogging,
+			unaryAuth,
+		),
+		grpc.ChainStreamInterceptor(
+			streamRequestID,
+			streamLogging,
+			streamAuth,
+		),
+	}
+
+	if tlsEnabled {
+		creds, err := credentials.NewServerTLSFromFile(tlsCert, tlsKey)
+		if err != nil {
+			logger.Error("failed to load tls credentials for grpc server", "err", err)
+			os.Exit(1)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	grpcServer := transport.NewGRPCServer(port, endpoints, logger, broadcaster, opts...)
+
+	go func() {
+		if err := grpcServer.Serve(); err != nil {
+			logger.Error("grpc server stopped", "err", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		grpcServer.Shutdown(shutdownCtx)
+	}()
+}
+
+// checks the health of the connected cache instance
+func cacheHealthCheck(ctx context.Context) error {
+	return sdkCache.HealthCheck(ctx)
 }
 
 // newMetricStore creates a MetricStore. If we are running as a read replica it returns a MetricStore that pushes
@@ -653,9 +1314,12 @@ func newMetricStore(ctx context.Context, logger log.Logger, readReplica bool, re
 		return metricsservice.NewStream(
 			stream.NewPrometheusStream(
 				"ff_proxy_replica_metrics_stream_producer",
-				stream.NewRedisStream(
-					redisClient,
-					stream.WithMaxLen(maxLen),
+				stream.NewNamespaceStream(
+					redisNamespace,
+					stream.NewRedisStream(
+						redisClient,
+						stream.WithMaxLen(maxLen),
+					),
 				),
 				promReg,
 			),
@@ -665,6 +1329,34 @@ func newMetricStore(ctx context.Context, logger log.Logger, readReplica bool, re
 	return metricsservice.NewQueue(ctx, logger, time.Duration(metricPostDuration)*time.Second)
 }
 
+// newMetricsStreamConsumer creates the RedisStream that the Primary reads metrics from. If a consumer group
+// is configured each Primary reads as a consumer in it, named after its host, so that metrics are only sent once.
+// Metrics are acked by the Worker once they've been posted so they have to be left pending for longer than it
+// takes to post them before another Primary can claim them.
+func newMetricsStreamConsumer(logger log.Logger, redisClient redis.UniversalClient, postDuration time.Duration) stream.RedisStream {
+	if metricsStreamConsumerGroup == "" {
+		return stream.NewRedisStream(redisClient)
+	}
+
+	consumer, err := os.Hostname()
+	if err != nil {
+		logger.Error("failed to get hostname to use as the metrics stream consumer", "err", err)
+		os.Exit(1)
+	}
+	claimMinIdle := 2 * postDuration
+	if claimMinIdle < time.Minute {
+		claimMinIdle = time.Minute
+	}
+
+	return stream.NewRedisStream(
+		redisClient,
+		stream.WithConsumerGroup(metricsStreamConsumerGroup, consumer),
+		stream.WithManualAck(),
+		stream.WithClaimMinIdle(claimMinIdle),
+		stream.WithLogger(logger),
+	)
+}
+
 func removeRedisScheme(addr string) string {
 	return strings.TrimPrefix(strings.TrimPrefix(addr, "redis://"), "rediss://")
 }
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/harness/ff-proxy/v2/codec"
+	"github.com/harness/ff-proxy/v2/config/local"
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/export"
+	"github.com/harness/ff-proxy/v2/signing"
+)
+
+// secretFlags are the flags whose values are redacted when the config is printed
+var secretFlags = map[string]struct{}{
+	proxyKeyFlag:      {},
+	authSecretFlag:    {},
+	redisPasswordFlag: {},
+	adminTokenFlag:    {},
+}
+
+// loadSettings sets every flag that wasn't passed on the command line from the
+// config file and environment variables. The order of precedence is config file,
+// then environment variables, then flags so a flag always wins.
+//
+// It should be called after the FlagSet has been parsed.
+func loadSettings(fs *flag.FlagSet, envToFlag map[string]string) error {
+	setOnCommandLine := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+
+	path := fs.Lookup(configFileFlag).Value.String()
+	if !setOnCommandLine[configFileFlag] {
+		if p := os.Getenv(configFileEnv); p != "" {
+			path = p
+		}
+	}
+
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+
+		// Sort the names so that errors are reported in the same order every time
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if fs.Lookup(name) == nil {
+				return fmt.Errorf("unknown setting %q in config file %s", name, path)
+			}
+			if setOnCommandLine[name] {
+				continue
+			}
+			if err := fs.Set(name, values[name]); err != nil {
+				return fmt.Errorf("invalid value for %q in config file %s: %s", name, path, err)
+			}
+		}
+	}
+
+	for env, name := range envToFlag {
+		val := os.Getenv(env)
+		if val == "" || setOnCommandLine[name] {
+			continue
+		}
+		if err := fs.Set(name, val); err != nil {
+			return fmt.Errorf("invalid value for %s: %s", env, err)
+		}
+	}
+	return nil
+}
+
+// readConfigFile reads a YAML config file and returns the value for each setting
+// in it. Settings are named after their flags and can either be written flat e.g.
+//
+//	redis-address: localhost:6379
+//
+// or grouped by the first part of their name e.g.
+//
+//	redis:
+//	  address: localhost:6379
+func readConfigFile(path string) (map[string]string, error) {
+	// #nosec G304
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %s", err)
+	}
+
+	doc := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %s", path, err)
+	}
+
+	values := map[string]string{}
+	flattenSettings("", doc, values)
+	return values, nil
+}
+
+func flattenSettings(prefix string, doc map[string]interface{}, values map[string]string) {
+	for k, v := range doc {
+		name := k
+		if prefix != "" {
+			name = prefix + "-" + k
+		}
+
+		switch t := v.(type) {
+		case map[string]interface{}:
+			flattenSettings(name, t, values)
+		case []interface{}:
+			items := make([]string, 0, len(t))
+			for _, item := range t {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[name] = strings.Join(items, ",")
+		case nil:
+			values[name] = ""
+		default:
+			values[name] = fmt.Sprint(t)
+		}
+	}
+}
+
+// settingError describes why the value of a setting is invalid
+type settingError struct {
+	Setting string
+	Reason  string
+}
+
+func (s settingError) Error() string {
+	return fmt.Sprintf("%s: %s", s.Setting, s.Reason)
+}
+
+// settingErrors is every invalid setting that was found during validation
+type settingErrors []settingError
+
+func (s settingErrors) Error() string {
+	msgs := make([]string, 0, len(s))
+	for _, e := range s {
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Sprintf("invalid config: %s", strings.Join(msgs, "; "))
+}
+
+// validateSettings checks that the settings the Proxy has been configured with
+// make sense together and returns a settingErrors if any of them don't
+//
+//nolint:gocognit,cyclop
+func validateSettings() error {
+	errs := settingErrors{}
+	invalid := func(setting string, format string, args ...interface{}) {
+		errs = append(errs, settingError{Setting: setting, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if !offline && !readReplica && len(parseProxyKeys()) == 0 {
+		invalid(proxyKeyFlag, "a proxy key is required unless the Proxy is running offline or as a read replica")
+	}
+	seenKeys := map[string]struct{}{}
+	for _, key := range parseProxyKeys() {
+		if _, ok := seenKeys[key]; ok {
+			invalid(proxyKeyFlag, "each proxy key can only be listed once")
+			break
+		}
+		seenKeys[key] = struct{}{}
+	}
+
+	if offline && readReplica {
+		invalid(readReplicaFlag, "a read replica can't run in offline mode")
+	}
+	if offline && generateOfflineConfig {
+		invalid(generateOfflineConfigFlag, "offline config can't be generated in offline mode")
+	}
+	if readReplica && generateOfflineConfig {
+		invalid(generateOfflineConfigFlag, "offline config can't be generated by a read replica")
+	}
+	if offline && resyncInterval > 0 {
+		invalid(resyncIntervalFlag, "config can't be resynced in offline mode")
+	}
+	if offline && exportInterval > 0 {
+		invalid(exportIntervalFlag, "config snapshots can't be taken in offline mode")
+	}
+	if flagOverridesFile != "" {
+		if _, err := os.Stat(flagOverridesFile); err != nil {
+			invalid(flagOverridesFileFlag, "%s", err)
+		}
+		if !flagOverridesEnabled {
+			invalid(flagOverridesFileFlag, "%s has to be enabled to load an overrides file", flagOverridesEnabledFlag)
+		}
+		if readReplica {
+			invalid(flagOverridesFileFlag, "the overrides file is loaded by the primary proxy")
+		}
+	}
+	if readReplica && redisAddress == "" {
+		invalid(redisAddressFlag, "a redis address is required to run as a read replica")
+	}
+
+	if tlsEnabled {
+		for setting, path := range map[string]string{tlsCertFlag: tlsCert, tlsKeyFlag: tlsKey} {
+			if path == "" {
+				invalid(setting, "is required when tls is enabled")
+				continue
+			}
+			if _, err := os.Stat(path); err != nil {
+				invalid(setting, "%s", err)
+			}
+		}
+	}
+
+	for setting, p := range map[string]int{portFlag: port, prometheusPortFlag: prometheusPort} {
+		if p < 1 || p > 65535 {
+			invalid(setting, "%d is not a valid port", p)
+		}
+	}
+	if grpcPort != 0 {
+		if grpcPort < 0 || grpcPort > 65535 {
+			invalid(grpcPortFlag, "%d is not a valid port", grpcPort)
+		}
+		if grpcPort == port {
+			invalid(grpcPortFlag, "can't be the same as %s", portFlag)
+		}
+	}
+
+	for setting, v := range map[string]int{
+		metricPostDurationFlag:  metricPostDuration,
+		heartbeatIntervalFlag:   heartbeatInterval,
+		resyncIntervalFlag:      resyncInterval,
+		configWatchIntervalFlag: configWatchInterval,
+		exportIntervalFlag:      exportInterval,
+		exportSnapshotsKeepFlag: exportSnapshotsKeep,
+		redisDBFlag:             redisDB,
+		eventHistoryFlag:        eventHistory,
+	} {
+		if v < 0 {
+			invalid(setting, "can't be negative")
+		}
+	}
+	if redisPoolSize < 1 {
+		invalid(redisPoolSizeFlag, "must be at least 1")
+	}
+
+	switch logLevel {
+	case "INFO", "DEBUG", "ERROR":
+	default:
+		invalid(logLevelFlag, "%q isn't a valid log level, valid options are INFO, DEBUG & ERROR", logLevel)
+	}
+
+	if tracingSampleRatio < 0 || tracingSampleRatio > 1 {
+		invalid(tracingSampleRatioFlag, "must be between 0 and 1")
+	}
+
+	if _, err := export.ParseFormat(exportFormat); err != nil {
+		invalid(exportFormatFlag, "%s", err)
+	}
+	if generateOfflineConfig && local.IsBundle(configDir) != (exportFormat == string(export.FormatZip) || exportFormat == string(export.FormatTarGz)) {
+		invalid(configDirFlag, "%s should be a directory for the json & yaml export formats and a .zip or .tar.gz file for the zip & tar.gz formats", configDir)
+	}
+	if configPublicKey != "" {
+		if _, err := signing.LoadPublicKey(configPublicKey); err != nil {
+			invalid(configPublicKeyFlag, "%s", err)
+		}
+		if !offline {
+			invalid(configPublicKeyFlag, "config is only verified in offline mode")
+		}
+	}
+	if exportSigningKey != "" {
+		if _, err := signing.LoadPrivateKey(exportSigningKey); err != nil {
+			invalid(exportSigningKeyFlag, "%s", err)
+		}
+	}
+	if _, err := codec.Parse(cacheCodec); err != nil {
+		invalid(cacheCodecFlag, "%s", err)
+	}
+	if err := domain.ValidateNamespace(redisNamespace); err != nil {
+		invalid(redisNamespaceFlag, "%s", err)
+	}
+	if _, err := parseRateLimits(); err != nil {
+		invalid("rate-limit", "%s", err)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Setting < errs[j].Setting
+	})
+	return errs
+}
+
+// effectiveSettings returns the value of every setting with the secrets redacted
+func effectiveSettings(fs *flag.FlagSet) map[string]interface{} {
+	settings := map[string]interface{}{}
+	fs.VisitAll(func(f *flag.Flag) {
+		var value interface{} = f.Value.String()
+		if getter, ok := f.Value.(flag.Getter); ok {
+			value = getter.Get()
+		}
+
+		if _, ok := secretFlags[f.Name]; ok && f.Value.String() != "" {
+			value = "<redacted>"
+		}
+		settings[f.Name] = value
+	})
+	return settings
+}
@@ -0,0 +1,222 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Values encoded by a Codec are wrapped in an envelope that starts with a
+// small header so that readers can tell how the body was encoded:
+//
+//	| 0xFF | version | format | compression | body... |
+//
+// 0xFF can never be the first byte of a json document so values without the
+// header are treated as plain json. This means Proxies can read values written
+// before the codec was introduced and values written by Proxies using a
+// different codec while a fleet is being rolled out.
+const (
+	envelopeMagic   byte = 0xFF
+	envelopeVersion byte = 1
+	headerLen            = 4
+)
+
+var (
+	// ErrUnsupportedEnvelope is returned when we try to decode a value that has
+	// an envelope header we don't understand
+	ErrUnsupportedEnvelope = errors.New("unsupported envelope")
+
+	// ErrInvalidCodec is returned when we can't parse a codec name
+	ErrInvalidCodec = errors.New("invalid codec")
+)
+
+// Format is how the body of an envelope is encoded
+type Format byte
+
+const (
+	// FormatJSON encodes values as json
+	FormatJSON Format = 1
+	// FormatMsgpack encodes values as msgpack
+	FormatMsgpack Format = 2
+)
+
+// Compression is how the body of an envelope is compressed
+type Compression byte
+
+const (
+	// CompressionNone doesn't compress values
+	CompressionNone Compression = 0
+	// CompressionZstd compresses values with zstd
+	CompressionZstd Compression = 1
+)
+
+// zstd encoders and decoders are expensive to create but safe to share when
+// using EncodeAll/DecodeAll so we only create them once they're needed
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil)
+	})
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil)
+	})
+)
+
+// Codec marshals and unmarshals values. Its Marshal and Unmarshal methods can be
+// passed to cache.WithMarshalFunc and cache.WithUnmarshalFunc.
+type Codec struct {
+	format      Format
+	compression Compression
+}
+
+// New creates a Codec
+func New(format Format, compression Compression) Codec {
+	return Codec{format: format, compression: compression}
+}
+
+// Parse creates a Codec from a name in the form <format>[+<compression>]
+// e.g. json, msgpack or msgpack+zstd
+func Parse(s string) (Codec, error) {
+	name, compressionName, _ := strings.Cut(s, "+")
+
+	var format Format
+	switch name {
+	case "json":
+		format = FormatJSON
+	case "msgpack":
+		format = FormatMsgpack
+	default:
+		return Codec{}, fmt.Errorf("%w: unknown format %q", ErrInvalidCodec, name)
+	}
+
+	var compression Compression
+	switch compressionName {
+	case "", "none":
+		compression = CompressionNone
+	case "zstd":
+		compression = CompressionZstd
+	default:
+		return Codec{}, fmt.Errorf("%w: unknown compression %q", ErrInvalidCodec, compressionName)
+	}
+
+	return New(format, compression), nil
+}
+
+// String returns the name of the codec in the same form that Parse accepts
+func (c Codec) String() string {
+	name := "json"
+	if c.format == FormatMsgpack {
+		name = "msgpack"
+	}
+	if c.compression == CompressionZstd {
+		name += "+zstd"
+	}
+	return name
+}
+
+// Marshal encodes the value. Uncompressed json is written without an envelope
+// so that it can still be read by Proxies that predate the codec.
+func (c Codec) Marshal(v interface{}) ([]byte, error) {
+	if c.format == FormatJSON && c.compression == CompressionNone {
+		return jsoniter.Marshal(v)
+	}
+
+	body, err := encode(c.format, v)
+	if err != nil {
+		return nil, err
+	}
+
+	header := []byte{envelopeMagic, envelopeVersion, byte(c.format), byte(c.compression)}
+
+	switch c.compression {
+	case CompressionNone:
+		return append(header, body...), nil
+	case CompressionZstd:
+		enc, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return enc.EncodeAll(body, header), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown compression %d", ErrUnsupportedEnvelope, c.compression)
+	}
+}
+
+// Unmarshal decodes the value. It can decode values written by any Codec, not
+// just ones that use the same format and compression as c.
+func (c Codec) Unmarshal(b []byte, v interface{}) error {
+	return Unmarshal(b, v)
+}
+
+// Unmarshal decodes a value that was written by a Codec or that is plain json
+func Unmarshal(b []byte, v interface{}) error {
+	if len(b) == 0 || b[0] != envelopeMagic {
+		return jsoniter.Unmarshal(b, v)
+	}
+
+	if len(b) < headerLen {
+		return fmt.Errorf("%w: header is %d bytes long, expected %d", ErrUnsupportedEnvelope, len(b), headerLen)
+	}
+
+	version, format, compression, body := b[1], Format(b[2]), Compression(b[3]), b[headerLen:]
+	if version != envelopeVersion {
+		return fmt.Errorf("%w: unknown version %d", ErrUnsupportedEnvelope, version)
+	}
+
+	switch compression {
+	case CompressionNone:
+	case CompressionZstd:
+		dec, err := zstdDecoder()
+		if err != nil {
+			return err
+		}
+
+		body, err = dec.DecodeAll(body, nil)
+		if err != nil {
+			return fmt.Errorf("failed to decompress value: %w", err)
+		}
+	default:
+		return fmt.Errorf("%w: unknown compression %d", ErrUnsupportedEnvelope, compression)
+	}
+
+	return decode(format, body, v)
+}
+
+func encode(format Format, v interface{}) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return jsoniter.Marshal(v)
+	case FormatMsgpack:
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		// Our types are generated from the OpenAPI spec and only have json tags
+		enc.SetCustomStructTag("json")
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown format %d", ErrUnsupportedEnvelope, format)
+	}
+}
+
+func decode(format Format, body []byte, v interface{}) error {
+	switch format {
+	case FormatJSON:
+		return jsoniter.Unmarshal(body, v)
+	case FormatMsgpack:
+		dec := msgpack.NewDecoder(bytes.NewReader(body))
+		dec.SetCustomStructTag("json")
+		// Without this numbers in interface{} values, e.g. target attributes,
+		// would be decoded to the smallest type that fits them
+		dec.UseLooseInterfaceDecoding(true)
+		return dec.Decode(v)
+	default:
+		return fmt.Errorf("%w: unknown format %d", ErrUnsupportedEnvelope, format)
+	}
+}
@@ -0,0 +1,164 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type variation struct {
+	Identifier string  `json:"identifier"`
+	Value      string  `json:"value"`
+	Name       *string `json:"name,omitempty"`
+}
+
+type featureConfig struct {
+	Feature     string                  `json:"feature"`
+	Version     *int64                  `json:"version,omitempty"`
+	Variations  []variation             `json:"variations"`
+	Attributes  *map[string]interface{} `json:"attributes,omitempty"`
+	Environment string                  `json:"environment"`
+}
+
+func newFeatureConfig() featureConfig {
+	version := int64(3)
+	name := "True"
+	attributes := map[string]interface{}{"email": "foo@bar.com", "age": int64(42)}
+
+	return featureConfig{
+		Feature: "bool-flag",
+		Version: &version,
+		Variations: []variation{
+			{Identifier: "true", Value: "true", Name: &name},
+			{Identifier: "false", Value: "false"},
+		},
+		Attributes:  &attributes,
+		Environment: "123",
+	}
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	testCases := map[string]struct {
+		codec string
+	}{
+		"Given I use json":              {codec: "json"},
+		"Given I use json with zstd":    {codec: "json+zstd"},
+		"Given I use msgpack":           {codec: "msgpack"},
+		"Given I use msgpack with zstd": {codec: "msgpack+zstd"},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			c, err := Parse(tc.codec)
+			require.NoError(t, err)
+			assert.Equal(t, tc.codec, c.String())
+
+			expected := []featureConfig{newFeatureConfig()}
+
+			b, err := c.Marshal(expected)
+			require.NoError(t, err)
+
+			var actual []featureConfig
+			require.NoError(t, c.Unmarshal(b, &actual))
+
+			// json decodes numbers in interface{} values as float64
+			if tc.codec == "json" || tc.codec == "json+zstd" {
+				(*expected[0].Attributes)["age"] = float64(42)
+			}
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestCodec_MixedFleet(t *testing.T) {
+	expected := newFeatureConfig()
+	expected.Attributes = nil
+
+	jsonCodec := New(FormatJSON, CompressionNone)
+	msgpackCodec := New(FormatMsgpack, CompressionZstd)
+
+	legacy, err := jsonCodec.Marshal(expected)
+	require.NoError(t, err)
+
+	// Plain json shouldn't have a header so that older Proxies can still read it
+	assert.Equal(t, byte('{'), legacy[0])
+
+	enveloped, err := msgpackCodec.Marshal(expected)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{envelopeMagic, envelopeVersion, byte(FormatMsgpack), byte(CompressionZstd)}, enveloped[:headerLen])
+
+	// A Proxy using either codec should be able to read values written by the other
+	for _, b := range [][]byte{legacy, enveloped} {
+		var actual featureConfig
+		require.NoError(t, jsonCodec.Unmarshal(b, &actual))
+		assert.Equal(t, expected, actual)
+
+		actual = featureConfig{}
+		require.NoError(t, msgpackCodec.Unmarshal(b, &actual))
+		assert.Equal(t, expected, actual)
+	}
+}
+
+func TestUnmarshal_UnsupportedEnvelope(t *testing.T) {
+	testCases := map[string][]byte{
+		"Given I have a truncated header":     {envelopeMagic, envelopeVersion},
+		"Given I have an unknown version":     {envelopeMagic, 99, byte(FormatJSON), byte(CompressionNone), '{', '}'},
+		"Given I have an unknown format":      {envelopeMagic, envelopeVersion, 99, byte(CompressionNone), '{', '}'},
+		"Given I have an unknown compression": {envelopeMagic, envelopeVersion, byte(FormatJSON), 99, '{', '}'},
+	}
+
+	for desc, b := range testCases {
+		b := b
+		t.Run(desc, func(t *testing.T) {
+			var v map[string]interface{}
+			err := Unmarshal(b, &v)
+			assert.True(t, errors.Is(err, ErrUnsupportedEnvelope), "expected ErrUnsupportedEnvelope, got %v", err)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := map[string]struct {
+		name      string
+		expected  Codec
+		shouldErr bool
+	}{
+		"Given I have json": {
+			name:     "json",
+			expected: New(FormatJSON, CompressionNone),
+		},
+		"Given I have msgpack with no compression": {
+			name:     "msgpack+none",
+			expected: New(FormatMsgpack, CompressionNone),
+		},
+		"Given I have msgpack with zstd": {
+			name:     "msgpack+zstd",
+			expected: New(FormatMsgpack, CompressionZstd),
+		},
+		"Given I have an unknown format": {
+			name:      "protobuf",
+			shouldErr: true,
+		},
+		"Given I have an unknown compression": {
+			name:      "json+gzip",
+			shouldErr: true,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			actual, err := Parse(tc.name)
+			if tc.shouldErr {
+				assert.True(t, errors.Is(err, ErrInvalidCodec))
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -3,7 +3,6 @@ package config
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/harness/ff-proxy/v2/config/local"
 	"github.com/harness/ff-proxy/v2/config/remote"
@@ -37,13 +36,25 @@ type Config interface {
 	AccountID() string
 }
 
-// NewConfig creates either a local or remote config type that implements the Config interface
-func NewConfig(offline bool, configDir string, proxyKey string, clientService domain.ClientService, stream stream.Stream) (Config, error) {
+// NewConfig creates either a local or remote config type that implements the Config interface.
+// A remote config publishes events for changes it fetches to the stream and sdkStream after
+// recording them with the recorder if it's set, the localOpts are only used when creating a
+// local config.
+func NewConfig(offline bool, configDir string, proxyKey string, clientService domain.ClientService, stream stream.Stream, sdkStream domain.Publisher, recorder remote.EventRecorder, localOpts ...func(c *local.Config)) (Config, error) {
 	if !offline {
-		return remote.NewConfig(proxyKey, clientService, stream), nil
+		remoteOpts := []func(c *remote.Config){remote.WithSDKStream(sdkStream)}
+		if recorder != nil {
+			remoteOpts = append(remoteOpts, remote.WithEventRecorder(recorder))
+		}
+		return remote.NewConfig(proxyKey, clientService, stream, remoteOpts...), nil
 	}
 
-	conf, err := local.NewConfig(os.DirFS(configDir))
+	fileSystem, err := local.OpenFS(configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local config: %s", err)
+	}
+
+	conf, err := local.NewConfig(fileSystem, localOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load local config: %s", err)
 	}
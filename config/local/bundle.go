@@ -0,0 +1,231 @@
+package local
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	extensionZip   = ".zip"
+	extensionTarGz = ".tar.gz"
+	extensionTgz   = ".tgz"
+)
+
+// IsBundle returns true if the path is a single file bundle of config rather
+// than a directory
+func IsBundle(p string) bool {
+	return strings.HasSuffix(p, extensionZip) || strings.HasSuffix(p, extensionTarGz) || strings.HasSuffix(p, extensionTgz)
+}
+
+// OpenFS returns a FileSystem for the config at the path. The path can either
+// be a directory or a zip or tar.gz bundle that holds the same directory layout.
+func OpenFS(p string) (fs.FS, error) {
+	if !IsBundle(p) {
+		return os.DirFS(p), nil
+	}
+
+	// #nosec G304
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return NewBundleFS(p, b)
+}
+
+// NewBundleFS returns a FileSystem for the contents of a zip or tar.gz bundle,
+// the name is used to work out which kind of bundle it is.
+func NewBundleFS(name string, b []byte) (fs.FS, error) {
+	switch {
+	case strings.HasSuffix(name, extensionZip):
+		return zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	case strings.HasSuffix(name, extensionTarGz), strings.HasSuffix(name, extensionTgz):
+		return newTarFS(bytes.NewReader(b))
+	default:
+		return nil, fmt.Errorf("unsupported bundle %s, supported bundles are %s, %s, %s", name, extensionZip, extensionTarGz, extensionTgz)
+	}
+}
+
+// tarFS is a read only FileSystem that holds the contents of a tar archive in memory
+type tarFS struct {
+	files map[string][]byte
+	dirs  map[string]map[string]struct{}
+}
+
+func newTarFS(r io.Reader) (tarFS, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return tarFS{}, err
+	}
+	defer gz.Close()
+
+	t := tarFS{
+		files: map[string][]byte{},
+		dirs:  map[string]map[string]struct{}{".": {}},
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return tarFS{}, err
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if !fs.ValidPath(name) {
+			return tarFS{}, fmt.Errorf("invalid path %q in bundle", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			t.addDir(name)
+		case tar.TypeReg:
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				return tarFS{}, err
+			}
+			t.files[name] = b
+			t.addDir(path.Dir(name))
+			t.dirs[path.Dir(name)][path.Base(name)] = struct{}{}
+		}
+	}
+	return t, nil
+}
+
+// addDir adds a directory and all of its parents
+func (t tarFS) addDir(name string) {
+	for name != "." {
+		if _, ok := t.dirs[name]; !ok {
+			t.dirs[name] = map[string]struct{}{}
+		}
+
+		parent := path.Dir(name)
+		if _, ok := t.dirs[parent]; !ok {
+			t.dirs[parent] = map[string]struct{}{}
+		}
+		t.dirs[parent][path.Base(name)] = struct{}{}
+		name = parent
+	}
+}
+
+// Open opens the named file or directory
+func (t tarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if b, ok := t.files[name]; ok {
+		return &tarFile{Reader: bytes.NewReader(b), info: tarFileInfo{name: path.Base(name), size: int64(len(b))}}, nil
+	}
+	if _, ok := t.dirs[name]; ok {
+		entries, err := t.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &tarDir{info: tarFileInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir returns the entries in the named directory sorted by name
+func (t tarFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	children, ok := t.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for child := range children {
+		info := tarFileInfo{name: child}
+
+		full := path.Join(name, child)
+		if b, ok := t.files[full]; ok {
+			info.size = int64(len(b))
+		} else {
+			info.dir = true
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+type tarFile struct {
+	*bytes.Reader
+	info tarFileInfo
+}
+
+func (f *tarFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *tarFile) Close() error { return nil }
+
+type tarDir struct {
+	info    tarFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *tarDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *tarDir) Read(_ []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *tarDir) Close() error { return nil }
+
+// ReadDir returns the next n entries in the directory or all of the remaining
+// entries if n <= 0
+func (d *tarDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
+
+type tarFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i tarFileInfo) Name() string { return i.name }
+
+func (i tarFileInfo) Size() int64 { return i.size }
+
+func (i tarFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+func (i tarFileInfo) ModTime() time.Time { return time.Time{} }
+
+func (i tarFileInfo) IsDir() bool { return i.dir }
+
+func (i tarFileInfo) Sys() interface{} { return nil }
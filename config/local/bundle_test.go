@@ -0,0 +1,278 @@
+package local
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/cache"
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/export"
+	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/repository"
+	"github.com/harness/ff-proxy/v2/signing"
+)
+
+// zipFiles creates a zip file that holds the files
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// exportConfig populates a cache with the config and exports it in the format
+func exportConfig(t *testing.T, conf Config, format export.Format, path string, opts ...func(s *export.Service)) {
+	t.Helper()
+	ctx := context.Background()
+
+	memCache := cache.NewMemCache()
+	hashCache := cache.NewHashCache(memCache, 1*time.Minute, 2*time.Minute)
+	authRepo := repository.NewAuthRepo(memCache)
+	flagRepo := repository.NewFeatureFlagRepo(hashCache)
+	segmentRepo := repository.NewSegmentRepo(hashCache)
+	targetRepo := repository.NewTargetRepo(memCache, log.NewNoOpLogger())
+
+	require.NoError(t, conf.Populate(ctx, authRepo, flagRepo, segmentRepo))
+
+	authConfig := map[domain.AuthAPIKey]string{}
+	for _, o := range conf.config {
+		if len(o.Targets) > 0 {
+			require.NoError(t, targetRepo.DeltaAdd(ctx, o.Environment, o.Targets...))
+		}
+		for _, key := range o.Auth {
+			authConfig[domain.NewAuthAPIKey(string(key))] = o.Environment
+		}
+	}
+
+	logger, err := log.NewStructuredLogger("ERROR")
+	require.NoError(t, err)
+
+	opts = append([]func(s *export.Service){export.WithFormat(format)}, opts...)
+	s := export.NewService(logger, flagRepo, targetRepo, segmentRepo, authRepo, authConfig, path, opts...)
+	require.NoError(t, s.Persist(ctx))
+}
+
+func TestConfig_RoundTrip(t *testing.T) {
+	testFS, err := fs.Sub(testConfig, testDir)
+	require.NoError(t, err)
+
+	expected, err := NewConfig(testFS)
+	require.NoError(t, err)
+
+	// Each format is exported from the config that was loaded from the format
+	// before it so that we know the formats can be converted between each other
+	formats := []struct {
+		format export.Format
+		path   string
+	}{
+		{format: export.FormatYAML, path: "yaml"},
+		{format: export.FormatZip, path: "config.zip"},
+		{format: export.FormatTarGz, path: "config.tar.gz"},
+		{format: export.FormatJSON, path: "json"},
+		{format: export.FormatTarGz, path: "again.tgz"},
+		{format: export.FormatYAML, path: "yaml-again"},
+	}
+
+	dir := t.TempDir()
+	conf := expected
+
+	for _, f := range formats {
+		path := filepath.Join(dir, f.path)
+		exportConfig(t, conf, f.format, path)
+
+		fileSystem, err := OpenFS(path)
+		require.NoError(t, err, f.path)
+
+		conf, err = NewConfig(fileSystem)
+		require.NoError(t, err, f.path)
+
+		// The auth keys are exported in a random order
+		for env, o := range conf.config {
+			assert.ElementsMatch(t, expected.config[env].Auth, o.Auth, f.path)
+			o.Auth = expected.config[env].Auth
+			conf.config[env] = o
+		}
+		assert.Equal(t, expected.config, conf.config, f.path)
+	}
+}
+
+func TestNewConfig_YAML(t *testing.T) {
+	yamlConfig := `
+auth:
+  - 1234abcd
+featureConfig:
+  - feature: dark-mode
+    environment: production
+    kind: boolean
+    state: on
+    offVariation: "false"
+    defaultServe:
+      variation: "true"
+    variations:
+      - identifier: "true"
+        value: "true"
+      - identifier: "false"
+        value: "false"
+    version: 3
+segments:
+  - identifier: beta-users
+    name: Beta Users
+    included:
+      - identifier: dave
+        name: Dave
+`
+
+	fileSystem, err := NewBundleFS("config.zip", zipFiles(t, map[string]string{
+		"env-1234.yaml":            yamlConfig,
+		"env-5678/segments.yml":    "[]",
+		"env-5678/auth_config.yml": "[abcd1234]",
+	}))
+	require.NoError(t, err)
+
+	conf, err := NewConfig(fileSystem)
+	require.NoError(t, err)
+
+	env := conf.config["env-1234"]
+	assert.Equal(t, "1234", env.Environment)
+	assert.Equal(t, []domain.AuthAPIKey{"1234abcd"}, env.Auth)
+	require.Len(t, env.FeatureFlags, 1)
+	assert.Equal(t, "dark-mode", env.FeatureFlags[0].Feature)
+	assert.Equal(t, "on", string(env.FeatureFlags[0].State))
+	assert.Equal(t, domain.ToPtr("true"), env.FeatureFlags[0].DefaultServe.Variation)
+	assert.Equal(t, domain.ToPtr(int64(3)), env.FeatureFlags[0].Version)
+	require.Len(t, env.Segments, 1)
+	assert.Equal(t, "dave", (*env.Segments[0].Included)[0].Identifier)
+
+	assert.Equal(t, []domain.AuthAPIKey{"abcd1234"}, conf.config["env-5678"].Auth)
+}
+
+func TestNewConfig_Signed(t *testing.T) {
+	testFS, err := fs.Sub(testConfig, testDir)
+	require.NoError(t, err)
+
+	expected, err := NewConfig(testFS)
+	require.NoError(t, err)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		format      export.Format
+		path        string
+		signed      bool
+		key         ed25519.PublicKey
+		tamper      func(t *testing.T, path string)
+		shouldErr   bool
+		expectedErr error
+	}{
+		"Given I load a signed directory with the public key": {
+			format:    export.FormatJSON,
+			path:      "json",
+			signed:    true,
+			key:       publicKey,
+			shouldErr: false,
+		},
+		"Given I load a signed tar.gz bundle with the public key": {
+			format:    export.FormatTarGz,
+			path:      "config.tar.gz",
+			signed:    true,
+			key:       publicKey,
+			shouldErr: false,
+		},
+		"Given I load a signed yaml directory with the public key": {
+			format:    export.FormatYAML,
+			path:      "yaml",
+			signed:    true,
+			key:       publicKey,
+			shouldErr: false,
+		},
+		"Given I load a signed zip bundle with a different public key": {
+			format:      export.FormatZip,
+			path:        "config.zip",
+			signed:      true,
+			key:         otherKey,
+			shouldErr:   true,
+			expectedErr: signing.ErrInvalidSignature,
+		},
+		"Given I load an unsigned directory with the public key": {
+			format:      export.FormatJSON,
+			path:        "json",
+			signed:      false,
+			key:         publicKey,
+			shouldErr:   true,
+			expectedErr: signing.ErrMissingSignature,
+		},
+		"Given I load a signed directory that's had its auth config changed": {
+			format: export.FormatJSON,
+			path:   "json",
+			signed: true,
+			key:    publicKey,
+			tamper: func(t *testing.T, path string) {
+				matches, err := filepath.Glob(filepath.Join(path, "env-*", "auth_config.json"))
+				require.NoError(t, err)
+				require.NotEmpty(t, matches)
+				require.NoError(t, os.WriteFile(matches[0], []byte(`["evil"]`), 0600))
+			},
+			shouldErr:   true,
+			expectedErr: signing.ErrDigestMismatch,
+		},
+		"Given I load an unsigned directory without a public key": {
+			format:    export.FormatJSON,
+			path:      "json",
+			signed:    false,
+			key:       nil,
+			shouldErr: false,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.path)
+
+			opts := []func(s *export.Service){}
+			if tc.signed {
+				opts = append(opts, export.WithSigningKey(privateKey))
+			}
+			exportConfig(t, expected, tc.format, path, opts...)
+
+			if tc.tamper != nil {
+				tc.tamper(t, path)
+			}
+
+			fileSystem, err := OpenFS(path)
+			require.NoError(t, err)
+
+			conf, err := NewConfig(fileSystem, WithPublicKey(tc.key))
+			if tc.shouldErr {
+				assert.True(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, conf.config, len(expected.config))
+		})
+	}
+}
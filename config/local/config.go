@@ -2,13 +2,16 @@ package local
 
 import (
 	"context"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"io/fs"
+	"path"
 	"strings"
 
 	"github.com/harness/ff-proxy/v2/domain"
 	"github.com/harness/ff-proxy/v2/hash"
+	"github.com/harness/ff-proxy/v2/signing"
 )
 
 type configObject struct {
@@ -22,18 +25,46 @@ type configObject struct {
 // Config is a type that can traverse a tree of files and decode
 // FeatureFlag, Target and Segment information from them.
 type Config struct {
-	config map[string]configObject
-	hasher hash.Hasher
+	config     map[string]configObject
+	hasher     hash.Hasher
+	publicKey  ed25519.PublicKey
+	targetRepo domain.TargetRepo
+}
+
+// WithPublicKey makes NewConfig refuse to load config that hasn't been signed
+// by the private key that pairs with the public key
+func WithPublicKey(key ed25519.PublicKey) func(c *Config) {
+	return func(c *Config) {
+		c.publicKey = key
+	}
+}
+
+// WithTargetRepo makes Populate add the Targets from the config to the TargetRepo
+// so that they can be used to evaluate attribute based rules
+func WithTargetRepo(r domain.TargetRepo) func(c *Config) {
+	return func(c *Config) {
+		c.targetRepo = r
+	}
 }
 
 // NewConfig creates a new FeatureFlagConfig that loads configObject from
 // the passed FileSystem and directory.
-func NewConfig(fs fs.FS) (Config, error) {
+func NewConfig(fs fs.FS, opts ...func(c *Config)) (Config, error) {
 	o := Config{
 		config: make(map[string]configObject),
 		hasher: hash.NewSha256(),
 	}
 
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.publicKey != nil {
+		if err := signing.Verify(fs, o.publicKey); err != nil {
+			return Config{}, fmt.Errorf("failed to verify config: %w", err)
+		}
+	}
+
 	if err := o.loadConfig(fs); err != nil {
 		return Config{}, err
 	}
@@ -72,13 +103,16 @@ func (c Config) SetProxyConfig(_ []domain.ProxyConfig) {
 
 }
 
-func (c Config) FetchAndPopulate(_ context.Context, _ domain.InventoryRepo, _ domain.AuthRepo, _ domain.FlagRepo, _ domain.SegmentRepo) error {
-	return nil
+// FetchAndPopulate populates the repos with the config loaded from the file system,
+// there's nothing to fetch because local config has already been read from disk
+func (c Config) FetchAndPopulate(ctx context.Context, _ domain.InventoryRepo, authRepo domain.AuthRepo, flagRepo domain.FlagRepo, segmentRepo domain.SegmentRepo) error {
+	return c.Populate(ctx, authRepo, flagRepo, segmentRepo)
 }
 
 // Populate populates the repos with the config loaded from the file system
 func (c Config) Populate(ctx context.Context, authRepo domain.AuthRepo, flagRepo domain.FlagRepo, segmentRepo domain.SegmentRepo) error {
 	authConfig := make([]domain.AuthConfig, 0, len(c.config))
+	apiConfigs := make(map[string][]string, len(c.config))
 	flagConfig := make([]domain.FlagConfig, 0, len(c.config))
 	segmentConfig := make([]domain.SegmentConfig, 0, len(c.config))
 
@@ -89,6 +123,7 @@ func (c Config) Populate(ctx context.Context, authRepo domain.AuthRepo, flagRepo
 				APIKey:        domain.NewAuthAPIKey(string(key)),
 				EnvironmentID: domain.EnvironmentID(f.Environment),
 			})
+			apiConfigs[f.Environment] = append(apiConfigs[f.Environment], string(domain.NewAuthAPIKey(string(key))))
 		}
 
 		flagConfig = append(flagConfig, domain.FlagConfig{
@@ -106,6 +141,12 @@ func (c Config) Populate(ctx context.Context, authRepo domain.AuthRepo, flagRepo
 		return fmt.Errorf("failed to add auth config to cache: %s", err)
 	}
 
+	for env, apiKeys := range apiConfigs {
+		if err := authRepo.AddAPIConfigsForEnvironment(ctx, env, apiKeys); err != nil {
+			return fmt.Errorf("failed to add auth config to cache: %s", err)
+		}
+	}
+
 	if err := flagRepo.Add(ctx, flagConfig...); err != nil {
 		return fmt.Errorf("failed to add flag config to cache: %s", err)
 	}
@@ -114,6 +155,21 @@ func (c Config) Populate(ctx context.Context, authRepo domain.AuthRepo, flagRepo
 		return fmt.Errorf("failed to add segment config to cache: %s", err)
 	}
 
+	if c.targetRepo == nil {
+		return nil
+	}
+
+	for _, f := range c.config {
+		// DeltaAdd refuses to add zero targets so that it can't wipe an environment's targets
+		if len(f.Targets) == 0 {
+			continue
+		}
+
+		if err := c.targetRepo.DeltaAdd(ctx, f.Environment, f.Targets...); err != nil {
+			return fmt.Errorf("failed to add target config to cache: %s", err)
+		}
+	}
+
 	return nil
 }
 
@@ -139,7 +195,7 @@ func getParentDirFromPath(path string) (string, error) {
 //
 //nolint:gocognit,cyclop
 func decodeConfigFiles(c map[string]configObject, fileSystem fs.FS) fs.WalkDirFunc {
-	return func(path string, d fs.DirEntry, err error) error {
+	return func(filePath string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -164,48 +220,59 @@ func decodeConfigFiles(c map[string]configObject, fileSystem fs.FS) fs.WalkDirFu
 			return nil
 		}
 
-		// Seems like the only way of getting the name of the directory that a
-		// file is in is by parsing the path
-		env, err := getParentDirFromPath(path)
-		if err != nil {
-			return nil
-		}
+		name := strings.TrimSuffix(i.Name(), path.Ext(i.Name()))
 
-		if i.Name() == "feature_config.json" {
-			cfg := c[env]
-			if err := DecodeFile(fileSystem, path, &cfg.FeatureFlags); err != nil {
+		// An environment can also be written as a single env-<id>.yaml file
+		// that holds all of its config, which is easier to write by hand
+		if strings.HasPrefix(name, "env-") && isYAML(i.Name()) {
+			cfg := configObject{
+				FeatureFlags: []domain.FeatureFlag{},
+				Targets:      []domain.Target{},
+				Segments:     []domain.Segment{},
+				Auth:         []domain.AuthAPIKey{},
+			}
+			if err := DecodeFile(fileSystem, filePath, &cfg); err != nil {
 				return err
 			}
-			c[env] = cfg
+			cfg.Environment = strings.TrimPrefix(name, "env-")
+			c[name] = cfg
 			return nil
 		}
 
-		if i.Name() == "targets.json" {
-			cfg := c[env]
-			if err := DecodeFile(fileSystem, path, &cfg.Targets); err != nil {
-				return err
-			}
-			c[env] = cfg
+		// Seems like the only way of getting the name of the directory that a
+		// file is in is by parsing the path
+		env, err := getParentDirFromPath(filePath)
+		if err != nil {
 			return nil
 		}
 
-		if i.Name() == "segments.json" {
-			cfg := c[env]
-			if err := DecodeFile(fileSystem, path, &cfg.Segments); err != nil {
-				return err
-			}
-			c[env] = cfg
+		cfg, ok := c[env]
+		if !ok {
 			return nil
 		}
 
-		if i.Name() == "auth_config.json" {
-			cfg := c[env]
-			if err := DecodeFile(fileSystem, path, &cfg.Auth); err != nil {
-				return err
-			}
-
-			c[env] = cfg
+		switch name {
+		case "feature_config":
+			err = DecodeFile(fileSystem, filePath, &cfg.FeatureFlags)
+		case "targets":
+			err = DecodeFile(fileSystem, filePath, &cfg.Targets)
+		case "segments":
+			err = DecodeFile(fileSystem, filePath, &cfg.Segments)
+		case "auth_config":
+			err = DecodeFile(fileSystem, filePath, &cfg.Auth)
+		default:
+			return nil
 		}
+		if err != nil {
+			return err
+		}
+
+		c[env] = cfg
 		return nil
 	}
 }
+
+func isYAML(name string) bool {
+	ext := path.Ext(name)
+	return ext == extensionYAML || ext == extensionYML
+}
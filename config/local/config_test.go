@@ -36,6 +36,9 @@ func (m mockAuthRepo) GetKeysForEnvironment(ctx context.Context, envID string) (
 }
 
 func (m mockAuthRepo) AddAPIConfigsForEnvironment(ctx context.Context, envID string, apiKeys []string) error {
+	if m.addAPIConfigsForEnvironmentFn == nil {
+		return nil
+	}
 	return m.addAPIConfigsForEnvironmentFn(ctx, envID, apiKeys)
 }
 
@@ -7,12 +7,13 @@ import (
 	"io/fs"
 	"path/filepath"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	extensionJSON = ".json"
 	extensionYAML = ".yaml"
+	extensionYML  = ".yml"
 )
 
 // DecodeFile is a convenience function that creates a FileDecoder and calls Decode
@@ -52,10 +53,10 @@ func NewFileDecoder(fileSystem fs.FS, file string) (*FileDecoder, error) {
 	switch ext {
 	case extensionJSON:
 		dec = json.NewDecoder(f)
-	case extensionYAML:
-		dec = yaml.NewDecoder(f)
+	case extensionYAML, extensionYML:
+		dec = yamlDecoder{dec: yaml.NewDecoder(f)}
 	default:
-		return nil, fmt.Errorf("unsupported file extension %s, supported extensions are %s, %s", ext, extensionJSON, extensionYAML)
+		return nil, fmt.Errorf("unsupported file extension %s, supported extensions are %s, %s, %s", ext, extensionJSON, extensionYAML, extensionYML)
 	}
 
 	return &FileDecoder{file: f, dec: dec}, nil
@@ -67,3 +68,45 @@ func (f *FileDecoder) Decode(v interface{}) error {
 	defer f.file.Close()
 	return f.dec.Decode(v)
 }
+
+// yamlDecoder decodes YAML into types that only have json tags, which is all of
+// the generated types, by converting the YAML to JSON before decoding it. This
+// means the YAML uses the same field names as the JSON config.
+type yamlDecoder struct {
+	dec *yaml.Decoder
+}
+
+// Decode decodes the next YAML document into v
+func (y yamlDecoder) Decode(v interface{}) error {
+	var doc interface{}
+	if err := y.dec.Decode(&doc); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(yamlToJSON(doc))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// yamlToJSON converts the map[interface{}]interface{} values that the yaml
+// package decodes objects into to map[string]interface{} so they can be
+// marshaled to JSON
+func yamlToJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = yamlToJSON(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range t {
+			t[i] = yamlToJSON(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
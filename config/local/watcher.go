@@ -0,0 +1,490 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+// WithWatchInterval sets how often the Watcher checks the config directory for changes
+func WithWatchInterval(d time.Duration) func(w *Watcher) {
+	return func(w *Watcher) {
+		w.interval = d
+	}
+}
+
+// Watcher polls the offline config directory or bundle and when any of the files in it
+// change it loads the new config, populates the repos with it and sends events
+// for the flags and segments that changed so that connected SDKs refetch them.
+//
+// The directory is polled rather than watched with inotify because editors and
+// volume mounts often replace files rather than writing to them.
+type Watcher struct {
+	log         log.Logger
+	dir         string
+	interval    time.Duration
+	authRepo    domain.AuthRepo
+	flagRepo    domain.FlagRepo
+	segmentRepo domain.SegmentRepo
+	handler     domain.MessageHandler
+
+	config      Config
+	fingerprint string
+}
+
+// NewWatcher creates a Watcher for the config directory. The passed Config should
+// be the config that was loaded from the directory and used to populate the repos.
+func NewWatcher(l log.Logger, dir string, conf Config, authRepo domain.AuthRepo, flagRepo domain.FlagRepo, segmentRepo domain.SegmentRepo, handler domain.MessageHandler, opts ...func(w *Watcher)) (*Watcher, error) {
+	w := &Watcher{
+		log:         l.With("component", "ConfigWatcher"),
+		dir:         dir,
+		interval:    10 * time.Second,
+		authRepo:    authRepo,
+		flagRepo:    flagRepo,
+		segmentRepo: segmentRepo,
+		handler:     handler,
+		config:      conf,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	fileSystem, err := OpenFS(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory %s: %s", dir, err)
+	}
+
+	fingerprint, err := fingerprintDir(fileSystem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory %s: %s", dir, err)
+	}
+	w.fingerprint = fingerprint
+
+	return w, nil
+}
+
+// Watch checks the config directory for changes every interval until the context is cancelled
+func (w *Watcher) Watch(ctx context.Context) {
+	w.log.Info("watching config directory for changes", "dir", w.dir, "interval", w.interval.String())
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.reload(ctx); err != nil {
+				w.log.Error("failed to reload config, continuing to serve the previous config", "dir", w.dir, "err", err)
+			}
+		}
+	}
+}
+
+// reload loads the config directory if it's changed since it was last loaded and
+// returns true if the new config was loaded. If any of the files are invalid none
+// of the new config is loaded.
+func (w *Watcher) reload(ctx context.Context) (bool, error) {
+	fileSystem, err := OpenFS(w.dir)
+	if err != nil {
+		return false, err
+	}
+
+	fingerprint, err := fingerprintDir(fileSystem)
+	if err != nil {
+		return false, err
+	}
+
+	if fingerprint == w.fingerprint {
+		return false, nil
+	}
+
+	conf, err := NewConfig(fileSystem, WithPublicKey(w.config.publicKey), WithTargetRepo(w.config.targetRepo))
+	if err != nil {
+		return false, err
+	}
+
+	bumpVersions(w.config, conf)
+
+	// Populate the new config before removing what's been deleted so that if
+	// it fails we carry on serving the previous config
+	if err := conf.Populate(ctx, w.authRepo, w.flagRepo, w.segmentRepo); err != nil {
+		return false, err
+	}
+
+	if err := w.removeDeleted(ctx, w.config, conf); err != nil {
+		return false, err
+	}
+
+	events := diffConfig(w.config, conf)
+	for _, event := range events {
+		if err := w.handler.HandleMessage(ctx, event); err != nil {
+			w.log.Error("failed to send config change event", "environment", event.Environment, "domain", event.Domain, "identifier", event.Identifier, "err", err)
+		}
+	}
+
+	w.config = conf
+	w.fingerprint = fingerprint
+	w.log.Info("reloaded config", "dir", w.dir, "events", len(events))
+	return true, nil
+}
+
+// removeDeleted removes the auth keys, flags, segments and targets that are in the
+// old config but not the new config from the repos since Populate only adds to them
+func (w *Watcher) removeDeleted(ctx context.Context, prev Config, next Config) error {
+	nextEnvs := configByEnvironment(next)
+
+	for env, p := range configByEnvironment(prev) {
+		n, ok := nextEnvs[env]
+		if !ok {
+			if err := w.removeEnvironment(ctx, env, p); err != nil {
+				return err
+			}
+			continue
+		}
+
+		authKeys := map[domain.AuthAPIKey]struct{}{}
+		for _, key := range n.Auth {
+			authKeys[key] = struct{}{}
+		}
+
+		removedKeys := []string{}
+		for _, key := range p.Auth {
+			if _, ok := authKeys[key]; !ok {
+				removedKeys = append(removedKeys, string(domain.NewAuthAPIKey(string(key))))
+			}
+		}
+		// Populate only sets the keys for environments that have at least one
+		if len(n.Auth) == 0 && len(p.Auth) > 0 {
+			removedKeys = append(removedKeys, string(domain.NewAPIConfigsKey(env)))
+		}
+		if err := w.authRepo.Remove(ctx, removedKeys); err != nil {
+			return fmt.Errorf("failed to remove auth config from cache: %s", err)
+		}
+
+		flags := flagsByIdentifier(n.FeatureFlags)
+		for _, f := range p.FeatureFlags {
+			if _, ok := flags[f.Feature]; !ok {
+				if err := w.flagRepo.Remove(ctx, string(domain.NewFeatureConfigKey(env, f.Feature))); err != nil {
+					return fmt.Errorf("failed to remove flag config from cache: %s", err)
+				}
+			}
+		}
+
+		segments := segmentsByIdentifier(n.Segments)
+		for _, s := range p.Segments {
+			if _, ok := segments[s.Identifier]; !ok {
+				if err := w.segmentRepo.Remove(ctx, string(domain.NewSegmentKey(env, s.Identifier))); err != nil {
+					return fmt.Errorf("failed to remove segment config from cache: %s", err)
+				}
+			}
+		}
+
+		if err := w.removeDeletedTargets(ctx, env, p.Targets, n.Targets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeDeletedTargets removes the targets that are in the old config for an
+// environment but not the new config
+func (w *Watcher) removeDeletedTargets(ctx context.Context, env string, prev []domain.Target, next []domain.Target) error {
+	targetRepo := w.config.targetRepo
+	if targetRepo == nil {
+		return nil
+	}
+
+	// Populate doesn't add anything for environments without targets so we
+	// have to remove the list of targets as well as the targets themselves
+	if len(next) == 0 && len(prev) > 0 {
+		if err := targetRepo.RemoveAllTargetsForEnvironment(ctx, env); err != nil {
+			return fmt.Errorf("failed to remove target config from cache: %s", err)
+		}
+		return nil
+	}
+
+	targets := make(map[string]struct{}, len(next))
+	for _, t := range next {
+		targets[t.Identifier] = struct{}{}
+	}
+	for _, t := range prev {
+		if _, ok := targets[t.Identifier]; !ok {
+			if err := targetRepo.Remove(ctx, string(domain.NewTargetKey(env, t.Identifier))); err != nil {
+				return fmt.Errorf("failed to remove target config from cache: %s", err)
+			}
+		}
+	}
+	return nil
+}
+
+// removeEnvironment removes everything that was loaded for an environment whose
+// directory has been deleted
+func (w *Watcher) removeEnvironment(ctx context.Context, env string, o configObject) error {
+	authKeys := []string{string(domain.NewAPIConfigsKey(env))}
+	for _, key := range o.Auth {
+		authKeys = append(authKeys, string(domain.NewAuthAPIKey(string(key))))
+	}
+	if err := w.authRepo.Remove(ctx, authKeys); err != nil {
+		return fmt.Errorf("failed to remove auth config from cache: %s", err)
+	}
+
+	flagKeys := []string{string(domain.NewFeatureConfigsKey(env))}
+	for _, f := range o.FeatureFlags {
+		flagKeys = append(flagKeys, string(domain.NewFeatureConfigKey(env, f.Feature)))
+	}
+	for _, key := range flagKeys {
+		if err := w.flagRepo.Remove(ctx, key); err != nil {
+			return fmt.Errorf("failed to remove flag config from cache: %s", err)
+		}
+	}
+
+	segmentKeys := []string{string(domain.NewSegmentsKey(env))}
+	for _, seg := range o.Segments {
+		segmentKeys = append(segmentKeys, string(domain.NewSegmentKey(env, seg.Identifier)))
+	}
+	for _, key := range segmentKeys {
+		if err := w.segmentRepo.Remove(ctx, key); err != nil {
+			return fmt.Errorf("failed to remove segment config from cache: %s", err)
+		}
+	}
+
+	if w.config.targetRepo != nil {
+		if err := w.config.targetRepo.RemoveAllTargetsForEnvironment(ctx, env); err != nil {
+			return fmt.Errorf("failed to remove target config from cache: %s", err)
+		}
+	}
+	return nil
+}
+
+// bumpVersions sets the version of each flag and segment in the next config so that
+// ones that have changed have a higher version than SDKs have already seen, since they
+// ignore versions they've got. Flags and segments in offline config are often edited by
+// hand without bumping the version so ones that haven't changed keep their old version.
+func bumpVersions(prev Config, next Config) {
+	prevEnvs := configByEnvironment(prev)
+
+	for _, n := range next.config {
+		p := prevEnvs[n.Environment]
+
+		prevFlags := flagsByIdentifier(p.FeatureFlags)
+		for i := range n.FeatureFlags {
+			f := &n.FeatureFlags[i]
+			f.Version = nextVersion(prevFlags[f.Feature], *f, func(f *domain.FeatureFlag) **int64 { return &f.Version })
+		}
+
+		prevSegments := segmentsByIdentifier(p.Segments)
+		for i := range n.Segments {
+			s := &n.Segments[i]
+			s.Version = nextVersion(prevSegments[s.Identifier], *s, func(s *domain.Segment) **int64 { return &s.Version })
+		}
+	}
+}
+
+// nextVersion returns the version a flag or segment should be served with. It keeps
+// the version in the next config if it's been bumped, otherwise it keeps the previous
+// version if nothing else has changed or bumps it if something has.
+func nextVersion[T any](prev *T, next T, version func(*T) **int64) *int64 {
+	if prev == nil {
+		return *version(&next)
+	}
+
+	prevVersion, nextVersion := derefVersion(*version(prev)), derefVersion(*version(&next))
+	if nextVersion > prevVersion {
+		return *version(&next)
+	}
+
+	*version(&next) = *version(prev)
+	p, pErr := jsoniter.Marshal(prev)
+	n, nErr := jsoniter.Marshal(next)
+	if pErr == nil && nErr == nil && bytes.Equal(p, n) {
+		return *version(prev)
+	}
+
+	bumped := prevVersion + 1
+	return &bumped
+}
+
+func derefVersion(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// diffConfig returns create, patch and delete events for each flag and segment
+// that's different between the two configs
+func diffConfig(prev Config, next Config) []domain.SSEMessage {
+	prevEnvs := configByEnvironment(prev)
+	nextEnvs := configByEnvironment(next)
+
+	envs := make([]string, 0, len(prevEnvs)+len(nextEnvs))
+	for env := range prevEnvs {
+		envs = append(envs, env)
+	}
+	for env := range nextEnvs {
+		if _, ok := prevEnvs[env]; !ok {
+			envs = append(envs, env)
+		}
+	}
+	sort.Strings(envs)
+
+	events := []domain.SSEMessage{}
+	for _, env := range envs {
+		p, n := prevEnvs[env], nextEnvs[env]
+
+		prevFlags, nextFlags := flagsByIdentifier(p.FeatureFlags), flagsByIdentifier(n.FeatureFlags)
+		for _, id := range sortedKeys(prevFlags, nextFlags) {
+			event, changed := diffEvent(prevFlags[id], nextFlags[id])
+			if changed {
+				events = append(events, newConfigEvent(env, domain.MsgDomainFeature, event, id, flagVersion(nextFlags[id])))
+			}
+		}
+
+		prevSegments, nextSegments := segmentsByIdentifier(p.Segments), segmentsByIdentifier(n.Segments)
+		for _, id := range sortedKeys(prevSegments, nextSegments) {
+			event, changed := diffEvent(prevSegments[id], nextSegments[id])
+			if changed {
+				events = append(events, newConfigEvent(env, domain.MsgDomainSegment, event, id, segmentVersion(nextSegments[id])))
+			}
+		}
+	}
+	return events
+}
+
+// diffEvent compares two versions of a flag or segment and returns the event
+// that should be sent if they're different. A nil value means it doesn't exist.
+func diffEvent[T any](prev *T, next *T) (string, bool) {
+	switch {
+	case prev == nil && next == nil:
+		return "", false
+	case prev == nil:
+		return domain.EventCreate, true
+	case next == nil:
+		return domain.EventDelete, true
+	}
+
+	// Flags and segments in offline config are often edited by hand without
+	// bumping the version so we compare their contents rather than versions
+	p, pErr := jsoniter.Marshal(prev)
+	n, nErr := jsoniter.Marshal(next)
+	if pErr != nil || nErr != nil || !bytes.Equal(p, n) {
+		return domain.EventPatch, true
+	}
+	return "", false
+}
+
+func newConfigEvent(env string, msgDomain string, event string, identifier string, version int64) domain.SSEMessage {
+	return domain.SSEMessage{
+		Event:       event,
+		Domain:      msgDomain,
+		Identifier:  identifier,
+		Version:     int(version),
+		Environment: env,
+	}
+}
+
+func configByEnvironment(c Config) map[string]configObject {
+	envs := make(map[string]configObject, len(c.config))
+	for _, o := range c.config {
+		envs[o.Environment] = o
+	}
+	return envs
+}
+
+func flagsByIdentifier(flags []domain.FeatureFlag) map[string]*domain.FeatureFlag {
+	m := make(map[string]*domain.FeatureFlag, len(flags))
+	for i := range flags {
+		m[flags[i].Feature] = &flags[i]
+	}
+	return m
+}
+
+func segmentsByIdentifier(segments []domain.Segment) map[string]*domain.Segment {
+	m := make(map[string]*domain.Segment, len(segments))
+	for i := range segments {
+		m[segments[i].Identifier] = &segments[i]
+	}
+	return m
+}
+
+func flagVersion(f *domain.FeatureFlag) int64 {
+	if f == nil || f.Version == nil {
+		return 0
+	}
+	return *f.Version
+}
+
+func segmentVersion(s *domain.Segment) int64 {
+	if s == nil || s.Version == nil {
+		return 0
+	}
+	return *s.Version
+}
+
+// sortedKeys returns the keys that are in either map in order
+func sortedKeys[T any](a map[string]T, b map[string]T) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fingerprintDir returns a hash of the path and contents of every file in the directory
+func fingerprintDir(fileSystem fs.FS) (string, error) {
+	h := sha256.New()
+
+	err := fs.WalkDir(fileSystem, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		// Symlinks aren't followed by WalkDir so make sure this isn't a link to a directory
+		info, err := fs.Stat(fileSystem, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := fileSystem.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, _ = io.WriteString(h, path)
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
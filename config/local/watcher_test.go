@@ -0,0 +1,298 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/cache"
+	"github.com/harness/ff-proxy/v2/domain"
+	clientgen "github.com/harness/ff-proxy/v2/gen/client"
+	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/repository"
+)
+
+type recordingHandler struct {
+	events []domain.SSEMessage
+}
+
+func (r *recordingHandler) HandleMessage(_ context.Context, m domain.SSEMessage) error {
+	r.events = append(r.events, m)
+	return nil
+}
+
+// copyTestConfig copies the test config directory into a temporary directory
+// so that the tests can modify it
+func copyTestConfig(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	envDir := filepath.Join(dir, "env-1234")
+	require.NoError(t, os.MkdirAll(envDir, 0o755))
+
+	files, err := os.ReadDir(filepath.Join(testDir, "env-1234"))
+	require.NoError(t, err)
+
+	for _, f := range files {
+		b, err := os.ReadFile(filepath.Join(testDir, "env-1234", f.Name()))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(envDir, f.Name()), b, 0o600))
+	}
+	return dir
+}
+
+func replaceInFile(t *testing.T, path string, old string, new string) {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(b), old, new, 1)), 0o600))
+}
+
+func TestWatcher_reload(t *testing.T) {
+	ctx := context.Background()
+
+	dir := copyTestConfig(t)
+	featureConfigFile := filepath.Join(dir, "env-1234", "feature_config.json")
+	segmentsFile := filepath.Join(dir, "env-1234", "segments.json")
+
+	memCache := cache.NewMemCache()
+	hashCache := cache.NewHashCache(memCache, 1*time.Minute, 2*time.Minute)
+	authRepo := repository.NewAuthRepo(memCache)
+	flagRepo := repository.NewFeatureFlagRepo(hashCache)
+	segmentRepo := repository.NewSegmentRepo(hashCache)
+	handler := &recordingHandler{}
+
+	conf, err := NewConfig(os.DirFS(dir))
+	require.NoError(t, err)
+	require.NoError(t, conf.Populate(ctx, authRepo, flagRepo, segmentRepo))
+
+	w, err := NewWatcher(log.NewNoOpLogger(), dir, conf, authRepo, flagRepo, segmentRepo, handler)
+	require.NoError(t, err)
+
+	// Nothing has changed so nothing should be loaded
+	reloaded, err := w.reload(ctx)
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+	assert.Empty(t, handler.events)
+
+	// An invalid file shouldn't change what's being served
+	require.NoError(t, os.WriteFile(segmentsFile, []byte("[{"), 0o600))
+	replaceInFile(t, featureConfigFile, `"state": "on"`, `"state": "off"`)
+
+	reloaded, err = w.reload(ctx)
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+	assert.Empty(t, handler.events)
+
+	flag, err := flagRepo.GetByIdentifier(ctx, "1234", "harnessappdemodarkmode")
+	require.NoError(t, err)
+	assert.Equal(t, clientgen.On, flag.State)
+
+	// Once the file is fixed the new flag config is loaded and a patch event is sent
+	b, err := os.ReadFile(filepath.Join(testDir, "env-1234", "segments.json"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(segmentsFile, b, 0o600))
+
+	reloaded, err = w.reload(ctx)
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+
+	// The flag was edited without bumping its version so the Proxy bumps it,
+	// otherwise SDKs would ignore it because they've already got that version
+	flag, err = flagRepo.GetByIdentifier(ctx, "1234", "harnessappdemodarkmode")
+	require.NoError(t, err)
+	assert.Equal(t, clientgen.Off, flag.State)
+	assert.Equal(t, int64(569), *flag.Version)
+
+	expected := []domain.SSEMessage{
+		{
+			Event:       domain.EventPatch,
+			Domain:      domain.MsgDomainFeature,
+			Identifier:  "harnessappdemodarkmode",
+			Version:     569,
+			Environment: "1234",
+		},
+	}
+	assert.Equal(t, expected, handler.events)
+}
+
+func TestBumpVersions(t *testing.T) {
+	withVersion := func(f domain.FeatureFlag, version int64) domain.FeatureFlag {
+		f.Version = domain.ToPtr(version)
+		return f
+	}
+
+	offFlag := harnessAppDemoDarkModeConfig
+	offFlag.State = "off"
+
+	newConfig := func(flags ...domain.FeatureFlag) Config {
+		return Config{config: map[string]configObject{
+			"env-1234": {Environment: "1234", FeatureFlags: flags},
+		}}
+	}
+
+	testCases := map[string]struct {
+		prev            Config
+		next            Config
+		expectedVersion int64
+	}{
+		"Given a flag hasn't changed": {
+			prev:            newConfig(harnessAppDemoDarkModeConfig),
+			next:            newConfig(harnessAppDemoDarkModeConfig),
+			expectedVersion: 568,
+		},
+		"Given a flag has been edited without bumping its version": {
+			prev:            newConfig(harnessAppDemoDarkModeConfig),
+			next:            newConfig(offFlag),
+			expectedVersion: 569,
+		},
+		"Given a flag that had its version bumped by the Proxy hasn't changed": {
+			prev:            newConfig(withVersion(offFlag, 569)),
+			next:            newConfig(offFlag),
+			expectedVersion: 569,
+		},
+		"Given a flag has been edited and its version bumped": {
+			prev:            newConfig(harnessAppDemoDarkModeConfig),
+			next:            newConfig(withVersion(offFlag, 600)),
+			expectedVersion: 600,
+		},
+		"Given a flag has been added": {
+			prev:            newConfig(),
+			next:            newConfig(offFlag),
+			expectedVersion: 568,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			bumpVersions(tc.prev, tc.next)
+			assert.Equal(t, tc.expectedVersion, *tc.next.config["env-1234"].FeatureFlags[0].Version)
+		})
+	}
+}
+
+func TestDiffConfig(t *testing.T) {
+	offFlag := harnessAppDemoDarkModeConfig
+	offFlag.State = "off"
+
+	newConfig := func(flags []domain.FeatureFlag, segments []domain.Segment) Config {
+		return Config{config: map[string]configObject{
+			"env-1234": {Environment: "1234", FeatureFlags: flags, Segments: segments},
+		}}
+	}
+
+	testCases := map[string]struct {
+		prev     Config
+		next     Config
+		expected []domain.SSEMessage
+	}{
+		"Given nothing has changed": {
+			prev:     newConfig([]domain.FeatureFlag{harnessAppDemoDarkModeConfig}, []domain.Segment{flagsTeamSegment}),
+			next:     newConfig([]domain.FeatureFlag{harnessAppDemoDarkModeConfig}, []domain.Segment{flagsTeamSegment}),
+			expected: []domain.SSEMessage{},
+		},
+		"Given a flag has been edited without bumping its version": {
+			prev: newConfig([]domain.FeatureFlag{harnessAppDemoDarkModeConfig}, nil),
+			next: newConfig([]domain.FeatureFlag{offFlag}, nil),
+			expected: []domain.SSEMessage{
+				{Event: domain.EventPatch, Domain: domain.MsgDomainFeature, Identifier: "harnessappdemodarkmode", Version: 568, Environment: "1234"},
+			},
+		},
+		"Given a flag and segment have been added and a flag removed": {
+			prev: newConfig([]domain.FeatureFlag{harnessAppDemoDarkModeConfig}, nil),
+			next: newConfig([]domain.FeatureFlag{yetAnotherFlagConfig}, []domain.Segment{flagsTeamSegment}),
+			expected: []domain.SSEMessage{
+				{Event: domain.EventDelete, Domain: domain.MsgDomainFeature, Identifier: "harnessappdemodarkmode", Version: 0, Environment: "1234"},
+				{Event: domain.EventCreate, Domain: domain.MsgDomainFeature, Identifier: "yet_another_flag", Version: 6, Environment: "1234"},
+				{Event: domain.EventCreate, Domain: domain.MsgDomainSegment, Identifier: "flagsTeam", Version: 1, Environment: "1234"},
+			},
+		},
+		"Given an environment has been removed": {
+			prev: newConfig([]domain.FeatureFlag{yetAnotherFlagConfig}, []domain.Segment{flagsTeamSegment}),
+			next: Config{config: map[string]configObject{}},
+			expected: []domain.SSEMessage{
+				{Event: domain.EventDelete, Domain: domain.MsgDomainFeature, Identifier: "yet_another_flag", Version: 0, Environment: "1234"},
+				{Event: domain.EventDelete, Domain: domain.MsgDomainSegment, Identifier: "flagsTeam", Version: 0, Environment: "1234"},
+			},
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, diffConfig(tc.prev, tc.next))
+		})
+	}
+}
+
+func TestWatcher_reloadTargets(t *testing.T) {
+	ctx := context.Background()
+
+	dir := copyTestConfig(t)
+	targetsFile := filepath.Join(dir, "env-1234", "targets.json")
+
+	memCache := cache.NewMemCache()
+	authRepo := repository.NewAuthRepo(memCache)
+	flagRepo := repository.NewFeatureFlagRepo(memCache)
+	segmentRepo := repository.NewSegmentRepo(memCache)
+	targetRepo := repository.NewTargetRepo(memCache, log.NewNoOpLogger())
+
+	conf, err := NewConfig(os.DirFS(dir), WithTargetRepo(targetRepo))
+	require.NoError(t, err)
+	require.NoError(t, conf.Populate(ctx, authRepo, flagRepo, segmentRepo))
+
+	w, err := NewWatcher(log.NewNoOpLogger(), dir, conf, authRepo, flagRepo, segmentRepo, &recordingHandler{})
+	require.NoError(t, err)
+
+	// The targets and their attributes should be loaded when the repos are populated
+	target, err := targetRepo.GetByIdentifier(ctx, "1234", "james")
+	require.NoError(t, err)
+	assert.Equal(t, float64(55), (*target.Attributes)["age"])
+
+	// When a target's attributes change the new attributes are loaded
+	replaceInFile(t, targetsFile, `"age": 55`, `"age": 60`)
+
+	reloaded, err := w.reload(ctx)
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	target, err = targetRepo.GetByIdentifier(ctx, "1234", "james")
+	require.NoError(t, err)
+	assert.Equal(t, float64(60), (*target.Attributes)["age"])
+
+	// When a target is removed from the config it's removed from the repo
+	require.NoError(t, os.WriteFile(targetsFile, []byte(`[{"identifier": "foo", "name": "foo", "attributes": {"age": 57}}]`), 0o600))
+
+	reloaded, err = w.reload(ctx)
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	_, err = targetRepo.GetByIdentifier(ctx, "1234", "james")
+	assert.ErrorIs(t, err, domain.ErrCacheNotFound)
+
+	targets, err := targetRepo.Get(ctx, "1234")
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	assert.Equal(t, "foo", targets[0].Identifier)
+	assert.Equal(t, float64(57), (*targets[0].Attributes)["age"])
+
+	// When every target is removed the environment's targets are removed
+	require.NoError(t, os.WriteFile(targetsFile, []byte(`[]`), 0o600))
+
+	reloaded, err = w.reload(ctx)
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	_, err = targetRepo.Get(ctx, "1234")
+	assert.ErrorIs(t, err, domain.ErrCacheNotFound)
+	_, err = targetRepo.GetByIdentifier(ctx, "1234", "foo")
+	assert.ErrorIs(t, err, domain.ErrCacheNotFound)
+}
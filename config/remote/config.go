@@ -30,24 +30,102 @@ func (s *safeString) Get() string {
 	return s.value
 }
 
+type safeEnvironments struct {
+	*sync.RWMutex
+	ids map[string]struct{}
+}
+
+// Set replaces the environment IDs
+func (s *safeEnvironments) Set(ids map[string]struct{}) {
+	s.Lock()
+	defer s.Unlock()
+	s.ids = ids
+}
+
+func (s *safeEnvironments) Add(id string) {
+	s.Lock()
+	defer s.Unlock()
+	s.ids[id] = struct{}{}
+}
+
+func (s *safeEnvironments) List() []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	ids := make([]string, 0, len(s.ids))
+	for id := range s.ids {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (s *safeEnvironments) Has(id string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	_, ok := s.ids[id]
+	return ok
+}
+
 // Config is the type that fetches config from Harness SaaS
 type Config struct {
-	key               string
-	token             *safeString
+	key           string
+	token         *safeString
+	ClientService domain.ClientService
+	stream        stream.Stream
+	sdkStream     domain.Publisher
+	recorder      EventRecorder
+
+	// mx guards the clusterIdentifier, proxyConfig and accountID which are
+	// replaced each time config is fetched
+	mx                *sync.RWMutex
 	clusterIdentifier string
 	proxyConfig       []domain.ProxyConfig
-	ClientService     domain.ClientService
-	stream            stream.Stream
 	accountID         string
+
+	// environments are the IDs of the environments that the key has access to
+	environments *safeEnvironments
+
+	// fetchMx makes sure that only one fetch happens at a time because config
+	// can be fetched when the saas stream connects or disconnects and on resync
+	fetchMx *sync.Mutex
+}
+
+// WithSDKStream sets a stream that the Config publishes events to for any flags or
+// segments that have changed when it fetches config so that connected SDKs are
+// notified of changes. The events are always published to the read replica stream.
+func WithSDKStream(p domain.Publisher) func(c *Config) {
+	return func(c *Config) {
+		c.sdkStream = p
+	}
+}
+
+// EventRecorder gives the messages that are sent to SDKs their event IDs
+type EventRecorder interface {
+	Record(ctx context.Context, msg domain.SSEMessage) domain.SSEMessage
+}
+
+// WithEventRecorder sets the EventRecorder that the events the Config publishes
+// are recorded with before they're sent to read replicas and SDKs
+func WithEventRecorder(r EventRecorder) func(c *Config) {
+	return func(c *Config) {
+		c.recorder = r
+	}
 }
 
 // NewConfig creates a new Config
-func NewConfig(key string, cs domain.ClientService, s stream.Stream) *Config {
+func NewConfig(key string, cs domain.ClientService, s stream.Stream, opts ...func(c *Config)) *Config {
 	c := &Config{
 		token:         &safeString{RWMutex: &sync.RWMutex{}, value: ""},
 		key:           key,
 		ClientService: cs,
 		stream:        s,
+		environments:  &safeEnvironments{RWMutex: &sync.RWMutex{}, ids: map[string]struct{}{}},
+		fetchMx:       &sync.Mutex{},
+		mx:            &sync.RWMutex{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
 	return c
 }
@@ -59,6 +137,8 @@ func (c *Config) Token() string {
 
 // AccountID returns the accountID for the account the Proxy is configured to work with
 func (c *Config) AccountID() string {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
 	return c.accountID
 }
 
@@ -74,6 +154,9 @@ func (c *Config) RefreshToken() (string, error) {
 
 // ClusterIdentifier returns the identifier of the cluster that the Config authenticated against
 func (c *Config) ClusterIdentifier() string {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+
 	if c.clusterIdentifier == "" {
 		return "1"
 	}
@@ -85,64 +168,116 @@ func (c *Config) Key() string {
 	return c.key
 }
 
+// HasEnvironment returns true if the environment belongs to the key
+func (c *Config) HasEnvironment(envID string) bool {
+	return c.environments.Has(envID)
+}
+
+// Environments returns the IDs of the environments that belong to the key
+func (c *Config) Environments() []string {
+	return c.environments.List()
+}
+
 // SetProxyConfig sets the proxy config member
 func (c *Config) SetProxyConfig(proxyConfig []domain.ProxyConfig) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
 	c.proxyConfig = proxyConfig
 }
 
 // FetchAndPopulate Fetches and populates repositories with the config
 func (c *Config) FetchAndPopulate(ctx context.Context, inventory domain.InventoryRepo, authRepo domain.AuthRepo, flagRepo domain.FlagRepo, segmentRepo domain.SegmentRepo) error {
+	c.fetchMx.Lock()
+	defer c.fetchMx.Unlock()
 
 	authResp, err := authenticate(c.key, c.ClientService)
 	if err != nil {
 		return err
 	}
 	c.token.Set(authResp.Token)
+
+	// It's not the end of the world if we fail to
+	// get the accountID from the auth token
+	accountID, _ := parseAuthToken(authResp.Token)
+
+	c.mx.Lock()
 	c.clusterIdentifier = authResp.ClusterIdentifier
+	c.accountID = accountID
+	c.mx.Unlock()
 
 	proxyConfig, err := retrieveConfig(c.key, authResp.Token, authResp.ClusterIdentifier, c.ClientService)
 	if err != nil {
 		return err
 	}
 
-	// It's not the end of the world if we fail to
-	// get the accountID from the auth token
-	c.accountID, _ = parseAuthToken(authResp.Token)
-
-	// TODO we probably should defer that
 	// compare new and old config assets and delete difference.
 	notificationsToSend, err := inventory.Cleanup(ctx, c.key, proxyConfig)
 	if err != nil {
 		return err
 	}
 
-	err = c.notifySDKs(ctx, notificationsToSend)
-	if err != nil {
+	if err := c.populate(ctx, proxyConfig, authRepo, flagRepo, segmentRepo); err != nil {
 		return err
 	}
 
-	c.proxyConfig = proxyConfig
-	return c.Populate(ctx, authRepo, flagRepo, segmentRepo)
+	// We only swap in the new environments and config once the cache has been
+	// populated so that lookups keep using the old ones until then
+	environments := map[string]struct{}{}
+	for _, cfg := range proxyConfig {
+		for _, env := range cfg.Environments {
+			environments[env.ID.String()] = struct{}{}
+		}
+	}
+	c.environments.Set(environments)
+	c.SetProxyConfig(proxyConfig)
+
+	// We only notify SDKs once the cache has been populated so that they don't
+	// refetch the config before it's changed
+	return c.notifySDKs(ctx, notificationsToSend)
 }
 
 func (c *Config) notifySDKs(ctx context.Context, notificationsToSend []domain.SSEMessage) error {
 	// send the notifications
 	for _, v := range notificationsToSend {
+		if c.recorder != nil {
+			v = c.recorder.Record(ctx, v)
+		}
+
 		err := c.stream.Publish(ctx, v)
 		if err != nil {
 			return err
 		}
+
+		if c.sdkStream == nil {
+			continue
+		}
+		if err := c.sdkStream.Pub(ctx, v.Environment, v); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 // Populate populates repositories with the config
 func (c *Config) Populate(ctx context.Context, authRepo domain.AuthRepo, flagRepo domain.FlagRepo, segmentRepo domain.SegmentRepo) error {
+	c.mx.RLock()
+	proxyConfig := c.proxyConfig
+	c.mx.RUnlock()
+
+	for _, cfg := range proxyConfig {
+		for _, env := range cfg.Environments {
+			c.environments.Add(env.ID.String())
+		}
+	}
+	return c.populate(ctx, proxyConfig, authRepo, flagRepo, segmentRepo)
+}
+
+func (c *Config) populate(ctx context.Context, proxyConfig []domain.ProxyConfig, authRepo domain.AuthRepo, flagRepo domain.FlagRepo, segmentRepo domain.SegmentRepo) error {
 	var wg sync.WaitGroup
 	errchan := make(chan error)
 	semaphore := make(chan struct{}, 1000)
 
-	for _, cfg := range c.proxyConfig {
+	for _, cfg := range proxyConfig {
 		for _, targetEnv := range cfg.Environments {
 			wg.Add(1)
 			go func(env domain.Environments) {
@@ -585,9 +585,8 @@ func Benchmark_ConfigPopulate100Env30Flags(b *testing.B) {
 	authRepo := repository.NewAuthRepo(r)
 	flagRepo := repository.NewFeatureFlagRepo(r)
 	segmentRepo := repository.NewSegmentRepo(r)
-	c := Config{
-		proxyConfig: []domain.ProxyConfig{proxyConfig},
-	}
+	c := NewConfig("", nil, stream.Stream{})
+	c.SetProxyConfig([]domain.ProxyConfig{proxyConfig})
 
 	// Limit to 1 CPU core
 	runtime.GOMAXPROCS(1)
@@ -613,9 +612,8 @@ func Benchmark_ConfigPopulate200Env30Flags(b *testing.B) {
 	authRepo := repository.NewAuthRepo(r)
 	flagRepo := repository.NewFeatureFlagRepo(r)
 	segmentRepo := repository.NewSegmentRepo(r)
-	c := Config{
-		proxyConfig: []domain.ProxyConfig{proxyConfig},
-	}
+	c := NewConfig("", nil, stream.Stream{})
+	c.SetProxyConfig([]domain.ProxyConfig{proxyConfig})
 
 	// Limit to 1 CPU core
 	runtime.GOMAXPROCS(1)
@@ -641,9 +639,8 @@ func Benchmark_ConfigPopulate300Env30Flags(b *testing.B) {
 	authRepo := repository.NewAuthRepo(r)
 	flagRepo := repository.NewFeatureFlagRepo(r)
 	segmentRepo := repository.NewSegmentRepo(r)
-	c := Config{
-		proxyConfig: proxyConfigs,
-	}
+	c := NewConfig("", nil, stream.Stream{})
+	c.SetProxyConfig(proxyConfigs)
 
 	// Limit to 1 CPU core
 	runtime.GOMAXPROCS(1)
@@ -670,9 +667,8 @@ func Benchmark_ConfigPopulate500Env30Flags(b *testing.B) {
 	authRepo := repository.NewAuthRepo(r)
 	flagRepo := repository.NewFeatureFlagRepo(r)
 	segmentRepo := repository.NewSegmentRepo(r)
-	c := Config{
-		proxyConfig: proxyConfigs,
-	}
+	c := NewConfig("", nil, stream.Stream{})
+	c.SetProxyConfig(proxyConfigs)
 
 	// Limit to 1 CPU core
 	runtime.GOMAXPROCS(1)
@@ -699,9 +695,8 @@ func Benchmark_ConfigPopulate1000Env30Flags(b *testing.B) {
 	authRepo := repository.NewAuthRepo(r)
 	flagRepo := repository.NewFeatureFlagRepo(r)
 	segmentRepo := repository.NewSegmentRepo(r)
-	c := Config{
-		proxyConfig: proxyConfigs,
-	}
+	c := NewConfig("", nil, stream.Stream{})
+	c.SetProxyConfig(proxyConfigs)
 
 	// Limit to 1 CPU core
 	runtime.GOMAXPROCS(1)
@@ -734,11 +729,10 @@ func Benchmark_ConfigCleanup100Env30Flags(b *testing.B) {
 		os.Exit(1)
 	}
 	inventoryRepo := repository.NewInventoryRepo(r, l)
-	c := Config{
-		proxyConfig: []domain.ProxyConfig{proxyConfig},
-	}
+	c := NewConfig("", nil, stream.Stream{})
+	c.SetProxyConfig([]domain.ProxyConfig{proxyConfig})
 
-	newAssets, _ := inventoryRepo.BuildAssetListFromConfig(c.proxyConfig)
+	newAssets, _ := inventoryRepo.BuildAssetListFromConfig([]domain.ProxyConfig{proxyConfig})
 	inventoryRepo.Add(context.Background(), "test", newAssets)
 
 	// Limit to 1 CPU core
@@ -779,11 +773,10 @@ func Benchmark_ConfigCleanup1000Env30Flags(b *testing.B) {
 		os.Exit(1)
 	}
 	inventoryRepo := repository.NewInventoryRepo(r, l)
-	c := Config{
-		proxyConfig: proxyConfigs,
-	}
+	c := NewConfig("", nil, stream.Stream{})
+	c.SetProxyConfig(proxyConfigs)
 
-	newAssets, _ := inventoryRepo.BuildAssetListFromConfig(c.proxyConfig)
+	newAssets, _ := inventoryRepo.BuildAssetListFromConfig(proxyConfigs)
 	inventoryRepo.Add(context.Background(), "test", newAssets)
 
 	// Limit to 1 CPU core
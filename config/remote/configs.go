@@ -0,0 +1,46 @@
+package remote
+
+// Configs is the Config for each of the proxy keys that the Proxy is configured with
+type Configs []*Config
+
+// ForEnvironment returns the Config for the key that the environment belongs to. If
+// none of the keys have the environment it falls back to the first Config.
+func (c Configs) ForEnvironment(envID string) (*Config, bool) {
+	if len(c) == 0 {
+		return nil, false
+	}
+
+	for _, conf := range c {
+		if conf.HasEnvironment(envID) {
+			return conf, true
+		}
+	}
+	return c[0], true
+}
+
+// Token returns the auth token for the key that the environment belongs to
+func (c Configs) Token(envID string) string {
+	conf, ok := c.ForEnvironment(envID)
+	if !ok {
+		return ""
+	}
+	return conf.Token()
+}
+
+// ClusterIdentifier returns the cluster identifier for the key that the environment belongs to
+func (c Configs) ClusterIdentifier(envID string) string {
+	conf, ok := c.ForEnvironment(envID)
+	if !ok {
+		return "1"
+	}
+	return conf.ClusterIdentifier()
+}
+
+// AccountID returns the account ID for the key that the environment belongs to
+func (c Configs) AccountID(envID string) string {
+	conf, ok := c.ForEnvironment(envID)
+	if !ok {
+		return ""
+	}
+	return conf.AccountID()
+}
@@ -0,0 +1,100 @@
+package remote
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/cache"
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/repository"
+	"github.com/harness/ff-proxy/v2/stream"
+)
+
+func TestConfigs_ForEnvironment(t *testing.T) {
+	fooEnv := uuid.MustParse("5a1c1f8e-8f4e-4d3c-9b8e-2f36c1b1e7a1")
+	barEnv := uuid.MustParse("c3d1b0a2-45d8-4b6a-8a7e-6f9b0a3c2d10")
+
+	newConfig := func(key string, token string, cluster string, env uuid.UUID) *Config {
+		clientService := mockClientService{
+			authProxyKey: func() (domain.AuthenticateProxyKeyResponse, error) {
+				return domain.AuthenticateProxyKeyResponse{Token: token, ClusterIdentifier: cluster}, nil
+			},
+			pageProxyConfig: func() ([]domain.ProxyConfig, error) {
+				return []domain.ProxyConfig{{Environments: []domain.Environments{{ID: env, APIKeys: []string{key + "-apikey"}}}}}, nil
+			},
+		}
+		return NewConfig(key, clientService, stream.NewStream(log.NoOpLogger{}, "replicas", newRecordingStream(), domain.NoOpMessageHandler{}))
+	}
+
+	memCache := cache.NewMemCache()
+	inventoryRepo := repository.NewInventoryRepo(memCache, log.NoOpLogger{})
+	authRepo := repository.NewAuthRepo(memCache)
+	flagRepo := repository.NewFeatureFlagRepo(memCache)
+	segmentRepo := repository.NewSegmentRepo(memCache)
+
+	foo := newConfig("foo", "foo.eyJhY2NvdW50IjoiZm9vLWFjY291bnQifQ.token", "1", fooEnv)
+	bar := newConfig("bar", "bar.eyJhY2NvdW50IjoiYmFyLWFjY291bnQifQ.token", "2", barEnv)
+
+	configs := Configs{foo, bar}
+	for _, conf := range configs {
+		require.NoError(t, conf.FetchAndPopulate(context.Background(), inventoryRepo, authRepo, flagRepo, segmentRepo))
+	}
+
+	testCases := map[string]struct {
+		configs         Configs
+		envID           string
+		expectedOK      bool
+		expectedToken   string
+		expectedCluster string
+		expectedAccount string
+	}{
+		"Given the environment belongs to the first key": {
+			configs:         configs,
+			envID:           fooEnv.String(),
+			expectedOK:      true,
+			expectedToken:   "foo.eyJhY2NvdW50IjoiZm9vLWFjY291bnQifQ.token",
+			expectedCluster: "1",
+			expectedAccount: "foo-account",
+		},
+		"Given the environment belongs to the second key": {
+			configs:         configs,
+			envID:           barEnv.String(),
+			expectedOK:      true,
+			expectedToken:   "bar.eyJhY2NvdW50IjoiYmFyLWFjY291bnQifQ.token",
+			expectedCluster: "2",
+			expectedAccount: "bar-account",
+		},
+		"Given the environment doesn't belong to any key": {
+			configs:         configs,
+			envID:           "unknown",
+			expectedOK:      true,
+			expectedToken:   "foo.eyJhY2NvdW50IjoiZm9vLWFjY291bnQifQ.token",
+			expectedCluster: "1",
+			expectedAccount: "foo-account",
+		},
+		"Given there are no configs": {
+			configs:         Configs{},
+			envID:           fooEnv.String(),
+			expectedOK:      false,
+			expectedToken:   "",
+			expectedCluster: "1",
+			expectedAccount: "",
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			_, ok := tc.configs.ForEnvironment(tc.envID)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedToken, tc.configs.Token(tc.envID))
+			assert.Equal(t, tc.expectedCluster, tc.configs.ClusterIdentifier(tc.envID))
+			assert.Equal(t, tc.expectedAccount, tc.configs.AccountID(tc.envID))
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package remote
+
+import (
+	"context"
+	"time"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+// Resync fetches the config from Harness SaaS every interval until the context is
+// cancelled. The fetched config is diffed against the inventory and events are sent
+// for every flag and segment that's changed, which means SDKs are kept up to date
+// with changes even if the stream between the Proxy and Harness SaaS is down.
+func (c *Config) Resync(ctx context.Context, l log.Logger, interval time.Duration, inventory domain.InventoryRepo, authRepo domain.AuthRepo, flagRepo domain.FlagRepo, segmentRepo domain.SegmentRepo) {
+	l = l.With("component", "Resync")
+	l.Info("scheduling config resync", "interval", interval.String())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.FetchAndPopulate(ctx, inventory, authRepo, flagRepo, segmentRepo); err != nil {
+				l.Error("failed to resync config", "err", err)
+				continue
+			}
+			l.Debug("resynced config")
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package remote
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/cache"
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/repository"
+	"github.com/harness/ff-proxy/v2/stream"
+)
+
+// recordingStream records the messages that are published to it
+type recordingStream struct {
+	*sync.Mutex
+	msgs map[string][]domain.SSEMessage
+}
+
+func newRecordingStream() *recordingStream {
+	return &recordingStream{Mutex: &sync.Mutex{}, msgs: map[string][]domain.SSEMessage{}}
+}
+
+func (r *recordingStream) Pub(_ context.Context, channel string, value interface{}) error {
+	r.Lock()
+	defer r.Unlock()
+
+	msg, _ := value.(domain.SSEMessage)
+	r.msgs[channel] = append(r.msgs[channel], msg)
+	return nil
+}
+
+func (r *recordingStream) Close(_ string) error { return nil }
+
+func (r *recordingStream) Sub(_ context.Context, _ string, _ string, _ domain.HandleMessageFn) error {
+	return nil
+}
+
+func (r *recordingStream) get(channel string) []domain.SSEMessage {
+	r.Lock()
+	defer r.Unlock()
+	return append([]domain.SSEMessage{}, r.msgs[channel]...)
+}
+
+func TestConfig_Resync(t *testing.T) {
+	envID := uuid.MustParse("2fd10ce3-7ed6-466f-a768-e4df08f566b0")
+	env := envID.String()
+
+	version := &atomic.Int64{}
+	version.Store(1)
+
+	clientService := mockClientService{
+		authProxyKey: func() (domain.AuthenticateProxyKeyResponse, error) {
+			return domain.AuthenticateProxyKeyResponse{Token: "header.e30.signature", ClusterIdentifier: "1"}, nil
+		},
+		pageProxyConfig: func() ([]domain.ProxyConfig, error) {
+			v := version.Load()
+			return []domain.ProxyConfig{
+				{
+					Environments: []domain.Environments{
+						{
+							ID:             envID,
+							APIKeys:        []string{"123"},
+							FeatureConfigs: []domain.FeatureFlag{{Feature: "dark-mode", Environment: env, Version: &v}},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+
+	memCache := cache.NewMemCache()
+	inventoryRepo := repository.NewInventoryRepo(memCache, log.NoOpLogger{})
+	authRepo := repository.NewAuthRepo(memCache)
+	flagRepo := repository.NewFeatureFlagRepo(memCache)
+	segmentRepo := repository.NewSegmentRepo(memCache)
+
+	replicaStream := newRecordingStream()
+	sdkStream := newRecordingStream()
+
+	c := NewConfig("key", clientService, stream.NewStream(log.NoOpLogger{}, "replicas", replicaStream, domain.NoOpMessageHandler{}), WithSDKStream(sdkStream))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, c.FetchAndPopulate(ctx, inventoryRepo, authRepo, flagRepo, segmentRepo))
+	createEvent := domain.SSEMessage{Domain: domain.MsgDomainFeature, Event: domain.EventCreate, Identifier: "dark-mode", Environment: env, Version: 1}
+	assert.Equal(t, []domain.SSEMessage{createEvent}, sdkStream.get(env))
+
+	go c.Resync(ctx, log.NoOpLogger{}, 10*time.Millisecond, inventoryRepo, authRepo, flagRepo, segmentRepo)
+
+	// Resyncing config that hasn't changed shouldn't send any events
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []domain.SSEMessage{createEvent}, sdkStream.get(env))
+
+	// Once the flag changes SDKs and replicas should get a patch event for it
+	version.Store(2)
+	patchEvent := domain.SSEMessage{Domain: domain.MsgDomainFeature, Event: domain.EventPatch, Identifier: "dark-mode", Environment: env, Version: 2}
+
+	assert.Eventually(t, func() bool {
+		return len(sdkStream.get(env)) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []domain.SSEMessage{createEvent, patchEvent}, sdkStream.get(env))
+	assert.Equal(t, []domain.SSEMessage{createEvent, patchEvent}, replicaStream.get("replicas"))
+
+	flag, err := flagRepo.GetByIdentifier(ctx, env, "dark-mode")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *flag.Version)
+}
+
+func TestConfig_FetchAndPopulateWithEventRecorder(t *testing.T) {
+	envID := uuid.MustParse("2fd10ce3-7ed6-466f-a768-e4df08f566b0")
+	env := envID.String()
+
+	clientService := mockClientService{
+		authProxyKey: func() (domain.AuthenticateProxyKeyResponse, error) {
+			return domain.AuthenticateProxyKeyResponse{Token: "header.e30.signature", ClusterIdentifier: "1"}, nil
+		},
+		pageProxyConfig: func() ([]domain.ProxyConfig, error) {
+			return []domain.ProxyConfig{
+				{
+					Environments: []domain.Environments{
+						{
+							ID:             envID,
+							APIKeys:        []string{"123"},
+							FeatureConfigs: []domain.FeatureFlag{{Feature: "dark-mode", Environment: env, Version: domain.ToPtr(int64(1))}},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+
+	ctx := context.Background()
+	memCache := cache.NewMemCache()
+	history := stream.NewMemoryEventHistory(10)
+
+	replicaStream := newRecordingStream()
+	sdkStream := newRecordingStream()
+
+	c := NewConfig("key", clientService, stream.NewStream(log.NoOpLogger{}, "replicas", replicaStream, domain.NoOpMessageHandler{}),
+		WithSDKStream(sdkStream),
+		WithEventRecorder(stream.NewEventRecorder(log.NoOpLogger{}, history, domain.NoOpMessageHandler{})),
+	)
+
+	require.NoError(t, c.FetchAndPopulate(ctx, repository.NewInventoryRepo(memCache, log.NoOpLogger{}), repository.NewAuthRepo(memCache), repository.NewFeatureFlagRepo(memCache), repository.NewSegmentRepo(memCache)))
+
+	// Replicas and SDKs should get the event with the ID it was recorded with
+	createEvent := domain.SSEMessage{Domain: domain.MsgDomainFeature, Event: domain.EventCreate, Identifier: "dark-mode", Environment: env, Version: 1, EventID: 1}
+	assert.Equal(t, []domain.SSEMessage{createEvent}, sdkStream.get(env))
+	assert.Equal(t, []domain.SSEMessage{createEvent}, replicaStream.get("replicas"))
+
+	latest, err := history.Latest(ctx, env)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), latest)
+}
+
+func TestConfig_FetchAndPopulateEnvironments(t *testing.T) {
+	envA := uuid.MustParse("2fd10ce3-7ed6-466f-a768-e4df08f566b0")
+	envB := uuid.MustParse("8b1bbc1e-5ae6-4d6d-8aa5-a2ad1a4e4a8b")
+
+	var (
+		c          *Config
+		calls      int
+		hadOldEnvs bool
+	)
+
+	clientService := mockClientService{
+		authProxyKey: func() (domain.AuthenticateProxyKeyResponse, error) {
+			return domain.AuthenticateProxyKeyResponse{Token: "header.e30.signature", ClusterIdentifier: "1"}, nil
+		},
+		pageProxyConfig: func() ([]domain.ProxyConfig, error) {
+			calls++
+			if calls == 1 {
+				return []domain.ProxyConfig{{Environments: []domain.Environments{{ID: envA, APIKeys: []string{"123"}}}}}, nil
+			}
+
+			// The environments from the previous fetch should still be available while we refetch
+			hadOldEnvs = c.HasEnvironment(envA.String())
+			return []domain.ProxyConfig{{Environments: []domain.Environments{{ID: envB, APIKeys: []string{"456"}}}}}, nil
+		},
+	}
+
+	ctx := context.Background()
+	memCache := cache.NewMemCache()
+	inventoryRepo := repository.NewInventoryRepo(memCache, log.NoOpLogger{})
+	authRepo := repository.NewAuthRepo(memCache)
+	flagRepo := repository.NewFeatureFlagRepo(memCache)
+	segmentRepo := repository.NewSegmentRepo(memCache)
+
+	c = NewConfig("key", clientService, stream.NewStream(log.NoOpLogger{}, "replicas", newRecordingStream(), domain.NoOpMessageHandler{}))
+
+	require.NoError(t, c.FetchAndPopulate(ctx, inventoryRepo, authRepo, flagRepo, segmentRepo))
+	assert.True(t, c.HasEnvironment(envA.String()))
+	assert.False(t, c.HasEnvironment(envB.String()))
+
+	require.NoError(t, c.FetchAndPopulate(ctx, inventoryRepo, authRepo, flagRepo, segmentRepo))
+	assert.True(t, hadOldEnvs)
+	assert.False(t, c.HasEnvironment(envA.String()))
+	assert.True(t, c.HasEnvironment(envB.String()))
+}
@@ -0,0 +1,68 @@
+package domain
+
+// AdminEnvironmentsResponse contains the fields returned by a GET /admin/environments request
+type AdminEnvironmentsResponse struct {
+	Environments []string `json:"environments"`
+}
+
+// AdminEnvironmentRequest contains the fields sent in a GET /admin/environments/{environmentUUID} request
+type AdminEnvironmentRequest struct {
+	EnvironmentID string
+}
+
+// AdminEnvironmentResponse contains the fields returned by a GET /admin/environments/{environmentUUID} request
+type AdminEnvironmentResponse struct {
+	EnvironmentID string       `json:"environmentId"`
+	APIKeys       []string     `json:"apiKeys"`
+	Flags         []AdminAsset `json:"flags"`
+	Segments      []AdminAsset `json:"segments"`
+}
+
+// AdminAsset is a flag or segment identifier and the version of it that the Proxy has
+type AdminAsset struct {
+	Identifier string `json:"identifier"`
+	Version    int64  `json:"version"`
+}
+
+// AdminInventoryResponse contains the fields returned by a GET /admin/inventory request
+type AdminInventoryResponse struct {
+	Assets []string `json:"assets"`
+}
+
+// AdminStreamsResponse contains the fields returned by a GET /admin/streams request
+type AdminStreamsResponse struct {
+	Channels []string `json:"channels"`
+}
+
+// FlagOverrideRequest contains the fields sent in a PUT /admin/environments/{environmentUUID}/overrides/{feature}
+// request. It's also the format of each override in the overrides file.
+type FlagOverrideRequest struct {
+	EnvironmentID string            `json:"environment"`
+	Feature       string            `json:"feature"`
+	State         string            `json:"state,omitempty"`
+	DefaultServe  string            `json:"defaultServe,omitempty"`
+	Targets       map[string]string `json:"targets,omitempty"`
+	Reason        string            `json:"reason,omitempty"`
+
+	// TTL is how long the override lasts for e.g. 30m, it takes precedence over ExpiresAt
+	TTL string `json:"ttl,omitempty"`
+
+	// ExpiresAt is when the override expires in unix milliseconds
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
+}
+
+// AdminDeleteFlagOverrideRequest contains the fields sent in a DELETE /admin/environments/{environmentUUID}/overrides/{feature} request
+type AdminDeleteFlagOverrideRequest struct {
+	EnvironmentID string
+	Feature       string
+}
+
+// AdminFlagOverridesResponse contains the fields returned by a GET /admin/environments/{environmentUUID}/overrides request
+type AdminFlagOverridesResponse struct {
+	Overrides []FlagOverride `json:"overrides"`
+}
+
+// AdminFlagOverrideAuditResponse contains the fields returned by a GET /admin/environments/{environmentUUID}/overrides/audit request
+type AdminFlagOverrideAuditResponse struct {
+	Entries []FlagOverrideAuditEntry `json:"entries"`
+}
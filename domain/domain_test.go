@@ -96,3 +96,15 @@ func Test_ToPtr(t *testing.T) {
 	actual := ToPtr(s)
 	assert.True(t, reflect.ValueOf(actual).Kind() == reflect.Ptr)
 }
+
+func TestNewNamespacedKey(t *testing.T) {
+	assert.Equal(t, "env-123-segments", NewNamespacedKey("", "env-123-segments"))
+	assert.Equal(t, "staging:env-123-segments", NewNamespacedKey("staging", "env-123-segments"))
+}
+
+func TestValidateNamespace(t *testing.T) {
+	assert.Nil(t, ValidateNamespace(""))
+	assert.Nil(t, ValidateNamespace("prod-eu_1.2"))
+	assert.NotNil(t, ValidateNamespace("prod:eu"))
+	assert.NotNil(t, ValidateNamespace("prod*"))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/harness/ff-golang-server-sdk/rest"
+	"github.com/harness/ff-proxy/v2/codec"
 	clientgen "github.com/harness/ff-proxy/v2/gen/client"
 	jsoniter "github.com/json-iterator/go"
 )
@@ -34,9 +35,10 @@ func (f *FeatureFlag) MarshalBinary() ([]byte, error) {
 	return jsoniter.Marshal(f)
 }
 
-// UnmarshalBinary unmarshals bytes to a FeatureFlag
+// UnmarshalBinary unmarshals bytes to a FeatureFlag. It can read plain json as well as
+// values that were encoded by a cache codec
 func (f *FeatureFlag) UnmarshalBinary(b []byte) error {
-	return jsoniter.Unmarshal(b, f)
+	return codec.Unmarshal(b, f)
 }
 
 // FeatureFlag stores feature flag data
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"fmt"
+	"strconv"
 )
 
 // KeyInventory maps all assets associated with for proxy key.
@@ -17,3 +18,12 @@ type Assets struct {
 func NewKeyInventory(key string) KeyInventory {
 	return KeyInventory(fmt.Sprintf("key-%s-inventory", key))
 }
+
+// NewAssetVersion returns the value that's stored in a key inventory for a flag
+// or segment. It's the asset's version so that changes to it can be detected.
+func NewAssetVersion(version *int64) string {
+	if version == nil {
+		return ""
+	}
+	return strconv.FormatInt(*version, 10)
+}
@@ -26,6 +26,20 @@ type healther interface {
 	SetHealthy(ctx context.Context) error
 }
 
+type environmentHealther interface {
+	SetUnhealthy(envs ...string)
+	SetHealthy(envs ...string)
+}
+
+// WithEnvironmentHealth sets the environmentHealther that's updated when the Primary
+// connects or disconnects from the Saas stream for a subset of environments, e.g.
+// because it's configured with more than one key and only one of their streams is down
+func WithEnvironmentHealth(e environmentHealther) func(r *ReadReplicaMessageHandler) {
+	return func(r *ReadReplicaMessageHandler) {
+		r.environmentStatus = e
+	}
+}
+
 // ReadReplicaMessageHandler defines the message handler used by the read replica.
 // The ReadReplica doesn't need to care about 99% of the messages it receives, and
 // the only thing it really needs to do is forward these messages on to any connected
@@ -35,21 +49,27 @@ type healther interface {
 // The Replica can then use these events to forcibly disconnect SDKs and block new stream
 // requests until the Writer Proxy -> SaaS stream has been reestablished
 type ReadReplicaMessageHandler struct {
-	log              log.Logger
-	streamStatus     healther
-	connectedStreams func() map[string]interface{}
-	pushpin          Closer
+	log               log.Logger
+	streamStatus      healther
+	environmentStatus environmentHealther
+	connectedStreams  func() map[string]interface{}
+	pushpin           Closer
 }
 
 // NewReadReplicaMessageHandler creates a ReadReplicaMessageHandler
-func NewReadReplicaMessageHandler(l log.Logger, s healther, cs func() map[string]interface{}, pp Closer) ReadReplicaMessageHandler {
+func NewReadReplicaMessageHandler(l log.Logger, s healther, cs func() map[string]interface{}, pp Closer, opts ...func(r *ReadReplicaMessageHandler)) ReadReplicaMessageHandler {
 	l = l.With("component", "ReadReplicaMessageHandler")
-	return ReadReplicaMessageHandler{
+	r := &ReadReplicaMessageHandler{
 		log:              l,
 		streamStatus:     s,
 		connectedStreams: cs,
 		pushpin:          pp,
 	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	return *r
 }
 
 // HandleMessage makes ReadReplicaMessageHandler implement the MessageHandler interface.
@@ -68,6 +88,10 @@ func (r ReadReplicaMessageHandler) HandleMessage(ctx context.Context, msg SSEMes
 
 // handleStreamAction sets the internal StreamHealth in the read replica based on the type of message we get
 func (r ReadReplicaMessageHandler) handleStreamAction(ctx context.Context, msg SSEMessage) error {
+	if len(msg.Environments) > 0 && r.environmentStatus != nil {
+		return r.handleEnvironmentsStreamAction(msg)
+	}
+
 	if msg.Domain == StreamStateDisconnected.String() {
 		r.log.Info("received stream disconnected event from primary proxy")
 
@@ -96,3 +120,29 @@ func (r ReadReplicaMessageHandler) handleStreamAction(ctx context.Context, msg S
 
 	return nil
 }
+
+// handleEnvironmentsStreamAction sets the health of the environments in the message and
+// only closes the SDK streams for those environments if they've been disconnected
+func (r ReadReplicaMessageHandler) handleEnvironmentsStreamAction(msg SSEMessage) error {
+	if msg.Domain == StreamStateDisconnected.String() {
+		r.log.Info("received stream disconnected event from primary proxy", "environments", len(msg.Environments))
+		r.environmentStatus.SetUnhealthy(msg.Environments...)
+
+		connected := r.connectedStreams()
+		for _, env := range msg.Environments {
+			if _, ok := connected[env]; !ok {
+				continue
+			}
+			if err := r.pushpin.Close(env); err != nil {
+				r.log.Error("failed to close Proxy->SDK stream", "streamID", env, "err", err)
+			}
+		}
+		return nil
+	}
+
+	if msg.Domain == StreamStateConnected.String() {
+		r.log.Info("received stream connected event from primary proxy", "environments", len(msg.Environments))
+		r.environmentStatus.SetHealthy(msg.Environments...)
+	}
+	return nil
+}
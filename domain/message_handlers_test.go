@@ -152,3 +152,58 @@ func TestReadReplicaMessageHandler_HandleMessage(t *testing.T) {
 		})
 	}
 }
+
+type mockEnvironmentHealth struct {
+	unhealthy map[string]bool
+}
+
+func (m *mockEnvironmentHealth) SetUnhealthy(envs ...string) {
+	for _, env := range envs {
+		m.unhealthy[env] = true
+	}
+}
+
+func (m *mockEnvironmentHealth) SetHealthy(envs ...string) {
+	for _, env := range envs {
+		delete(m.unhealthy, env)
+	}
+}
+
+type recordingCloser struct {
+	closed []string
+}
+
+func (r *recordingCloser) Close(topic string) error {
+	r.closed = append(r.closed, topic)
+	return nil
+}
+
+func TestReadReplicaMessageHandler_HandleEnvironmentsMessage(t *testing.T) {
+	connectedStreams := func() map[string]interface{} {
+		return map[string]interface{}{
+			"env-123": struct{}{},
+			"env-456": struct{}{},
+		}
+	}
+
+	ctx := context.Background()
+	health := &mockHealth{Mutex: &sync.Mutex{}, healthy: true}
+	envHealth := &mockEnvironmentHealth{unhealthy: map[string]bool{}}
+	closer := &recordingCloser{}
+
+	r := NewReadReplicaMessageHandler(log.NoOpLogger{}, health, connectedStreams, closer, WithEnvironmentHealth(envHealth))
+
+	t.Log("When I get a stream disconnect event for some of the environments")
+	assert.Nil(t, r.HandleMessage(ctx, SSEMessage{Event: "stream_action", Domain: StreamStateDisconnected.String(), Environments: []string{"env-123", "env-789"}}))
+
+	t.Log("Then only the streams for those environments are closed and marked unhealthy")
+	assert.True(t, health.getHealth())
+	assert.Equal(t, map[string]bool{"env-123": true, "env-789": true}, envHealth.unhealthy)
+	assert.Equal(t, []string{"env-123"}, closer.closed)
+
+	t.Log("When I get a stream connect event for the environments")
+	assert.Nil(t, r.HandleMessage(ctx, SSEMessage{Event: "stream_action", Domain: StreamStateConnected.String(), Environments: []string{"env-123", "env-789"}}))
+
+	t.Log("Then they're marked healthy again")
+	assert.Equal(t, map[string]bool{}, envHealth.unhealthy)
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var namespaceRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]*$`)
+
+// NewNamespacedKey prefixes a cache key or stream topic with the namespace so that
+// multiple Proxy deployments can share the same redis. If the namespace is empty
+// the key is returned unchanged.
+func NewNamespacedKey(namespace string, key string) string {
+	if namespace == "" {
+		return key
+	}
+	return fmt.Sprintf("%s:%s", namespace, key)
+}
+
+// ValidateNamespace checks that a namespace only contains letters, numbers, '_',
+// '-' and '.'. Other characters, e.g. '*' or ':', would break pattern matching
+// on namespaced keys.
+func ValidateNamespace(namespace string) error {
+	if !namespaceRegex.MatchString(namespace) {
+		return fmt.Errorf("invalid namespace %q, namespaces can only contain letters, numbers, '_', '-' and '.'", namespace)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/harness/ff-proxy/v2/codec"
+	clientgen "github.com/harness/ff-proxy/v2/gen/client"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// FlagOverrideActionSet is the audit action recorded when an override is created or replaced
+	FlagOverrideActionSet = "set"
+
+	// FlagOverrideActionDelete is the audit action recorded when an override is removed
+	FlagOverrideActionDelete = "delete"
+
+	// FlagOverrideActionExpire is the audit action recorded when an override is removed because it expired
+	FlagOverrideActionExpire = "expire"
+)
+
+// FlagOverrideKey is the key that maps to the FlagOverrides or audit trail for an environment
+type FlagOverrideKey string
+
+// NewFlagOverridesKey creates a FlagOverrideKey for the overrides in an environment
+func NewFlagOverridesKey(envID string) FlagOverrideKey {
+	return FlagOverrideKey(fmt.Sprintf("env-%s-flag-overrides", envID))
+}
+
+// NewFlagOverrideAuditKey creates a FlagOverrideKey for the override audit trail of an environment
+func NewFlagOverrideAuditKey(envID string) FlagOverrideKey {
+	return FlagOverrideKey(fmt.Sprintf("env-%s-flag-override-audit", envID))
+}
+
+// FlagOverride pins parts of a flag's config at the Proxy so that they take
+// precedence over the config from Harness SaaS until the override is removed
+// or it expires.
+type FlagOverride struct {
+	Feature string `json:"feature"`
+
+	// State pins the flag on or off
+	State string `json:"state,omitempty"`
+
+	// DefaultServe is the identifier of the variation to serve by default
+	DefaultServe string `json:"defaultServe,omitempty"`
+
+	// Targets maps target identifiers to the identifier of the variation they're served
+	Targets map[string]string `json:"targets,omitempty"`
+
+	Reason    string `json:"reason,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
+
+	// ExpiresAt is when the override expires in unix milliseconds, zero means it never expires
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
+}
+
+// Expired returns true if the override has an expiry that's passed
+func (f FlagOverride) Expired(now time.Time) bool {
+	return f.ExpiresAt > 0 && now.UnixMilli() >= f.ExpiresAt
+}
+
+// FlagOverrides are the FlagOverrides for an environment keyed by flag identifier.
+//
+// Revisions counts the changes that have been made to each flag's override and is
+// added to the flag's version. This makes SDKs treat a flag as newer than the one
+// they have whenever its override is set or removed, since they ignore flags with
+// a version they've already seen.
+type FlagOverrides struct {
+	Overrides map[string]FlagOverride `json:"overrides"`
+	Revisions map[string]int64        `json:"revisions"`
+}
+
+// NewFlagOverrides creates an empty FlagOverrides
+func NewFlagOverrides() FlagOverrides {
+	return FlagOverrides{
+		Overrides: map[string]FlagOverride{},
+		Revisions: map[string]int64{},
+	}
+}
+
+// MarshalBinary marshals FlagOverrides to bytes
+func (f *FlagOverrides) MarshalBinary() ([]byte, error) {
+	return jsoniter.Marshal(f)
+}
+
+// UnmarshalBinary unmarshals bytes to FlagOverrides. It can read plain json as well as
+// values that were encoded by a cache codec
+func (f *FlagOverrides) UnmarshalBinary(b []byte) error {
+	return codec.Unmarshal(b, f)
+}
+
+// Revision returns the total number of changes that have been made to the overrides
+// by now
+func (f FlagOverrides) Revision(now time.Time) int64 {
+	var total int64
+	for feature := range f.Revisions {
+		total += f.revision(feature, now)
+	}
+	return total
+}
+
+// revision returns the number of changes that have been made to a flag's override by
+// now. An override that has expired but hasn't been removed yet counts as removed so
+// that SDKs see the flag change as soon as it expires rather than when it's removed.
+func (f FlagOverrides) revision(feature string, now time.Time) int64 {
+	r := f.Revisions[feature]
+	if o, ok := f.Overrides[feature]; ok && o.Expired(now) {
+		r++
+	}
+	return r
+}
+
+// Apply returns a copy of the flag with its override applied. Expired overrides
+// are treated as if they'd already been removed.
+func (f FlagOverrides) Apply(flag FeatureFlag, now time.Time) FeatureFlag {
+	if r := f.revision(flag.Feature, now); r > 0 && flag.Version != nil {
+		version := *flag.Version + r
+		flag.Version = &version
+	}
+
+	o, ok := f.Overrides[flag.Feature]
+	if !ok || o.Expired(now) {
+		return flag
+	}
+
+	if o.State != "" {
+		flag.State = clientgen.FeatureState(o.State)
+	}
+
+	if o.DefaultServe != "" {
+		variation := o.DefaultServe
+		flag.DefaultServe = clientgen.Serve{Variation: &variation}
+	}
+
+	if len(o.Targets) > 0 {
+		flag.VariationToTargetMap = overrideVariationToTargetMap(o.Targets, flag.VariationToTargetMap)
+	}
+	return flag
+}
+
+// overrideVariationToTargetMap returns a new VariationToTargetMap with the targets from an
+// override ahead of the existing ones. Targets are served the variation from the first
+// entry they're in so we also take them out of the existing entries.
+func overrideVariationToTargetMap(targets map[string]string, existing *[]clientgen.VariationMap) *[]clientgen.VariationMap {
+	byVariation := map[string][]clientgen.TargetMap{}
+	for identifier, variation := range targets {
+		byVariation[variation] = append(byVariation[variation], clientgen.TargetMap{Identifier: identifier, Name: identifier})
+	}
+
+	variations := make([]string, 0, len(byVariation))
+	for v := range byVariation {
+		variations = append(variations, v)
+	}
+	sort.Strings(variations)
+
+	result := make([]clientgen.VariationMap, 0, len(variations)+len(SafePtrDereference(existing)))
+	for _, v := range variations {
+		t := byVariation[v]
+		sort.Slice(t, func(i, j int) bool { return t[i].Identifier < t[j].Identifier })
+		result = append(result, clientgen.VariationMap{Variation: v, Targets: &t})
+	}
+
+	for _, vm := range SafePtrDereference(existing) {
+		if vm.Targets != nil {
+			remaining := make([]clientgen.TargetMap, 0, len(*vm.Targets))
+			for _, t := range *vm.Targets {
+				if _, ok := targets[t.Identifier]; !ok {
+					remaining = append(remaining, t)
+				}
+			}
+			vm.Targets = &remaining
+		}
+		result = append(result, vm)
+	}
+	return &result
+}
+
+// FlagOverrideAuditEntry records a change to a FlagOverride
+type FlagOverrideAuditEntry struct {
+	Timestamp int64  `json:"timestamp"`
+	Action    string `json:"action"`
+	Feature   string `json:"feature"`
+
+	// Source is what made the change e.g. the admin API or the overrides file
+	Source   string        `json:"source"`
+	Override *FlagOverride `json:"override,omitempty"`
+}
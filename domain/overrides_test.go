@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	clientgen "github.com/harness/ff-proxy/v2/gen/client"
+)
+
+func TestFlagOverrides_Apply(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+
+	newFlag := func() FeatureFlag {
+		return FeatureFlag{
+			Feature:      "dark-mode",
+			State:        clientgen.On,
+			DefaultServe: clientgen.Serve{Variation: ToPtr("true")},
+			OffVariation: "false",
+			VariationToTargetMap: &[]clientgen.VariationMap{
+				{Variation: "true", Targets: &[]clientgen.TargetMap{{Identifier: "alice", Name: "alice"}, {Identifier: "bob", Name: "bob"}}},
+			},
+			Version: ToPtr(int64(5)),
+		}
+	}
+
+	testCases := map[string]struct {
+		overrides FlagOverrides
+		expected  func(f FeatureFlag) FeatureFlag
+	}{
+		"Given there are no overrides": {
+			overrides: NewFlagOverrides(),
+			expected:  func(f FeatureFlag) FeatureFlag { return f },
+		},
+		"Given the flag's override pins its state and default serve": {
+			overrides: FlagOverrides{
+				Overrides: map[string]FlagOverride{"dark-mode": {Feature: "dark-mode", State: "off", DefaultServe: "false"}},
+				Revisions: map[string]int64{"dark-mode": 1},
+			},
+			expected: func(f FeatureFlag) FeatureFlag {
+				f.State = clientgen.Off
+				f.DefaultServe = clientgen.Serve{Variation: ToPtr("false")}
+				f.Version = ToPtr(int64(6))
+				return f
+			},
+		},
+		"Given the flag's override serves a target a different variation": {
+			overrides: FlagOverrides{
+				Overrides: map[string]FlagOverride{"dark-mode": {Feature: "dark-mode", Targets: map[string]string{"bob": "false"}}},
+				Revisions: map[string]int64{"dark-mode": 2},
+			},
+			expected: func(f FeatureFlag) FeatureFlag {
+				f.VariationToTargetMap = &[]clientgen.VariationMap{
+					{Variation: "false", Targets: &[]clientgen.TargetMap{{Identifier: "bob", Name: "bob"}}},
+					{Variation: "true", Targets: &[]clientgen.TargetMap{{Identifier: "alice", Name: "alice"}}},
+				}
+				f.Version = ToPtr(int64(7))
+				return f
+			},
+		},
+		"Given the flag's override has expired": {
+			overrides: FlagOverrides{
+				Overrides: map[string]FlagOverride{"dark-mode": {Feature: "dark-mode", State: "off", ExpiresAt: now.UnixMilli()}},
+				Revisions: map[string]int64{"dark-mode": 1},
+			},
+			// The version is the same as it will be once the expired override is removed
+			expected: func(f FeatureFlag) FeatureFlag {
+				f.Version = ToPtr(int64(7))
+				return f
+			},
+		},
+		"Given the flag's override has been removed": {
+			overrides: FlagOverrides{
+				Overrides: map[string]FlagOverride{},
+				Revisions: map[string]int64{"dark-mode": 2},
+			},
+			expected: func(f FeatureFlag) FeatureFlag {
+				f.Version = ToPtr(int64(7))
+				return f
+			},
+		},
+		"Given only a different flag has an override": {
+			overrides: FlagOverrides{
+				Overrides: map[string]FlagOverride{"light-mode": {Feature: "light-mode", State: "off"}},
+				Revisions: map[string]int64{"light-mode": 1},
+			},
+			expected: func(f FeatureFlag) FeatureFlag { return f },
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			flag := newFlag()
+			actual := tc.overrides.Apply(flag, now)
+
+			assert.Equal(t, tc.expected(newFlag()), actual)
+
+			// Applying overrides shouldn't modify the flag that was passed in
+			assert.Equal(t, newFlag(), flag)
+		})
+	}
+}
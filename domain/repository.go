@@ -41,3 +41,10 @@ type SegmentRepo interface {
 	RemoveAllSegmentsForEnvironment(ctx context.Context, id string) error
 	GetSegmentsForEnvironment(ctx context.Context, envID string) ([]Segment, bool)
 }
+
+// TargetRepo is the interface for the TargetRepository
+type TargetRepo interface {
+	DeltaAdd(ctx context.Context, envID string, targets ...Target) error
+	Remove(ctx context.Context, key string) error
+	RemoveAllTargetsForEnvironment(ctx context.Context, envID string) error
+}
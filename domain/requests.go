@@ -25,6 +25,15 @@ type AuthResponse struct {
 // FeatureConfigRequest contains the fields sent in a GET /client/env/{environmentUUID}/feature-configs
 type FeatureConfigRequest struct {
 	EnvironmentID string
+	IfNoneMatch   string
+}
+
+// FeatureConfigResponse contains the fields returned by a GET /client/env/{environmentUUID}/feature-configs request.
+// If NotModified is true the FeatureConfigs the client already has, identified by the ETag, are still up to date.
+type FeatureConfigResponse struct {
+	ETag           string
+	NotModified    bool
+	FeatureConfigs []FeatureConfig
 }
 
 // FeatureConfigByIdentifierRequest contains the fields sent in a GET /client/env/{environmentUUID}/feature-configs/{identifier}
@@ -37,6 +46,15 @@ type FeatureConfigByIdentifierRequest struct {
 type TargetSegmentsRequest struct {
 	EnvironmentID string
 	Rules         string
+	IfNoneMatch   string
+}
+
+// TargetSegmentsResponse contains the fields returned by a GET /client/env/{environmentUUID}/target-segments request.
+// If NotModified is true the Segments the client already has, identified by the ETag, are still up to date.
+type TargetSegmentsResponse struct {
+	ETag        string
+	NotModified bool
+	Segments    []Segment
 }
 
 // TargetSegmentsByIdentifierRequest contains the fields sent in a GET /client/env/{environmentUUID}/target-segments/{identifier}
@@ -59,14 +77,31 @@ type EvaluationsByFeatureRequest struct {
 	FeatureIdentifier string
 }
 
+// BatchEvaluationsRequest contains the fields sent in a POST /client/env/{environmentUUID}/target-evaluations request
+type BatchEvaluationsRequest struct {
+	EnvironmentID string   `json:"-"`
+	Targets       []Target `json:"targets"`
+	Flags         []string `json:"flags,omitempty"`
+}
+
+// BatchEvaluationsResponse maps target identifiers to their evaluations
+type BatchEvaluationsResponse map[string][]clientgen.Evaluation
+
 // StreamRequest contains the fields sent in a GET /stream request
 type StreamRequest struct {
 	APIKey string `json:"api_key"`
+
+	// LastEventID is the ID of the last event the SDK received before it reconnected
+	LastEventID string `json:"-"`
 }
 
 // StreamResponse contains the fields returned by a Stream request
 type StreamResponse struct {
 	GripChannel string
+
+	// LastEventID is the ID of the last event the SDK received, the events after it
+	// are replayed when the stream is opened
+	LastEventID string
 }
 
 // MetricsRequest contains the fields sent in a POST /metrics request
@@ -75,6 +110,10 @@ type MetricsRequest struct {
 	Size          int    `json:"-"`
 	EnvironmentID string `json:"environment_id"`
 	clientgen.Metrics
+
+	// StreamIDs are the IDs of the stream messages that the Primary built the request from. They're only used
+	// internally so that the messages can be acked once the request has been posted to Saas.
+	StreamIDs []string `json:"-"`
 }
 
 // MarshalBinary makes MetricsRequest implement the encoding.BinaryMarshaler func
@@ -84,9 +123,24 @@ func (m *MetricsRequest) MarshalBinary() (data []byte, err error) {
 
 // HealthResponse contains the fields returned in a healthcheck response
 type HealthResponse struct {
+	ConfigStatus ConfigStatus     `json:"configStatus"`
+	StreamStatus StreamStatus     `json:"streamStatus"`
+	CacheStatus  string           `json:"cacheStatus"`
+	ProxyKeys    []ProxyKeyHealth `json:"proxyKeys,omitempty"`
+}
+
+// ProxyKeyHealth contains the config and stream status for one of the Proxy's keys.
+// The key is masked so that it isn't exposed by the health endpoints.
+type ProxyKeyHealth struct {
+	Key          string       `json:"key"`
 	ConfigStatus ConfigStatus `json:"configStatus"`
 	StreamStatus StreamStatus `json:"streamStatus"`
-	CacheStatus  string       `json:"cacheStatus"`
+}
+
+// ReadinessResponse is what we return from /ready
+type ReadinessResponse struct {
+	Ready  bool   `json:"ready"`
+	Reason string `json:"reason,omitempty"`
 }
 
 type GetProxyConfigInput struct {
@@ -106,7 +160,7 @@ type AuthenticateProxyKeyResponse struct {
 
 func AddHarnessXHeaders(envID string) func(ctx context.Context, req *http.Request) error {
 	return func(ctx context.Context, req *http.Request) error {
-		accountID := ctx.Value(ContextKeyAccountID).(string)
+		accountID, _ := ctx.Value(ContextKeyAccountID).(string)
 
 		req.Header.Set("Harness-Accountid", accountID)
 		req.Header.Set("Harness-Environmentid", envID)
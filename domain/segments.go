@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/harness/ff-golang-server-sdk/rest"
+	"github.com/harness/ff-proxy/v2/codec"
 	clientgen "github.com/harness/ff-proxy/v2/gen/client"
 	jsoniter "github.com/json-iterator/go"
 )
@@ -30,9 +31,10 @@ func (s *Segment) MarshalBinary() ([]byte, error) {
 	return jsoniter.Marshal(s)
 }
 
-// UnmarshalBinary unmarshals bytes to a Segment
+// UnmarshalBinary unmarshals bytes to a Segment. It can read plain json as well as
+// values that were encoded by a cache codec
 func (s *Segment) UnmarshalBinary(b []byte) error {
-	return jsoniter.Unmarshal(b, s)
+	return codec.Unmarshal(b, s)
 }
 
 func (s *Segment) ToSDKSegment() rest.Segment {
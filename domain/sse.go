@@ -11,6 +11,10 @@ type SSEMessage struct {
 	Environment  string   `json:"environment"`
 	Environments []string `json:"environments,omitempty"`
 	APIKey       string   `json:"apiKey"`
+
+	// EventID is the ID the message was given when it was added to the EventHistory
+	// for its environment, it's sent to SDKs as the id of the SSE event
+	EventID int64 `json:"eventId,omitempty"`
 }
 
 // MarshalBinary makes SSEMessage implement the BinaryMarshaler interface
@@ -43,4 +47,8 @@ const (
 	EventEnvironmentRemoved = "environmentsRemoved"
 	EventAPIKeyAdded        = "apiKeyAdded"
 	EventAPIKeyRemoved      = "apiKeyRemoved"
+
+	// EventRefetchAll tells an SDK that it missed events that can't be replayed so
+	// it should refetch all of its flags and target groups
+	EventRefetchAll = "refetchAll"
 )
@@ -21,6 +21,12 @@ type Subscriber interface {
 	Sub(ctx context.Context, channel string, id string, message HandleMessageFn) error
 }
 
+// Acker defines the interface for acknowledging messages read from a stream so that
+// they aren't delivered again
+type Acker interface {
+	Ack(ctx context.Context, channel string, ids ...string) error
+}
+
 // Closer defines the interface for closing a stream
 type Closer interface {
 	Close(topic string) error
@@ -28,3 +34,19 @@ type Closer interface {
 
 // HandleMessageFn is the function that gets called whenever a subscriber receives a message on a stream
 type HandleMessageFn func(id string, v interface{}) error
+
+// EventHistory assigns IDs to the events sent to SDKs on a channel and keeps a
+// bounded history of them so they can be replayed to SDKs that reconnect
+type EventHistory interface {
+	// Add stores the message and returns the ID it was given, IDs increase
+	// monotonically for each channel
+	Add(ctx context.Context, channel string, msg SSEMessage) (int64, error)
+
+	// Since returns the messages added to the channel after the one with the given
+	// ID, oldest first, with their EventID set. It returns an error if any of them
+	// are no longer in the history.
+	Since(ctx context.Context, channel string, id int64) ([]SSEMessage, error)
+
+	// Latest returns the ID of the most recent message added to the channel
+	Latest(ctx context.Context, channel string) (int64, error)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/harness/ff-golang-server-sdk/evaluation"
+	"github.com/harness/ff-proxy/v2/codec"
 	clientgen "github.com/harness/ff-proxy/v2/gen/client"
 	jsoniter "github.com/json-iterator/go"
 )
@@ -32,9 +33,10 @@ func (t *Target) MarshalBinary() ([]byte, error) {
 	return jsoniter.Marshal(t)
 }
 
-// UnmarshalBinary unmarshals bytes to a Target
+// UnmarshalBinary unmarshals bytes to a Target. It can read plain json as well as
+// values that were encoded by a cache codec
 func (t *Target) UnmarshalBinary(b []byte) error {
-	return jsoniter.Unmarshal(b, t)
+	return codec.Unmarshal(b, t)
 }
 
 // ConvertTarget converts types.Target to the evaluation.Target
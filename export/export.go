@@ -2,10 +2,11 @@ package export
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
+	"path"
 	"strings"
 	"time"
 
@@ -51,48 +52,68 @@ type Service struct {
 	authRepo    repository.AuthRepo
 	authConfig  map[domain.AuthAPIKey]string
 	configDir   string
+	format      Format
+	signingKey  ed25519.PrivateKey
 }
 
-// NewService creates and returns an ExportService
-func NewService(logger log.StructuredLogger, featureRepo repository.FeatureFlagRepo, targetRepo repository.TargetRepo,
-	segmentRepo repository.SegmentRepo, authRepo repository.AuthRepo, authConfig map[domain.AuthAPIKey]string, configDir string) Service {
-	l := logger.With("component", "ExportService")
-
-	// The AuthRepo will give us back a map of hashed API keys to environments but the apikeys will be prefixed
-	// with 'auth-key'. The code that loads the authconfig in from the exported file expectes hashed api keys
-	// with no prefixes so we remove them here to avoid any issues reading config in from the exported file.
-	authc := map[domain.AuthAPIKey]string{}
-	for key, env := range authConfig {
-		if strings.HasPrefix(string(key), "auth-key-") {
-			cleanKey := strings.TrimPrefix(string(key), "auth-key-")
-			authc[domain.AuthAPIKey(cleanKey)] = env
-			continue
-		}
+// WithFormat sets the format that the Service exports config in, it defaults to FormatJSON
+func WithFormat(f Format) func(s *Service) {
+	return func(s *Service) {
+		s.format = f
+	}
+}
 
-		authc[key] = env
+// WithSigningKey signs the exported config with the key so that the Proxy can
+// verify it hasn't been tampered with before loading it
+func WithSigningKey(key ed25519.PrivateKey) func(s *Service) {
+	return func(s *Service) {
+		s.signingKey = key
 	}
+}
+
+// NewService creates and returns an ExportService. If authConfig is nil the
+// api keys are read from the AuthRepo each time the config is persisted.
+func NewService(logger log.StructuredLogger, featureRepo repository.FeatureFlagRepo, targetRepo repository.TargetRepo,
+	segmentRepo repository.SegmentRepo, authRepo repository.AuthRepo, authConfig map[domain.AuthAPIKey]string, configDir string, opts ...func(s *Service)) Service {
+	l := logger.With("component", "ExportService")
 
-	return Service{
+	s := Service{
 		logger:      l,
 		featureRepo: featureRepo,
 		targetRepo:  targetRepo,
 		segmentRepo: segmentRepo,
 		authRepo:    authRepo,
-		authConfig:  authc,
+		authConfig:  cleanAuthConfig(authConfig),
 		configDir:   configDir,
+		format:      FormatJSON,
 	}
+
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
 }
 
-// Persist saves all config to disk
+// Persist saves all config to disk in the Service's format
 //
 //nolint:cyclop
 func (s Service) Persist(ctx context.Context) error {
+	authConfig := s.authConfig
+	if authConfig == nil {
+		var err error
+		authConfig, err = s.loadAuthConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load auth config: %s", err)
+		}
+	}
+
 	configMap := map[string]OfflineConfig{}
-	for hashedKey, env := range s.authConfig {
+	for hashedKey, env := range authConfig {
 		// If we haven't got a config for the env yet lets initialise one and
 		// add it to the map
 		if _, ok := configMap[env]; !ok {
-			features, _ := s.featureRepo.Get(ctx, env)
+			// Overrides are local to the Proxy so we export the flags without them
+			features, _ := s.featureRepo.GetFeatureConfigForEnvironment(ctx, env)
 			targets, _ := s.targetRepo.Get(ctx, env)
 			segments, _ := s.segmentRepo.Get(ctx, env)
 
@@ -112,81 +133,163 @@ func (s Service) Persist(ctx context.Context) error {
 		configMap[env] = c
 	}
 
-	// make config directory
-	err := os.Mkdir(s.configDir, createDirPermissionLevel)
+	w, err := s.newWriter()
 	if err != nil {
-		return fmt.Errorf("failed to create config directory: %s", err)
+		return err
 	}
 
-	for environment, config := range configMap {
-		dirName := fmt.Sprintf("%s/env-%s", s.configDir, environment)
+	var signer *signingWriter
+	if s.signingKey != nil {
+		signer = newSigningWriter(w, s.signingKey)
+		w = signer
+	}
 
+	for environment, config := range configMap {
 		if len(config.APIKeys) == 0 {
 			continue
 		}
 
-		if err := os.MkdirAll(dirName, createDirPermissionLevel); err != nil {
-			return fmt.Errorf("failed to create directory %q: %s", dirName, err)
+		if err := s.writeEnvironment(w, environment, config); err != nil {
+			w.Close()
+			return err
 		}
+	}
 
-		authFilename := fmt.Sprintf("%s/auth_config.json", dirName)
-		if err := saveConfig(authFilename, config.APIKeys); err != nil {
-			return fmt.Errorf("failed to save auth config: %s", err)
+	if signer != nil {
+		if err := signer.writeManifest(); err != nil {
+			w.Close()
+			return fmt.Errorf("failed to sign config: %s", err)
 		}
+	}
 
-		s.logger.Info("writing targets", "count", len(config.Targets))
-		targetFilename := fmt.Sprintf("%s/targets.json", dirName)
-		if err := saveConfig(targetFilename, config.Targets); err != nil {
-			return fmt.Errorf("failed to save target config: %s", err)
-		}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write config: %s", err)
+	}
 
-		s.logger.Info("writing features", "count", len(config.Features))
-		featureFilename := fmt.Sprintf("%s/feature_config.json", dirName)
-		if err := saveConfig(featureFilename, config.Features); err != nil {
-			return fmt.Errorf("failed to save feature config: %s", err)
-		}
+	s.logger.Info("Exported config successfully", "format", s.format)
 
-		s.logger.Info("writing segments", "count", len(config.Segments))
-		segmentsFilename := fmt.Sprintf("%s/segments.json", dirName)
-		if err := saveConfig(segmentsFilename, config.Segments); err != nil {
-			return fmt.Errorf("failed to save segment config: %s", err)
-		}
+	return nil
+}
 
-		readme, err := os.OpenFile(fmt.Sprintf("%s/README.md", dirName), os.O_CREATE|os.O_WRONLY, createFilePermissionLevel)
+// loadAuthConfig gets the hashed api keys for every environment from the AuthRepo
+func (s Service) loadAuthConfig(ctx context.Context) (map[domain.AuthAPIKey]string, error) {
+	envs, err := s.authRepo.Environments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	authConfig := map[domain.AuthAPIKey]string{}
+	for _, env := range envs {
+		keys, err := s.authRepo.GetKeysForEnvironment(ctx, env)
 		if err != nil {
-			readme.Close()
-			return fmt.Errorf("failed to open README: %s", err)
+			return nil, err
+		}
+		for _, key := range keys {
+			authConfig[domain.AuthAPIKey(key)] = env
 		}
+	}
+	return cleanAuthConfig(authConfig), nil
+}
 
-		var envName string
-		if len(config.Features) > 0 {
-			envName = config.Features[0].Environment
+// cleanAuthConfig removes the prefixes from the api keys in the auth config.
+//
+// The AuthRepo will give us back a map of hashed API keys to environments but the apikeys will be prefixed
+// with 'auth-key'. The code that loads the authconfig in from the exported file expectes hashed api keys
+// with no prefixes so we remove them here to avoid any issues reading config in from the exported file.
+func cleanAuthConfig(authConfig map[domain.AuthAPIKey]string) map[domain.AuthAPIKey]string {
+	if authConfig == nil {
+		return nil
+	}
+
+	authc := make(map[domain.AuthAPIKey]string, len(authConfig))
+	for key, env := range authConfig {
+		authc[domain.AuthAPIKey(strings.TrimPrefix(string(key), "auth-key-"))] = env
+	}
+	return authc
+}
+
+// writeEnvironment writes the config for an environment in the Service's format
+func (s Service) writeEnvironment(w configWriter, environment string, config OfflineConfig) error {
+	s.logger.Info("writing config", "environment", environment, "targets", len(config.Targets), "features", len(config.Features), "segments", len(config.Segments))
+
+	if s.format == FormatYAML {
+		b, err := encodeYAML(map[string]interface{}{
+			"auth":          config.APIKeys,
+			"featureConfig": config.Features,
+			"segments":      config.Segments,
+			"targets":       config.Targets,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode config: %s", err)
 		}
+		if err := w.WriteFile(fmt.Sprintf("env-%s.yaml", environment), b); err != nil {
+			return fmt.Errorf("failed to save config: %s", err)
+		}
+		return nil
+	}
+
+	dirName := fmt.Sprintf("env-%s", environment)
+
+	files := []struct {
+		name  string
+		value interface{}
+		kind  string
+	}{
+		{name: "auth_config.json", value: config.APIKeys, kind: "auth"},
+		{name: "targets.json", value: config.Targets, kind: "target"},
+		{name: "feature_config.json", value: config.Features, kind: "feature"},
+		{name: "segments.json", value: config.Segments, kind: "segment"},
+	}
 
-		_, err = io.WriteString(readme, fmt.Sprintf(readmeTemplate, environment, envName, len(config.Features), len(config.Targets), len(config.Segments), time.Now().Format("2006-01-02 15:04:05")))
+	for _, f := range files {
+		b, err := json.Marshal(f.value)
 		if err != nil {
-			return fmt.Errorf("failed writing to readme: %s", err)
+			return fmt.Errorf("failed to encode %s config: %s", f.kind, err)
+		}
+		if err := w.WriteFile(path.Join(dirName, f.name), append(b, '\n')); err != nil {
+			return fmt.Errorf("failed to save %s config: %s", f.kind, err)
 		}
 	}
 
-	s.logger.Info("Exported config successfully")
+	var envName string
+	if len(config.Features) > 0 {
+		envName = config.Features[0].Environment
+	}
 
+	readme := fmt.Sprintf(readmeTemplate, environment, envName, len(config.Features), len(config.Targets), len(config.Segments), time.Now().Format("2006-01-02 15:04:05"))
+	if err := w.WriteFile(path.Join(dirName, "README.md"), []byte(readme)); err != nil {
+		return fmt.Errorf("failed writing to readme: %s", err)
+	}
 	return nil
 }
 
-func saveConfig(filename string, v interface{}) error {
-	// #nosec
-	f, err := os.Create(filename)
-
-	if err != nil {
-		return fmt.Errorf("failed to open file: %s", err)
-	}
+// newWriter creates the configWriter for the Service's format
+func (s Service) newWriter() (configWriter, error) {
+	switch s.format {
+	case FormatZip, FormatTarGz:
+		// #nosec G304
+		f, err := os.OpenFile(s.configDir, os.O_CREATE|os.O_EXCL|os.O_WRONLY, createFilePermissionLevel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create config bundle: %s", err)
+		}
+		if s.format == FormatZip {
+			return newZipWriter(f), nil
+		}
+		return newTarGzWriter(f), nil
+	default:
+		if err := os.MkdirAll(s.configDir, createDirPermissionLevel); err != nil {
+			return nil, fmt.Errorf("failed to create config directory: %s", err)
+		}
 
-	enc := json.NewEncoder(f)
-	if err := enc.Encode(v); err != nil {
-		f.Close()
-		return fmt.Errorf("failed to write to file: %s", err)
+		// The directory is often a mounted volume so it can already exist, but we
+		// don't want to mix the exported config up with whatever's already there
+		entries, err := os.ReadDir(s.configDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config directory: %s", err)
+		}
+		if len(entries) > 0 {
+			return nil, fmt.Errorf("config directory %s isn't empty", s.configDir)
+		}
+		return dirWriter{dir: s.configDir}, nil
 	}
-
-	return f.Close()
 }
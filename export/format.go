@@ -0,0 +1,219 @@
+package export
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/harness/ff-proxy/v2/signing"
+)
+
+// Format is the format that offline config is exported in
+type Format string
+
+const (
+	// FormatJSON exports a directory with an env-<id> directory of json files for each environment
+	FormatJSON Format = "json"
+
+	// FormatYAML exports a directory with a single env-<id>.yaml file for each environment
+	FormatYAML Format = "yaml"
+
+	// FormatZip exports the json directory layout as a single zip file
+	FormatZip Format = "zip"
+
+	// FormatTarGz exports the json directory layout as a single tar.gz file
+	FormatTarGz Format = "tar.gz"
+)
+
+// ParseFormat parses the name of an export Format
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatJSON, FormatYAML, FormatZip, FormatTarGz:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q, supported formats are %s, %s, %s & %s", s, FormatJSON, FormatYAML, FormatZip, FormatTarGz)
+	}
+}
+
+// configWriter writes the exported config files
+type configWriter interface {
+	// WriteFile writes a file, the name is a slash separated path
+	WriteFile(name string, b []byte) error
+
+	// Close finishes writing the config
+	Close() error
+}
+
+// dirWriter writes config files to a directory
+type dirWriter struct {
+	dir string
+}
+
+func (d dirWriter) WriteFile(name string, b []byte) error {
+	p := filepath.Join(d.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), createDirPermissionLevel); err != nil {
+		return fmt.Errorf("failed to create directory %q: %s", filepath.Dir(p), err)
+	}
+	return os.WriteFile(p, b, createFilePermissionLevel)
+}
+
+func (d dirWriter) Close() error {
+	return nil
+}
+
+// zipWriter writes config files to a zip file
+type zipWriter struct {
+	file io.Closer
+	zw   *zip.Writer
+}
+
+func newZipWriter(f io.WriteCloser) zipWriter {
+	return zipWriter{file: f, zw: zip.NewWriter(f)}
+}
+
+func (z zipWriter) WriteFile(name string, b []byte) error {
+	w, err := z.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (z zipWriter) Close() error {
+	if err := z.zw.Close(); err != nil {
+		z.file.Close()
+		return err
+	}
+	return z.file.Close()
+}
+
+// tarGzWriter writes config files to a gzipped tar file
+type tarGzWriter struct {
+	file io.Closer
+	gz   *gzip.Writer
+	tw   *tar.Writer
+}
+
+func newTarGzWriter(f io.WriteCloser) tarGzWriter {
+	gz := gzip.NewWriter(f)
+	return tarGzWriter{file: f, gz: gz, tw: tar.NewWriter(gz)}
+}
+
+func (t tarGzWriter) WriteFile(name string, b []byte) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     createFilePermissionLevel,
+		Size:     int64(len(b)),
+		ModTime:  time.Now(),
+	}
+	if err := t.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := t.tw.Write(b)
+	return err
+}
+
+func (t tarGzWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		t.file.Close()
+		return err
+	}
+	if err := t.gz.Close(); err != nil {
+		t.file.Close()
+		return err
+	}
+	return t.file.Close()
+}
+
+// signingWriter records the digest of every file that's written so that a
+// signed manifest of them can be written once they're all done
+type signingWriter struct {
+	next     configWriter
+	key      ed25519.PrivateKey
+	manifest signing.Manifest
+}
+
+func newSigningWriter(next configWriter, key ed25519.PrivateKey) *signingWriter {
+	return &signingWriter{next: next, key: key, manifest: signing.NewManifest()}
+}
+
+func (s *signingWriter) WriteFile(name string, b []byte) error {
+	if err := s.next.WriteFile(name, b); err != nil {
+		return err
+	}
+	s.manifest.Add(name, b)
+	return nil
+}
+
+// writeManifest signs the manifest of the files that have been written and
+// writes it alongside them
+func (s *signingWriter) writeManifest() error {
+	manifest, sig, err := s.manifest.Sign(s.key)
+	if err != nil {
+		return err
+	}
+
+	if err := s.next.WriteFile(signing.ManifestFile, manifest); err != nil {
+		return err
+	}
+	return s.next.WriteFile(signing.SignatureFile, sig)
+}
+
+func (s *signingWriter) Close() error {
+	return s.next.Close()
+}
+
+// encodeYAML encodes v as YAML using the field names from its json tags so
+// that the YAML uses the same field names as the json config
+func encodeYAML(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(jsonNumbers(doc))
+}
+
+// jsonNumbers converts the json.Numbers in a decoded json document to ints or
+// floats so that they're written as YAML numbers rather than strings
+func jsonNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			t[k] = jsonNumbers(val)
+		}
+		return t
+	case []interface{}:
+		for i, val := range t {
+			t[i] = jsonNumbers(val)
+		}
+		return t
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	default:
+		return v
+	}
+}
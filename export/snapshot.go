@@ -0,0 +1,134 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// snapshotPrefix is the prefix of every snapshot's name
+	snapshotPrefix = "config-"
+
+	// latestSnapshot is the name of the link to the most recent snapshot
+	latestSnapshot = "latest"
+
+	snapshotTimeFormat = "20060102T150405Z"
+)
+
+// Snapshot persists the config to a new snapshot in the config directory that's
+// named after the time it was taken and returns its path. The snapshot is written
+// to a temporary path and renamed once it's complete so a partially written
+// snapshot is never left behind. Once the snapshot has been written the latest
+// link is pointed at it and any snapshots older than the most recent keep are
+// removed.
+func (s Service) Snapshot(ctx context.Context, now time.Time, keep int) (string, error) {
+	if err := os.MkdirAll(s.configDir, createDirPermissionLevel); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %s", err)
+	}
+
+	name := snapshotPrefix + now.UTC().Format(snapshotTimeFormat) + s.extension()
+	path := filepath.Join(s.configDir, name)
+	tmpPath := filepath.Join(s.configDir, ".tmp-"+name)
+
+	tmp := s
+	tmp.configDir = tmpPath
+	if err := tmp.Persist(ctx); err != nil {
+		_ = os.RemoveAll(tmpPath)
+		return "", err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.RemoveAll(tmpPath)
+		return "", fmt.Errorf("failed to move snapshot into place: %s", err)
+	}
+
+	if err := s.linkLatest(name); err != nil {
+		s.logger.Warn("failed to link latest snapshot", "snapshot", path, "err", err)
+	}
+
+	if err := s.pruneSnapshots(keep); err != nil {
+		s.logger.Warn("failed to remove old snapshots", "dir", s.configDir, "err", err)
+	}
+	return path, nil
+}
+
+// ScheduleSnapshots takes a snapshot every interval until the context is cancelled
+func (s Service) ScheduleSnapshots(ctx context.Context, interval time.Duration, keep int) {
+	s.logger.Info("scheduling offline config snapshots", "dir", s.configDir, "interval", interval.String(), "keep", keep)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-ticker.C:
+			path, err := s.Snapshot(ctx, t, keep)
+			if err != nil {
+				s.logger.Error("failed to snapshot offline config", "dir", s.configDir, "err", err)
+				continue
+			}
+			s.logger.Info("wrote offline config snapshot", "snapshot", path)
+		}
+	}
+}
+
+// linkLatest points the latest link at the named snapshot. The new link is
+// renamed over the old one so that there's always a latest snapshot to load.
+func (s Service) linkLatest(name string) error {
+	link := latestSnapshot + s.extension()
+	tmpLink := filepath.Join(s.configDir, ".tmp-"+link)
+	_ = os.Remove(tmpLink)
+
+	if err := os.Symlink(name, tmpLink); err != nil {
+		return err
+	}
+	return os.Rename(tmpLink, filepath.Join(s.configDir, link))
+}
+
+// pruneSnapshots removes all but the most recent keep snapshots
+func (s Service) pruneSnapshots(keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(s.configDir)
+	if err != nil {
+		return err
+	}
+
+	// The snapshot names are timestamps so sorting them by name sorts them by age
+	snapshots := []string{}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), snapshotPrefix) {
+			snapshots = append(snapshots, e.Name())
+		}
+	}
+	sort.Strings(snapshots)
+
+	for len(snapshots) > keep {
+		if err := os.RemoveAll(filepath.Join(s.configDir, snapshots[0])); err != nil {
+			return err
+		}
+		snapshots = snapshots[1:]
+	}
+	return nil
+}
+
+// extension returns the file extension for snapshots in the Service's format
+func (s Service) extension() string {
+	switch s.format {
+	case FormatZip:
+		return ".zip"
+	case FormatTarGz:
+		return ".tar.gz"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/cache"
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/repository"
+)
+
+func newTestService(t *testing.T, dir string, opts ...func(s *Service)) Service {
+	t.Helper()
+	ctx := context.Background()
+
+	memCache := cache.NewMemCache()
+	hashCache := cache.NewHashCache(memCache, 1*time.Minute, 2*time.Minute)
+	authRepo := repository.NewAuthRepo(memCache)
+	flagRepo := repository.NewFeatureFlagRepo(hashCache)
+	segmentRepo := repository.NewSegmentRepo(hashCache)
+	targetRepo := repository.NewTargetRepo(memCache, log.NewNoOpLogger())
+
+	apiKey := domain.NewAuthAPIKey("1234abcd")
+	require.NoError(t, authRepo.Add(ctx, domain.AuthConfig{APIKey: apiKey, EnvironmentID: "123"}))
+	require.NoError(t, authRepo.AddAPIConfigsForEnvironment(ctx, "123", []string{string(apiKey)}))
+	require.NoError(t, flagRepo.Add(ctx, domain.FlagConfig{
+		EnvironmentID:  "123",
+		FeatureConfigs: []domain.FeatureFlag{{Feature: "dark-mode", Environment: "123", State: "on"}},
+	}))
+
+	logger, err := log.NewStructuredLogger("ERROR")
+	require.NoError(t, err)
+
+	return NewService(logger, flagRepo, targetRepo, segmentRepo, authRepo, nil, dir, opts...)
+}
+
+func TestService_Persist(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "config")
+	s := newTestService(t, dir)
+
+	require.NoError(t, s.Persist(context.Background()))
+
+	b, err := os.ReadFile(filepath.Join(dir, "env-123", "auth_config.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `["1234abcd"]`, string(b))
+
+	// We shouldn't write config into a directory that already has something in it
+	assert.Error(t, s.Persist(context.Background()))
+}
+
+func TestService_Snapshot(t *testing.T) {
+	testCases := map[string]struct {
+		format   Format
+		expected []string
+		latest   string
+	}{
+		"Given I take snapshots in the json format": {
+			format:   FormatJSON,
+			expected: []string{"config-20240102T030407Z", "config-20240102T030408Z", "latest"},
+			latest:   "config-20240102T030408Z",
+		},
+		"Given I take snapshots in the tar.gz format": {
+			format:   FormatTarGz,
+			expected: []string{"config-20240102T030407Z.tar.gz", "config-20240102T030408Z.tar.gz", "latest.tar.gz"},
+			latest:   "config-20240102T030408Z.tar.gz",
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			dir := t.TempDir()
+			s := newTestService(t, dir, WithFormat(tc.format))
+
+			start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			for i := 0; i < 4; i++ {
+				_, err := s.Snapshot(context.Background(), start.Add(time.Duration(i)*time.Second), 2)
+				require.NoError(t, err)
+			}
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+
+			actual := []string{}
+			for _, e := range entries {
+				actual = append(actual, e.Name())
+			}
+			assert.Equal(t, tc.expected, actual)
+
+			latest, err := os.Readlink(filepath.Join(dir, tc.expected[len(tc.expected)-1]))
+			require.NoError(t, err)
+			assert.Equal(t, tc.latest, latest)
+		})
+	}
+}
@@ -0,0 +1,472 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: proto/proxy.proto
+
+package proxypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ProxyService_Authenticate_FullMethodName      = "/ffproxy.v1.ProxyService/Authenticate"
+	ProxyService_GetFeatureConfigs_FullMethodName = "/ffproxy.v1.ProxyService/GetFeatureConfigs"
+	ProxyService_GetFeatureConfig_FullMethodName  = "/ffproxy.v1.ProxyService/GetFeatureConfig"
+	ProxyService_GetTargetSegments_FullMethodName = "/ffproxy.v1.ProxyService/GetTargetSegments"
+	ProxyService_GetTargetSegment_FullMethodName  = "/ffproxy.v1.ProxyService/GetTargetSegment"
+	ProxyService_GetEvaluations_FullMethodName    = "/ffproxy.v1.ProxyService/GetEvaluations"
+	ProxyService_GetEvaluation_FullMethodName     = "/ffproxy.v1.ProxyService/GetEvaluation"
+	ProxyService_PostMetrics_FullMethodName       = "/ffproxy.v1.ProxyService/PostMetrics"
+	ProxyService_Health_FullMethodName            = "/ffproxy.v1.ProxyService/Health"
+	ProxyService_Subscribe_FullMethodName         = "/ffproxy.v1.ProxyService/Subscribe"
+)
+
+// ProxyServiceClient is the client API for ProxyService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ProxyServiceClient interface {
+	Authenticate(ctx context.Context, in *AuthenticateRequest, opts ...grpc.CallOption) (*AuthenticateResponse, error)
+	GetFeatureConfigs(ctx context.Context, in *GetFeatureConfigsRequest, opts ...grpc.CallOption) (*GetFeatureConfigsResponse, error)
+	GetFeatureConfig(ctx context.Context, in *GetFeatureConfigRequest, opts ...grpc.CallOption) (*FeatureConfig, error)
+	GetTargetSegments(ctx context.Context, in *GetTargetSegmentsRequest, opts ...grpc.CallOption) (*GetTargetSegmentsResponse, error)
+	GetTargetSegment(ctx context.Context, in *GetTargetSegmentRequest, opts ...grpc.CallOption) (*Segment, error)
+	GetEvaluations(ctx context.Context, in *GetEvaluationsRequest, opts ...grpc.CallOption) (*GetEvaluationsResponse, error)
+	GetEvaluation(ctx context.Context, in *GetEvaluationRequest, opts ...grpc.CallOption) (*Evaluation, error)
+	PostMetrics(ctx context.Context, in *PostMetricsRequest, opts ...grpc.CallOption) (*PostMetricsResponse, error)
+	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
+	// Subscribe streams flag change events, it's the gRPC equivalent of the /stream endpoint
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (ProxyService_SubscribeClient, error)
+}
+
+type proxyServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProxyServiceClient(cc grpc.ClientConnInterface) ProxyServiceClient {
+	return &proxyServiceClient{cc}
+}
+
+func (c *proxyServiceClient) Authenticate(ctx context.Context, in *AuthenticateRequest, opts ...grpc.CallOption) (*AuthenticateResponse, error) {
+	out := new(AuthenticateResponse)
+	err := c.cc.Invoke(ctx, ProxyService_Authenticate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyServiceClient) GetFeatureConfigs(ctx context.Context, in *GetFeatureConfigsRequest, opts ...grpc.CallOption) (*GetFeatureConfigsResponse, error) {
+	out := new(GetFeatureConfigsResponse)
+	err := c.cc.Invoke(ctx, ProxyService_GetFeatureConfigs_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyServiceClient) GetFeatureConfig(ctx context.Context, in *GetFeatureConfigRequest, opts ...grpc.CallOption) (*FeatureConfig, error) {
+	out := new(FeatureConfig)
+	err := c.cc.Invoke(ctx, ProxyService_GetFeatureConfig_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyServiceClient) GetTargetSegments(ctx context.Context, in *GetTargetSegmentsRequest, opts ...grpc.CallOption) (*GetTargetSegmentsResponse, error) {
+	out := new(GetTargetSegmentsResponse)
+	err := c.cc.Invoke(ctx, ProxyService_GetTargetSegments_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyServiceClient) GetTargetSegment(ctx context.Context, in *GetTargetSegmentRequest, opts ...grpc.CallOption) (*Segment, error) {
+	out := new(Segment)
+	err := c.cc.Invoke(ctx, ProxyService_GetTargetSegment_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyServiceClient) GetEvaluations(ctx context.Context, in *GetEvaluationsRequest, opts ...grpc.CallOption) (*GetEvaluationsResponse, error) {
+	out := new(GetEvaluationsResponse)
+	err := c.cc.Invoke(ctx, ProxyService_GetEvaluations_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyServiceClient) GetEvaluation(ctx context.Context, in *GetEvaluationRequest, opts ...grpc.CallOption) (*Evaluation, error) {
+	out := new(Evaluation)
+	err := c.cc.Invoke(ctx, ProxyService_GetEvaluation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyServiceClient) PostMetrics(ctx context.Context, in *PostMetricsRequest, opts ...grpc.CallOption) (*PostMetricsResponse, error) {
+	out := new(PostMetricsResponse)
+	err := c.cc.Invoke(ctx, ProxyService_PostMetrics_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyServiceClient) Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, ProxyService_Health_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *proxyServiceClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (ProxyService_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ProxyService_ServiceDesc.Streams[0], ProxyService_Subscribe_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &proxyServiceSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ProxyService_SubscribeClient interface {
+	Recv() (*FlagChangeEvent, error)
+	grpc.ClientStream
+}
+
+type proxyServiceSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *proxyServiceSubscribeClient) Recv() (*FlagChangeEvent, error) {
+	m := new(FlagChangeEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ProxyServiceServer is the server API for ProxyService service.
+// All implementations must embed UnimplementedProxyServiceServer
+// for forward compatibility
+type ProxyServiceServer interface {
+	Authenticate(context.Context, *AuthenticateRequest) (*AuthenticateResponse, error)
+	GetFeatureConfigs(context.Context, *GetFeatureConfigsRequest) (*GetFeatureConfigsResponse, error)
+	GetFeatureConfig(context.Context, *GetFeatureConfigRequest) (*FeatureConfig, error)
+	GetTargetSegments(context.Context, *GetTargetSegmentsRequest) (*GetTargetSegmentsResponse, error)
+	GetTargetSegment(context.Context, *GetTargetSegmentRequest) (*Segment, error)
+	GetEvaluations(context.Context, *GetEvaluationsRequest) (*GetEvaluationsResponse, error)
+	GetEvaluation(context.Context, *GetEvaluationRequest) (*Evaluation, error)
+	PostMetrics(context.Context, *PostMetricsRequest) (*PostMetricsResponse, error)
+	Health(context.Context, *HealthRequest) (*HealthResponse, error)
+	// Subscribe streams flag change events, it's the gRPC equivalent of the /stream endpoint
+	Subscribe(*SubscribeRequest, ProxyService_SubscribeServer) error
+	mustEmbedUnimplementedProxyServiceServer()
+}
+
+// UnimplementedProxyServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedProxyServiceServer struct {
+}
+
+func (UnimplementedProxyServiceServer) Authenticate(context.Context, *AuthenticateRequest) (*AuthenticateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Authenticate not implemented")
+}
+func (UnimplementedProxyServiceServer) GetFeatureConfigs(context.Context, *GetFeatureConfigsRequest) (*GetFeatureConfigsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFeatureConfigs not implemented")
+}
+func (UnimplementedProxyServiceServer) GetFeatureConfig(context.Context, *GetFeatureConfigRequest) (*FeatureConfig, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFeatureConfig not implemented")
+}
+func (UnimplementedProxyServiceServer) GetTargetSegments(context.Context, *GetTargetSegmentsRequest) (*GetTargetSegmentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTargetSegments not implemented")
+}
+func (UnimplementedProxyServiceServer) GetTargetSegment(context.Context, *GetTargetSegmentRequest) (*Segment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTargetSegment not implemented")
+}
+func (UnimplementedProxyServiceServer) GetEvaluations(context.Context, *GetEvaluationsRequest) (*GetEvaluationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEvaluations not implemented")
+}
+func (UnimplementedProxyServiceServer) GetEvaluation(context.Context, *GetEvaluationRequest) (*Evaluation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEvaluation not implemented")
+}
+func (UnimplementedProxyServiceServer) PostMetrics(context.Context, *PostMetricsRequest) (*PostMetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostMetrics not implemented")
+}
+func (UnimplementedProxyServiceServer) Health(context.Context, *HealthRequest) (*HealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Health not implemented")
+}
+func (UnimplementedProxyServiceServer) Subscribe(*SubscribeRequest, ProxyService_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedProxyServiceServer) mustEmbedUnimplementedProxyServiceServer() {}
+
+// UnsafeProxyServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProxyServiceServer will
+// result in compilation errors.
+type UnsafeProxyServiceServer interface {
+	mustEmbedUnimplementedProxyServiceServer()
+}
+
+func RegisterProxyServiceServer(s grpc.ServiceRegistrar, srv ProxyServiceServer) {
+	s.RegisterService(&ProxyService_ServiceDesc, srv)
+}
+
+func _ProxyService_Authenticate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthenticateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServiceServer).Authenticate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProxyService_Authenticate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServiceServer).Authenticate(ctx, req.(*AuthenticateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyService_GetFeatureConfigs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFeatureConfigsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServiceServer).GetFeatureConfigs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProxyService_GetFeatureConfigs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServiceServer).GetFeatureConfigs(ctx, req.(*GetFeatureConfigsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyService_GetFeatureConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFeatureConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServiceServer).GetFeatureConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProxyService_GetFeatureConfig_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServiceServer).GetFeatureConfig(ctx, req.(*GetFeatureConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyService_GetTargetSegments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTargetSegmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServiceServer).GetTargetSegments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProxyService_GetTargetSegments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServiceServer).GetTargetSegments(ctx, req.(*GetTargetSegmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyService_GetTargetSegment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTargetSegmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServiceServer).GetTargetSegment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProxyService_GetTargetSegment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServiceServer).GetTargetSegment(ctx, req.(*GetTargetSegmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyService_GetEvaluations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEvaluationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServiceServer).GetEvaluations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProxyService_GetEvaluations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServiceServer).GetEvaluations(ctx, req.(*GetEvaluationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyService_GetEvaluation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEvaluationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServiceServer).GetEvaluation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProxyService_GetEvaluation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServiceServer).GetEvaluation(ctx, req.(*GetEvaluationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyService_PostMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServiceServer).PostMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProxyService_PostMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServiceServer).PostMetrics(ctx, req.(*PostMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyService_Health_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProxyServiceServer).Health(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProxyService_Health_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProxyServiceServer).Health(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProxyService_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProxyServiceServer).Subscribe(m, &proxyServiceSubscribeServer{stream})
+}
+
+type ProxyService_SubscribeServer interface {
+	Send(*FlagChangeEvent) error
+	grpc.ServerStream
+}
+
+type proxyServiceSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *proxyServiceSubscribeServer) Send(m *FlagChangeEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// ProxyService_ServiceDesc is the grpc.ServiceDesc for ProxyService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ProxyService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ffproxy.v1.ProxyService",
+	HandlerType: (*ProxyServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Authenticate",
+			Handler:    _ProxyService_Authenticate_Handler,
+		},
+		{
+			MethodName: "GetFeatureConfigs",
+			Handler:    _ProxyService_GetFeatureConfigs_Handler,
+		},
+		{
+			MethodName: "GetFeatureConfig",
+			Handler:    _ProxyService_GetFeatureConfig_Handler,
+		},
+		{
+			MethodName: "GetTargetSegments",
+			Handler:    _ProxyService_GetTargetSegments_Handler,
+		},
+		{
+			MethodName: "GetTargetSegment",
+			Handler:    _ProxyService_GetTargetSegment_Handler,
+		},
+		{
+			MethodName: "GetEvaluations",
+			Handler:    _ProxyService_GetEvaluations_Handler,
+		},
+		{
+			MethodName: "GetEvaluation",
+			Handler:    _ProxyService_GetEvaluation_Handler,
+		},
+		{
+			MethodName: "PostMetrics",
+			Handler:    _ProxyService_PostMetrics_Handler,
+		},
+		{
+			MethodName: "Health",
+			Handler:    _ProxyService_Health_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _ProxyService_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/proxy.proto",
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/harness/ff-proxy/v2/domain"
 	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/token"
 )
 
 // Heartbeat kicks off a goroutine that polls the /health endpoint at intervals
@@ -86,19 +87,42 @@ type ProxyHealth struct {
 	configHealth domain.ConfigStatus
 	streamHealth func(context.Context) (domain.StreamStatus, error)
 	cacheHealth  func(context.Context) error
+	proxyKeys    []proxyKeyHealth
 
 	cacheHealthy *domain.SafeBool
 }
 
+type proxyKeyHealth struct {
+	key          string
+	configHealth domain.ConfigStatus
+	streamHealth func(context.Context) (domain.StreamStatus, error)
+}
+
+// WithProxyKeyHealth makes the ProxyHealth report the config and stream status of a proxy key
+func WithProxyKeyHealth(key string, config domain.ConfigStatus, stream func(ctx context.Context) (domain.StreamStatus, error)) func(p *ProxyHealth) {
+	return func(p *ProxyHealth) {
+		p.proxyKeys = append(p.proxyKeys, proxyKeyHealth{
+			key:          token.MaskRight(key),
+			configHealth: config,
+			streamHealth: stream,
+		})
+	}
+}
+
 // NewProxyHealth creates a ProxyHealth
-func NewProxyHealth(l log.Logger, config domain.ConfigStatus, stream func(ctx context.Context) (domain.StreamStatus, error), cache func(ctx context.Context) error) ProxyHealth {
-	return ProxyHealth{
+func NewProxyHealth(l log.Logger, config domain.ConfigStatus, stream func(ctx context.Context) (domain.StreamStatus, error), cache func(ctx context.Context) error, opts ...func(p *ProxyHealth)) ProxyHealth {
+	p := ProxyHealth{
 		logger:       l,
 		configHealth: config,
 		streamHealth: stream,
 		cacheHealth:  cache,
 		cacheHealthy: domain.NewSafeBool(false),
 	}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+	return p
 }
 
 // Health returns the status of the Proxy's Stream and Cache
@@ -114,10 +138,25 @@ func (p ProxyHealth) Health(ctx context.Context) domain.HealthResponse {
 		p.logger.Error("failed to get proxy health", "err", err)
 	}
 
+	var proxyKeys []domain.ProxyKeyHealth
+	for _, k := range p.proxyKeys {
+		keyStreamStatus, err := k.streamHealth(ctx)
+		if err != nil {
+			p.logger.Error("failed to get stream health for proxy key", "key", k.key, "err", err)
+		}
+
+		proxyKeys = append(proxyKeys, domain.ProxyKeyHealth{
+			Key:          k.key,
+			ConfigStatus: k.configHealth,
+			StreamStatus: keyStreamStatus,
+		})
+	}
+
 	return domain.HealthResponse{
 		ConfigStatus: p.configHealth,
 		StreamStatus: streamStatus,
 		CacheStatus:  boolToHealthString(cacheHealthy),
+		ProxyKeys:    proxyKeys,
 	}
 }
 
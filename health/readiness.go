@@ -0,0 +1,180 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harness/ff-proxy/v2/cache"
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+// Readiness tracks whether the Proxy has the config it needs to serve SDK requests.
+// Primaries are ready once they've populated the cache but read replicas have to
+// wait for the primary to have populated it before they can serve anything.
+type Readiness struct {
+	logger log.Logger
+
+	mtx    *sync.RWMutex
+	ready  bool
+	reason string
+}
+
+// NewReadiness creates a Readiness
+func NewReadiness(l log.Logger, ready bool) *Readiness {
+	r := &Readiness{
+		logger: l.With("component", "Readiness"),
+		mtx:    &sync.RWMutex{},
+		ready:  ready,
+	}
+	if !ready {
+		r.reason = "waiting for the cache to be populated"
+	}
+	return r
+}
+
+// Ready returns true if the Proxy is ready to serve requests
+func (r *Readiness) Ready() bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return r.ready
+}
+
+// Status returns the readiness of the Proxy and if it isn't ready why not
+func (r *Readiness) Status() domain.ReadinessResponse {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	return domain.ReadinessResponse{
+		Ready:  r.ready,
+		Reason: r.reason,
+	}
+}
+
+// SetReady marks the Proxy as ready to serve requests
+func (r *Readiness) SetReady() {
+	r.set(true, "")
+	r.logger.Info("proxy is ready to serve requests")
+}
+
+func (r *Readiness) set(ready bool, reason string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.ready = ready
+	r.reason = reason
+}
+
+// WaitUntilReady runs the check every interval until it succeeds and then marks
+// the Proxy as ready. It blocks until then or until the context is cancelled.
+func (r *Readiness) WaitUntilReady(ctx context.Context, interval time.Duration, check func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := check(ctx)
+		if err == nil {
+			r.SetReady()
+			return
+		}
+
+		r.set(false, err.Error())
+		r.logger.Info("proxy isn't ready to serve requests yet", "reason", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type inventoryRepo interface {
+	Inventories(ctx context.Context) (map[string]map[string]string, error)
+	KeyExists(ctx context.Context, key string) bool
+}
+
+// NewCacheReadinessCheck returns a check for read replicas that passes once every
+// asset in the primary's key inventory exists in the cache. Once they all exist
+// it calls warm for each environment so config can be loaded into any local
+// caches before the replica starts serving requests.
+func NewCacheReadinessCheck(inventory inventoryRepo, warm func(ctx context.Context, envID string) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		inventories, err := inventory.Inventories(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get key inventory: %w", err)
+		}
+
+		if len(inventories) == 0 {
+			return fmt.Errorf("key inventory doesn't exist yet")
+		}
+
+		environments := map[string]struct{}{}
+		missing := 0
+		authKeys := 0
+
+		for _, assets := range inventories {
+			for key := range assets {
+				// The HashCache can't read the feature-configs or segments for an
+				// environment until their latest hash has been written so we check
+				// for that rather than the key itself
+				envID, isCollection := environmentFromKey(key)
+				existsKey := key
+				if isCollection {
+					existsKey = cache.NewLatestHashKey(key)
+				}
+
+				if !inventory.KeyExists(ctx, existsKey) {
+					missing++
+					continue
+				}
+
+				if strings.HasPrefix(key, "auth-key-") {
+					authKeys++
+				}
+				if isCollection {
+					environments[envID] = struct{}{}
+				}
+			}
+		}
+
+		if missing > 0 {
+			return fmt.Errorf("%d keys in the key inventory don't exist in the cache yet", missing)
+		}
+		if authKeys == 0 {
+			return fmt.Errorf("no auth keys exist in the cache yet")
+		}
+
+		envs := make([]string, 0, len(environments))
+		for env := range environments {
+			envs = append(envs, env)
+		}
+		sort.Strings(envs)
+
+		for _, env := range envs {
+			if err := warm(ctx, env); err != nil {
+				return fmt.Errorf("failed to warm cache for environment %s: %w", env, err)
+			}
+		}
+		return nil
+	}
+}
+
+// environmentFromKey returns the environment from feature-configs and segments keys
+func environmentFromKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, "env-") {
+		return "", false
+	}
+
+	for _, suffix := range []string{"-feature-configs", "-segments"} {
+		if strings.HasSuffix(key, suffix) {
+			return strings.TrimSuffix(strings.TrimPrefix(key, "env-"), suffix), true
+		}
+	}
+	return "", false
+}
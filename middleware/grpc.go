@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/harness/ff-proxy/v2/gen/proxypb"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+// unauthenticatedGRPCMethods are the gRPC methods that don't require an auth token,
+// these mirror the http routes that the auth middleware skips
+var unauthenticatedGRPCMethods = map[string]struct{}{
+	proxypb.ProxyService_Authenticate_FullMethodName: {},
+	proxypb.ProxyService_Health_FullMethodName:       {},
+}
+
+// NewGRPCAuthInterceptors returns unary and stream interceptors that check the
+// authorization metadata on gRPC requests is valid in the same way that the
+// echo auth middleware does for http requests
+func NewGRPCAuthInterceptors(logger log.Logger, authRepo keyLookUp, secret []byte, bypassAuth bool) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	authenticate := func(ctx context.Context, method string) error {
+		if bypassAuth {
+			return nil
+		}
+
+		if _, ok := unauthenticatedGRPCMethods[method]; ok {
+			return nil
+		}
+
+		token, ok := bearerTokenFromMetadata(ctx)
+		if !ok {
+			return status.Error(codes.Unauthenticated, "missing bearer token")
+		}
+
+		if err := validateToken(ctx, logger, authRepo, secret, token); err != nil {
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil
+	}
+
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authenticate(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authenticate(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+
+	return unary, stream
+}
+
+// bearerTokenFromMetadata extracts the token from the authorization metadata
+func bearerTokenFromMetadata(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	for _, v := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(v, "Bearer "); ok {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// NewGRPCRequestIDInterceptors returns unary and stream interceptors that either use a provided
+// requestID from the metadata or generate one and add it to the request context
+func NewGRPCRequestIDInterceptors() (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	withRequestID := func(ctx context.Context) (context.Context, string) {
+		reqID := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if ids := md.Get("x-request-id"); len(ids) > 0 {
+				reqID = ids[0]
+			}
+		}
+
+		if reqID == "" {
+			requestUUID, _ := uuid.NewRandom()
+			reqID = requestUUID.String()
+		}
+
+		return context.WithValue(ctx, log.RequestIDKey, reqID), reqID
+	}
+
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, reqID := withRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", reqID))
+
+		return handler(ctx, req)
+	}
+
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, reqID := withRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs("x-request-id", reqID))
+
+		return handler(srv, contextServerStream{ServerStream: ss, ctx: ctx})
+	}
+
+	return unary, stream
+}
+
+// NewGRPCLoggingInterceptors returns unary and stream interceptors that log requests
+// and their response code
+func NewGRPCLoggingInterceptors(l log.Logger) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	logRequest := func(ctx context.Context, method string, start time.Time, err error) {
+		reqID, _ := ctx.Value(log.RequestIDKey).(string)
+		l.Info("request", "component", "LoggingInterceptor", "method", method, "code", status.Code(err).String(), "took", time.Since(start).String(), "reqID", reqID)
+	}
+
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		logRequest(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+
+		err := handler(srv, ss)
+
+		logRequest(ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+
+	return unary, stream
+}
+
+// contextServerStream is a grpc.ServerStream that returns a different context to
+// the one it wraps so that stream interceptors can add values to it
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the wrapped context
+func (c contextServerStream) Context() context.Context {
+	return c.ctx
+}
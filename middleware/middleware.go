@@ -2,7 +2,9 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -14,9 +16,14 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/harness/ff-proxy/v2/domain"
 	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/tracing"
 )
 
 // keyLookUp checks if the key exists in cache
@@ -47,21 +54,10 @@ func NewEchoAuthMiddleware(logger log.Logger, authRepo keyLookUp, secret []byte,
 		AuthScheme:  "Bearer",
 		TokenLookup: "header:Authorization",
 		ParseTokenFunc: func(auth string, c echo.Context) (interface{}, error) {
-			if auth == "" {
-				return nil, errors.New("token was empty")
-			}
-
-			token, err := jwt.ParseWithClaims(auth, &domain.Claims{}, func(t *jwt.Token) (interface{}, error) {
-				return secret, nil
-			})
-			if err != nil {
+			if err := validateToken(c.Request().Context(), logger, authRepo, secret, auth); err != nil {
 				return nil, err
 			}
-
-			if claims, ok := token.Claims.(*domain.Claims); ok && token.Valid && isKeyInCache(c.Request().Context(), logger, authRepo, claims) {
-				return nil, nil
-			}
-			return nil, errors.New("invalid token")
+			return nil, nil
 		},
 		Skipper: func(c echo.Context) bool {
 			if bypassAuth {
@@ -71,7 +67,10 @@ func NewEchoAuthMiddleware(logger log.Logger, authRepo keyLookUp, secret []byte,
 			urlPath := c.Request().URL.Path
 			prometheusRequest := urlPath == "/metrics" && c.Request().Method == http.MethodGet
 
-			return urlPath == "/client/auth" || urlPath == "/health" || prometheusRequest
+			// The admin API has its own auth middleware
+			adminRequest := strings.HasPrefix(urlPath, "/admin/")
+
+			return urlPath == "/client/auth" || urlPath == "/health" || urlPath == "/ready" || prometheusRequest || adminRequest
 		},
 		ErrorHandlerWithContext: func(err error, c echo.Context) error {
 			return c.JSON(http.StatusUnauthorized, err)
@@ -79,6 +78,41 @@ func NewEchoAuthMiddleware(logger log.Logger, authRepo keyLookUp, secret []byte,
 	})
 }
 
+// NewEchoAdminAuthMiddleware returns an echo middleware that checks requests have
+// the admin token as a bearer token in their Authorization header
+func NewEchoAdminAuthMiddleware(token string) echo.MiddlewareFunc {
+	return middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
+		AuthScheme: "Bearer",
+		KeyLookup:  "header:Authorization",
+		Validator: func(key string, c echo.Context) (bool, error) {
+			return subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1, nil
+		},
+		ErrorHandler: func(err error, c echo.Context) error {
+			return c.JSON(http.StatusUnauthorized, err)
+		},
+	})
+}
+
+// validateToken checks that the auth token was signed with the secret and that
+// the key it was issued for still exists
+func validateToken(ctx context.Context, logger log.Logger, authRepo keyLookUp, secret []byte, auth string) error {
+	if auth == "" {
+		return errors.New("token was empty")
+	}
+
+	token, err := jwt.ParseWithClaims(auth, &domain.Claims{}, func(t *jwt.Token) (interface{}, error) {
+		return secret, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if claims, ok := token.Claims.(*domain.Claims); ok && token.Valid && isKeyInCache(ctx, logger, authRepo, claims) {
+		return nil
+	}
+	return errors.New("invalid token")
+}
+
 func isKeyInCache(ctx context.Context, logger log.Logger, repo keyLookUp, claims *domain.Claims) bool {
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
@@ -187,7 +221,7 @@ func NewPrometheusMiddleware(reg prometheus.Registerer) echo.MiddlewareFunc {
 		return func(c echo.Context) error {
 			// We don't care about tracking metrics for these endpoints
 			urlPath := c.Request().URL.Path
-			if urlPath == "/health" || urlPath == "/prometheus/metrics" {
+			if urlPath == "/health" || urlPath == "/ready" || urlPath == "/prometheus/metrics" {
 				return next(c)
 			}
 
@@ -225,11 +259,63 @@ func NewPrometheusMiddleware(reg prometheus.Registerer) echo.MiddlewareFunc {
 	}
 }
 
-// NewCorsMiddleware returns a cors middleware
+// NewEchoTracingMiddleware returns an echo middleware that starts a server span for
+// each request. If the request contains W3C trace context headers the span is
+// created as a child of the caller's span.
+func NewEchoTracingMiddleware(tp trace.TracerProvider) echo.MiddlewareFunc {
+	tracer := tp.Tracer(tracing.ScopeName)
+	propagator := tracing.Propagator()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// We don't care about tracing requests to the health or readiness endpoints
+			if c.Request().URL.Path == "/health" || c.Request().URL.Path == "/ready" {
+				return next(c)
+			}
+
+			req := c.Request()
+			ctx := propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", req.Method, c.Path()),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.method", req.Method),
+					attribute.String("http.route", c.Path()),
+					attribute.String("environment.id", c.Param("environment_uuid")),
+				),
+			)
+			defer span.End()
+
+			if reqID, ok := ctx.Value(log.RequestIDKey).(string); ok {
+				span.SetAttributes(attribute.String("request.id", reqID))
+			}
+
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+
+			status := c.Response().Status
+			httpErr := &echo.HTTPError{}
+			if errors.As(err, &httpErr) {
+				status = httpErr.Code
+			}
+
+			span.SetAttributes(attribute.Int("http.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return err
+		}
+	}
+}
+
+// NewCorsMiddleware returns a cors middleware. PUT and DELETE aren't allowed so that
+// browsers can't make cross-origin requests to the admin routes that change state.
 func NewCorsMiddleware() echo.MiddlewareFunc {
 	return middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{http.MethodGet, http.MethodOptions, http.MethodPost},
-		AllowHeaders: []string{"*", "Authorization"},
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{http.MethodGet, http.MethodOptions, http.MethodPost},
+		AllowHeaders:  []string{"*", "Authorization"},
+		ExposeHeaders: []string{"ETag"},
 	})
 }
@@ -0,0 +1,340 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/hash"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+// RouteClass is used to group routes that share the same rate limit
+type RouteClass string
+
+const (
+	// RouteClassAuth is the class for the auth route
+	RouteClassAuth RouteClass = "auth"
+
+	// RouteClassPolling is the class for the feature-configs and target-segments
+	// routes that server SDKs poll
+	RouteClassPolling RouteClass = "polling"
+
+	// RouteClassEvaluations is the class for the evaluations routes that client
+	// SDKs poll
+	RouteClassEvaluations RouteClass = "evaluations"
+
+	// RouteClassMetrics is the class for the route SDKs post metrics to
+	RouteClassMetrics RouteClass = "metrics"
+)
+
+// RateLimitKey is what requests are grouped by when applying rate limits
+type RateLimitKey string
+
+const (
+	// RateLimitKeyAPIKey applies limits to each api key
+	RateLimitKeyAPIKey RateLimitKey = "api-key"
+
+	// RateLimitKeyEnvironment applies limits to each environment
+	RateLimitKeyEnvironment RateLimitKey = "environment"
+)
+
+// RateLimit is the number of requests that can be made in a period
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseRateLimit parses a rate limit in the format <requests>/<period> e.g. 100/1m.
+// An empty string returns a zero RateLimit which means requests aren't limited.
+func ParseRateLimit(s string) (RateLimit, error) {
+	if s == "" {
+		return RateLimit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected format <requests>/<period> e.g. 100/1m", s)
+	}
+
+	r, err := strconv.Atoi(requests)
+	if err != nil || r <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, requests must be a positive integer", s)
+	}
+
+	p, err := time.ParseDuration(period)
+	if err != nil || p <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, period must be a positive duration", s)
+	}
+
+	return RateLimit{Requests: r, Period: p}, nil
+}
+
+// RateLimiter is the interface for a type that tracks how many requests have been
+// made for a key and decides whether another one is allowed
+type RateLimiter interface {
+	// Allow returns true if a request for the key is allowed. If it isn't it also
+	// returns how long the caller should wait before retrying.
+	Allow(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error)
+}
+
+// memoryRateLimiterSweepInterval is how often the MemoryRateLimiter removes idle buckets
+const memoryRateLimiterSweepInterval = 1 * time.Minute
+
+// MemoryRateLimiter is a RateLimiter that uses in memory token buckets. Limits are
+// only applied to requests made to this Proxy instance.
+type MemoryRateLimiter struct {
+	mx        *sync.Mutex
+	limiters  map[string]*memoryBucket
+	lastSweep *time.Time
+	now       func() time.Time
+}
+
+// memoryBucket is the token bucket for a key and when it was last used
+type memoryBucket struct {
+	limiter  *rate.Limiter
+	period   time.Duration
+	lastSeen time.Time
+}
+
+// NewMemoryRateLimiter creates a MemoryRateLimiter
+func NewMemoryRateLimiter() MemoryRateLimiter {
+	return MemoryRateLimiter{
+		mx:        &sync.Mutex{},
+		limiters:  map[string]*memoryBucket{},
+		lastSweep: &time.Time{},
+		now:       time.Now,
+	}
+}
+
+// Allow makes MemoryRateLimiter implement the RateLimiter interface
+func (m MemoryRateLimiter) Allow(_ context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	now := m.now()
+
+	m.mx.Lock()
+	m.sweep(now)
+
+	bucket, ok := m.limiters[key]
+	if !ok {
+		every := limit.Period / time.Duration(limit.Requests)
+		bucket = &memoryBucket{limiter: rate.NewLimiter(rate.Every(every), limit.Requests), period: limit.Period}
+		m.limiters[key] = bucket
+	}
+	bucket.lastSeen = now
+	m.mx.Unlock()
+
+	reservation := bucket.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		return true, 0, nil
+	}
+
+	// We don't want requests that we reject to use up tokens
+	reservation.CancelAt(now)
+	return false, delay, nil
+}
+
+// sweep removes the buckets that haven't been used for a whole period. They've refilled
+// by then so they're no different to a new bucket, and removing them stops the number of
+// buckets growing forever. The caller must hold the lock.
+func (m MemoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(*m.lastSweep) < memoryRateLimiterSweepInterval {
+		return
+	}
+	*m.lastSweep = now
+
+	for key, bucket := range m.limiters {
+		if now.Sub(bucket.lastSeen) >= bucket.period {
+			delete(m.limiters, key)
+		}
+	}
+}
+
+// RateLimitKeyPattern matches the keys that the RedisRateLimiter writes to redis
+const RateLimitKeyPattern = rateLimitKeyPrefix + "*"
+
+const rateLimitKeyPrefix = "ffproxy:ratelimit:"
+
+// RedisRateLimiter is a RateLimiter that stores counters in redis so that limits
+// are shared by every Proxy instance that uses the same redis. It uses a fixed
+// window that's the length of the limit's Period.
+type RedisRateLimiter struct {
+	client    redis.UniversalClient
+	namespace string
+	now       func() time.Time
+}
+
+// WithRateLimitNamespace prefixes the keys the RedisRateLimiter stores in redis
+// with the namespace
+func WithRateLimitNamespace(namespace string) func(r *RedisRateLimiter) {
+	return func(r *RedisRateLimiter) {
+		r.namespace = namespace
+	}
+}
+
+// NewRedisRateLimiter creates a RedisRateLimiter
+func NewRedisRateLimiter(client redis.UniversalClient, opts ...func(r *RedisRateLimiter)) RedisRateLimiter {
+	r := RedisRateLimiter{
+		client: client,
+		now:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(&r)
+	}
+	return r
+}
+
+// Allow makes RedisRateLimiter implement the RateLimiter interface
+func (r RedisRateLimiter) Allow(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	now := r.now()
+	window := now.UnixNano() / limit.Period.Nanoseconds()
+	windowEnd := time.Unix(0, (window+1)*limit.Period.Nanoseconds())
+
+	redisKey := domain.NewNamespacedKey(r.namespace, fmt.Sprintf("%s%s:%d", rateLimitKeyPrefix, key, window))
+
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, redisKey)
+	pipe.PExpire(ctx, redisKey, limit.Period)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, 0, err
+	}
+
+	if incr.Val() > int64(limit.Requests) {
+		return false, windowEnd.Sub(now), nil
+	}
+	return true, 0, nil
+}
+
+// NewEchoRateLimitMiddleware returns an echo middleware that limits the number of
+// requests that can be made to each class of routes. Requests are grouped by the
+// hashed api key or the environment in the request's auth token. Auth requests don't
+// have a token yet so they're grouped by the api key in the body if apiKeyExists says
+// it's one of the Proxy's keys, or by the client's IP if it isn't. Classes that don't
+// have a limit aren't rate limited. If the limiter errors the request is allowed.
+func NewEchoRateLimitMiddleware(logger log.Logger, limiter RateLimiter, limits map[RouteClass]RateLimit, keyBy RateLimitKey, apiKeyExists func(ctx context.Context, hashedAPIKey string) bool) echo.MiddlewareFunc {
+	logger = logger.With("component", "RateLimitMiddleware")
+	hasher := hash.NewSha256()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			class, ok := routeClass(c)
+			if !ok {
+				return next(c)
+			}
+
+			limit, ok := limits[class]
+			if !ok || limit.Requests == 0 {
+				return next(c)
+			}
+
+			key, ok := rateLimitKey(c, hasher, keyBy, apiKeyExists)
+			if !ok {
+				return next(c)
+			}
+
+			ctx := c.Request().Context()
+			allowed, retryAfter, err := limiter.Allow(ctx, fmt.Sprintf("%s:%s", class, key), limit)
+			if err != nil {
+				logger.Error("failed to check rate limit, allowing request", "class", class, "err", err)
+				return next(c)
+			}
+
+			if !allowed {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
+
+// routeClass returns the RouteClass for the matched route
+func routeClass(c echo.Context) (RouteClass, bool) {
+	path := c.Path()
+
+	switch {
+	case path == "/client/auth":
+		return RouteClassAuth, true
+	case strings.HasSuffix(path, "/evaluations") ||
+		strings.HasSuffix(path, "/evaluations/:feature") ||
+		strings.HasSuffix(path, "/target-evaluations"):
+		return RouteClassEvaluations, true
+	case strings.Contains(path, "/feature-configs") || strings.Contains(path, "/target-segments"):
+		return RouteClassPolling, true
+	case path == "/metrics/:environment_uuid" && c.Request().Method == http.MethodPost:
+		return RouteClassMetrics, true
+	default:
+		return "", false
+	}
+}
+
+// maxAuthBodySize is the most of an auth request's body that we read to get the api key
+const maxAuthBodySize = 1 << 16
+
+// rateLimitKey works out what key to use for a request. Auth requests don't have a token yet
+// so we use the hashed api key from the request body instead, as long as it's a key that
+// exists. Otherwise anyone could make up keys to get around the limit, so we use the client's IP.
+func rateLimitKey(c echo.Context, hasher hash.Hasher, keyBy RateLimitKey, apiKeyExists func(ctx context.Context, hashedAPIKey string) bool) (string, bool) {
+	req := c.Request()
+
+	if c.Path() == "/client/auth" {
+		if hashedAPIKey, ok := authAPIKey(req, hasher); ok && apiKeyExists != nil && apiKeyExists(req.Context(), hashedAPIKey) {
+			return hashedAPIKey, true
+		}
+		return "ip:" + c.RealIP(), true
+	}
+
+	tokenString, ok := strings.CutPrefix(req.Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !ok {
+		return "", false
+	}
+
+	// The auth middleware has already validated the token by the time we get
+	// here so we don't need to verify it again
+	claims := &domain.Claims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(tokenString, claims); err != nil {
+		return "", false
+	}
+
+	if keyBy == RateLimitKeyEnvironment {
+		return claims.Environment, claims.Environment != ""
+	}
+	return claims.APIKey, claims.APIKey != ""
+}
+
+// authAPIKey returns the hashed api key from the body of an auth request. We only read up to
+// maxAuthBodySize of the body since the request hasn't been authenticated yet, and whatever
+// we read is put back so the handler can still decode the whole body.
+func authAPIKey(req *http.Request, hasher hash.Hasher) (string, bool) {
+	b, err := io.ReadAll(io.LimitReader(req.Body, maxAuthBodySize+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), req.Body), req.Body}
+	if err != nil || len(b) > maxAuthBodySize {
+		return "", false
+	}
+
+	body := struct {
+		APIKey string `json:"apiKey"`
+	}{}
+	if err := jsoniter.Unmarshal(b, &body); err != nil || body.APIKey == "" {
+		return "", false
+	}
+	return hasher.Hash(body.APIKey), true
+}
@@ -0,0 +1,306 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	testCases := map[string]struct {
+		input     string
+		expected  RateLimit
+		shouldErr bool
+	}{
+		"Given I have an empty string": {
+			input:    "",
+			expected: RateLimit{},
+		},
+		"Given I have a valid rate limit": {
+			input:    "100/1m",
+			expected: RateLimit{Requests: 100, Period: time.Minute},
+		},
+		"Given I have a rate limit without a period": {
+			input:     "100",
+			shouldErr: true,
+		},
+		"Given I have a rate limit with zero requests": {
+			input:     "0/1m",
+			shouldErr: true,
+		},
+		"Given I have a rate limit with an invalid period": {
+			input:     "10/foo",
+			shouldErr: true,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			actual, err := ParseRateLimit(tc.input)
+			if tc.shouldErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestRateLimiters(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	memLimiter := NewMemoryRateLimiter()
+	memLimiter.now = func() time.Time { return now }
+
+	mr := miniredis.RunT(t)
+	redisLimiter := NewRedisRateLimiter(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	redisLimiter.now = func() time.Time { return now }
+
+	limiters := map[string]RateLimiter{
+		"MemoryRateLimiter": memLimiter,
+		"RedisRateLimiter":  redisLimiter,
+	}
+
+	limit := RateLimit{Requests: 2, Period: time.Minute}
+
+	for name, limiter := range limiters {
+		limiter := limiter
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			for i := 0; i < limit.Requests; i++ {
+				allowed, _, err := limiter.Allow(ctx, "foo", limit)
+				assert.Nil(t, err)
+				assert.True(t, allowed)
+			}
+
+			allowed, retryAfter, err := limiter.Allow(ctx, "foo", limit)
+			assert.Nil(t, err)
+			assert.False(t, allowed)
+			assert.Greater(t, retryAfter, time.Duration(0))
+			assert.LessOrEqual(t, retryAfter, limit.Period)
+
+			// Other keys should have their own limits
+			allowed, _, err = limiter.Allow(ctx, "bar", limit)
+			assert.Nil(t, err)
+			assert.True(t, allowed)
+		})
+	}
+}
+
+func TestMemoryRateLimiter_RemovesIdleBuckets(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	limiter := NewMemoryRateLimiter()
+	limiter.now = func() time.Time { return now }
+
+	limit := RateLimit{Requests: 1, Period: 10 * time.Minute}
+
+	for _, key := range []string{"foo", "bar"} {
+		allowed, _, err := limiter.Allow(ctx, key, limit)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+	}
+
+	// foo is still being used but bar has been idle for a whole period
+	now = now.Add(5 * time.Minute)
+	allowed, _, err := limiter.Allow(ctx, "foo", limit)
+	assert.Nil(t, err)
+	assert.False(t, allowed)
+
+	now = now.Add(5 * time.Minute)
+	allowed, _, err = limiter.Allow(ctx, "baz", limit)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	assert.Len(t, limiter.limiters, 2)
+	assert.Contains(t, limiter.limiters, "foo")
+	assert.Contains(t, limiter.limiters, "baz")
+}
+
+func TestRedisRateLimiter_Namespace(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := RateLimit{Requests: 1, Period: time.Minute}
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	staging := NewRedisRateLimiter(client, WithRateLimitNamespace("staging"))
+	staging.now = func() time.Time { return now }
+
+	prod := NewRedisRateLimiter(client, WithRateLimitNamespace("prod"))
+	prod.now = func() time.Time { return now }
+
+	allowed, _, err := staging.Allow(context.Background(), "foo", limit)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	// Proxies in different namespaces shouldn't share limits
+	allowed, _, err = prod.Allow(context.Background(), "foo", limit)
+	assert.Nil(t, err)
+	assert.True(t, allowed)
+
+	for _, k := range mr.Keys() {
+		assert.True(t, strings.HasPrefix(k, "staging:") || strings.HasPrefix(k, "prod:"), "unexpected key %q", k)
+	}
+}
+
+type mockRateLimiter struct {
+	keys []string
+	fn   func() (bool, time.Duration, error)
+}
+
+func (m *mockRateLimiter) Allow(_ context.Context, key string, _ RateLimit) (bool, time.Duration, error) {
+	m.keys = append(m.keys, key)
+	return m.fn()
+}
+
+func TestNewEchoRateLimitMiddleware(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, domain.Claims{APIKey: "hashed-key", Environment: "env-123"}).SignedString([]byte("secret"))
+	assert.Nil(t, err)
+
+	limits := map[RouteClass]RateLimit{
+		RouteClassAuth:    {Requests: 1, Period: time.Minute},
+		RouteClassPolling: {Requests: 1, Period: time.Minute},
+	}
+
+	// sha256 of apikey1
+	hashedAPIKey1 := "d4f79b313f8106f5af108ad96ff516222dbfd5a0ab52f4308e4b1ad1d740de60"
+
+	denied := func() (bool, time.Duration, error) { return false, 1500 * time.Millisecond, nil }
+
+	testCases := map[string]struct {
+		method             string
+		route              string
+		target             string
+		body               string
+		keyBy              RateLimitKey
+		limiter            func() (bool, time.Duration, error)
+		expectedStatus     int
+		expectedRetryAfter string
+		expectedKeys       []string
+	}{
+		"Given I make an auth request and I'm over the limit": {
+			method:             http.MethodPost,
+			route:              "/client/auth",
+			target:             "/client/auth",
+			body:               `{"apiKey": "apikey1"}`,
+			limiter:            denied,
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedRetryAfter: "2",
+			expectedKeys:       []string{"auth:" + hashedAPIKey1},
+		},
+		"Given I make an auth request and I'm under the limit": {
+			method:         http.MethodPost,
+			route:          "/client/auth",
+			target:         "/client/auth",
+			body:           `{"apiKey": "apikey1"}`,
+			limiter:        func() (bool, time.Duration, error) { return true, 0, nil },
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"auth:" + hashedAPIKey1},
+		},
+		"Given I make an auth request with an api key that doesn't exist": {
+			method:         http.MethodPost,
+			route:          "/client/auth",
+			target:         "/client/auth",
+			body:           `{"apiKey": "made-up-key"}`,
+			limiter:        func() (bool, time.Duration, error) { return true, 0, nil },
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"auth:ip:192.0.2.1"},
+		},
+		"Given I make an auth request with a body that's too big to read": {
+			method:         http.MethodPost,
+			route:          "/client/auth",
+			target:         "/client/auth",
+			body:           `{"apiKey": "apikey1", "padding": "` + strings.Repeat("a", maxAuthBodySize) + `"}`,
+			limiter:        func() (bool, time.Duration, error) { return true, 0, nil },
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"auth:ip:192.0.2.1"},
+		},
+		"Given I make a polling request keyed by api key and I'm over the limit": {
+			method:             http.MethodGet,
+			route:              "/client/env/:environment_uuid/feature-configs",
+			target:             "/client/env/env-123/feature-configs",
+			keyBy:              RateLimitKeyAPIKey,
+			limiter:            denied,
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedRetryAfter: "2",
+			expectedKeys:       []string{"polling:hashed-key"},
+		},
+		"Given I make a polling request keyed by environment and I'm under the limit": {
+			method:         http.MethodGet,
+			route:          "/client/env/:environment_uuid/feature-configs",
+			target:         "/client/env/env-123/feature-configs",
+			keyBy:          RateLimitKeyEnvironment,
+			limiter:        func() (bool, time.Duration, error) { return true, 0, nil },
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"polling:env-123"},
+		},
+		"Given I make a request to a route class that doesn't have a limit": {
+			method:         http.MethodGet,
+			route:          "/client/env/:environment_uuid/target/:target/evaluations",
+			target:         "/client/env/env-123/target/foo/evaluations",
+			limiter:        denied,
+			expectedStatus: http.StatusOK,
+			expectedKeys:   nil,
+		},
+		"Given the limiter errors": {
+			method:         http.MethodGet,
+			route:          "/client/env/:environment_uuid/feature-configs",
+			target:         "/client/env/env-123/feature-configs",
+			limiter:        func() (bool, time.Duration, error) { return false, 0, assert.AnError },
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"polling:hashed-key"},
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			limiter := &mockRateLimiter{fn: tc.limiter}
+
+			e := echo.New()
+			apiKeyExists := func(_ context.Context, hashedAPIKey string) bool { return hashedAPIKey == hashedAPIKey1 }
+			e.Use(NewEchoRateLimitMiddleware(log.NoOpLogger{}, limiter, limits, tc.keyBy, apiKeyExists))
+
+			var body string
+			e.Add(tc.method, tc.route, func(c echo.Context) error {
+				b, _ := io.ReadAll(c.Request().Body)
+				body = string(b)
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Equal(t, tc.expectedRetryAfter, rec.Header().Get("Retry-After"))
+			assert.Equal(t, tc.expectedKeys, limiter.keys)
+
+			// Make sure the middleware didn't consume the body
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, tc.body, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package overrides
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	clientgen "github.com/harness/ff-proxy/v2/gen/client"
+	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/repository"
+)
+
+const (
+	// SourceAdmin is the audit source for overrides changed through the admin API
+	SourceAdmin = "admin"
+
+	// SourceFile is the audit source for overrides loaded from the overrides file
+	SourceFile = "file"
+
+	// SourceExpiry is the audit source for overrides removed because they expired
+	SourceExpiry = "expiry"
+)
+
+var (
+	// ErrFlagNotFound is returned when an override is for a flag that doesn't exist
+	ErrFlagNotFound = errors.New("flag not found")
+
+	// ErrOverrideNotFound is returned when a flag doesn't have an override
+	ErrOverrideNotFound = errors.New("override not found")
+
+	// ErrInvalidOverride is returned when an override can't be applied to its flag
+	ErrInvalidOverride = errors.New("invalid override")
+
+	// ErrReadOnly is returned when overrides are changed through a Service that can't change them
+	ErrReadOnly = errors.New("overrides can only be changed on the primary proxy")
+)
+
+// WithClock sets the func the Service uses to get the current time
+func WithClock(now func() time.Time) func(s *Service) {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+// WithReadOnly stops the Service from changing overrides. Read replicas can't send
+// the events for the changes to the SDKs that are connected to the primary so they
+// only serve the overrides that the primary sets.
+func WithReadOnly() func(s *Service) {
+	return func(s *Service) {
+		s.readOnly = true
+	}
+}
+
+// Service manages the FlagOverrides that the Proxy applies on top of the config
+// from Harness SaaS. Every change is recorded in the audit trail and a patch
+// event is sent for the flag so that connected SDKs refetch it.
+type Service struct {
+	logger       log.Logger
+	overrideRepo repository.FlagOverrideRepo
+	flagRepo     repository.FeatureFlagRepo
+	authRepo     repository.AuthRepo
+	handler      domain.MessageHandler
+	now          func() time.Time
+	readOnly     bool
+}
+
+// NewService creates a Service. The handler is called with the patch event for
+// each flag whose override changes.
+func NewService(l log.Logger, overrideRepo repository.FlagOverrideRepo, flagRepo repository.FeatureFlagRepo, authRepo repository.AuthRepo, handler domain.MessageHandler, opts ...func(s *Service)) Service {
+	s := Service{
+		logger:       l.With("component", "OverrideService"),
+		overrideRepo: overrideRepo,
+		flagRepo:     flagRepo,
+		authRepo:     authRepo,
+		handler:      handler,
+		now:          time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// Set validates the override in the request and stores it, replacing any override
+// the flag already has
+func (s Service) Set(ctx context.Context, req domain.FlagOverrideRequest, source string) (domain.FlagOverride, error) {
+	if s.readOnly {
+		return domain.FlagOverride{}, ErrReadOnly
+	}
+
+	override, err := s.toOverride(req)
+	if err != nil {
+		return domain.FlagOverride{}, err
+	}
+
+	flag, err := s.flagRepo.GetByIdentifier(ctx, req.EnvironmentID, req.Feature)
+	if err != nil {
+		if errors.Is(err, domain.ErrCacheNotFound) {
+			return domain.FlagOverride{}, fmt.Errorf("%w: %s", ErrFlagNotFound, req.Feature)
+		}
+		return domain.FlagOverride{}, err
+	}
+
+	if err := validate(flag, override); err != nil {
+		return domain.FlagOverride{}, err
+	}
+
+	if err := s.overrideRepo.Add(ctx, req.EnvironmentID, override); err != nil {
+		return domain.FlagOverride{}, fmt.Errorf("failed to store override: %s", err)
+	}
+
+	s.record(ctx, req.EnvironmentID, domain.FlagOverrideActionSet, req.Feature, source, &override)
+	s.notify(ctx, req.EnvironmentID, req.Feature)
+
+	s.logger.Info("set flag override", "environment", req.EnvironmentID, "feature", req.Feature, "source", source, "reason", override.Reason)
+	return override, nil
+}
+
+// Delete removes a flag's override
+func (s Service) Delete(ctx context.Context, envID string, feature string, source string) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	return s.remove(ctx, envID, feature, domain.FlagOverrideActionDelete, source)
+}
+
+// List returns the overrides for an environment sorted by flag identifier
+func (s Service) List(ctx context.Context, envID string) ([]domain.FlagOverride, error) {
+	overrides, err := s.overrideRepo.Get(ctx, envID)
+	if err != nil && !errors.Is(err, domain.ErrCacheNotFound) {
+		return nil, err
+	}
+
+	result := make([]domain.FlagOverride, 0, len(overrides.Overrides))
+	for _, o := range overrides.Overrides {
+		result = append(result, o)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Feature < result[j].Feature
+	})
+	return result, nil
+}
+
+// Audit returns the audit trail of override changes for an environment, oldest first
+func (s Service) Audit(ctx context.Context, envID string) ([]domain.FlagOverrideAuditEntry, error) {
+	entries, err := s.overrideRepo.GetAudit(ctx, envID)
+	if err != nil && !errors.Is(err, domain.ErrCacheNotFound) {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// LoadFile sets the overrides in a JSON file containing a list of overrides. Overrides
+// that are the same as the ones that are already stored, or that have expired, are
+// skipped so that restarting the Proxy doesn't make SDKs refetch them. An override
+// that can't be set is logged rather than stopping the rest from being loaded.
+func (s Service) LoadFile(ctx context.Context, path string) error {
+	b, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		return fmt.Errorf("failed to read overrides file: %s", err)
+	}
+
+	var reqs []domain.FlagOverrideRequest
+	if err := jsoniter.Unmarshal(b, &reqs); err != nil {
+		return fmt.Errorf("failed to decode overrides file: %s", err)
+	}
+
+	now := s.now()
+	for _, req := range reqs {
+		if req.TTL == "" && req.ExpiresAt > 0 && now.UnixMilli() >= req.ExpiresAt {
+			continue
+		}
+
+		if s.isStored(ctx, req) {
+			continue
+		}
+
+		if _, err := s.Set(ctx, req, SourceFile); err != nil {
+			s.logger.Error("failed to set override from file", "environment", req.EnvironmentID, "feature", req.Feature, "err", err)
+		}
+	}
+	return nil
+}
+
+// Expire removes the overrides that have expired in every environment
+func (s Service) Expire(ctx context.Context) error {
+	envs, err := s.authRepo.Environments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get environments: %s", err)
+	}
+
+	now := s.now()
+	for _, env := range envs {
+		// The expired overrides are checked and removed in one update so we don't
+		// remove an override that's been replaced since we looked at it
+		features, err := s.overrideRepo.RemoveExpired(ctx, env, now)
+		if err != nil {
+			return fmt.Errorf("failed to remove expired overrides: %s", err)
+		}
+
+		for _, feature := range features {
+			s.record(ctx, env, domain.FlagOverrideActionExpire, feature, SourceExpiry, nil)
+			s.notify(ctx, env, feature)
+
+			s.logger.Info("removed flag override", "environment", env, "feature", feature, "source", SourceExpiry)
+		}
+	}
+	return nil
+}
+
+// ScheduleExpiry removes expired overrides every interval until the context is cancelled
+func (s Service) ScheduleExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Expire(ctx); err != nil {
+				s.logger.Error("failed to remove expired overrides", "err", err)
+			}
+		}
+	}
+}
+
+func (s Service) remove(ctx context.Context, envID string, feature string, action string, source string) error {
+	if err := s.overrideRepo.Remove(ctx, envID, feature); err != nil {
+		if errors.Is(err, domain.ErrCacheNotFound) {
+			return fmt.Errorf("%w: %s", ErrOverrideNotFound, feature)
+		}
+		return fmt.Errorf("failed to remove override: %s", err)
+	}
+
+	s.record(ctx, envID, action, feature, source, nil)
+	s.notify(ctx, envID, feature)
+
+	s.logger.Info("removed flag override", "environment", envID, "feature", feature, "source", source)
+	return nil
+}
+
+// record adds an entry to the audit trail. The override has already been changed
+// by the time we record it so we only log failures rather than returning them.
+func (s Service) record(ctx context.Context, envID string, action string, feature string, source string, override *domain.FlagOverride) {
+	entry := domain.FlagOverrideAuditEntry{
+		Timestamp: s.now().UnixMilli(),
+		Action:    action,
+		Feature:   feature,
+		Source:    source,
+		Override:  override,
+	}
+
+	if err := s.overrideRepo.AddAudit(ctx, envID, entry); err != nil {
+		s.logger.Error("failed to record override in audit trail", "environment", envID, "feature", feature, "err", err)
+	}
+}
+
+// notify sends a patch event for the flag so that connected SDKs refetch it
+func (s Service) notify(ctx context.Context, envID string, feature string) {
+	msg := domain.SSEMessage{
+		Event:       domain.EventPatch,
+		Domain:      domain.MsgDomainFeature,
+		Identifier:  feature,
+		Environment: envID,
+	}
+
+	if flag, err := s.flagRepo.GetByIdentifier(ctx, envID, feature); err == nil {
+		msg.Version = int(domain.SafePtrDereference(flag.Version))
+	}
+
+	if err := s.handler.HandleMessage(ctx, msg); err != nil {
+		s.logger.Error("failed to send override event", "environment", envID, "feature", feature, "err", err)
+	}
+}
+
+// isStored returns true if the override in the request is already stored
+func (s Service) isStored(ctx context.Context, req domain.FlagOverrideRequest) bool {
+	override, err := s.toOverride(req)
+	if err != nil {
+		return false
+	}
+
+	overrides, err := s.overrideRepo.Get(ctx, req.EnvironmentID)
+	if err != nil {
+		return false
+	}
+
+	existing, ok := overrides.Overrides[req.Feature]
+	if !ok {
+		return false
+	}
+
+	// A TTL gives a new expiry each time the file is loaded so it's always stored again
+	override.CreatedAt = existing.CreatedAt
+	return req.TTL == "" && reflect.DeepEqual(existing, override)
+}
+
+// toOverride converts a request to a FlagOverride
+func (s Service) toOverride(req domain.FlagOverrideRequest) (domain.FlagOverride, error) {
+	if req.EnvironmentID == "" || req.Feature == "" {
+		return domain.FlagOverride{}, fmt.Errorf("%w: environment and feature are required", ErrInvalidOverride)
+	}
+
+	if req.State == "" && req.DefaultServe == "" && len(req.Targets) == 0 {
+		return domain.FlagOverride{}, fmt.Errorf("%w: one of state, defaultServe or targets is required", ErrInvalidOverride)
+	}
+
+	now := s.now()
+	override := domain.FlagOverride{
+		Feature:      req.Feature,
+		State:        req.State,
+		DefaultServe: req.DefaultServe,
+		Targets:      req.Targets,
+		Reason:       req.Reason,
+		CreatedAt:    now.UnixMilli(),
+		ExpiresAt:    req.ExpiresAt,
+	}
+
+	if req.TTL != "" {
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			return domain.FlagOverride{}, fmt.Errorf("%w: ttl must be a positive duration e.g. 30m", ErrInvalidOverride)
+		}
+		override.ExpiresAt = now.Add(ttl).UnixMilli()
+	}
+
+	if override.Expired(now) {
+		return domain.FlagOverride{}, fmt.Errorf("%w: expiresAt is in the past", ErrInvalidOverride)
+	}
+	return override, nil
+}
+
+// validate checks that the override can be applied to the flag
+func validate(flag domain.FeatureFlag, override domain.FlagOverride) error {
+	switch clientgen.FeatureState(override.State) {
+	case "", clientgen.On, clientgen.Off:
+	default:
+		return fmt.Errorf("%w: state must be %q or %q", ErrInvalidOverride, clientgen.On, clientgen.Off)
+	}
+
+	variations := make(map[string]struct{}, len(flag.Variations))
+	for _, v := range flag.Variations {
+		variations[v.Identifier] = struct{}{}
+	}
+
+	if _, ok := variations[override.DefaultServe]; override.DefaultServe != "" && !ok {
+		return fmt.Errorf("%w: flag %q doesn't have a variation %q", ErrInvalidOverride, flag.Feature, override.DefaultServe)
+	}
+
+	for target, variation := range override.Targets {
+		if _, ok := variations[variation]; !ok {
+			return fmt.Errorf("%w: flag %q doesn't have a variation %q for target %q", ErrInvalidOverride, flag.Feature, variation, target)
+		}
+	}
+	return nil
+}
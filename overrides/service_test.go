@@ -0,0 +1,295 @@
+package overrides
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/cache"
+	"github.com/harness/ff-proxy/v2/domain"
+	clientgen "github.com/harness/ff-proxy/v2/gen/client"
+	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/repository"
+)
+
+// recordingHandler records the messages it's asked to handle
+type recordingHandler struct {
+	*sync.Mutex
+	msgs []domain.SSEMessage
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{Mutex: &sync.Mutex{}}
+}
+
+func (r *recordingHandler) HandleMessage(_ context.Context, msg domain.SSEMessage) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.msgs = append(r.msgs, msg)
+	return nil
+}
+
+func (r *recordingHandler) get() []domain.SSEMessage {
+	r.Lock()
+	defer r.Unlock()
+	return append([]domain.SSEMessage{}, r.msgs...)
+}
+
+// clock is a time source that tests can move forwards
+type clock struct {
+	*sync.Mutex
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.t
+}
+
+func (c *clock) add(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.t = c.t.Add(d)
+}
+
+type fixture struct {
+	service      Service
+	flagRepo     repository.FeatureFlagRepo
+	overrideRepo repository.FlagOverrideRepo
+	handler      *recordingHandler
+	clock        *clock
+}
+
+func setup(t *testing.T) fixture {
+	ctx := context.Background()
+	memCache := cache.NewMemCache()
+
+	overrideRepo := repository.NewFlagOverrideRepo(memCache)
+	flagRepo := repository.NewFeatureFlagRepo(memCache, repository.WithFlagOverrides(overrideRepo))
+	authRepo := repository.NewAuthRepo(memCache)
+
+	require.NoError(t, authRepo.AddAPIConfigsForEnvironment(ctx, "123", []string{"apikey"}))
+	require.NoError(t, flagRepo.Add(ctx, domain.FlagConfig{
+		EnvironmentID: "123",
+		FeatureConfigs: []domain.FeatureFlag{
+			{
+				Feature:      "dark-mode",
+				Environment:  "123",
+				Kind:         "boolean",
+				State:        clientgen.On,
+				DefaultServe: clientgen.Serve{Variation: domain.ToPtr("true")},
+				OffVariation: "false",
+				Variations: []clientgen.Variation{
+					{Identifier: "true", Value: "true"},
+					{Identifier: "false", Value: "false"},
+				},
+				Version: domain.ToPtr(int64(10)),
+			},
+		},
+	}))
+
+	// The FeatureFlagRepo applies overrides using the real time so the clock has to start from it
+	c := &clock{Mutex: &sync.Mutex{}, t: time.Now()}
+	handler := newRecordingHandler()
+
+	return fixture{
+		service:      NewService(log.NoOpLogger{}, overrideRepo, flagRepo, authRepo, handler, WithClock(c.now)),
+		flagRepo:     flagRepo,
+		overrideRepo: overrideRepo,
+		handler:      handler,
+		clock:        c,
+	}
+}
+
+func TestService_SetValidation(t *testing.T) {
+	testCases := map[string]struct {
+		req         domain.FlagOverrideRequest
+		expectedErr error
+	}{
+		"Given the override doesn't change anything": {
+			req:         domain.FlagOverrideRequest{EnvironmentID: "123", Feature: "dark-mode"},
+			expectedErr: ErrInvalidOverride,
+		},
+		"Given the override has an invalid state": {
+			req:         domain.FlagOverrideRequest{EnvironmentID: "123", Feature: "dark-mode", State: "paused"},
+			expectedErr: ErrInvalidOverride,
+		},
+		"Given the override serves a variation the flag doesn't have": {
+			req:         domain.FlagOverrideRequest{EnvironmentID: "123", Feature: "dark-mode", DefaultServe: "maybe"},
+			expectedErr: ErrInvalidOverride,
+		},
+		"Given the override serves a target a variation the flag doesn't have": {
+			req:         domain.FlagOverrideRequest{EnvironmentID: "123", Feature: "dark-mode", Targets: map[string]string{"alice": "maybe"}},
+			expectedErr: ErrInvalidOverride,
+		},
+		"Given the override has an invalid ttl": {
+			req:         domain.FlagOverrideRequest{EnvironmentID: "123", Feature: "dark-mode", State: "off", TTL: "soon"},
+			expectedErr: ErrInvalidOverride,
+		},
+		"Given the override has already expired": {
+			req:         domain.FlagOverrideRequest{EnvironmentID: "123", Feature: "dark-mode", State: "off", ExpiresAt: 1},
+			expectedErr: ErrInvalidOverride,
+		},
+		"Given the override is for a flag that doesn't exist": {
+			req:         domain.FlagOverrideRequest{EnvironmentID: "123", Feature: "light-mode", State: "off"},
+			expectedErr: ErrFlagNotFound,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			f := setup(t)
+
+			_, err := f.service.Set(context.Background(), tc.req, SourceAdmin)
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Empty(t, f.handler.get())
+		})
+	}
+}
+
+func TestService_SetAndDelete(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t)
+
+	override, err := f.service.Set(ctx, domain.FlagOverrideRequest{
+		EnvironmentID: "123",
+		Feature:       "dark-mode",
+		State:         "off",
+		Reason:        "incident",
+		TTL:           "30m",
+	}, SourceAdmin)
+	require.NoError(t, err)
+
+	expected := domain.FlagOverride{
+		Feature:   "dark-mode",
+		State:     "off",
+		Reason:    "incident",
+		CreatedAt: f.clock.now().UnixMilli(),
+		ExpiresAt: f.clock.now().Add(30 * time.Minute).UnixMilli(),
+	}
+	assert.Equal(t, expected, override)
+
+	list, err := f.service.List(ctx, "123")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.FlagOverride{expected}, list)
+
+	flag, err := f.flagRepo.GetByIdentifier(ctx, "123", "dark-mode")
+	require.NoError(t, err)
+	assert.Equal(t, clientgen.Off, flag.State)
+
+	require.NoError(t, f.service.Delete(ctx, "123", "dark-mode", SourceAdmin))
+	assert.ErrorIs(t, f.service.Delete(ctx, "123", "dark-mode", SourceAdmin), ErrOverrideNotFound)
+
+	flag, err = f.flagRepo.GetByIdentifier(ctx, "123", "dark-mode")
+	require.NoError(t, err)
+	assert.Equal(t, clientgen.On, flag.State)
+
+	// SDKs get a patch event with the flag's new version for each change
+	assert.Equal(t, []domain.SSEMessage{
+		{Event: domain.EventPatch, Domain: domain.MsgDomainFeature, Identifier: "dark-mode", Environment: "123", Version: 11},
+		{Event: domain.EventPatch, Domain: domain.MsgDomainFeature, Identifier: "dark-mode", Environment: "123", Version: 12},
+	}, f.handler.get())
+
+	audit, err := f.service.Audit(ctx, "123")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.FlagOverrideAuditEntry{
+		{Timestamp: f.clock.now().UnixMilli(), Action: domain.FlagOverrideActionSet, Feature: "dark-mode", Source: SourceAdmin, Override: &expected},
+		{Timestamp: f.clock.now().UnixMilli(), Action: domain.FlagOverrideActionDelete, Feature: "dark-mode", Source: SourceAdmin},
+	}, audit)
+}
+
+func TestService_ReadOnly(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t)
+
+	_, err := f.service.Set(ctx, domain.FlagOverrideRequest{EnvironmentID: "123", Feature: "dark-mode", State: "off"}, SourceAdmin)
+	require.NoError(t, err)
+
+	readOnly := NewService(log.NoOpLogger{}, f.overrideRepo, f.flagRepo, repository.NewAuthRepo(cache.NewMemCache()), f.handler, WithReadOnly())
+
+	_, err = readOnly.Set(ctx, domain.FlagOverrideRequest{EnvironmentID: "123", Feature: "dark-mode", State: "on"}, SourceAdmin)
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.ErrorIs(t, readOnly.Delete(ctx, "123", "dark-mode", SourceAdmin), ErrReadOnly)
+
+	// The overrides set by other Proxies can still be read
+	list, err := readOnly.List(ctx, "123")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "off", list[0].State)
+	assert.Len(t, f.handler.get(), 1)
+}
+
+func TestService_Expire(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t)
+
+	_, err := f.service.Set(ctx, domain.FlagOverrideRequest{EnvironmentID: "123", Feature: "dark-mode", DefaultServe: "false", TTL: "1m"}, SourceAdmin)
+	require.NoError(t, err)
+
+	// Nothing should be removed before the override expires
+	require.NoError(t, f.service.Expire(ctx))
+	list, err := f.service.List(ctx, "123")
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	f.clock.add(time.Minute)
+	require.NoError(t, f.service.Expire(ctx))
+
+	list, err = f.service.List(ctx, "123")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	msgs := f.handler.get()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, 12, msgs[1].Version)
+
+	audit, err := f.service.Audit(ctx, "123")
+	require.NoError(t, err)
+	require.Len(t, audit, 2)
+	assert.Equal(t, domain.FlagOverrideActionExpire, audit[1].Action)
+	assert.Equal(t, SourceExpiry, audit[1].Source)
+}
+
+func TestService_LoadFile(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t)
+
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	content := `[
+  {"environment": "123", "feature": "dark-mode", "state": "off", "targets": {"alice": "true"}, "reason": "incident"},
+  {"environment": "123", "feature": "light-mode", "state": "off"},
+  {"environment": "123", "feature": "dark-mode", "defaultServe": "false", "expiresAt": 1}
+]`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	// The override for the flag that doesn't exist and the expired override are skipped
+	require.NoError(t, f.service.LoadFile(ctx, path))
+
+	list, err := f.service.List(ctx, "123")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "off", list[0].State)
+	assert.Equal(t, map[string]string{"alice": "true"}, list[0].Targets)
+	assert.Len(t, f.handler.get(), 1)
+
+	// Loading the same file again shouldn't change the overrides or send events
+	f.clock.add(time.Hour)
+	require.NoError(t, f.service.LoadFile(ctx, path))
+	assert.Len(t, f.handler.get(), 1)
+
+	audit, err := f.service.Audit(ctx, "123")
+	require.NoError(t, err)
+	require.Len(t, audit, 1)
+	assert.Equal(t, SourceFile, audit[0].Source)
+
+	assert.Error(t, f.service.LoadFile(ctx, filepath.Join(t.TempDir(), "missing.json")))
+}
@@ -0,0 +1,234 @@
+package proxyservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/overrides"
+	"github.com/harness/ff-proxy/v2/repository"
+)
+
+// overrideService is the interface for the service that manages flag overrides
+type overrideService interface {
+	Set(ctx context.Context, req domain.FlagOverrideRequest, source string) (domain.FlagOverride, error)
+	Delete(ctx context.Context, envID string, feature string, source string) error
+	List(ctx context.Context, envID string) ([]domain.FlagOverride, error)
+	Audit(ctx context.Context, envID string) ([]domain.FlagOverrideAuditEntry, error)
+}
+
+// AdminConfig is the config for an AdminService
+type AdminConfig struct {
+	Logger        log.Logger
+	AuthRepo      repository.AuthRepo
+	FeatureRepo   repository.FeatureFlagRepo
+	SegmentRepo   repository.SegmentRepo
+	InventoryRepo repository.InventoryRepo
+
+	// ProxyKeys are the keys the Proxy was started with, they're used to look
+	// up the Proxy's inventory
+	ProxyKeys []string
+
+	// ConnectedStreams returns the channels that SDKs have open streams on
+	ConnectedStreams func() map[string]interface{}
+
+	Health func(ctx context.Context) domain.HealthResponse
+
+	// Overrides manages flag overrides, the override endpoints return
+	// ErrNotImplemented if it isn't set
+	Overrides overrideService
+}
+
+// AdminService lets operators inspect the state of the Proxy without having to
+// query the cache directly. The only changes it can make are to flag overrides.
+type AdminService struct {
+	logger           log.Logger
+	authRepo         repository.AuthRepo
+	featureRepo      repository.FeatureFlagRepo
+	segmentRepo      repository.SegmentRepo
+	inventoryRepo    repository.InventoryRepo
+	proxyKeys        []string
+	connectedStreams func() map[string]interface{}
+	health           func(ctx context.Context) domain.HealthResponse
+	overrides        overrideService
+}
+
+// NewAdminService creates an AdminService
+func NewAdminService(c AdminConfig) AdminService {
+	l := c.Logger.With("component", "AdminService")
+	return AdminService{
+		logger:           l,
+		authRepo:         c.AuthRepo,
+		featureRepo:      c.FeatureRepo,
+		segmentRepo:      c.SegmentRepo,
+		inventoryRepo:    c.InventoryRepo,
+		proxyKeys:        c.ProxyKeys,
+		connectedStreams: c.ConnectedStreams,
+		health:           c.Health,
+		overrides:        c.Overrides,
+	}
+}
+
+// Environments returns the IDs of all the environments the Proxy has config for
+func (a AdminService) Environments(ctx context.Context) (domain.AdminEnvironmentsResponse, error) {
+	envs, err := a.authRepo.Environments(ctx)
+	if err != nil {
+		a.logger.Error("failed to get environments", "err", err)
+		return domain.AdminEnvironmentsResponse{}, ErrInternal
+	}
+
+	return domain.AdminEnvironmentsResponse{Environments: envs}, nil
+}
+
+// Environment returns the hashed api keys and the flag and segment versions that
+// the Proxy has for an environment
+func (a AdminService) Environment(ctx context.Context, req domain.AdminEnvironmentRequest) (domain.AdminEnvironmentResponse, error) {
+	apiKeys, err := a.authRepo.GetKeysForEnvironment(ctx, req.EnvironmentID)
+	if err != nil {
+		return domain.AdminEnvironmentResponse{}, ErrNotFound
+	}
+
+	// An environment can exist without any flags or segments so we don't
+	// treat these as not found
+	flags, _ := a.featureRepo.GetFeatureConfigForEnvironment(ctx, req.EnvironmentID)
+	segments, _ := a.segmentRepo.GetSegmentsForEnvironment(ctx, req.EnvironmentID)
+
+	// The keys are stored with a prefix in the cache but we only want to return the hashes
+	hashes := make([]string, 0, len(apiKeys))
+	for _, k := range apiKeys {
+		hashes = append(hashes, strings.TrimPrefix(k, "auth-key-"))
+	}
+
+	resp := domain.AdminEnvironmentResponse{
+		EnvironmentID: req.EnvironmentID,
+		APIKeys:       hashes,
+		Flags:         make([]domain.AdminAsset, 0, len(flags)),
+		Segments:      make([]domain.AdminAsset, 0, len(segments)),
+	}
+
+	for _, f := range flags {
+		resp.Flags = append(resp.Flags, domain.AdminAsset{Identifier: f.Feature, Version: derefInt64(f.Version)})
+	}
+
+	for _, s := range segments {
+		resp.Segments = append(resp.Segments, domain.AdminAsset{Identifier: s.Identifier, Version: derefInt64(s.Version)})
+	}
+
+	return resp, nil
+}
+
+// Inventory returns the cache keys of the assets that the Proxy has stored for its keys
+func (a AdminService) Inventory(ctx context.Context) (domain.AdminInventoryResponse, error) {
+	assets := []string{}
+	for _, key := range a.proxyKeys {
+		inventory, err := a.inventoryRepo.Get(ctx, key)
+		if err != nil {
+			a.logger.Error("failed to get inventory", "err", err)
+			return domain.AdminInventoryResponse{}, ErrInternal
+		}
+
+		for k := range inventory {
+			assets = append(assets, k)
+		}
+	}
+	sort.Strings(assets)
+
+	return domain.AdminInventoryResponse{Assets: assets}, nil
+}
+
+// Streams returns the channels that SDKs have open streams on
+func (a AdminService) Streams(_ context.Context) (domain.AdminStreamsResponse, error) {
+	streams := a.connectedStreams()
+
+	channels := make([]string, 0, len(streams))
+	for k := range streams {
+		channels = append(channels, k)
+	}
+	sort.Strings(channels)
+
+	return domain.AdminStreamsResponse{Channels: channels}, nil
+}
+
+// Health returns the health of the Proxy's config, stream and cache. Unlike the
+// ProxyService's Health method it doesn't error if the Proxy is unhealthy because
+// the caller wants to see the state either way.
+func (a AdminService) Health(ctx context.Context) (domain.HealthResponse, error) {
+	return a.health(ctx), nil
+}
+
+// FlagOverrides returns the flag overrides for an environment
+func (a AdminService) FlagOverrides(ctx context.Context, req domain.AdminEnvironmentRequest) (domain.AdminFlagOverridesResponse, error) {
+	if a.overrides == nil {
+		return domain.AdminFlagOverridesResponse{}, ErrNotImplemented
+	}
+
+	o, err := a.overrides.List(ctx, req.EnvironmentID)
+	if err != nil {
+		return domain.AdminFlagOverridesResponse{}, a.overrideError(err)
+	}
+	return domain.AdminFlagOverridesResponse{Overrides: o}, nil
+}
+
+// SetFlagOverride creates or replaces a flag's override
+func (a AdminService) SetFlagOverride(ctx context.Context, req domain.FlagOverrideRequest) (domain.FlagOverride, error) {
+	if a.overrides == nil {
+		return domain.FlagOverride{}, ErrNotImplemented
+	}
+
+	o, err := a.overrides.Set(ctx, req, overrides.SourceAdmin)
+	if err != nil {
+		return domain.FlagOverride{}, a.overrideError(err)
+	}
+	return o, nil
+}
+
+// DeleteFlagOverride removes a flag's override
+func (a AdminService) DeleteFlagOverride(ctx context.Context, req domain.AdminDeleteFlagOverrideRequest) error {
+	if a.overrides == nil {
+		return ErrNotImplemented
+	}
+
+	if err := a.overrides.Delete(ctx, req.EnvironmentID, req.Feature, overrides.SourceAdmin); err != nil {
+		return a.overrideError(err)
+	}
+	return nil
+}
+
+// FlagOverrideAudit returns the audit trail of override changes for an environment
+func (a AdminService) FlagOverrideAudit(ctx context.Context, req domain.AdminEnvironmentRequest) (domain.AdminFlagOverrideAuditResponse, error) {
+	if a.overrides == nil {
+		return domain.AdminFlagOverrideAuditResponse{}, ErrNotImplemented
+	}
+
+	entries, err := a.overrides.Audit(ctx, req.EnvironmentID)
+	if err != nil {
+		return domain.AdminFlagOverrideAuditResponse{}, a.overrideError(err)
+	}
+	return domain.AdminFlagOverrideAuditResponse{Entries: entries}, nil
+}
+
+// overrideError converts errors from the overrideService to the errors the AdminService returns
+func (a AdminService) overrideError(err error) error {
+	switch {
+	case errors.Is(err, overrides.ErrInvalidOverride):
+		return fmt.Errorf("%w: %s", ErrBadRequest, err)
+	case errors.Is(err, overrides.ErrFlagNotFound), errors.Is(err, overrides.ErrOverrideNotFound):
+		return fmt.Errorf("%w: %s", ErrNotFound, err)
+	case errors.Is(err, overrides.ErrReadOnly):
+		return fmt.Errorf("%w: %s", ErrNotImplemented, err)
+	default:
+		a.logger.Error("failed to manage flag override", "err", err)
+		return ErrInternal
+	}
+}
+
+func derefInt64(i *int64) int64 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
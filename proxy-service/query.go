@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/harness/ff-golang-server-sdk/rest"
 	"github.com/harness/ff-proxy/v2/domain"
@@ -119,3 +120,76 @@ func (s Service) GenerateQueryStore(ctx context.Context, environmentID string, s
 		},
 	}
 }
+
+// newMemoizedQueryStore wraps a QueryStore so that the results of fetching flags and segments
+// are stored the first time they're requested and returned from memory on subsequent calls.
+// It's intended to be used for the lifetime of a single request that performs many evaluations
+// e.g. a batch of targets, so that the cache isn't read again for every evaluation.
+func newMemoizedQueryStore(q QueryStore) QueryStore {
+	var (
+		mu       sync.Mutex
+		flags    = map[string]rest.FeatureConfig{}
+		segments = map[string]rest.Segment{}
+
+		flagList    []rest.FeatureConfig
+		flagListErr error
+		flagListSet bool
+
+		flagMap    map[string]*rest.FeatureConfig
+		flagMapErr error
+		flagMapSet bool
+	)
+
+	return QueryStore{
+		F: func(identifier string) (rest.FeatureConfig, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if flag, ok := flags[identifier]; ok {
+				return flag, nil
+			}
+
+			flag, err := q.F(identifier)
+			if err != nil {
+				return flag, err
+			}
+			flags[identifier] = flag
+			return flag, nil
+		},
+		S: func(identifier string) (rest.Segment, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if segment, ok := segments[identifier]; ok {
+				return segment, nil
+			}
+
+			segment, err := q.S(identifier)
+			if err != nil {
+				return segment, err
+			}
+			segments[identifier] = segment
+			return segment, nil
+		},
+		L: func() ([]rest.FeatureConfig, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if !flagListSet {
+				flagList, flagListErr = q.L()
+				flagListSet = true
+			}
+			return flagList, flagListErr
+		},
+		GetFlagMapFn: func() (map[string]*rest.FeatureConfig, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if !flagMapSet {
+				flagMap, flagMapErr = q.GetFlagMapFn()
+				flagMapSet = true
+			}
+			return flagMap, flagMapErr
+		},
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/harness/ff-golang-server-sdk/evaluation"
@@ -25,13 +26,13 @@ type ProxyService interface {
 	Authenticate(ctx context.Context, req domain.AuthRequest) (domain.AuthResponse, error)
 
 	// FeatureConfig gets all FeatureConfig for an environment
-	FeatureConfig(ctx context.Context, req domain.FeatureConfigRequest) ([]domain.FeatureConfig, error)
+	FeatureConfig(ctx context.Context, req domain.FeatureConfigRequest) (domain.FeatureConfigResponse, error)
 
 	// FeatureConfigByIdentifier gets the feature config for a feature
 	FeatureConfigByIdentifier(ctx context.Context, req domain.FeatureConfigByIdentifierRequest) (domain.FeatureConfig, error)
 
 	// TargetSegments gets all of the TargetSegments in an environment
-	TargetSegments(ctx context.Context, req domain.TargetSegmentsRequest) ([]domain.Segment, error)
+	TargetSegments(ctx context.Context, req domain.TargetSegmentsRequest) (domain.TargetSegmentsResponse, error)
 
 	// TargetSegmentsByIdentifier get a TargetSegments from an environment by its identifier
 	TargetSegmentsByIdentifier(ctx context.Context, req domain.TargetSegmentsByIdentifierRequest) (domain.Segment, error)
@@ -42,6 +43,9 @@ type ProxyService interface {
 	// Evaluations gets all of the evaluations in an environment for a target for a particular feature
 	EvaluationsByFeature(ctx context.Context, req domain.EvaluationsByFeatureRequest) (clientgen.Evaluation, error)
 
+	// BatchEvaluations gets the evaluations in an environment for multiple targets
+	BatchEvaluations(ctx context.Context, req domain.BatchEvaluationsRequest) (domain.BatchEvaluationsResponse, error)
+
 	// Stream returns the name of the GripChannel that the client should subscribe to
 	Stream(ctx context.Context, req domain.StreamRequest) (domain.StreamResponse, error)
 
@@ -70,6 +74,10 @@ var (
 	// ErrStreamDisconnected is the error that the proxy service returns when
 	// its internal sdk has disconnected from the SaaS stream
 	ErrStreamDisconnected = errors.New("SaaS stream disconnected")
+
+	// ErrBadRequest is the error returned when a request is valid JSON but can't
+	// be carried out e.g. an override for a variation that a flag doesn't have
+	ErrBadRequest = errors.New("bad request")
 )
 
 // authTokenFn is a function that can generate an auth token
@@ -106,7 +114,8 @@ type Config struct {
 	Offline       bool
 	Hasher        hash.Hasher
 
-	HealthySaasStream func() bool
+	// HealthySaasStream returns true if the Saas stream that the environment's changes come from is healthy
+	HealthySaasStream func(envID string) bool
 
 	// SDKStreamConnected is a callback that we call whenee
 	SDKStreamConnected func(envID string)
@@ -120,6 +129,7 @@ type Config struct {
 type segmentRepo interface {
 	Get(ctx context.Context, environmentID string) ([]domain.Segment, error)
 	GetByIdentifier(ctx context.Context, environmentID string, identifier string) (domain.Segment, error)
+	GetHash(ctx context.Context, environmentID string) (string, error)
 }
 
 // Service is the proxy service implementation
@@ -134,7 +144,7 @@ type Service struct {
 	metricService      MetricStore
 	offline            bool
 	hasher             hash.Hasher
-	healthySassStream  func() bool
+	healthySassStream  func(envID string) bool
 	sdkStreamConnected func(envID string)
 
 	health func(ctx context.Context) domain.HealthResponse
@@ -209,14 +219,21 @@ func (s Service) Authenticate(ctx context.Context, req domain.AuthRequest) (doma
 }
 
 // FeatureConfig gets all FeatureConfig for an environment
-func (s Service) FeatureConfig(ctx context.Context, req domain.FeatureConfigRequest) ([]domain.FeatureConfig, error) {
+func (s Service) FeatureConfig(ctx context.Context, req domain.FeatureConfigRequest) (domain.FeatureConfigResponse, error) {
 	s.logger = s.logger.With("method", "FeatureConfig")
 
+	// We have to get the hash before we get the flags. If we got it afterwards and the flags
+	// changed in between we'd give the client an ETag for data that it doesn't have.
+	etag := s.makeETag(ctx, s.featureRepo.GetHash, req.EnvironmentID, "")
+	if etagMatches(req.IfNoneMatch, etag) {
+		return domain.FeatureConfigResponse{ETag: etag, NotModified: true}, nil
+	}
+
 	// fetch flags
 	flags, err := s.featureRepo.Get(ctx, req.EnvironmentID)
 	if err != nil {
 		if !errors.Is(err, domain.ErrCacheNotFound) {
-			return []domain.FeatureConfig{}, fmt.Errorf("%w: %s", ErrInternal, err)
+			return domain.FeatureConfigResponse{}, fmt.Errorf("%w: %s", ErrInternal, err)
 		}
 		// we don't return not found because we can't currently tell the difference between no features existing
 		// and the environment itself not existing
@@ -240,7 +257,7 @@ func (s Service) FeatureConfig(ctx context.Context, req domain.FeatureConfigRequ
 		})
 	}
 
-	return configs, nil
+	return domain.FeatureConfigResponse{ETag: etag, FeatureConfigs: configs}, nil
 }
 
 // FeatureConfigByIdentifier gets the feature config for a feature
@@ -262,12 +279,25 @@ func (s Service) FeatureConfigByIdentifier(ctx context.Context, req domain.Featu
 }
 
 // TargetSegments gets all of the TargetSegments in an environment
-func (s Service) TargetSegments(ctx context.Context, req domain.TargetSegmentsRequest) ([]domain.Segment, error) {
+func (s Service) TargetSegments(ctx context.Context, req domain.TargetSegmentsRequest) (domain.TargetSegmentsResponse, error) {
+	v2Rules := s.andRulesEnabled && req.Rules == "v2"
+
+	// The response body differs depending on which rules are requested so
+	// the ETag has to as well
+	variant := ""
+	if v2Rules {
+		variant = "v2"
+	}
+
+	etag := s.makeETag(ctx, s.segmentRepo.GetHash, req.EnvironmentID, variant)
+	if etagMatches(req.IfNoneMatch, etag) {
+		return domain.TargetSegmentsResponse{ETag: etag, NotModified: true}, nil
+	}
 
 	segments, err := s.segmentRepo.Get(ctx, req.EnvironmentID)
 	if err != nil {
 		if !errors.Is(err, domain.ErrCacheNotFound) {
-			return []domain.Segment{}, fmt.Errorf("%w: %s", ErrInternal, err)
+			return domain.TargetSegmentsResponse{}, fmt.Errorf("%w: %s", ErrInternal, err)
 		}
 		// we don't return not found because we can't currently tell the difference between no segments existing
 		// and the environment itself not existing
@@ -279,7 +309,7 @@ func (s Service) TargetSegments(ctx context.Context, req domain.TargetSegmentsRe
 	newGroups = append(newGroups, segments...)
 
 	// if and rules flag enabled and rules=v2 param passed, return serving_rules, otherwise return rules
-	if s.andRulesEnabled && req.Rules == "v2" {
+	if v2Rules {
 		for i := range newGroups {
 			newGroups[i].Rules = nil
 		}
@@ -289,7 +319,7 @@ func (s Service) TargetSegments(ctx context.Context, req domain.TargetSegmentsRe
 		}
 	}
 
-	return newGroups, nil
+	return domain.TargetSegmentsResponse{ETag: etag, Segments: newGroups}, nil
 }
 
 // TargetSegmentsByIdentifier get a TargetSegments from an environment by its identifier
@@ -350,21 +380,67 @@ func (s Service) Evaluations(ctx context.Context, req domain.EvaluationsRequest)
 		return nil, err
 	}
 	//package all into the evaluations
-	for i, fv := range flagVariations {
-
-		kind := string(fv.Kind)
-		eval := clientgen.Evaluation{
-			Flag:       fv.FlagIdentifier,
-			Value:      toString(fv.Variation, kind),
-			Kind:       kind,
-			Identifier: &flagVariations[i].Variation.Identifier,
-		}
-		evaluations = append(evaluations, eval)
+	for _, fv := range flagVariations {
+		evaluations = append(evaluations, toEvaluation(fv))
 	}
 
 	return evaluations, nil
 }
 
+// BatchEvaluations gets the evaluations in an environment for each of the targets in
+// the request. The targets and their attributes are taken from the request body rather
+// than the cache, and if the request contains a list of flags only those flags are evaluated.
+func (s Service) BatchEvaluations(ctx context.Context, req domain.BatchEvaluationsRequest) (domain.BatchEvaluationsResponse, error) {
+	methodLogger := s.logger.With("method", "BatchEvaluations")
+
+	// The segments and flags are the same for every target in the batch so we
+	// fetch them once up front and share them between all of the evaluations
+	// rather than reading them from the cache for each target.
+	segmentMap := s.makeSegmentMap(ctx, req.EnvironmentID)
+	if !s.andRulesEnabled {
+		for i := range segmentMap {
+			segmentMap[i].ServingRules = nil
+		}
+	}
+
+	query := newMemoizedQueryStore(s.GenerateQueryStore(ctx, req.EnvironmentID, segmentMap))
+	sdkEvaluator, _ := evaluation.NewEvaluator(query, nil, logger.NewNoOpLogger())
+
+	resp := make(domain.BatchEvaluationsResponse, len(req.Targets))
+	for _, t := range req.Targets {
+		target := domain.ConvertTarget(t)
+
+		if len(req.Flags) == 0 {
+			flagVariations, err := sdkEvaluator.EvaluateAll(&target)
+			if err != nil {
+				methodLogger.Error(ctx, "failed to perform evaluation", "environment", req.EnvironmentID, "target", target.Identifier, "err", err)
+				return nil, fmt.Errorf("%w: %s", ErrInternal, err)
+			}
+
+			evaluations := make([]clientgen.Evaluation, 0, len(flagVariations))
+			for _, fv := range flagVariations {
+				evaluations = append(evaluations, toEvaluation(fv))
+			}
+			resp[target.Identifier] = evaluations
+			continue
+		}
+
+		evaluations := make([]clientgen.Evaluation, 0, len(req.Flags))
+		for _, flag := range req.Flags {
+			fv, err := sdkEvaluator.Evaluate(flag, &target)
+			if err != nil {
+				// A flag in the filter that doesn't exist shouldn't fail the whole batch
+				methodLogger.Debug(ctx, "failed to evaluate flag, skipping it", "environment", req.EnvironmentID, "feature", flag, "target", target.Identifier, "err", err)
+				continue
+			}
+			evaluations = append(evaluations, toEvaluation(fv))
+		}
+		resp[target.Identifier] = evaluations
+	}
+
+	return resp, nil
+}
+
 // EvaluationsByFeature gets all the evaluations in an environment for a target for a particular feature
 func (s Service) EvaluationsByFeature(ctx context.Context, req domain.EvaluationsByFeatureRequest) (clientgen.Evaluation, error) {
 
@@ -390,12 +466,7 @@ func (s Service) EvaluationsByFeature(ctx context.Context, req domain.Evaluation
 		return clientgen.Evaluation{}, err
 	}
 
-	return clientgen.Evaluation{
-		Flag:       flagVariation.FlagIdentifier,
-		Value:      toString(flagVariation.Variation, string(flagVariation.Kind)),
-		Kind:       string(flagVariation.Kind),
-		Identifier: &flagVariation.Variation.Identifier,
-	}, nil
+	return toEvaluation(flagVariation), nil
 }
 
 // Stream does a lookup for the environmentID for the APIKey in the StreamRequest
@@ -406,11 +477,8 @@ func (s Service) Stream(ctx context.Context, req domain.StreamRequest) (domain.S
 	// during a polling operation don't have an SSE event associated with them so there's no way to notify
 	// connected SDKs that a change has happened. Refusing stream requests when this stream is down forces
 	// SDKs to poll the Proxy for changes until the stream is healthy again, meaning SDKs won't miss out on
-	// changes pulled down via polling.
-	if !s.healthySassStream() {
-		return domain.StreamResponse{}, fmt.Errorf("%w: streaming endpoint disabled", ErrStreamDisconnected)
-	}
-
+	// changes pulled down via polling. Only the stream for the key that the environment belongs to matters
+	// so we look up the environment first.
 	hashedAPIKey := s.hasher.Hash(req.APIKey)
 	envID, ok, err := s.authRepo.Get(ctx, domain.NewAuthAPIKey(hashedAPIKey))
 	if err != nil {
@@ -420,9 +488,13 @@ func (s Service) Stream(ctx context.Context, req domain.StreamRequest) (domain.S
 		return domain.StreamResponse{}, fmt.Errorf("%w: no environment found for apiKey %q", ErrNotFound, req.APIKey)
 	}
 
+	if !s.healthySassStream(envID) {
+		return domain.StreamResponse{}, fmt.Errorf("%w: streaming endpoint disabled", ErrStreamDisconnected)
+	}
+
 	s.sdkStreamConnected(envID)
 
-	return domain.StreamResponse{GripChannel: envID}, nil
+	return domain.StreamResponse{GripChannel: envID, LastEventID: req.LastEventID}, nil
 }
 
 // Metrics forwards metrics to the analytics service
@@ -443,6 +515,18 @@ func (s Service) Health(ctx context.Context) (domain.HealthResponse, error) {
 	return healthResp, nil
 }
 
+// toEvaluation converts a FlagVariation returned by the evaluator to the Evaluation
+// type that we return to SDKs
+func toEvaluation(fv evaluation.FlagVariation) clientgen.Evaluation {
+	kind := string(fv.Kind)
+	return clientgen.Evaluation{
+		Flag:       fv.FlagIdentifier,
+		Value:      toString(fv.Variation, kind),
+		Kind:       kind,
+		Identifier: &fv.Variation.Identifier,
+	}
+}
+
 func toString(variation rest.Variation, kind string) string {
 	value := fmt.Sprintf("%v", variation.Value)
 	if kind == "json" {
@@ -456,6 +540,49 @@ func toString(variation rest.Variation, kind string) string {
 	return value
 }
 
+// makeETag builds an ETag from the hash that's stored alongside an environment's config. If we don't
+// have a hash, e.g. because the cache doesn't store them, we return an empty ETag and the response
+// won't be cacheable by clients.
+func (s Service) makeETag(ctx context.Context, getHash func(ctx context.Context, envID string) (string, error), envID string, variant string) string {
+	hash, err := getHash(ctx, envID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrCacheNotFound) {
+			s.logger.Warn(ctx, "failed to get config hash for ETag", "environment", envID, "err", err)
+		}
+		return ""
+	}
+
+	if hash == "" {
+		return ""
+	}
+
+	if variant != "" {
+		return fmt.Sprintf("%q", fmt.Sprintf("%s-%s", hash, variant))
+	}
+	return fmt.Sprintf("%q", hash)
+}
+
+// etagMatches checks if the ETag matches any of the ETags in an If-None-Match header
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+
+		// We only ever generate strong ETags but clients are allowed to send
+		// them back to us as weak ETags and the comparison should still succeed
+		if strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
 func (s Service) makeSegmentMap(ctx context.Context, envID string) map[string]*domain.Segment {
 	var segmentMap map[string]*domain.Segment
 
@@ -356,6 +356,10 @@ func (m mockSegmentRepo) GetByIdentifier(ctx context.Context, environmentID stri
 	return m.getIdentifierFn()
 }
 
+func (m mockSegmentRepo) GetHash(ctx context.Context, environmentID string) (string, error) {
+	return "", domain.ErrCacheNotFound
+}
+
 func Test_makeSegmentMap(t *testing.T) {
 	fooSegment := domain.Segment{Identifier: "foo"}
 	fooSegment2 := domain.Segment{Identifier: "foo"}
@@ -469,3 +473,56 @@ func Test_makeSegmentMap(t *testing.T) {
 		})
 	}
 }
+
+func Test_etagMatches(t *testing.T) {
+	testCases := map[string]struct {
+		ifNoneMatch string
+		etag        string
+		expected    bool
+	}{
+		"Given the If-None-Match header is empty": {
+			ifNoneMatch: "",
+			etag:        `"foo"`,
+			expected:    false,
+		},
+		"Given the ETag is empty": {
+			ifNoneMatch: `"foo"`,
+			etag:        "",
+			expected:    false,
+		},
+		"Given the If-None-Match header matches the ETag": {
+			ifNoneMatch: `"foo"`,
+			etag:        `"foo"`,
+			expected:    true,
+		},
+		"Given the If-None-Match header doesn't match the ETag": {
+			ifNoneMatch: `"bar"`,
+			etag:        `"foo"`,
+			expected:    false,
+		},
+		"Given the If-None-Match header contains a list of ETags and one of them matches": {
+			ifNoneMatch: `"bar", "foo"`,
+			etag:        `"foo"`,
+			expected:    true,
+		},
+		"Given the If-None-Match header contains a weak ETag that matches": {
+			ifNoneMatch: `W/"foo"`,
+			etag:        `"foo"`,
+			expected:    true,
+		},
+		"Given the If-None-Match header is a wildcard": {
+			ifNoneMatch: "*",
+			etag:        `"foo"`,
+			expected:    true,
+		},
+	}
+
+	for desc, tc := range testCases {
+		desc := desc
+		tc := tc
+
+		t.Run(desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, etagMatches(tc.ifNoneMatch, tc.etag), desc)
+		})
+	}
+}
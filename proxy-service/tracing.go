@@ -0,0 +1,140 @@
+package proxyservice
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	clientgen "github.com/harness/ff-proxy/v2/gen/client"
+	"github.com/harness/ff-proxy/v2/tracing"
+)
+
+// TracingService is a decorator for a ProxyService that creates a span for
+// each call made to the service
+type TracingService struct {
+	next   ProxyService
+	tracer trace.Tracer
+}
+
+// NewTracingService creates a TracingService
+func NewTracingService(next ProxyService, tp trace.TracerProvider) TracingService {
+	return TracingService{
+		next:   next,
+		tracer: tp.Tracer(tracing.ScopeName),
+	}
+}
+
+// Authenticate makes TracingService implement the ProxyService interface
+func (t TracingService) Authenticate(ctx context.Context, req domain.AuthRequest) (resp domain.AuthResponse, err error) {
+	ctx, span := t.start(ctx, "Authenticate", attribute.String("target.identifier", req.Target.Identifier))
+	defer func() { endSpan(span, err) }()
+
+	return t.next.Authenticate(ctx, req)
+}
+
+// FeatureConfig makes TracingService implement the ProxyService interface
+func (t TracingService) FeatureConfig(ctx context.Context, req domain.FeatureConfigRequest) (resp domain.FeatureConfigResponse, err error) {
+	ctx, span := t.start(ctx, "FeatureConfig", attribute.String("environment.id", req.EnvironmentID))
+	defer func() { endSpan(span, err) }()
+
+	return t.next.FeatureConfig(ctx, req)
+}
+
+// FeatureConfigByIdentifier makes TracingService implement the ProxyService interface
+func (t TracingService) FeatureConfigByIdentifier(ctx context.Context, req domain.FeatureConfigByIdentifierRequest) (resp domain.FeatureConfig, err error) {
+	ctx, span := t.start(ctx, "FeatureConfigByIdentifier",
+		attribute.String("environment.id", req.EnvironmentID),
+		attribute.String("feature.identifier", req.Identifier),
+	)
+	defer func() { endSpan(span, err) }()
+
+	return t.next.FeatureConfigByIdentifier(ctx, req)
+}
+
+// TargetSegments makes TracingService implement the ProxyService interface
+func (t TracingService) TargetSegments(ctx context.Context, req domain.TargetSegmentsRequest) (resp domain.TargetSegmentsResponse, err error) {
+	ctx, span := t.start(ctx, "TargetSegments", attribute.String("environment.id", req.EnvironmentID))
+	defer func() { endSpan(span, err) }()
+
+	return t.next.TargetSegments(ctx, req)
+}
+
+// TargetSegmentsByIdentifier makes TracingService implement the ProxyService interface
+func (t TracingService) TargetSegmentsByIdentifier(ctx context.Context, req domain.TargetSegmentsByIdentifierRequest) (resp domain.Segment, err error) {
+	ctx, span := t.start(ctx, "TargetSegmentsByIdentifier",
+		attribute.String("environment.id", req.EnvironmentID),
+		attribute.String("segment.identifier", req.Identifier),
+	)
+	defer func() { endSpan(span, err) }()
+
+	return t.next.TargetSegmentsByIdentifier(ctx, req)
+}
+
+// Evaluations makes TracingService implement the ProxyService interface
+func (t TracingService) Evaluations(ctx context.Context, req domain.EvaluationsRequest) (resp []clientgen.Evaluation, err error) {
+	ctx, span := t.start(ctx, "Evaluations",
+		attribute.String("environment.id", req.EnvironmentID),
+		attribute.String("target.identifier", req.TargetIdentifier),
+	)
+	defer func() { endSpan(span, err) }()
+
+	return t.next.Evaluations(ctx, req)
+}
+
+// EvaluationsByFeature makes TracingService implement the ProxyService interface
+func (t TracingService) EvaluationsByFeature(ctx context.Context, req domain.EvaluationsByFeatureRequest) (resp clientgen.Evaluation, err error) {
+	ctx, span := t.start(ctx, "EvaluationsByFeature",
+		attribute.String("environment.id", req.EnvironmentID),
+		attribute.String("target.identifier", req.TargetIdentifier),
+		attribute.String("feature.identifier", req.FeatureIdentifier),
+	)
+	defer func() { endSpan(span, err) }()
+
+	return t.next.EvaluationsByFeature(ctx, req)
+}
+
+// BatchEvaluations makes TracingService implement the ProxyService interface
+func (t TracingService) BatchEvaluations(ctx context.Context, req domain.BatchEvaluationsRequest) (resp domain.BatchEvaluationsResponse, err error) {
+	ctx, span := t.start(ctx, "BatchEvaluations",
+		attribute.String("environment.id", req.EnvironmentID),
+		attribute.Int("targets.count", len(req.Targets)),
+	)
+	defer func() { endSpan(span, err) }()
+
+	return t.next.BatchEvaluations(ctx, req)
+}
+
+// Stream makes TracingService implement the ProxyService interface
+func (t TracingService) Stream(ctx context.Context, req domain.StreamRequest) (resp domain.StreamResponse, err error) {
+	ctx, span := t.start(ctx, "Stream")
+	defer func() { endSpan(span, err) }()
+
+	return t.next.Stream(ctx, req)
+}
+
+// Metrics makes TracingService implement the ProxyService interface
+func (t TracingService) Metrics(ctx context.Context, req domain.MetricsRequest) (err error) {
+	ctx, span := t.start(ctx, "Metrics", attribute.String("environment.id", req.EnvironmentID))
+	defer func() { endSpan(span, err) }()
+
+	return t.next.Metrics(ctx, req)
+}
+
+// Health makes TracingService implement the ProxyService interface
+func (t TracingService) Health(ctx context.Context) (resp domain.HealthResponse, err error) {
+	ctx, span := t.start(ctx, "Health")
+	defer func() { endSpan(span, err) }()
+
+	return t.next.Health(ctx)
+}
+
+func (t TracingService) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "ProxyService."+method, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	tracing.RecordError(span, err)
+	span.End()
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"golang.org/x/exp/slices"
 
@@ -80,6 +82,23 @@ func (a AuthRepo) GetKeysForEnvironment(ctx context.Context, envID string) ([]st
 	return apiKeys, nil
 }
 
+// Environments returns the IDs of all the environments that have api keys in the cache
+func (a AuthRepo) Environments(ctx context.Context) ([]string, error) {
+	keys, err := a.cache.Keys(ctx, string(domain.NewAPIConfigsKey("*")))
+	if err != nil {
+		return nil, err
+	}
+
+	envs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		env := strings.TrimSuffix(strings.TrimPrefix(k, "env-"), "-api-configs")
+		envs = append(envs, env)
+	}
+
+	sort.Strings(envs)
+	return envs, nil
+}
+
 // RemoveAllKeysForEnvironment all api keys for given environment
 func (a AuthRepo) RemoveAllKeysForEnvironment(ctx context.Context, envID string) error {
 
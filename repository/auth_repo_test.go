@@ -228,3 +228,40 @@ func TestAPIRepo_PatchAPIConfigForEnvironment(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthRepo_Environments(t *testing.T) {
+	testCases := map[string]struct {
+		envs     map[string][]string
+		expected []string
+	}{
+		"Given I have an empty AuthRepo": {
+			envs:     map[string][]string{},
+			expected: []string{},
+		},
+		"Given I have an AuthRepo with keys for multiple environments": {
+			envs: map[string][]string{
+				"env-456": {"apikey-bar"},
+				"env-123": {"apikey-foo"},
+			},
+			expected: []string{"env-123", "env-456"},
+		},
+	}
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewAuthRepo(cache.NewMemCache())
+
+			for env, keys := range tc.envs {
+				assert.Nil(t, repo.AddAPIConfigsForEnvironment(ctx, env, keys))
+			}
+
+			// Add an auth config to make sure keys that aren't for an environment are ignored
+			assert.Nil(t, repo.Add(ctx, domain.AuthConfig{APIKey: "apikey-foo", EnvironmentID: "env-123"}))
+
+			actual, err := repo.Environments(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/harness/ff-proxy/v2/cache"
 	clientgen "github.com/harness/ff-proxy/v2/gen/client"
@@ -12,16 +13,29 @@ import (
 
 // FeatureFlagRepo is a repository that stores FeatureFlags
 type FeatureFlagRepo struct {
-	cache cache.Cache
+	cache     cache.Cache
+	overrides *FlagOverrideRepo
+}
+
+// WithFlagOverrides makes the FeatureFlagRepo apply the overrides in the FlagOverrideRepo
+// to the FeatureFlags returned by Get and GetByIdentifier
+func WithFlagOverrides(r FlagOverrideRepo) func(f *FeatureFlagRepo) {
+	return func(f *FeatureFlagRepo) {
+		f.overrides = &r
+	}
 }
 
 // NewFeatureFlagRepo creates a FeatureFlagRepo. It can optionally preload the repo with data
 // from the passed config
-func NewFeatureFlagRepo(c cache.Cache) FeatureFlagRepo {
-	return FeatureFlagRepo{cache: c}
+func NewFeatureFlagRepo(c cache.Cache, opts ...func(f *FeatureFlagRepo)) FeatureFlagRepo {
+	f := FeatureFlagRepo{cache: c}
+	for _, opt := range opts {
+		opt(&f)
+	}
+	return f
 }
 
-// Get gets all the FeatureFlag for a given key
+// Get gets all the FeatureFlag for a given key with any overrides applied
 func (f FeatureFlagRepo) Get(ctx context.Context, envID string) ([]domain.FeatureFlag, error) {
 	var featureFlags []domain.FeatureFlag
 	key := domain.NewFeatureConfigsKey(envID)
@@ -31,7 +45,51 @@ func (f FeatureFlagRepo) Get(ctx context.Context, envID string) ([]domain.Featur
 		return []domain.FeatureFlag{}, err
 	}
 
-	return featureFlags, nil
+	overrides, ok := f.getOverrides(ctx, envID)
+	if !ok {
+		return featureFlags, nil
+	}
+
+	// The cache can return the same slice to every caller so we apply the
+	// overrides to a copy of it
+	now := time.Now()
+	result := make([]domain.FeatureFlag, 0, len(featureFlags))
+	for _, flag := range featureFlags {
+		result = append(result, overrides.Apply(flag, now))
+	}
+	return result, nil
+}
+
+// GetHash gets the hash of the FeatureFlags stored for an environment. The hash changes
+// whenever the FeatureFlags or their overrides for the environment change.
+func (f FeatureFlagRepo) GetHash(ctx context.Context, envID string) (string, error) {
+	var hash string
+	key := cache.NewLatestHashKey(string(domain.NewFeatureConfigsKey(envID)))
+
+	if err := f.cache.Get(ctx, key, &hash); err != nil {
+		return "", err
+	}
+
+	// The revision includes overrides that have expired so the ETag changes as soon
+	// as they expire rather than when they're removed
+	if overrides, ok := f.getOverrides(ctx, envID); ok && hash != "" {
+		return fmt.Sprintf("%s-%d", hash, overrides.Revision(time.Now())), nil
+	}
+	return hash, nil
+}
+
+// getOverrides returns the FlagOverrides for an environment and whether there are any to apply
+func (f FeatureFlagRepo) getOverrides(ctx context.Context, envID string) (domain.FlagOverrides, bool) {
+	if f.overrides == nil {
+		return domain.FlagOverrides{}, false
+	}
+
+	// If we can't get the overrides we serve the flags without them rather than failing the request
+	overrides, err := f.overrides.Get(ctx, envID)
+	if err != nil {
+		return domain.FlagOverrides{}, false
+	}
+	return overrides, overrides.Revision(time.Now()) > 0
 }
 
 // GetByIdentifier gets a FeatureFlag for a given key and identifier
@@ -43,6 +101,10 @@ func (f FeatureFlagRepo) GetByIdentifier(ctx context.Context, envID string, iden
 		return domain.FeatureFlag{}, err
 	}
 
+	if overrides, ok := f.getOverrides(ctx, envID); ok {
+		featureFlag = overrides.Apply(featureFlag, time.Now())
+	}
+
 	// some sdks e.g. .NET don't cope well with being returned a null VariationToTargetMap so we send back an empty struct here for now
 	// to match ff-server behaviour
 	if featureFlag.VariationToTargetMap == nil {
@@ -85,7 +147,8 @@ func (f FeatureFlagRepo) Add(ctx context.Context, config ...domain.FlagConfig) e
 	return nil
 }
 
-// GetFeatureConfigForEnvironment gets the feature config for environment from cache.
+// GetFeatureConfigForEnvironment gets the feature config for environment from cache. Unlike
+// Get it returns the flags as they were added, without any overrides applied.
 func (f FeatureFlagRepo) GetFeatureConfigForEnvironment(ctx context.Context, envID string) ([]domain.FeatureFlag, bool) {
 	var features []domain.FeatureFlag
 	key := domain.NewFeatureConfigsKey(envID)
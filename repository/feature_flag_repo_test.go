@@ -308,3 +308,86 @@ func TestFeatureFlagRepo_Remove(t *testing.T) {
 		})
 	}
 }
+
+func TestFeatureFlagRepo_WithFlagOverrides(t *testing.T) {
+	ctx := context.Background()
+
+	memCache := cache.NewMemCache()
+	overrideRepo := NewFlagOverrideRepo(memCache)
+	repo := NewFeatureFlagRepo(cache.NewHashCache(memCache, 1*time.Minute, 1*time.Minute), WithFlagOverrides(overrideRepo))
+
+	assert.Nil(t, repo.Add(ctx, domain.FlagConfig{EnvironmentID: "123", FeatureConfigs: []domain.FeatureFlag{featureFlagFoo, featureFlagBar}}))
+
+	hashBefore, err := repo.GetHash(ctx, "123")
+	assert.Nil(t, err)
+
+	assert.Nil(t, overrideRepo.Add(ctx, "123", domain.FlagOverride{Feature: "foo", State: "off"}))
+
+	expectedFoo := featureFlagFoo
+	expectedFoo.State = clientgen.Off
+	expectedFoo.Version = int64Ptr(569)
+
+	flags, err := repo.Get(ctx, "123")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []domain.FeatureFlag{expectedFoo, featureFlagBar}, flags)
+
+	flag, err := repo.GetByIdentifier(ctx, "123", "foo")
+	assert.Nil(t, err)
+	assert.Equal(t, expectedFoo, flag)
+
+	// The hash has to change so that SDKs polling with an ETag get the overridden flag
+	hashAfter, err := repo.GetHash(ctx, "123")
+	assert.Nil(t, err)
+	assert.NotEqual(t, hashBefore, hashAfter)
+
+	// The stored flags shouldn't be changed by the overrides
+	stored, ok := repo.GetFeatureConfigForEnvironment(ctx, "123")
+	assert.True(t, ok)
+	assert.ElementsMatch(t, []domain.FeatureFlag{featureFlagFoo, featureFlagBar}, stored)
+
+	// Once the override is removed the flag is served as it is but with a newer version
+	assert.Nil(t, overrideRepo.Remove(ctx, "123", "foo"))
+
+	expectedFoo = featureFlagFoo
+	expectedFoo.Version = int64Ptr(570)
+
+	flag, err = repo.GetByIdentifier(ctx, "123", "foo")
+	assert.Nil(t, err)
+	assert.Equal(t, expectedFoo, flag)
+}
+
+func TestFeatureFlagRepo_WithExpiredFlagOverrides(t *testing.T) {
+	ctx := context.Background()
+
+	memCache := cache.NewMemCache()
+	overrideRepo := NewFlagOverrideRepo(memCache)
+	repo := NewFeatureFlagRepo(cache.NewHashCache(memCache, 1*time.Minute, 1*time.Minute), WithFlagOverrides(overrideRepo))
+
+	assert.Nil(t, repo.Add(ctx, domain.FlagConfig{EnvironmentID: "123", FeatureConfigs: []domain.FeatureFlag{featureFlagFoo, featureFlagBar}}))
+
+	expiresAt := time.Now().Add(-time.Second)
+	assert.Nil(t, overrideRepo.Add(ctx, "123", domain.FlagOverride{Feature: "foo", State: "off", ExpiresAt: expiresAt.UnixMilli()}))
+
+	// The expired override hasn't been removed yet but the flag is served as if it has
+	expectedFoo := featureFlagFoo
+	expectedFoo.Version = int64Ptr(570)
+
+	flag, err := repo.GetByIdentifier(ctx, "123", "foo")
+	assert.Nil(t, err)
+	assert.Equal(t, expectedFoo, flag)
+
+	hashBeforeRemoval, err := repo.GetHash(ctx, "123")
+	assert.Nil(t, err)
+
+	// Removing the expired override doesn't change the flag or the hash that SDKs already have
+	_, err = overrideRepo.RemoveExpired(ctx, "123", time.Now())
+	assert.Nil(t, err)
+
+	flag, err = repo.GetByIdentifier(ctx, "123", "foo")
+	assert.Nil(t, err)
+	assert.Equal(t, expectedFoo, flag)
+
+	hashAfterRemoval, err := repo.GetHash(ctx, "123")
+	assert.Nil(t, err)
+	assert.Equal(t, hashBeforeRemoval, hashAfterRemoval)
+}
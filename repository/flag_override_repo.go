@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/harness/ff-proxy/v2/cache"
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+// maxFlagOverrideAuditEntries is the number of audit entries that are kept for
+// each environment, older entries are dropped as new ones are added
+const maxFlagOverrideAuditEntries = 100
+
+// errNothingExpired stops RemoveExpired from writing the overrides when none of them have expired
+var errNothingExpired = errors.New("no overrides have expired")
+
+// FlagOverrideRepo is a repository that stores FlagOverrides and their audit trail
+type FlagOverrideRepo struct {
+	cache cache.Cache
+}
+
+// NewFlagOverrideRepo creates a FlagOverrideRepo
+func NewFlagOverrideRepo(c cache.Cache) FlagOverrideRepo {
+	return FlagOverrideRepo{cache: c}
+}
+
+// Get gets the FlagOverrides for an environment
+func (f FlagOverrideRepo) Get(ctx context.Context, envID string) (domain.FlagOverrides, error) {
+	overrides := domain.NewFlagOverrides()
+	key := domain.NewFlagOverridesKey(envID)
+
+	if err := f.cache.Get(ctx, string(key), &overrides); err != nil {
+		return domain.NewFlagOverrides(), err
+	}
+
+	if overrides.Overrides == nil {
+		overrides.Overrides = map[string]domain.FlagOverride{}
+	}
+	if overrides.Revisions == nil {
+		overrides.Revisions = map[string]int64{}
+	}
+	return overrides, nil
+}
+
+// Add stores the override for a flag, replacing any existing override for it
+func (f FlagOverrideRepo) Add(ctx context.Context, envID string, override domain.FlagOverride) error {
+	return f.update(ctx, envID, func(overrides *domain.FlagOverrides) error {
+		overrides.Overrides[override.Feature] = override
+		overrides.Revisions[override.Feature]++
+		return nil
+	})
+}
+
+// Remove removes the override for a flag. It returns a domain.ErrCacheNotFound
+// error if the flag doesn't have an override.
+func (f FlagOverrideRepo) Remove(ctx context.Context, envID string, feature string) error {
+	return f.update(ctx, envID, func(overrides *domain.FlagOverrides) error {
+		if _, ok := overrides.Overrides[feature]; !ok {
+			return fmt.Errorf("%w: flag %q doesn't have an override", domain.ErrCacheNotFound, feature)
+		}
+
+		// The revision is kept after the override is removed so that the flag's
+		// version doesn't go backwards
+		delete(overrides.Overrides, feature)
+		overrides.Revisions[feature]++
+		return nil
+	})
+}
+
+// RemoveExpired removes the overrides that have expired by now and returns the
+// flags they were for
+func (f FlagOverrideRepo) RemoveExpired(ctx context.Context, envID string, now time.Time) ([]string, error) {
+	var removed []string
+
+	err := f.update(ctx, envID, func(overrides *domain.FlagOverrides) error {
+		// Reset on each attempt since the update is retried if the overrides change underneath it
+		removed = nil
+
+		for feature, o := range overrides.Overrides {
+			if !o.Expired(now) {
+				continue
+			}
+
+			delete(overrides.Overrides, feature)
+			overrides.Revisions[feature]++
+			removed = append(removed, feature)
+		}
+
+		if len(removed) == 0 {
+			return errNothingExpired
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errNothingExpired) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	sort.Strings(removed)
+	return removed, nil
+}
+
+// update changes the FlagOverrides for an environment. The overrides for an environment
+// are stored as a single value that's shared by every Proxy using the same cache, so
+// they're updated atomically to avoid one Proxy's change overwriting another's.
+func (f FlagOverrideRepo) update(ctx context.Context, envID string, fn func(overrides *domain.FlagOverrides) error) error {
+	var overrides domain.FlagOverrides
+	key := domain.NewFlagOverridesKey(envID)
+
+	return cache.Update(ctx, f.cache, string(key), &overrides, func() error {
+		if overrides.Overrides == nil {
+			overrides.Overrides = map[string]domain.FlagOverride{}
+		}
+		if overrides.Revisions == nil {
+			overrides.Revisions = map[string]int64{}
+		}
+		return fn(&overrides)
+	})
+}
+
+// GetAudit gets the audit trail of override changes for an environment, oldest first
+func (f FlagOverrideRepo) GetAudit(ctx context.Context, envID string) ([]domain.FlagOverrideAuditEntry, error) {
+	var entries []domain.FlagOverrideAuditEntry
+	key := domain.NewFlagOverrideAuditKey(envID)
+
+	if err := f.cache.Get(ctx, string(key), &entries); err != nil {
+		return []domain.FlagOverrideAuditEntry{}, err
+	}
+	return entries, nil
+}
+
+// AddAudit appends entries to the audit trail for an environment
+func (f FlagOverrideRepo) AddAudit(ctx context.Context, envID string, entries ...domain.FlagOverrideAuditEntry) error {
+	var existing []domain.FlagOverrideAuditEntry
+	key := domain.NewFlagOverrideAuditKey(envID)
+
+	return cache.Update(ctx, f.cache, string(key), &existing, func() error {
+		existing = append(existing, entries...)
+		if len(existing) > maxFlagOverrideAuditEntries {
+			existing = existing[len(existing)-maxFlagOverrideAuditEntries:]
+		}
+		return nil
+	})
+}
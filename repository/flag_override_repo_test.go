@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/cache"
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+func TestFlagOverrideRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := NewFlagOverrideRepo(cache.NewMemCache())
+
+	_, err := repo.Get(ctx, "123")
+	assert.ErrorIs(t, err, domain.ErrCacheNotFound)
+
+	require.NoError(t, repo.Add(ctx, "123", domain.FlagOverride{Feature: "foo", State: "off"}))
+	require.NoError(t, repo.Add(ctx, "123", domain.FlagOverride{Feature: "foo", State: "on"}))
+	require.NoError(t, repo.Add(ctx, "123", domain.FlagOverride{Feature: "bar", DefaultServe: "false"}))
+
+	overrides, err := repo.Get(ctx, "123")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]domain.FlagOverride{
+		"foo": {Feature: "foo", State: "on"},
+		"bar": {Feature: "bar", DefaultServe: "false"},
+	}, overrides.Overrides)
+	assert.Equal(t, map[string]int64{"foo": 2, "bar": 1}, overrides.Revisions)
+
+	// Removing an override keeps counting its revisions
+	require.NoError(t, repo.Remove(ctx, "123", "foo"))
+
+	overrides, err = repo.Get(ctx, "123")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]domain.FlagOverride{"bar": {Feature: "bar", DefaultServe: "false"}}, overrides.Overrides)
+	assert.Equal(t, map[string]int64{"foo": 3, "bar": 1}, overrides.Revisions)
+
+	assert.ErrorIs(t, repo.Remove(ctx, "123", "foo"), domain.ErrCacheNotFound)
+}
+
+func TestFlagOverrideRepo_AddAudit(t *testing.T) {
+	ctx := context.Background()
+	repo := NewFlagOverrideRepo(cache.NewMemCache())
+
+	_, err := repo.GetAudit(ctx, "123")
+	assert.ErrorIs(t, err, domain.ErrCacheNotFound)
+
+	for i := 0; i < maxFlagOverrideAuditEntries+5; i++ {
+		entry := domain.FlagOverrideAuditEntry{Timestamp: int64(i), Action: domain.FlagOverrideActionSet, Feature: fmt.Sprintf("flag-%d", i)}
+		require.NoError(t, repo.AddAudit(ctx, "123", entry))
+	}
+
+	// Only the most recent entries are kept
+	entries, err := repo.GetAudit(ctx, "123")
+	require.NoError(t, err)
+	require.Len(t, entries, maxFlagOverrideAuditEntries)
+	assert.Equal(t, "flag-5", entries[0].Feature)
+	assert.Equal(t, fmt.Sprintf("flag-%d", maxFlagOverrideAuditEntries+4), entries[len(entries)-1].Feature)
+}
+
+func TestFlagOverrideRepo_RemoveExpired(t *testing.T) {
+	ctx := context.Background()
+	repo := NewFlagOverrideRepo(cache.NewMemCache())
+	now := time.Now()
+
+	require.NoError(t, repo.Add(ctx, "123", domain.FlagOverride{Feature: "foo", State: "off", ExpiresAt: now.Add(-time.Minute).UnixMilli()}))
+	require.NoError(t, repo.Add(ctx, "123", domain.FlagOverride{Feature: "bar", State: "off", ExpiresAt: now.Add(-time.Second).UnixMilli()}))
+	require.NoError(t, repo.Add(ctx, "123", domain.FlagOverride{Feature: "baz", State: "off", ExpiresAt: now.Add(time.Minute).UnixMilli()}))
+
+	removed, err := repo.RemoveExpired(ctx, "123", now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "foo"}, removed)
+
+	overrides, err := repo.Get(ctx, "123")
+	require.NoError(t, err)
+	assert.Contains(t, overrides.Overrides, "baz")
+	assert.Len(t, overrides.Overrides, 1)
+	assert.Equal(t, map[string]int64{"foo": 2, "bar": 2, "baz": 1}, overrides.Revisions)
+
+	// Nothing else has expired so the revisions don't change
+	removed, err = repo.RemoveExpired(ctx, "123", now)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+
+	overrides, err = repo.Get(ctx, "123")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"foo": 2, "bar": 2, "baz": 1}, overrides.Revisions)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -83,6 +84,8 @@ func (i InventoryRepo) Cleanup(ctx context.Context, key string, config []domain.
 		return []domain.SSEMessage{}, err
 	}
 
+	oldAssets = migrateLegacyAssets(oldAssets, config)
+
 	//work out differences.
 	assets := diffAssets(oldAssets, newAssets)
 	notifications := i.BuildNotifications(assets)
@@ -132,6 +135,43 @@ func (i InventoryRepo) Cleanup(ctx context.Context, key string, config []domain.
 	return notifications, err
 }
 
+// migrateLegacyAssets returns a copy of an inventory with the segments in inventories
+// written before versions were stored keyed by identifier rather than name, so that
+// they aren't treated as deleted and created the first time we sync after upgrading
+func migrateLegacyAssets(assets map[string]string, config []domain.ProxyConfig) map[string]string {
+	migrated := make(map[string]string, len(assets))
+	for k, v := range assets {
+		migrated[k] = v
+	}
+
+	for _, cfg := range config {
+		for _, env := range cfg.Environments {
+			environment := env.ID.String()
+			for _, s := range env.Segments {
+				legacyKey := string(domain.NewSegmentKey(environment, s.Name))
+				key := string(domain.NewSegmentKey(environment, s.Identifier))
+				if legacyKey == key {
+					continue
+				}
+
+				v, isLegacy := migrated[legacyKey]
+				if _, exists := migrated[key]; !isLegacy || v != "" || exists {
+					continue
+				}
+
+				delete(migrated, legacyKey)
+				migrated[key] = ""
+			}
+		}
+	}
+	return migrated
+}
+
+// diffAssets works out which assets have been created, deleted or patched. The
+// value of a flag or segment asset is its version so an asset is only patched
+// if its version has changed. Inventories written before versions were stored
+// have empty values, we don't know if these assets have changed so we don't
+// treat them as patched.
 func diffAssets(oldMap, newMap map[string]string) domain.Assets {
 	deleted := make(map[string]string)
 	created := make(map[string]string)
@@ -139,10 +179,13 @@ func diffAssets(oldMap, newMap map[string]string) domain.Assets {
 
 	// Check elements in old but not in new
 	for key, value := range oldMap {
-		if newValue, exists := newMap[key]; !exists || newValue != value {
+		newValue, exists := newMap[key]
+		if !exists {
 			deleted[key] = value
-		} else {
-			patched[key] = value
+			continue
+		}
+		if value != "" && newValue != value {
+			patched[key] = newValue
 		}
 	}
 
@@ -159,7 +202,8 @@ func diffAssets(oldMap, newMap map[string]string) domain.Assets {
 	}
 }
 
-// BuildAssetListFromConfig returns the list of keys for all assets associated with this proxyKey
+// BuildAssetListFromConfig returns the list of keys for all assets associated with this proxyKey.
+// The value of each flag and segment key is its version so that changes can be detected.
 func (i InventoryRepo) BuildAssetListFromConfig(config []domain.ProxyConfig) (map[string]string, error) {
 
 	empty := ""
@@ -177,14 +221,14 @@ func (i InventoryRepo) BuildAssetListFromConfig(config []domain.ProxyConfig) (ma
 			if len(env.FeatureConfigs) > 0 {
 				inventory[string(domain.NewFeatureConfigsKey(environment))] = empty
 				for _, f := range env.FeatureConfigs {
-					inventory[string(domain.NewFeatureConfigKey(environment, f.Feature))] = empty
+					inventory[string(domain.NewFeatureConfigKey(environment, f.Feature))] = domain.NewAssetVersion(f.Version)
 				}
 			}
 
 			if len(env.Segments) > 0 {
 				inventory[string(domain.NewSegmentsKey(environment))] = empty
 				for _, s := range env.Segments {
-					inventory[string(domain.NewSegmentKey(environment, s.Name))] = empty
+					inventory[string(domain.NewSegmentKey(environment, s.Identifier))] = domain.NewAssetVersion(s.Version)
 				}
 			}
 		}
@@ -193,6 +237,26 @@ func (i InventoryRepo) BuildAssetListFromConfig(config []domain.ProxyConfig) (ma
 	return inventory, nil
 }
 
+// Inventories returns the inventory for every proxy key in the cache, keyed by
+// the inventory key. Read replicas don't know the proxy key the primary uses so
+// they can use this to find the inventory.
+func (i InventoryRepo) Inventories(ctx context.Context) (map[string]map[string]string, error) {
+	keys, err := i.cache.Keys(ctx, string(domain.NewKeyInventory("*")))
+	if err != nil {
+		return nil, err
+	}
+
+	inventories := make(map[string]map[string]string, len(keys))
+	for _, key := range keys {
+		var inventory map[string]string
+		if err := i.cache.Get(ctx, key, &inventory); err != nil {
+			return nil, err
+		}
+		inventories[key] = inventory
+	}
+	return inventories, nil
+}
+
 // KeyExists check if the given key exists in cache.
 func (i InventoryRepo) KeyExists(ctx context.Context, key string) bool {
 	var val interface{}
@@ -216,8 +280,6 @@ func (i InventoryRepo) BuildNotifications(assets domain.Assets) []domain.SSEMess
 	var events []domain.SSEMessage
 	events = append(events, i.getDeleteEvents(assets.Deleted)...)
 	events = append(events, i.getCreateEvents(assets.Created)...)
-	// TODO: this currently sends patch notification for all flags
-	// regardless weather they have changed or not
 	events = append(events, i.getPatchEvents(assets.Patched)...)
 	return events
 }
@@ -227,12 +289,12 @@ func (i InventoryRepo) getDeleteEvents(m map[string]string) []domain.SSEMessage
 	if m == nil {
 		return []domain.SSEMessage{}
 	}
-	for k := range m {
+	for k, v := range m {
 		if strings.Contains(k, featureVariant) {
-			res = append(res, i.parseFlagEntry(k, deleteVariant))
+			res = append(res, i.parseFlagEntry(k, deleteVariant, v))
 		}
 		if strings.Contains(k, segmentVariant) {
-			res = append(res, i.parseSegmentEntry(k, deleteVariant))
+			res = append(res, i.parseSegmentEntry(k, deleteVariant, v))
 		}
 	}
 	return res
@@ -243,12 +305,12 @@ func (i InventoryRepo) getCreateEvents(m map[string]string) []domain.SSEMessage
 	if m == nil {
 		return []domain.SSEMessage{}
 	}
-	for k := range m {
+	for k, v := range m {
 		if strings.Contains(k, featureVariant) {
-			res = append(res, i.parseFlagEntry(k, createVariant))
+			res = append(res, i.parseFlagEntry(k, createVariant, v))
 		}
 		if strings.Contains(k, segmentVariant) {
-			res = append(res, i.parseSegmentEntry(k, createVariant))
+			res = append(res, i.parseSegmentEntry(k, createVariant, v))
 		}
 	}
 	return res
@@ -259,18 +321,18 @@ func (i InventoryRepo) getPatchEvents(m map[string]string) []domain.SSEMessage {
 	if m == nil {
 		return []domain.SSEMessage{}
 	}
-	for k := range m {
+	for k, v := range m {
 		if strings.Contains(k, featureVariant) {
-			res = append(res, i.parseFlagEntry(k, patchVariant))
+			res = append(res, i.parseFlagEntry(k, patchVariant, v))
 		}
 		if strings.Contains(k, segmentVariant) {
-			res = append(res, i.parseSegmentEntry(k, patchVariant))
+			res = append(res, i.parseSegmentEntry(k, patchVariant, v))
 		}
 	}
 	return res
 }
 
-func (i InventoryRepo) parseFlagEntry(flagString, variant string) domain.SSEMessage {
+func (i InventoryRepo) parseFlagEntry(flagString, variant string, version string) domain.SSEMessage {
 	env, id, err := parseFlagString(flagString)
 	if err != nil {
 		i.log.Error("err", err)
@@ -281,10 +343,10 @@ func (i InventoryRepo) parseFlagEntry(flagString, variant string) domain.SSEMess
 		Event:       variant,
 		Identifier:  id,
 		Environment: env,
-		Version:     0,
+		Version:     parseAssetVersion(version),
 	}
 }
-func (i InventoryRepo) parseSegmentEntry(segmentString, variant string) domain.SSEMessage {
+func (i InventoryRepo) parseSegmentEntry(segmentString, variant string, version string) domain.SSEMessage {
 	env, id, err := parseSegmentString(segmentString)
 	if err != nil {
 		i.log.Error("err", err)
@@ -295,8 +357,18 @@ func (i InventoryRepo) parseSegmentEntry(segmentString, variant string) domain.S
 		Event:       variant,
 		Identifier:  id,
 		Environment: env,
-		Version:     0,
+		Version:     parseAssetVersion(version),
+	}
+}
+
+// parseAssetVersion parses the version stored for a flag or segment in the inventory,
+// it returns 0 if the version isn't known
+func parseAssetVersion(version string) int {
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return 0
 	}
+	return v
 }
 
 func parseFlagString(flagString string) (string, string, error) {
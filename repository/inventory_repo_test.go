@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/cache"
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+func TestInventoryRepo_Cleanup(t *testing.T) {
+	envID := uuid.MustParse("b9a2ef8d-6d1b-4b1b-8c28-0c6e5b9c5a11")
+	env := envID.String()
+
+	flag := func(identifier string, version int64) domain.FeatureFlag {
+		return domain.FeatureFlag{Feature: identifier, Environment: env, Version: int64Ptr(version)}
+	}
+	segment := func(identifier string, version int64) domain.Segment {
+		return domain.Segment{Identifier: identifier, Name: identifier + " name", Version: int64Ptr(version)}
+	}
+	proxyConfig := func(flags []domain.FeatureFlag, segments []domain.Segment) []domain.ProxyConfig {
+		return []domain.ProxyConfig{
+			{
+				Environments: []domain.Environments{
+					{ID: envID, APIKeys: []string{"apikey"}, FeatureConfigs: flags, Segments: segments},
+				},
+			},
+		}
+	}
+
+	initial := proxyConfig(
+		[]domain.FeatureFlag{flag("dark-mode", 1), flag("beta", 4)},
+		[]domain.Segment{segment("beta-users", 2)},
+	)
+
+	testCases := map[string]struct {
+		config   []domain.ProxyConfig
+		expected []domain.SSEMessage
+	}{
+		"Given nothing has changed": {
+			config:   initial,
+			expected: []domain.SSEMessage{},
+		},
+		"Given a flag's version has changed": {
+			config: proxyConfig(
+				[]domain.FeatureFlag{flag("dark-mode", 2), flag("beta", 4)},
+				[]domain.Segment{segment("beta-users", 2)},
+			),
+			expected: []domain.SSEMessage{
+				{Domain: domain.MsgDomainFeature, Event: domain.EventPatch, Identifier: "dark-mode", Environment: env, Version: 2},
+			},
+		},
+		"Given a flag has been deleted and a segment has been created": {
+			config: proxyConfig(
+				[]domain.FeatureFlag{flag("dark-mode", 1)},
+				[]domain.Segment{segment("beta-users", 2), segment("staff", 1)},
+			),
+			expected: []domain.SSEMessage{
+				{Domain: domain.MsgDomainFeature, Event: domain.EventDelete, Identifier: "beta", Environment: env, Version: 4},
+				{Domain: domain.MsgDomainSegment, Event: domain.EventCreate, Identifier: "staff", Environment: env, Version: 1},
+			},
+		},
+		"Given a segment's version has changed": {
+			config: proxyConfig(
+				[]domain.FeatureFlag{flag("dark-mode", 1), flag("beta", 4)},
+				[]domain.Segment{segment("beta-users", 3)},
+			),
+			expected: []domain.SSEMessage{
+				{Domain: domain.MsgDomainSegment, Event: domain.EventPatch, Identifier: "beta-users", Environment: env, Version: 3},
+			},
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewInventoryRepo(cache.NewMemCache(), log.NoOpLogger{})
+
+			_, err := repo.Cleanup(ctx, "key", initial)
+			require.NoError(t, err)
+
+			actual, err := repo.Cleanup(ctx, "key", tc.config)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.expected, actual)
+
+			// The inventory should have the versions from the latest config so
+			// that the next cleanup doesn't see any changes
+			actual, err = repo.Cleanup(ctx, "key", tc.config)
+			require.NoError(t, err)
+			assert.Empty(t, actual)
+		})
+	}
+}
+
+func TestInventoryRepo_CleanupLegacyInventory(t *testing.T) {
+	ctx := context.Background()
+	envID := uuid.MustParse("b9a2ef8d-6d1b-4b1b-8c28-0c6e5b9c5a11")
+	env := envID.String()
+
+	config := []domain.ProxyConfig{
+		{
+			Environments: []domain.Environments{
+				{
+					ID:             envID,
+					APIKeys:        []string{"apikey"},
+					FeatureConfigs: []domain.FeatureFlag{{Feature: "dark-mode", Environment: env, Version: int64Ptr(1)}},
+					Segments:       []domain.Segment{{Identifier: "beta-users", Name: "Beta Users", Version: int64Ptr(2)}},
+				},
+			},
+		},
+	}
+
+	// Inventories used to be written without versions and with segments keyed by name
+	repo := NewInventoryRepo(cache.NewMemCache(), log.NoOpLogger{})
+	require.NoError(t, repo.Add(ctx, "key", map[string]string{
+		string(domain.NewAPIConfigsKey(env)):                 "",
+		string(domain.NewAuthAPIKey("apikey")):               "",
+		string(domain.NewFeatureConfigsKey(env)):             "",
+		string(domain.NewFeatureConfigKey(env, "dark-mode")): "",
+		string(domain.NewFeatureConfigKey(env, "deleted")):   "",
+		string(domain.NewSegmentsKey(env)):                   "",
+		string(domain.NewSegmentKey(env, "Beta Users")):      "",
+	}))
+
+	// We don't know if the flags and segments that are still there have changed
+	// so only the one that's been deleted should get an event
+	actual, err := repo.Cleanup(ctx, "key", config)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.SSEMessage{
+		{Domain: domain.MsgDomainFeature, Event: domain.EventDelete, Identifier: "deleted", Environment: env},
+	}, actual)
+
+	inventory, err := repo.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "1", inventory[string(domain.NewFeatureConfigKey(env, "dark-mode"))])
+	assert.Equal(t, "2", inventory[string(domain.NewSegmentKey(env, "beta-users"))])
+}
@@ -33,6 +33,18 @@ func (s SegmentRepo) Get(ctx context.Context, envID string) ([]domain.Segment, e
 	return segments, nil
 }
 
+// GetHash gets the hash of the Segments stored for an environment. The hash changes
+// whenever the Segments for the environment change.
+func (s SegmentRepo) GetHash(ctx context.Context, envID string) (string, error) {
+	var hash string
+	key := cache.NewLatestHashKey(string(domain.NewSegmentsKey(envID)))
+
+	if err := s.cache.Get(ctx, key, &hash); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
 // GetByIdentifier gets a Segment for a given key and identifer
 func (s SegmentRepo) GetByIdentifier(ctx context.Context, envID string, identifier string) (domain.Segment, error) {
 	segment := domain.Segment{}
@@ -100,6 +100,35 @@ func (t TargetRepo) DeltaAdd(ctx context.Context, envID string, targets ...domai
 	return t.addTargets(ctx, envID, targets...)
 }
 
+// Remove removes the Target stored at the given key
+func (t TargetRepo) Remove(ctx context.Context, key string) error {
+	return t.cache.Delete(ctx, key)
+}
+
+// RemoveAllTargetsForEnvironment removes the list of Targets and each individual
+// Target for the given environment
+func (t TargetRepo) RemoveAllTargetsForEnvironment(ctx context.Context, envID string) error {
+	targets, err := t.Get(ctx, envID)
+	if err != nil {
+		if errors.Is(err, domain.ErrCacheNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := t.cache.Delete(ctx, string(domain.NewTargetsKey(envID))); err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		key := domain.NewTargetKey(envID, target.Identifier)
+		if err := t.cache.Delete(ctx, string(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Add adds a target or multiple targets to the given key
 func (t TargetRepo) addTarget(ctx context.Context, envID string, target domain.Target) error {
 	key := domain.NewTargetKey(envID, target.Identifier)
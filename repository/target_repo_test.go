@@ -277,3 +277,58 @@ func TestTargetRepo_DeltaAdd(t *testing.T) {
 		})
 	}
 }
+
+func TestTargetRepo_RemoveAllTargetsForEnvironment(t *testing.T) {
+	target1 := domain.Target{Target: clientgen.Target{Identifier: "target1", Name: "target1", Environment: "123"}}
+	target2 := domain.Target{Target: clientgen.Target{Identifier: "target2", Name: "target2", Environment: "123"}}
+	target3 := domain.Target{Target: clientgen.Target{Identifier: "target3", Name: "target3", Environment: "456"}}
+
+	testCases := map[string]struct {
+		repoConfig map[string][]domain.Target
+		env        string
+		shouldErr  bool
+	}{
+		"Given the environment has no Targets": {
+			repoConfig: map[string][]domain.Target{"456": {target3}},
+			env:        "123",
+			shouldErr:  false,
+		},
+		"Given the environment has Targets": {
+			repoConfig: map[string][]domain.Target{"123": {target1, target2}, "456": {target3}},
+			env:        "123",
+			shouldErr:  false,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+
+		t.Run(desc, func(t *testing.T) {
+			ctx := context.Background()
+
+			repo := NewTargetRepo(cache.NewMemCache(), log.NewNoOpLogger())
+			for env, targets := range tc.repoConfig {
+				assert.Nil(t, repo.DeltaAdd(ctx, env, targets...))
+			}
+
+			err := repo.RemoveAllTargetsForEnvironment(ctx, tc.env)
+			if (err != nil) != tc.shouldErr {
+				t.Errorf("(%s): error = %v, shouldErr = %v", desc, err, tc.shouldErr)
+			}
+
+			t.Log("And the environment's Targets should have been removed")
+			_, err = repo.Get(ctx, tc.env)
+			assert.ErrorIs(t, err, domain.ErrCacheNotFound)
+
+			for _, target := range tc.repoConfig[tc.env] {
+				_, err := repo.GetByIdentifier(ctx, tc.env, target.Identifier)
+				assert.ErrorIs(t, err, domain.ErrCacheNotFound)
+			}
+
+			t.Log("And the Targets for other environments should still exist")
+			actual, err := repo.Get(ctx, "456")
+			assert.Nil(t, err)
+			assert.ElementsMatch(t, []domain.Target{target3}, actual)
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// ManifestFile is the name of the file at the root of a config directory
+	// or bundle that holds the digest of every other file in it
+	ManifestFile = "manifest.json"
+
+	// SignatureFile is the name of the file that holds the base64 encoded
+	// ed25519 signature of the ManifestFile
+	SignatureFile = "manifest.sig"
+)
+
+var (
+	// ErrMissingSignature is returned when config is missing its manifest or signature
+	ErrMissingSignature = errors.New("signing: config isn't signed")
+
+	// ErrInvalidSignature is returned when a manifest's signature doesn't verify
+	ErrInvalidSignature = errors.New("signing: invalid signature")
+
+	// ErrDigestMismatch is returned when the files in the config don't match the manifest
+	ErrDigestMismatch = errors.New("signing: files don't match manifest")
+)
+
+// Manifest holds the sha256 digest of every file in a config directory or
+// bundle, keyed by the file's slash separated path
+type Manifest struct {
+	Files map[string]string `json:"files"`
+}
+
+// NewManifest creates an empty Manifest
+func NewManifest() Manifest {
+	return Manifest{Files: map[string]string{}}
+}
+
+// Add records the digest of a file in the Manifest
+func (m Manifest) Add(name string, b []byte) {
+	m.Files[name] = digest(b)
+}
+
+// Sign encodes the Manifest and signs it with the key. It returns the contents
+// of the ManifestFile and SignatureFile.
+func (m Manifest) Sign(key ed25519.PrivateKey) ([]byte, []byte, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, nil, fmt.Errorf("signing: invalid private key length %d", len(key))
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sig := ed25519.Sign(key, b)
+	return b, []byte(base64.StdEncoding.EncodeToString(sig)), nil
+}
+
+// Verify checks that the manifest in the file system has been signed by the
+// key and that every file in the file system matches the digest in it. Files
+// that aren't in the manifest and files in the manifest that are missing both
+// fail verification.
+func Verify(fileSystem fs.FS, key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("signing: invalid public key length %d", len(key))
+	}
+
+	b, err := fs.ReadFile(fileSystem, ManifestFile)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrMissingSignature, err)
+	}
+	encodedSig, err := fs.ReadFile(fileSystem, SignatureFile)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrMissingSignature, err)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSig)))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, err)
+	}
+	if !ed25519.Verify(key, b, sig) {
+		return ErrInvalidSignature
+	}
+
+	m := Manifest{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("failed to decode manifest: %s", err)
+	}
+
+	seen := map[string]bool{}
+	err = fs.WalkDir(fileSystem, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || name == ManifestFile || name == SignatureFile {
+			return nil
+		}
+
+		expected, ok := m.Files[name]
+		if !ok {
+			return fmt.Errorf("%w: %s isn't in the manifest", ErrDigestMismatch, name)
+		}
+
+		b, err := fs.ReadFile(fileSystem, name)
+		if err != nil {
+			return err
+		}
+		if digest(b) != expected {
+			return fmt.Errorf("%w: %s has been modified", ErrDigestMismatch, name)
+		}
+
+		seen[name] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	missing := []string{}
+	for name := range m.Files {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("%w: %s missing", ErrDigestMismatch, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// LoadPublicKey reads a PEM encoded ed25519 public key from a file
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %s", path, err)
+	}
+
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s isn't an ed25519 key", path)
+	}
+	return pub, nil
+}
+
+// LoadPrivateKey reads a PEM encoded PKCS #8 ed25519 private key from a file
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %s", path, err)
+	}
+
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s isn't an ed25519 key", path)
+	}
+	return priv, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	// #nosec G304
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %s", err)
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s doesn't contain a PEM encoded key", path)
+	}
+	return block, nil
+}
+
+func digest(b []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
@@ -0,0 +1,154 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signedFS creates a file system with the files and a manifest signed by the key
+func signedFS(t *testing.T, key ed25519.PrivateKey, files map[string]string) fstest.MapFS {
+	t.Helper()
+
+	fileSystem := fstest.MapFS{}
+	m := NewManifest()
+	for name, content := range files {
+		fileSystem[name] = &fstest.MapFile{Data: []byte(content)}
+		m.Add(name, []byte(content))
+	}
+
+	manifest, sig, err := m.Sign(key)
+	require.NoError(t, err)
+
+	fileSystem[ManifestFile] = &fstest.MapFile{Data: manifest}
+	fileSystem[SignatureFile] = &fstest.MapFile{Data: sig}
+	return fileSystem
+}
+
+func TestVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	otherPub, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	files := map[string]string{
+		"env-123/feature_config.json": `[]`,
+		"env-123/auth_config.json":    `["1234abcd"]`,
+	}
+
+	testCases := map[string]struct {
+		fileSystem  func() fstest.MapFS
+		key         ed25519.PublicKey
+		expectedErr error
+	}{
+		"Given the config has been signed by the key": {
+			fileSystem:  func() fstest.MapFS { return signedFS(t, priv, files) },
+			key:         pub,
+			expectedErr: nil,
+		},
+		"Given the config has been signed by a different key": {
+			fileSystem:  func() fstest.MapFS { return signedFS(t, otherPriv, files) },
+			key:         pub,
+			expectedErr: ErrInvalidSignature,
+		},
+		"Given the config isn't signed": {
+			fileSystem: func() fstest.MapFS {
+				fileSystem := signedFS(t, priv, files)
+				delete(fileSystem, SignatureFile)
+				return fileSystem
+			},
+			key:         pub,
+			expectedErr: ErrMissingSignature,
+		},
+		"Given the manifest has been modified": {
+			fileSystem: func() fstest.MapFS {
+				fileSystem := signedFS(t, priv, files)
+				fileSystem[ManifestFile].Data = append(fileSystem[ManifestFile].Data, ' ')
+				return fileSystem
+			},
+			key:         pub,
+			expectedErr: ErrInvalidSignature,
+		},
+		"Given a file has been modified": {
+			fileSystem: func() fstest.MapFS {
+				fileSystem := signedFS(t, priv, files)
+				fileSystem["env-123/auth_config.json"].Data = []byte(`["1234abcd", "evil"]`)
+				return fileSystem
+			},
+			key:         pub,
+			expectedErr: ErrDigestMismatch,
+		},
+		"Given a file has been added": {
+			fileSystem: func() fstest.MapFS {
+				fileSystem := signedFS(t, priv, files)
+				fileSystem["env-456/auth_config.json"] = &fstest.MapFile{Data: []byte(`["evil"]`)}
+				return fileSystem
+			},
+			key:         pub,
+			expectedErr: ErrDigestMismatch,
+		},
+		"Given a file has been removed": {
+			fileSystem: func() fstest.MapFS {
+				fileSystem := signedFS(t, priv, files)
+				delete(fileSystem, "env-123/feature_config.json")
+				return fileSystem
+			},
+			key:         pub,
+			expectedErr: ErrDigestMismatch,
+		},
+		"Given I verify with the key the config was signed with": {
+			fileSystem:  func() fstest.MapFS { return signedFS(t, otherPriv, files) },
+			key:         otherPub,
+			expectedErr: nil,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			err := Verify(tc.fileSystem(), tc.key)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tc.expectedErr), "expected %v, got %v", tc.expectedErr, err)
+		})
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	pubPath := filepath.Join(dir, "key.pub")
+	privPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0600))
+	require.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600))
+
+	loadedPub, err := LoadPublicKey(pubPath)
+	require.NoError(t, err)
+	assert.Equal(t, pub, loadedPub)
+
+	loadedPriv, err := LoadPrivateKey(privPath)
+	require.NoError(t, err)
+	assert.Equal(t, priv, loadedPriv)
+
+	_, err = LoadPublicKey(privPath)
+	assert.Error(t, err)
+}
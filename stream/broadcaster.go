@@ -0,0 +1,293 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+const (
+	// defaultHeartbeatInterval matches the keep-alive timeout we configure for Pushpin
+	defaultHeartbeatInterval = 15 * time.Second
+
+	// subscriberBufferSize is the number of events we'll buffer for an SDK before
+	// treating it as a slow consumer and dropping its connection
+	subscriberBufferSize = 64
+)
+
+// sseEvent is a JSON encoded message that's been published to a channel and its
+// EventID, which is zero if it doesn't have one
+type sseEvent struct {
+	id   int64
+	data []byte
+}
+
+// sseSubscriber is a single SDK connection that's listening on a channel, the
+// events it receives are the messages published to the channel
+type sseSubscriber struct {
+	events chan sseEvent
+	done   chan struct{}
+	once   *sync.Once
+}
+
+func newSSESubscriber() sseSubscriber {
+	return sseSubscriber{
+		events: make(chan sseEvent, subscriberBufferSize),
+		done:   make(chan struct{}),
+		once:   &sync.Once{},
+	}
+}
+
+// close signals to the subscriber that it should stop serving its stream
+func (s sseSubscriber) close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// Broadcaster is an in process SSE hub that can be used instead of Pushpin to
+// hold open streams with SDKs. It implements the same Pub & Close semantics as
+// the Pushpin type, channels are keyed by environment ID and each event that's
+// published to a channel is written to every SDK connected to that channel.
+type Broadcaster struct {
+	log               log.Logger
+	heartbeatInterval time.Duration
+	history           domain.EventHistory
+
+	mx          *sync.RWMutex
+	subscribers map[string]map[sseSubscriber]struct{}
+}
+
+// BroadcasterOption is type for passing optional config to a Broadcaster
+type BroadcasterOption func(b *Broadcaster)
+
+// WithHeartbeatInterval sets how often the Broadcaster writes keep-alive
+// comments to open streams
+func WithHeartbeatInterval(d time.Duration) BroadcasterOption {
+	return func(b *Broadcaster) {
+		b.heartbeatInterval = d
+	}
+}
+
+// WithEventHistory sets the EventHistory that events are replayed from when an
+// SDK reconnects with the ID of the last event it received
+func WithEventHistory(h domain.EventHistory) BroadcasterOption {
+	return func(b *Broadcaster) {
+		b.history = h
+	}
+}
+
+// NewBroadcaster creates a Broadcaster
+func NewBroadcaster(l log.Logger, opts ...BroadcasterOption) *Broadcaster {
+	l = l.With("component", "Broadcaster")
+
+	b := &Broadcaster{
+		log:               l,
+		heartbeatInterval: defaultHeartbeatInterval,
+		mx:                &sync.RWMutex{},
+		subscribers:       map[string]map[sseSubscriber]struct{}{},
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Pub makes Broadcaster implement the Publisher interface and writes the message
+// to every SDK that's connected to the channel
+func (b *Broadcaster) Pub(_ context.Context, channel string, value interface{}) error {
+	v, err := jsoniter.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("%w: failed to marshal message to bytes: %s", ErrPublishing, err)
+	}
+	event := sseEvent{id: eventID(value), data: v}
+
+	b.mx.RLock()
+	defer b.mx.RUnlock()
+
+	for sub := range b.subscribers[channel] {
+		select {
+		case sub.events <- event:
+		default:
+			// We never want a slow SDK to hold up publishing to every other SDK on
+			// the channel. Dropping the connection means the SDK will reconnect and
+			// poll for the latest config, so it won't miss out on any changes.
+			b.log.Warn("dropping slow sdk stream", "channel", channel)
+			sub.close()
+		}
+	}
+
+	return nil
+}
+
+// Close closes every open stream on the channel
+func (b *Broadcaster) Close(channel string) error {
+	b.mx.RLock()
+	defer b.mx.RUnlock()
+
+	for sub := range b.subscribers[channel] {
+		sub.close()
+	}
+	return nil
+}
+
+// Sub isn't implemented for the Broadcaster, SDKs subscribe by calling ServeStream
+func (b *Broadcaster) Sub(_ context.Context, _ string, _ string, _ domain.HandleMessageFn) error {
+	return errors.New("Broadcaster.Sub not implemented")
+}
+
+// Connections returns the number of open streams and listeners for each channel
+func (b *Broadcaster) Connections() map[string]int {
+	b.mx.RLock()
+	defer b.mx.RUnlock()
+
+	conns := make(map[string]int, len(b.subscribers))
+	for channel, subs := range b.subscribers {
+		conns[channel] = len(subs)
+	}
+	return conns
+}
+
+// ServeStream writes the SSE headers to w and then holds the stream open, writing
+// any events published to the channel and periodic heartbeats, until the ctx is
+// cancelled or the channel is closed. If the SDK sent the lastEventID it received
+// the events it missed are replayed first. A non nil error is only returned if the
+// stream couldn't be started, once it has been started any write errors are
+// treated as the SDK having gone away.
+func (b *Broadcaster) ServeStream(ctx context.Context, w http.ResponseWriter, channel string, lastEventID string) error {
+	rc := http.NewResponseController(w)
+
+	// Streams are long-lived so we don't want the server's WriteTimeout to
+	// kill them, not every ResponseWriter supports this which is fine.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("failed to clear write deadline: %s", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		return fmt.Errorf("failed to flush stream: %s", err)
+	}
+
+	sub := newSSESubscriber()
+	b.addSubscriber(channel, sub)
+	defer b.removeSubscriber(channel, sub)
+
+	ticker := time.NewTicker(b.heartbeatInterval)
+	defer ticker.Stop()
+
+	write := func(p []byte) bool {
+		if _, err := w.Write(p); err != nil {
+			b.log.Debug("failed to write to sdk stream", "channel", channel, "err", err)
+			return false
+		}
+		if err := rc.Flush(); err != nil {
+			b.log.Debug("failed to flush sdk stream", "channel", channel, "err", err)
+			return false
+		}
+		return true
+	}
+
+	// We subscribe before reading the history so that nothing published while we
+	// replay is missed, anything that's in both is only written once
+	var replayed int64
+	if b.history != nil {
+		msgs, err := Replay(ctx, b.history, channel, lastEventID)
+		if err != nil {
+			b.log.Warn("failed to replay events to sdk stream", "channel", channel, "last_event_id", lastEventID, "err", err)
+		}
+
+		for _, msg := range msgs {
+			data, err := jsoniter.Marshal(msg)
+			if err != nil {
+				continue
+			}
+			if !write(FormatEvent(msg.EventID, data)) {
+				return nil
+			}
+			replayed = msg.EventID
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.done:
+			return nil
+		case event := <-sub.events:
+			if event.id != 0 && event.id <= replayed {
+				continue
+			}
+			if !write(FormatEvent(event.id, event.data)) {
+				return nil
+			}
+		case <-ticker.C:
+			if !write([]byte(":\n\n")) {
+				return nil
+			}
+		}
+	}
+}
+
+// Listen registers a listener on the channel and calls fn with the JSON encoded
+// payload of every event published to it. It blocks until the ctx is cancelled,
+// the channel is closed or fn returns an error.
+func (b *Broadcaster) Listen(ctx context.Context, channel string, fn func(data []byte) error) error {
+	sub := newSSESubscriber()
+	b.addSubscriber(channel, sub)
+	defer b.removeSubscriber(channel, sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.done:
+			return nil
+		case event := <-sub.events:
+			if err := fn(event.data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (b *Broadcaster) addSubscriber(channel string, sub sseSubscriber) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	subs, ok := b.subscribers[channel]
+	if !ok {
+		subs = map[sseSubscriber]struct{}{}
+		b.subscribers[channel] = subs
+	}
+	subs[sub] = struct{}{}
+}
+
+func (b *Broadcaster) removeSubscriber(channel string, sub sseSubscriber) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	subs, ok := b.subscribers[channel]
+	if !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subscribers, channel)
+	}
+}
@@ -0,0 +1,236 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+// newBroadcasterServer returns a test server that serves the channel passed in
+// the 'channel' query param from the Broadcaster
+func newBroadcasterServer(b *Broadcaster) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = b.ServeStream(r.Context(), w, r.URL.Query().Get("channel"), r.Header.Get("Last-Event-ID"))
+	}))
+}
+
+// connect opens a stream with the server and waits until the Broadcaster has registered it
+func connect(t *testing.T, b *Broadcaster, url string, channel string) (*bufio.Reader, func()) {
+	t.Helper()
+	return connectFrom(t, b, url, channel, "")
+}
+
+// connectFrom opens a stream with the server like connect, sending the lastEventID
+// in the Last-Event-ID header if it's set
+func connectFrom(t *testing.T, b *Broadcaster, url string, channel string, lastEventID string) (*bufio.Reader, func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"?channel="+channel, nil)
+	assert.Nil(t, err)
+
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	before := b.Connections()[channel]
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		cancel()
+		t.FailNow()
+	}
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	assert.Eventually(t, func() bool {
+		return b.Connections()[channel] == before+1
+	}, time.Second, 10*time.Millisecond)
+
+	return bufio.NewReader(resp.Body), func() {
+		cancel()
+		resp.Body.Close()
+	}
+}
+
+// readEvent reads lines from the stream up until the blank line that terminates an event
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+
+	lines := []string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event from stream: %s", err)
+		}
+
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestBroadcaster_Pub(t *testing.T) {
+	b := NewBroadcaster(log.NoOpLogger{})
+	server := newBroadcasterServer(b)
+	defer server.Close()
+
+	envOneA, closeA := connect(t, b, server.URL, "env-1")
+	defer closeA()
+
+	envOneB, closeB := connect(t, b, server.URL, "env-1")
+	defer closeB()
+
+	envTwo, closeC := connect(t, b, server.URL, "env-2")
+	defer closeC()
+
+	assert.Equal(t, map[string]int{"env-1": 2, "env-2": 1}, b.Connections())
+
+	msg := domain.SSEMessage{Event: "patch", Domain: "flag", Identifier: "foo", Version: 1, Environment: "env-1"}
+	assert.Nil(t, b.Pub(context.Background(), "env-1", msg))
+
+	expected := `event: *
+data: {"event":"patch","domain":"flag","identifier":"foo","version":1,"environment":"env-1","apiKey":""}
+`
+	assert.Equal(t, expected, readEvent(t, envOneA))
+	assert.Equal(t, expected, readEvent(t, envOneB))
+
+	// Publish to env-2 and make sure it's the first thing the env-2 stream sees
+	msg.Environment = "env-2"
+	assert.Nil(t, b.Pub(context.Background(), "env-2", msg))
+	assert.Contains(t, readEvent(t, envTwo), `"environment":"env-2"`)
+}
+
+func TestBroadcaster_Replay(t *testing.T) {
+	ctx := context.Background()
+	history := NewMemoryEventHistory(2)
+
+	b := NewBroadcaster(log.NoOpLogger{}, WithEventHistory(history))
+	server := newBroadcasterServer(b)
+	defer server.Close()
+
+	publish := func(identifier string) {
+		msg := domain.SSEMessage{Event: "patch", Domain: "flag", Identifier: identifier, Environment: "env-1"}
+
+		id, err := history.Add(ctx, "env-1", msg)
+		assert.Nil(t, err)
+
+		msg.EventID = id
+		assert.Nil(t, b.Pub(ctx, "env-1", msg))
+	}
+
+	sdk, closeA := connect(t, b, server.URL, "env-1")
+	defer closeA()
+
+	publish("foo")
+	assert.Equal(t, `id: 1
+event: *
+data: {"event":"patch","domain":"flag","identifier":"foo","version":0,"environment":"env-1","apiKey":"","eventId":1}
+`, readEvent(t, sdk))
+
+	// Events published while an SDK is disconnected are replayed when it reconnects
+	publish("bar")
+	publish("baz")
+
+	reconnected, closeB := connectFrom(t, b, server.URL, "env-1", "2")
+	defer closeB()
+	assert.Contains(t, readEvent(t, reconnected), `"identifier":"baz","version":0,"environment":"env-1","apiKey":"","eventId":3`)
+
+	// Live events are sent once the missed ones have been replayed
+	publish("qux")
+	assert.Contains(t, readEvent(t, reconnected), `"identifier":"qux"`)
+
+	// If the SDK missed events that are no longer in the history it's told to refetch everything
+	behind, closeC := connectFrom(t, b, server.URL, "env-1", "1")
+	defer closeC()
+	assert.Equal(t, `id: 4
+event: *
+data: {"event":"refetchAll","domain":"proxy","identifier":"","version":0,"environment":"env-1","apiKey":"","eventId":4}
+`, readEvent(t, behind))
+}
+
+func TestBroadcaster_Close(t *testing.T) {
+	b := NewBroadcaster(log.NoOpLogger{})
+	server := newBroadcasterServer(b)
+	defer server.Close()
+
+	envOne, closeA := connect(t, b, server.URL, "env-1")
+	defer closeA()
+
+	_, closeB := connect(t, b, server.URL, "env-2")
+	defer closeB()
+
+	assert.Nil(t, b.Close("env-1"))
+
+	_, err := envOne.ReadString('\n')
+	assert.ErrorIs(t, err, io.EOF)
+
+	assert.Eventually(t, func() bool {
+		_, ok := b.Connections()["env-1"]
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, 1, b.Connections()["env-2"])
+}
+
+func TestBroadcaster_Heartbeat(t *testing.T) {
+	b := NewBroadcaster(log.NoOpLogger{}, WithHeartbeatInterval(20*time.Millisecond))
+	server := newBroadcasterServer(b)
+	defer server.Close()
+
+	envOne, closeFn := connect(t, b, server.URL, "env-1")
+	defer closeFn()
+
+	assert.Equal(t, ":\n", readEvent(t, envOne))
+}
+
+func TestBroadcaster_Listen(t *testing.T) {
+	b := NewBroadcaster(log.NoOpLogger{})
+
+	received := make(chan string, 1)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- b.Listen(context.Background(), "env-1", func(data []byte) error {
+			received <- string(data)
+			return nil
+		})
+	}()
+
+	assert.Eventually(t, func() bool {
+		return b.Connections()["env-1"] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	msg := domain.SSEMessage{Event: "patch", Domain: "target-segment", Identifier: "bar", Version: 2, Environment: "env-1"}
+	assert.Nil(t, b.Pub(context.Background(), "env-1", msg))
+	assert.Equal(t, `{"event":"patch","domain":"target-segment","identifier":"bar","version":2,"environment":"env-1","apiKey":""}`, <-received)
+
+	assert.Nil(t, b.Close("env-1"))
+	assert.Nil(t, <-done)
+	assert.Len(t, b.Connections(), 0)
+}
+
+func TestBroadcaster_Disconnect(t *testing.T) {
+	b := NewBroadcaster(log.NoOpLogger{})
+	server := newBroadcasterServer(b)
+	defer server.Close()
+
+	_, closeFn := connect(t, b, server.URL, "env-1")
+	closeFn()
+
+	assert.Eventually(t, func() bool {
+		return len(b.Connections()) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,48 @@
+package stream
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+// Fanout is a Publisher that publishes messages to multiple streams
+type Fanout struct {
+	streams []domain.Stream
+}
+
+// NewFanout creates a Fanout that publishes to all of the passed streams
+func NewFanout(streams ...domain.Stream) Fanout {
+	return Fanout{streams: streams}
+}
+
+// Pub publishes the value to the channel on every stream. A failure to publish
+// to one stream doesn't stop the value being published to the others.
+func (f Fanout) Pub(ctx context.Context, channel string, value interface{}) error {
+	var err error
+	for _, s := range f.streams {
+		if e := s.Pub(ctx, channel, value); e != nil {
+			err = multierror.Append(err, e)
+		}
+	}
+	return err
+}
+
+// Close closes the channel on every stream
+func (f Fanout) Close(channel string) error {
+	var err error
+	for _, s := range f.streams {
+		if e := s.Close(channel); e != nil {
+			err = multierror.Append(err, e)
+		}
+	}
+	return err
+}
+
+// Sub isn't implemented for Fanout
+func (f Fanout) Sub(_ context.Context, _ string, _ string, _ domain.HandleMessageFn) error {
+	return errors.New("Fanout.Sub not implemented")
+}
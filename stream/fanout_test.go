@@ -0,0 +1,60 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+type erroringStream struct {
+	mockStream
+}
+
+func (e *erroringStream) Pub(_ context.Context, _ string, _ interface{}) error {
+	return errors.New("pub error")
+}
+
+func (e *erroringStream) Close(_ string) error {
+	return errors.New("close error")
+}
+
+func TestFanout_Pub(t *testing.T) {
+	testCases := map[string]struct {
+		streams        []domain.Stream
+		shouldErr      bool
+		expectedEvents int
+	}{
+		"Given I have two healthy streams": {
+			streams:        []domain.Stream{&mockStream{}, &mockStream{}},
+			shouldErr:      false,
+			expectedEvents: 1,
+		},
+		"Given one of my streams fails to publish": {
+			streams:        []domain.Stream{&erroringStream{}, &mockStream{}},
+			shouldErr:      true,
+			expectedEvents: 1,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			f := NewFanout(tc.streams...)
+
+			err := f.Pub(context.Background(), "env-1", domain.SSEMessage{Event: "patch"})
+			if tc.shouldErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			// The last stream is always healthy so it should always get the message
+			last := tc.streams[len(tc.streams)-1].(*mockStream)
+			assert.Len(t, last.events, tc.expectedEvents)
+		})
+	}
+}
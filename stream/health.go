@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/harness/ff-proxy/v2/cache"
@@ -247,6 +248,143 @@ func (r ReplicaHealth) Status(_ context.Context) (domain.StreamStatus, error) {
 	return r.inMemStatus.Get(), nil
 }
 
+// KeyHealth tracks the health of the Saas stream for each of the keys that the Proxy
+// is configured with. SDK streams for an environment should only be affected by the
+// health of the key the environment belongs to, so the next Health is set as healthy
+// as soon as any of the keys' streams are healthy and is only set as unhealthy once
+// none of them are.
+type KeyHealth struct {
+	next     Health
+	mtx      *sync.RWMutex
+	statuses map[string]domain.StreamStatus
+}
+
+// NewKeyHealth creates a KeyHealth for the keys
+func NewKeyHealth(next Health, keys []string) *KeyHealth {
+	statuses := make(map[string]domain.StreamStatus, len(keys))
+	for _, key := range keys {
+		statuses[key] = domain.NewStreamStatus()
+	}
+
+	return &KeyHealth{
+		next:     next,
+		mtx:      &sync.RWMutex{},
+		statuses: statuses,
+	}
+}
+
+// ForKey returns the Health of the Saas stream for the key
+func (k *KeyHealth) ForKey(key string) Health {
+	return keyHealth{parent: k, key: key}
+}
+
+// Status returns the status of the Saas stream for the key
+func (k *KeyHealth) Status(key string) domain.StreamStatus {
+	k.mtx.RLock()
+	defer k.mtx.RUnlock()
+	return k.statuses[key]
+}
+
+// Statuses returns the status of the Saas stream for each key
+func (k *KeyHealth) Statuses() map[string]domain.StreamStatus {
+	k.mtx.RLock()
+	defer k.mtx.RUnlock()
+
+	statuses := make(map[string]domain.StreamStatus, len(k.statuses))
+	for key, status := range k.statuses {
+		statuses[key] = status
+	}
+	return statuses
+}
+
+// set updates the status of the key's stream and returns true if the stream
+// for any of the keys is connected
+func (k *KeyHealth) set(key string, state domain.StreamState) bool {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	if k.statuses[key].State != state {
+		k.statuses[key] = domain.StreamStatus{State: state, Since: time.Now().UnixMilli()}
+	}
+
+	for _, status := range k.statuses {
+		if status.State == domain.StreamStateConnected {
+			return true
+		}
+	}
+	return false
+}
+
+type keyHealth struct {
+	parent *KeyHealth
+	key    string
+}
+
+// SetHealthy sets the key's stream as CONNECTED and sets the next Health as healthy
+func (k keyHealth) SetHealthy(ctx context.Context) error {
+	k.parent.set(k.key, domain.StreamStateConnected)
+	return k.parent.next.SetHealthy(ctx)
+}
+
+// SetUnhealthy sets the key's stream as DISCONNECTED and sets the next Health as unhealthy
+// if none of the other keys' streams are connected either
+func (k keyHealth) SetUnhealthy(ctx context.Context) error {
+	if anyConnected := k.parent.set(k.key, domain.StreamStateDisconnected); anyConnected {
+		return nil
+	}
+	return k.parent.next.SetUnhealthy(ctx)
+}
+
+// EnvironmentHealth tracks the environments that belong to keys whose Saas stream
+// the Primary has disconnected from. Read replicas use it so that they only refuse
+// and close SDK streams for those environments.
+type EnvironmentHealth struct {
+	mtx          *sync.RWMutex
+	disconnected map[string]struct{}
+}
+
+// NewEnvironmentHealth creates an EnvironmentHealth
+func NewEnvironmentHealth() *EnvironmentHealth {
+	return &EnvironmentHealth{
+		mtx:          &sync.RWMutex{},
+		disconnected: map[string]struct{}{},
+	}
+}
+
+// SetHealthy marks the Saas stream for the environments as connected
+func (e *EnvironmentHealth) SetHealthy(envs ...string) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	for _, env := range envs {
+		delete(e.disconnected, env)
+	}
+}
+
+// SetUnhealthy marks the Saas stream for the environments as disconnected
+func (e *EnvironmentHealth) SetUnhealthy(envs ...string) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	for _, env := range envs {
+		e.disconnected[env] = struct{}{}
+	}
+}
+
+// Healthy returns false if the Saas stream for the environment is disconnected
+func (e *EnvironmentHealth) Healthy(env string) bool {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+
+	_, disconnected := e.disconnected[env]
+	return !disconnected
+}
+
+// Status returns the status of the key's stream
+func (k keyHealth) Status(_ context.Context) (domain.StreamStatus, error) {
+	return k.parent.Status(k.key), nil
+}
+
 type streamHealthMetrics struct {
 	next     Health
 	gauge    *prometheus.GaugeVec
@@ -332,19 +470,35 @@ func (p PollingStatusMetric) NotPolling() {
 	p.gauge.WithLabelValues(p.hostName).Set(0)
 }
 
+// WithKeyHealth makes the StatusWorker publish the status of each key's stream for
+// the environments that the environments func returns for the key too
+func WithKeyHealth(k *KeyHealth, environments func(key string) []string) func(s *StatusWorker) {
+	return func(s *StatusWorker) {
+		s.keyHealth = k
+		s.environments = environments
+	}
+}
+
 type StatusWorker struct {
-	health Health
-	pub    Stream
-	log    log.Logger
+	health       Health
+	pub          Stream
+	log          log.Logger
+	keyHealth    *KeyHealth
+	environments func(key string) []string
 }
 
-func NewStatusWorker(health Health, pub Stream, logger log.Logger) *StatusWorker {
+func NewStatusWorker(health Health, pub Stream, logger log.Logger, opts ...func(s *StatusWorker)) *StatusWorker {
 	l := logger.With("component", "StreamStatusWorker")
-	return &StatusWorker{
+	s := &StatusWorker{
 		health: health,
 		pub:    pub,
 		log:    l,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *StatusWorker) Start(ctx context.Context) {
@@ -369,6 +523,26 @@ func (s *StatusWorker) Start(ctx context.Context) {
 				continue
 			}
 			s.log.Info(fmt.Sprintf("successfully published %s message for replicas", status.State.String()))
+
+			s.publishKeyStatuses(ctx)
+		}
+	}
+}
+
+// publishKeyStatuses publishes the status of each key's stream for the key's environments
+func (s *StatusWorker) publishKeyStatuses(ctx context.Context) {
+	if s.keyHealth == nil {
+		return
+	}
+
+	for key, status := range s.keyHealth.Statuses() {
+		envs := s.environments(key)
+		if len(envs) == 0 {
+			continue
+		}
+
+		if err := s.pub.Publish(ctx, domain.SSEMessage{Event: "stream_action", Domain: status.State.String(), Environments: envs}); err != nil {
+			s.log.Error(fmt.Sprintf("failed to publish stream %s message for key's environments to redis", status.State.String()), "err", err)
 		}
 	}
 }
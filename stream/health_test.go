@@ -727,3 +727,92 @@ func TestReplicaHealth_SetUnhealthy(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyHealth(t *testing.T) {
+	type step struct {
+		key     string
+		healthy bool
+	}
+
+	testCases := map[string]struct {
+		steps            []step
+		expectedStatuses map[string]domain.StreamState
+		expectedNext     domain.StreamState
+	}{
+		"Given no streams have connected": {
+			steps:            []step{},
+			expectedStatuses: map[string]domain.StreamState{"foo": domain.StreamStateInitializing, "bar": domain.StreamStateInitializing},
+			expectedNext:     domain.StreamStateInitializing,
+		},
+		"Given one of the streams has connected": {
+			steps:            []step{{key: "foo", healthy: true}},
+			expectedStatuses: map[string]domain.StreamState{"foo": domain.StreamStateConnected, "bar": domain.StreamStateInitializing},
+			expectedNext:     domain.StreamStateConnected,
+		},
+		"Given all of the streams have connected": {
+			steps:            []step{{key: "foo", healthy: true}, {key: "bar", healthy: true}},
+			expectedStatuses: map[string]domain.StreamState{"foo": domain.StreamStateConnected, "bar": domain.StreamStateConnected},
+			expectedNext:     domain.StreamStateConnected,
+		},
+		"Given one of the streams disconnects after they've all connected": {
+			steps:            []step{{key: "foo", healthy: true}, {key: "bar", healthy: true}, {key: "foo", healthy: false}},
+			expectedStatuses: map[string]domain.StreamState{"foo": domain.StreamStateDisconnected, "bar": domain.StreamStateConnected},
+			expectedNext:     domain.StreamStateConnected,
+		},
+		"Given all of the streams disconnect after they've all connected": {
+			steps:            []step{{key: "foo", healthy: true}, {key: "bar", healthy: true}, {key: "foo", healthy: false}, {key: "bar", healthy: false}},
+			expectedStatuses: map[string]domain.StreamState{"foo": domain.StreamStateDisconnected, "bar": domain.StreamStateDisconnected},
+			expectedNext:     domain.StreamStateDisconnected,
+		},
+		"Given a stream reconnects while another is disconnected": {
+			steps:            []step{{key: "foo", healthy: false}, {key: "bar", healthy: false}, {key: "foo", healthy: true}},
+			expectedStatuses: map[string]domain.StreamState{"foo": domain.StreamStateConnected, "bar": domain.StreamStateDisconnected},
+			expectedNext:     domain.StreamStateConnected,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			ctx := context.Background()
+
+			next := NewReplicaHealth("stream-health", nil, log.NoOpLogger{})
+			keyHealth := NewKeyHealth(next, []string{"foo", "bar"})
+
+			for _, s := range tc.steps {
+				h := keyHealth.ForKey(s.key)
+				if s.healthy {
+					assert.Nil(t, h.SetHealthy(ctx))
+				} else {
+					assert.Nil(t, h.SetUnhealthy(ctx))
+				}
+			}
+
+			for key, expected := range tc.expectedStatuses {
+				assert.Equal(t, expected, keyHealth.Status(key).State, key)
+
+				status, err := keyHealth.ForKey(key).Status(ctx)
+				assert.Nil(t, err)
+				assert.Equal(t, expected, status.State, key)
+			}
+
+			status, err := next.Status(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedNext, status.State)
+		})
+	}
+}
+
+func TestEnvironmentHealth(t *testing.T) {
+	envHealth := NewEnvironmentHealth()
+	assert.True(t, envHealth.Healthy("foo"))
+
+	envHealth.SetUnhealthy("foo", "bar")
+	assert.False(t, envHealth.Healthy("foo"))
+	assert.False(t, envHealth.Healthy("bar"))
+	assert.True(t, envHealth.Healthy("baz"))
+
+	envHealth.SetHealthy("foo")
+	assert.True(t, envHealth.Healthy("foo"))
+	assert.False(t, envHealth.Healthy("bar"))
+}
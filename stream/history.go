@@ -0,0 +1,157 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+// ErrEventHistoryGap is the error returned when some of the events an SDK missed
+// are no longer in the EventHistory
+var ErrEventHistoryGap = errors.New("events are no longer in the history")
+
+// MemoryEventHistory is an EventHistory that keeps the most recent events for each
+// channel in memory, it's used when the Proxy isn't running with redis
+type MemoryEventHistory struct {
+	size     int
+	mx       *sync.Mutex
+	channels map[string]*eventLog
+}
+
+// eventLog is the history for a single channel
+type eventLog struct {
+	latest int64
+	msgs   []domain.SSEMessage
+}
+
+// NewMemoryEventHistory creates a MemoryEventHistory that keeps up to size events for each channel
+func NewMemoryEventHistory(size int) *MemoryEventHistory {
+	return &MemoryEventHistory{
+		size:     size,
+		mx:       &sync.Mutex{},
+		channels: map[string]*eventLog{},
+	}
+}
+
+// Add makes MemoryEventHistory implement the EventHistory interface
+func (m *MemoryEventHistory) Add(_ context.Context, channel string, msg domain.SSEMessage) (int64, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	l, ok := m.channels[channel]
+	if !ok {
+		l = &eventLog{}
+		m.channels[channel] = l
+	}
+
+	l.latest++
+	msg.EventID = l.latest
+
+	l.msgs = append(l.msgs, msg)
+	if len(l.msgs) > m.size {
+		l.msgs = l.msgs[len(l.msgs)-m.size:]
+	}
+	return l.latest, nil
+}
+
+// Since makes MemoryEventHistory implement the EventHistory interface
+func (m *MemoryEventHistory) Since(_ context.Context, channel string, id int64) ([]domain.SSEMessage, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	l, ok := m.channels[channel]
+	if !ok {
+		l = &eventLog{}
+	}
+
+	if id == l.latest {
+		return []domain.SSEMessage{}, nil
+	}
+
+	oldest := l.latest - int64(len(l.msgs)) + 1
+	if id > l.latest || id+1 < oldest {
+		return nil, fmt.Errorf("%w: channel=%s, id=%d, oldest=%d, latest=%d", ErrEventHistoryGap, channel, id, oldest, l.latest)
+	}
+
+	missed := l.msgs[id+1-oldest:]
+	result := make([]domain.SSEMessage, len(missed))
+	copy(result, missed)
+	return result, nil
+}
+
+// Latest makes MemoryEventHistory implement the EventHistory interface
+func (m *MemoryEventHistory) Latest(_ context.Context, channel string) (int64, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	l, ok := m.channels[channel]
+	if !ok {
+		return 0, nil
+	}
+	return l.latest, nil
+}
+
+// Replay returns the events to send to an SDK that's reconnected to a channel after
+// receiving the event with the lastEventID. If the SDK missed events that can't be
+// replayed, or we fail to get them, it returns a single EventRefetchAll event instead.
+func Replay(ctx context.Context, h domain.EventHistory, channel string, lastEventID string) ([]domain.SSEMessage, error) {
+	if lastEventID == "" {
+		return []domain.SSEMessage{}, nil
+	}
+
+	id, err := strconv.ParseInt(lastEventID, 10, 64)
+	if err != nil {
+		return refetchAll(ctx, h, channel), nil
+	}
+
+	msgs, err := h.Since(ctx, channel, id)
+	if err != nil {
+		if errors.Is(err, ErrEventHistoryGap) {
+			return refetchAll(ctx, h, channel), nil
+		}
+		return refetchAll(ctx, h, channel), err
+	}
+	return msgs, nil
+}
+
+// refetchAll creates an EventRefetchAll event with the latest ID for the channel so
+// that the SDK picks up from there if it reconnects again
+func refetchAll(ctx context.Context, h domain.EventHistory, channel string) []domain.SSEMessage {
+	// If we can't get the latest ID we still want the SDK to refetch
+	latest, _ := h.Latest(ctx, channel)
+
+	return []domain.SSEMessage{
+		{
+			Event:       domain.EventRefetchAll,
+			Domain:      domain.MsgDomainProxy,
+			Environment: channel,
+			EventID:     latest,
+		},
+	}
+}
+
+// FormatEvent formats the JSON encoded data as an SSE event, the id is only
+// included if it's been set
+func FormatEvent(id int64, data []byte) []byte {
+	if id > 0 {
+		return []byte(fmt.Sprintf("id: %d\nevent: *\ndata: %s\n\n", id, data))
+	}
+	return []byte(fmt.Sprintf("event: *\ndata: %s\n\n", data))
+}
+
+// eventID returns the EventID of the value if it's an SSEMessage
+func eventID(value interface{}) int64 {
+	switch v := value.(type) {
+	case domain.SSEMessage:
+		return v.EventID
+	case *domain.SSEMessage:
+		if v != nil {
+			return v.EventID
+		}
+	}
+	return 0
+}
@@ -0,0 +1,241 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+type mockEventHistory struct {
+	domain.EventHistory
+	since  func(id int64) ([]domain.SSEMessage, error)
+	latest int64
+}
+
+func (m mockEventHistory) Since(_ context.Context, _ string, id int64) ([]domain.SSEMessage, error) {
+	return m.since(id)
+}
+
+func (m mockEventHistory) Latest(_ context.Context, _ string) (int64, error) {
+	return m.latest, nil
+}
+
+func newFlagEvent(identifier string) domain.SSEMessage {
+	return domain.SSEMessage{Event: domain.EventPatch, Domain: domain.MsgDomainFeature, Identifier: identifier, Environment: "env-1"}
+}
+
+func TestEventHistory(t *testing.T) {
+	histories := map[string]func(t *testing.T) domain.EventHistory{
+		"MemoryEventHistory": func(t *testing.T) domain.EventHistory {
+			return NewMemoryEventHistory(3)
+		},
+		"RedisEventHistory": func(t *testing.T) domain.EventHistory {
+			mr := miniredis.RunT(t)
+			return NewRedisEventHistory(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test", 3)
+		},
+	}
+
+	withID := func(msg domain.SSEMessage, id int64) domain.SSEMessage {
+		msg.EventID = id
+		return msg
+	}
+
+	testCases := map[string]struct {
+		added       []domain.SSEMessage
+		since       int64
+		expected    []domain.SSEMessage
+		expectedErr error
+	}{
+		"Given nothing has been published to the channel": {
+			added:    []domain.SSEMessage{},
+			since:    0,
+			expected: []domain.SSEMessage{},
+		},
+		"Given the SDK has seen the latest event": {
+			added:    []domain.SSEMessage{newFlagEvent("foo"), newFlagEvent("bar")},
+			since:    2,
+			expected: []domain.SSEMessage{},
+		},
+		"Given the SDK missed events that are in the history": {
+			added:    []domain.SSEMessage{newFlagEvent("foo"), newFlagEvent("bar"), newFlagEvent("baz")},
+			since:    1,
+			expected: []domain.SSEMessage{withID(newFlagEvent("bar"), 2), withID(newFlagEvent("baz"), 3)},
+		},
+		"Given the SDK missed the oldest event in the history": {
+			added:    []domain.SSEMessage{newFlagEvent("foo"), newFlagEvent("bar"), newFlagEvent("baz"), newFlagEvent("qux")},
+			since:    1,
+			expected: []domain.SSEMessage{withID(newFlagEvent("bar"), 2), withID(newFlagEvent("baz"), 3), withID(newFlagEvent("qux"), 4)},
+		},
+		"Given the SDK missed events that are no longer in the history": {
+			added:       []domain.SSEMessage{newFlagEvent("foo"), newFlagEvent("bar"), newFlagEvent("baz"), newFlagEvent("qux"), newFlagEvent("quux")},
+			since:       1,
+			expectedErr: ErrEventHistoryGap,
+		},
+		"Given the SDK has an ID that's newer than the latest event": {
+			added:       []domain.SSEMessage{newFlagEvent("foo")},
+			since:       10,
+			expectedErr: ErrEventHistoryGap,
+		},
+	}
+
+	for name, newHistory := range histories {
+		newHistory := newHistory
+		t.Run(name, func(t *testing.T) {
+			for desc, tc := range testCases {
+				tc := tc
+				t.Run(desc, func(t *testing.T) {
+					ctx := context.Background()
+					h := newHistory(t)
+
+					for i, msg := range tc.added {
+						id, err := h.Add(ctx, "env-1", msg)
+						require.NoError(t, err)
+						assert.Equal(t, int64(i+1), id)
+					}
+
+					// Events on other channels have their own IDs
+					id, err := h.Add(ctx, "env-2", newFlagEvent("foo"))
+					require.NoError(t, err)
+					assert.Equal(t, int64(1), id)
+
+					latest, err := h.Latest(ctx, "env-1")
+					require.NoError(t, err)
+					assert.Equal(t, int64(len(tc.added)), latest)
+
+					actual, err := h.Since(ctx, "env-1", tc.since)
+					if tc.expectedErr != nil {
+						assert.ErrorIs(t, err, tc.expectedErr)
+						return
+					}
+
+					require.NoError(t, err)
+					assert.Equal(t, tc.expected, actual)
+				})
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	refetchAll := []domain.SSEMessage{{Event: domain.EventRefetchAll, Domain: domain.MsgDomainProxy, Environment: "env-1", EventID: 5}}
+
+	testCases := map[string]struct {
+		lastEventID string
+		since       func(id int64) ([]domain.SSEMessage, error)
+		expected    []domain.SSEMessage
+		shouldErr   bool
+	}{
+		"Given the SDK didn't send a Last-Event-ID": {
+			lastEventID: "",
+			expected:    []domain.SSEMessage{},
+		},
+		"Given the SDK sent a Last-Event-ID that isn't a number": {
+			lastEventID: "foo",
+			expected:    refetchAll,
+		},
+		"Given the SDK missed events that are in the history": {
+			lastEventID: "4",
+			since: func(id int64) ([]domain.SSEMessage, error) {
+				assert.Equal(t, int64(4), id)
+				return []domain.SSEMessage{{Identifier: "foo", EventID: 5}}, nil
+			},
+			expected: []domain.SSEMessage{{Identifier: "foo", EventID: 5}},
+		},
+		"Given the SDK missed events that are no longer in the history": {
+			lastEventID: "1",
+			since: func(id int64) ([]domain.SSEMessage, error) {
+				return nil, ErrEventHistoryGap
+			},
+			expected: refetchAll,
+		},
+		"Given the history errors": {
+			lastEventID: "1",
+			since: func(id int64) ([]domain.SSEMessage, error) {
+				return nil, errors.New("redis down")
+			},
+			expected:  refetchAll,
+			shouldErr: true,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			h := mockEventHistory{since: tc.since, latest: 5}
+
+			actual, err := Replay(context.Background(), h, "env-1", tc.lastEventID)
+			if tc.shouldErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+// TestRedisEventHistory_LostIDKey checks that events can still be added if the key holding
+// the latest event ID is lost while the stream is kept
+func TestRedisEventHistory_LostIDKey(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	history := NewRedisEventHistory(rc, "test", 3)
+
+	for _, identifier := range []string{"foo", "bar"} {
+		_, err := history.Add(ctx, "env-1", newFlagEvent(identifier))
+		require.NoError(t, err)
+	}
+
+	_, idKey := history.keys("env-1")
+	require.NoError(t, rc.Del(ctx, idKey).Err())
+
+	id, err := history.Add(ctx, "env-1", newFlagEvent("baz"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), id)
+
+	latest, err := history.Latest(ctx, "env-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), latest)
+
+	msgs, err := history.Since(ctx, "env-1", 1)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "bar", msgs[0].Identifier)
+	assert.Equal(t, "baz", msgs[1].Identifier)
+}
+
+func TestNamespaceEventHistoryKey(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	_, err := NewRedisEventHistory(rc, "", 3).Add(ctx, "env-1", newFlagEvent("foo"))
+	require.NoError(t, err)
+
+	keys, err := rc.Keys(ctx, EventHistoryKeyPattern).Result()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	for _, key := range keys {
+		require.NoError(t, rc.Rename(ctx, key, NamespaceEventHistoryKey("test", key)).Err())
+	}
+
+	history := NewRedisEventHistory(rc, "test", 3)
+
+	latest, err := history.Latest(ctx, "env-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), latest)
+
+	id, err := history.Add(ctx, "env-1", newFlagEvent("bar"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+}
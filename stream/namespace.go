@@ -0,0 +1,43 @@
+package stream
+
+import (
+	"context"
+
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+// NamespaceStream is a Stream decorator that prefixes every channel with a
+// namespace so that multiple Proxy deployments can share the same redis
+type NamespaceStream struct {
+	namespace string
+	next      domain.Stream
+}
+
+// NewNamespaceStream creates a NamespaceStream
+func NewNamespaceStream(namespace string, next domain.Stream) NamespaceStream {
+	return NamespaceStream{namespace: namespace, next: next}
+}
+
+// Pub publishes the value to the namespaced channel
+func (n NamespaceStream) Pub(ctx context.Context, channel string, value interface{}) error {
+	return n.next.Pub(ctx, domain.NewNamespacedKey(n.namespace, channel), value)
+}
+
+// Sub subscribes to the namespaced channel
+func (n NamespaceStream) Sub(ctx context.Context, channel string, id string, handleMessage domain.HandleMessageFn) error {
+	return n.next.Sub(ctx, domain.NewNamespacedKey(n.namespace, channel), id, handleMessage)
+}
+
+// Close closes the namespaced channel
+func (n NamespaceStream) Close(channel string) error {
+	return n.next.Close(domain.NewNamespacedKey(n.namespace, channel))
+}
+
+// Ack acks the messages on the namespaced channel if the decorated stream supports acks
+func (n NamespaceStream) Ack(ctx context.Context, channel string, ids ...string) error {
+	a, ok := n.next.(domain.Acker)
+	if !ok {
+		return nil
+	}
+	return a.Ack(ctx, domain.NewNamespacedKey(n.namespace, channel), ids...)
+}
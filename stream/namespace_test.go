@@ -0,0 +1,31 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceStream_Pub(t *testing.T) {
+	m := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{
+		Addr: m.Addr(),
+	})
+
+	ns := NewNamespaceStream("staging", NewRedisStream(rc))
+	assert.Nil(t, ns.Pub(context.Background(), "test-stream", "foo"))
+
+	assert.Equal(t, []string{"staging:test-stream"}, m.Keys())
+
+	xs, err := rc.XRead(context.Background(), &redis.XReadArgs{
+		Streams: []string{"staging:test-stream", "0"},
+		Count:   0,
+		Block:   0,
+	}).Result()
+	assert.Nil(t, err)
+	assert.Len(t, xs, 1)
+	assert.Equal(t, map[string]interface{}{"event": "foo"}, xs[0].Messages[0].Values)
+}
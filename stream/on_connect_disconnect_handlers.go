@@ -14,6 +14,10 @@ type pollFn func() error
 // getConnectedStreamsFn defines the function that returns the names of open streams between the Proxy & SDKs
 type getConnectedStreamsFn func() map[string]interface{}
 
+// getEnvironmentsFn defines the function that returns the environments whose Saas stream the handlers are for.
+// If it returns no environments the stream is for all of the environments.
+type getEnvironmentsFn func() []string
+
 type pollingStatus interface {
 	Polling()
 	NotPolling()
@@ -23,8 +27,8 @@ type pollingStatus interface {
 // - Sets the status of the SaaS stream in the cache to unhealthy, this means any new /stream requests to writer or read proxy's will be rejects
 // - Polls saas for the latest config and refreshes the cache with any changes
 // - Closes any 'Write Replica' Proxy -> SDK streams
-// - Notifies 'read replica' proxy's that there's been a disconnection between the 'Write replica' and SaaS
-func SaasStreamOnDisconnect(l log.Logger, streamHealth Health, pp Pushpin, redisSSEStream Stream, streams getConnectedStreamsFn, pollFn pollFn, pollingStatus pollingStatus) func() {
+// - Notifies 'read replica' proxy's that there's been a disconnection between the 'Write replica' and SaaS for the environments
+func SaasStreamOnDisconnect(l log.Logger, streamHealth Health, pp domain.Closer, redisSSEStream Stream, environments getEnvironmentsFn, streams getConnectedStreamsFn, pollFn pollFn, pollingStatus pollingStatus) func() {
 	return func() {
 		l.Info("disconnected from Harness SaaS SSE Stream")
 
@@ -60,7 +64,7 @@ func SaasStreamOnDisconnect(l log.Logger, streamHealth Health, pp Pushpin, redis
 		// Publish an event to the redis stream that the read replica proxy's are listening on to let them
 		// know we've disconnected from SaaS.
 		l.Info("publishing disconnected message for replicas")
-		if err := redisSSEStream.Publish(ctx, domain.SSEMessage{Event: "stream_action", Domain: domain.StreamStateDisconnected.String()}); err != nil {
+		if err := redisSSEStream.Publish(ctx, domain.SSEMessage{Event: "stream_action", Domain: domain.StreamStateDisconnected.String(), Environments: environments()}); err != nil {
 			l.Error("failed to publish stream disconnected message to redis", "err", err)
 			return
 		}
@@ -70,7 +74,7 @@ func SaasStreamOnDisconnect(l log.Logger, streamHealth Health, pp Pushpin, redis
 }
 
 // SaasStreamOnConnect sets the status of the SaaS stream to healthy in the cache
-func SaasStreamOnConnect(l log.Logger, streamHealth Health, reloadConfig func() error, redisSSEStream Stream, pollingStatus pollingStatus) func() {
+func SaasStreamOnConnect(l log.Logger, streamHealth Health, reloadConfig func() error, redisSSEStream Stream, environments getEnvironmentsFn, pollingStatus pollingStatus) func() {
 	return func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 		defer cancel()
@@ -108,7 +112,7 @@ func SaasStreamOnConnect(l log.Logger, streamHealth Health, reloadConfig func()
 		// Publish an event to the redis stream that the read replica proxy's are listening on to let them
 		// know we've connected to SaaS.
 		l.Info("publishing stream connected message for replicas")
-		if err := redisSSEStream.Publish(ctx, domain.SSEMessage{Event: "stream_action", Domain: domain.StreamStateConnected.String()}); err != nil {
+		if err := redisSSEStream.Publish(ctx, domain.SSEMessage{Event: "stream_action", Domain: domain.StreamStateConnected.String(), Environments: environments()}); err != nil {
 			l.Error("failed to publish stream connect message to redis", "err", err)
 			return
 		}
@@ -66,6 +66,7 @@ func TestSaasStreamOnDisconnect(t *testing.T) {
 		pushpin Pushpin
 		stream  *mockStream
 
+		environmentsFunc     func() []string
 		connectedStreamsFunc func() map[string]interface{}
 		pollFn               func() error
 	}
@@ -90,6 +91,9 @@ func TestSaasStreamOnDisconnect(t *testing.T) {
 				pollFn: func() error {
 					return nil
 				},
+				environmentsFunc: func() []string {
+					return nil
+				},
 				connectedStreamsFunc: func() map[string]interface{} {
 					return map[string]interface{}{"foo": struct{}{}}
 				},
@@ -101,6 +105,31 @@ func TestSaasStreamOnDisconnect(t *testing.T) {
 				streamHealth: false,
 			},
 		},
+		"Given I have a healthy streams status and disconnect from the Saas stream for a key's environments": {
+			mocks: mocks{
+				health: &mockHealth{
+					Mutex:   &sync.Mutex{},
+					healthy: true,
+				},
+				pushpin: Pushpin{stream: &mockGripStream{}},
+				stream:  &mockStream{events: []interface{}{}},
+				pollFn: func() error {
+					return nil
+				},
+				environmentsFunc: func() []string {
+					return []string{"foo", "bar"}
+				},
+				connectedStreamsFunc: func() map[string]interface{} {
+					return map[string]interface{}{"foo": struct{}{}}
+				},
+			},
+			expected: expected{
+				events: []interface{}{
+					domain.SSEMessage{Event: "stream_action", Domain: domain.StreamStateDisconnected.String(), Environments: []string{"foo", "bar"}},
+				},
+				streamHealth: false,
+			},
+		},
 	}
 
 	for desc, tc := range testCases {
@@ -118,7 +147,7 @@ func TestSaasStreamOnDisconnect(t *testing.T) {
 
 			ps := NewPollingStatusMetric(prometheus.NewRegistry())
 
-			SaasStreamOnDisconnect(log.NoOpLogger{}, tc.mocks.health, tc.mocks.pushpin, redisStream, tc.mocks.connectedStreamsFunc, tc.mocks.pollFn, ps)()
+			SaasStreamOnDisconnect(log.NoOpLogger{}, tc.mocks.health, tc.mocks.pushpin, redisStream, tc.mocks.environmentsFunc, tc.mocks.connectedStreamsFunc, tc.mocks.pollFn, ps)()
 
 			t.Log("Then the stream status will become unhealthy")
 			assert.Equal(t, tc.expected.streamHealth, tc.mocks.health.getHealth())
@@ -134,7 +163,8 @@ func TestSaasStreamOnConnect(t *testing.T) {
 		health *mockHealth
 		stream *mockStream
 
-		reloadConfig func() error
+		environmentsFunc func() []string
+		reloadConfig     func() error
 	}
 
 	type expected struct {
@@ -153,6 +183,9 @@ func TestSaasStreamOnConnect(t *testing.T) {
 					healthy: false,
 				},
 				stream: &mockStream{events: []interface{}{}},
+				environmentsFunc: func() []string {
+					return nil
+				},
 				reloadConfig: func() error {
 					return nil
 				},
@@ -180,7 +213,7 @@ func TestSaasStreamOnConnect(t *testing.T) {
 			)
 			ps := NewPollingStatusMetric(prometheus.NewRegistry())
 
-			SaasStreamOnConnect(log.NoOpLogger{}, tc.mocks.health, tc.mocks.reloadConfig, redisStream, ps)()
+			SaasStreamOnConnect(log.NoOpLogger{}, tc.mocks.health, tc.mocks.reloadConfig, redisStream, tc.mocks.environmentsFunc, ps)()
 
 			t.Log("Then the stream status will become healthy")
 			assert.Equal(t, tc.expected.streamHealth, tc.mocks.health.getHealth())
@@ -52,6 +52,18 @@ func NewPrometheusStream(name string, next domain.Stream, reg *prometheus.Regist
 	return p
 }
 
+// Decorate returns a PrometheusStream for next that records its metrics in the same
+// collectors as p. It's used when there's more than one stream of the same kind, e.g.
+// a Saas stream for each of the Proxy's keys, because collectors can only be registered once.
+func (p *PrometheusStream) Decorate(next domain.Stream) *PrometheusStream {
+	return &PrometheusStream{
+		next:              next,
+		messagesPublished: p.messagesPublished,
+		messagesReceived:  p.messagesReceived,
+		subscriptions:     p.subscriptions,
+	}
+}
+
 // Pub calls the decorated streams Pub method and increments a prometheus metric for each message published
 func (p *PrometheusStream) Pub(ctx context.Context, channel string, msg interface{}) (err error) {
 	defer func() {
@@ -75,6 +87,15 @@ func (p *PrometheusStream) Sub(ctx context.Context, channel string, id string, m
 	})
 }
 
+// Ack calls the decorated streams Ack method if it supports acks
+func (p *PrometheusStream) Ack(ctx context.Context, channel string, ids ...string) error {
+	a, ok := p.next.(domain.Acker)
+	if !ok {
+		return nil
+	}
+	return a.Ack(ctx, channel, ids...)
+}
+
 func parseErrorLabel(err error) string {
 	if err == nil {
 		return "false"
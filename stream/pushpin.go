@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/fanout/go-gripcontrol"
 	jsoniter "github.com/json-iterator/go"
@@ -44,9 +45,14 @@ func (p Pushpin) Pub(_ context.Context, channel string, value interface{}) error
 		return fmt.Errorf("%w: failed to marshal message to bytes: %s", ErrPublishing, err)
 	}
 
-	content := fmt.Sprintf("event: *\ndata: %s\n\n", b)
+	// The event's id is included in the content for SDKs and passed on to Pushpin
+	var id string
+	eid := eventID(value)
+	if eid > 0 {
+		id = strconv.FormatInt(eid, 10)
+	}
 
-	if err := p.stream.PublishHttpStream(channel, content, "", ""); err != nil {
+	if err := p.stream.PublishHttpStream(channel, string(FormatEvent(eid, b)), id, ""); err != nil {
 		return fmt.Errorf("PushpinStream: %w: %s", ErrPublishing, err)
 	}
 	return nil
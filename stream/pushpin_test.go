@@ -8,6 +8,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/fanout/go-pubcontrol"
+
+	"github.com/harness/ff-proxy/v2/domain"
 )
 
 type mockGripStream struct {
@@ -79,3 +81,54 @@ func TestPushpin_Pub(t *testing.T) {
 		})
 	}
 }
+
+type recordingGripStream struct {
+	mockGripStream
+	content interface{}
+	id      string
+}
+
+func (r *recordingGripStream) PublishHttpStream(channel string, content interface{}, id string, prevID string) error {
+	r.content = content
+	r.id = id
+	return nil
+}
+
+func TestPushpin_PubEventID(t *testing.T) {
+	testCases := map[string]struct {
+		message         domain.SSEMessage
+		expectedID      string
+		expectedContent string
+	}{
+		"Given I publish a message without an EventID": {
+			message:    domain.SSEMessage{Event: "patch", Domain: "flag", Identifier: "foo", Environment: "env-1"},
+			expectedID: "",
+			expectedContent: `event: *
+data: {"event":"patch","domain":"flag","identifier":"foo","version":0,"environment":"env-1","apiKey":""}
+
+`,
+		},
+		"Given I publish a message with an EventID": {
+			message:    domain.SSEMessage{Event: "patch", Domain: "flag", Identifier: "foo", Environment: "env-1", EventID: 3},
+			expectedID: "3",
+			expectedContent: `id: 3
+event: *
+data: {"event":"patch","domain":"flag","identifier":"foo","version":0,"environment":"env-1","apiKey":"","eventId":3}
+
+`,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			gs := &recordingGripStream{}
+
+			p := NewPushpin(gs)
+			assert.Nil(t, p.Pub(context.Background(), "env-1", tc.message))
+
+			assert.Equal(t, tc.expectedID, gs.id)
+			assert.Equal(t, tc.expectedContent, gs.content)
+		})
+	}
+}
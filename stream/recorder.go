@@ -0,0 +1,56 @@
+package stream
+
+import (
+	"context"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+// EventRecorder is a MessageHandler that adds the flag and target group messages
+// that get sent on to SDKs to an EventHistory before calling the next handler.
+// Messages are recorded by the Proxy they come into so that they keep the same
+// ID when they're forwarded on to read replicas.
+type EventRecorder struct {
+	log     log.Logger
+	history domain.EventHistory
+	next    domain.MessageHandler
+}
+
+// NewEventRecorder creates an EventRecorder
+func NewEventRecorder(l log.Logger, h domain.EventHistory, next domain.MessageHandler) EventRecorder {
+	l = l.With("component", "EventRecorder")
+	return EventRecorder{
+		log:     l,
+		history: h,
+		next:    next,
+	}
+}
+
+// HandleMessage makes EventRecorder implement the MessageHandler interface
+func (e EventRecorder) HandleMessage(ctx context.Context, msg domain.SSEMessage) error {
+	return e.next.HandleMessage(ctx, e.Record(ctx, msg))
+}
+
+// Record adds the message to the EventHistory and returns it with its EventID set.
+// Messages that already have an ID or that aren't sent to SDKs are returned as is.
+func (e EventRecorder) Record(ctx context.Context, msg domain.SSEMessage) domain.SSEMessage {
+	if msg.EventID != 0 || msg.Environment == "" {
+		return msg
+	}
+
+	if msg.Domain != domain.MsgDomainFeature && msg.Domain != domain.MsgDomainSegment {
+		return msg
+	}
+
+	// Failing to record the event shouldn't stop it being sent to SDKs, they'll
+	// just be told to refetch everything if they need it replayed
+	id, err := e.history.Add(ctx, msg.Environment, msg)
+	if err != nil {
+		e.log.Warn("failed to add event to history", "environment", msg.Environment, "err", err)
+		return msg
+	}
+
+	msg.EventID = id
+	return msg
+}
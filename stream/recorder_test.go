@@ -0,0 +1,73 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+type recordingMessageHandler struct {
+	msgs []domain.SSEMessage
+}
+
+func (m *recordingMessageHandler) HandleMessage(_ context.Context, msg domain.SSEMessage) error {
+	m.msgs = append(m.msgs, msg)
+	return nil
+}
+
+func TestEventRecorder_HandleMessage(t *testing.T) {
+	testCases := map[string]struct {
+		msg        domain.SSEMessage
+		expectedID int64
+	}{
+		"Given a flag message": {
+			msg:        domain.SSEMessage{Event: domain.EventPatch, Domain: domain.MsgDomainFeature, Identifier: "foo", Environment: "env-1"},
+			expectedID: 1,
+		},
+		"Given a target group message": {
+			msg:        domain.SSEMessage{Event: domain.EventPatch, Domain: domain.MsgDomainSegment, Identifier: "foo", Environment: "env-1"},
+			expectedID: 1,
+		},
+		"Given a message that's already been recorded": {
+			msg:        domain.SSEMessage{Event: domain.EventPatch, Domain: domain.MsgDomainFeature, Identifier: "foo", Environment: "env-1", EventID: 10},
+			expectedID: 10,
+		},
+		"Given a message that isn't sent to SDKs": {
+			msg:        domain.SSEMessage{Event: domain.EventAPIKeyAdded, Domain: domain.MsgDomainProxy, Environment: "env-1"},
+			expectedID: 0,
+		},
+		"Given a message without an environment": {
+			msg:        domain.SSEMessage{Event: domain.EventEnvironmentRemoved, Domain: domain.MsgDomainProxy, Environments: []string{"env-1"}},
+			expectedID: 0,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			ctx := context.Background()
+			history := NewMemoryEventHistory(10)
+			next := &recordingMessageHandler{}
+
+			recorder := NewEventRecorder(log.NoOpLogger{}, history, next)
+			assert.Nil(t, recorder.HandleMessage(ctx, tc.msg))
+
+			expected := tc.msg
+			expected.EventID = tc.expectedID
+			assert.Equal(t, []domain.SSEMessage{expected}, next.msgs)
+
+			// Only messages that the recorder gave an ID should be in the history
+			latest, err := history.Latest(ctx, "env-1")
+			assert.Nil(t, err)
+			if tc.msg.EventID == 0 && tc.expectedID != 0 {
+				assert.Equal(t, tc.expectedID, latest)
+			} else {
+				assert.Equal(t, int64(0), latest)
+			}
+		})
+	}
+}
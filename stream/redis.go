@@ -6,10 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 
 	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
 )
 
 // WithMaxLen sets the max length for a redis stream
@@ -19,17 +23,77 @@ func WithMaxLen(i int64) func(r *RedisStream) {
 	}
 }
 
+// WithConsumerGroup makes Sub read from the stream as the consumer in a consumer group, so
+// each message is only handled by one of the consumers in the group
+func WithConsumerGroup(group string, consumer string) func(r *RedisStream) {
+	return func(r *RedisStream) {
+		r.group = group
+		r.consumer = consumer
+	}
+}
+
+// WithClaimMinIdle sets how long a message in a consumer group can go without being acked
+// before another consumer claims it. It's also how often consumers check for these messages.
+func WithClaimMinIdle(d time.Duration) func(r *RedisStream) {
+	return func(r *RedisStream) {
+		r.claimMinIdle = d
+	}
+}
+
+// WithManualAck stops Sub from acking the messages in a consumer group once they've been handled.
+// They're left pending until they're acked with Ack, which lets the caller ack messages once it's
+// finished with them rather than once it's received them.
+func WithManualAck() func(r *RedisStream) {
+	return func(r *RedisStream) {
+		r.manualAck = true
+	}
+}
+
+// WithMaxDeliveries sets how many times a message in a consumer group is delivered to consumers
+// before it's moved to the stream's dead letter stream
+func WithMaxDeliveries(i int64) func(r *RedisStream) {
+	return func(r *RedisStream) {
+		r.maxDeliveries = i
+	}
+}
+
+// WithLogger sets the logger the RedisStream uses to log messages it moves to a dead letter stream
+func WithLogger(l log.Logger) func(r *RedisStream) {
+	return func(r *RedisStream) {
+		r.log = l
+	}
+}
+
 // RedisStream is a implementation of the Stream interface that is used for interacting with redis streams
 type RedisStream struct {
-	client redis.UniversalClient
-	maxLen int64
+	client        redis.UniversalClient
+	log           log.Logger
+	maxLen        int64
+	group         string
+	consumer      string
+	claimMinIdle  time.Duration
+	maxDeliveries int64
+	manualAck     bool
+
+	// positions holds the ID of the last message handled on each stream so that
+	// subscriptions can pick up where they left off if they're dropped
+	positions *sync.Map
+
+	// recovered holds the streams whose pending messages this consumer has already
+	// read since it started
+	recovered *sync.Map
 }
 
 // NewRedisStream creates a new redis streams client
 func NewRedisStream(u redis.UniversalClient, opts ...func(r *RedisStream)) RedisStream {
 	r := &RedisStream{
-		client: u,
-		maxLen: 1000, // Default to 1000 if not set
+		client:        u,
+		log:           log.NoOpLogger{},
+		maxLen:        1000, // Default to 1000 if not set
+		claimMinIdle:  1 * time.Minute,
+		maxDeliveries: 5,
+		positions:     &sync.Map{},
+		recovered:     &sync.Map{},
 	}
 
 	for _, opt := range opts {
@@ -67,12 +131,23 @@ func (r RedisStream) Pub(ctx context.Context, stream string, v interface{}) erro
 	return nil
 }
 
-// Sub subscribes to a redis stream starting at the id provided. If an id isn't provided then it will start at the last
-// message on the stream. Sub only exits if there is an error communicating with
-// redis or the context has been cancelled by the caller.
+// Sub subscribes to a redis stream starting at the id provided. If an id isn't provided then it will start after the
+// last message this RedisStream handled on the stream, or at the last message on the stream if it hasn't handled any,
+// so that resubscribing doesn't skip messages published while we were disconnected. Sub only exits if there is an
+// error communicating with redis or the context has been cancelled by the caller.
+//
+// If the RedisStream is using a consumer group the id is ignored and the group keeps track of what's been handled.
 func (r RedisStream) Sub(ctx context.Context, stream string, id string, handleMessage domain.HandleMessageFn) error {
+	if r.group != "" {
+		return r.subGroup(ctx, stream, handleMessage)
+	}
+
 	if id == "" {
-		id = "$"
+		var err error
+		id, err = r.position(ctx, stream)
+		if err != nil {
+			return fmt.Errorf("RedisStream: %w: %s", ErrSubscribing, err)
+		}
 	}
 
 	for {
@@ -101,10 +176,252 @@ func (r RedisStream) Sub(ctx context.Context, stream string, id string, handleMe
 					}
 
 					id = msg.ID
+					r.positions.Store(stream, id)
+				}
+			}
+		}
+	}
+}
+
+// position returns the ID to start reading the stream from when Sub isn't given one. It's the
+// ID of the last message we handled or, the first time we subscribe, the last message on the stream.
+func (r RedisStream) position(ctx context.Context, stream string) (string, error) {
+	if id, ok := r.positions.Load(stream); ok {
+		return id.(string), nil
+	}
+
+	xs, err := r.client.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", err
+	}
+
+	id := "0-0"
+	if len(xs) > 0 {
+		id = xs[0].ID
+	}
+
+	actual, _ := r.positions.LoadOrStore(stream, id)
+	return actual.(string), nil
+}
+
+// subGroup reads from the stream as a consumer in the RedisStream's consumer group. Messages are acked once
+// they've been handled, messages that aren't are left pending and are claimed by a consumer once they've been
+// idle for the claimMinIdle duration.
+func (r RedisStream) subGroup(ctx context.Context, stream string, handleMessage domain.HandleMessageFn) error {
+	err := r.client.XGroupCreateMkStream(ctx, stream, r.group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("RedisStream: %w: failed to create consumer group: %s", ErrSubscribing, err)
+	}
+
+	// Start with any messages we were given but didn't ack before we were last disconnected
+	// and then move on to new messages
+	id := "0"
+
+	// If the caller acks messages they're left pending while it's still working on them, so
+	// when we resubscribe we only start with the pending messages from before we started.
+	// Pending messages that the caller doesn't ack are claimed once they've been idle.
+	if r.manualAck {
+		if _, loaded := r.recovered.LoadOrStore(stream, true); loaded {
+			id = ">"
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			if id == ">" {
+				msgs, err := r.claim(ctx, stream)
+				if err != nil {
+					return fmt.Errorf("RedisStream: %w: %s", ErrSubscribing, err)
+				}
+
+				if err := r.handleGroupMessages(ctx, stream, msgs, true, handleMessage); err != nil {
+					return err
+				}
+
+				// Check the context again before we block waiting for new messages
+				if len(msgs) > 0 {
+					continue
+				}
+			}
+
+			xs, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+				Group:    r.group,
+				Consumer: r.consumer,
+				Streams:  []string{stream, id},
+				Count:    0,
+				Block:    r.claimMinIdle,
+			}).Result()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return fmt.Errorf("RedisStream: %w: %s", ErrSubscribing, err)
+			}
+
+			for _, x := range xs {
+				if err := r.handleGroupMessages(ctx, stream, x.Messages, id != ">", handleMessage); err != nil {
+					return err
+				}
+			}
+
+			id = ">"
+		}
+	}
+}
+
+// claim takes the messages that other consumers in the group have left pending for longer than claimMinIdle
+func (r RedisStream) claim(ctx context.Context, stream string) ([]redis.XMessage, error) {
+	claimed := []redis.XMessage{}
+
+	start := "0-0"
+	for {
+		msgs, next, err := r.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    r.group,
+			Consumer: r.consumer,
+			MinIdle:  r.claimMinIdle,
+			Start:    start,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim pending messages: %s", err)
+		}
+
+		claimed = append(claimed, msgs...)
+
+		if next == "0-0" {
+			return claimed, nil
+		}
+		start = next
+	}
+}
+
+// handleGroupMessages handles and acks each message. If handling a message fails it's left pending so that
+// it can be claimed and retried. Once a message has been delivered maxDeliveries times without being acked
+// it's moved to the dead letter stream so that it isn't retried forever. Messages that have been delivered
+// before, e.g. ones that we've claimed, are checked before they're handled since they might have been handled
+// successfully each time they were delivered but not acked by a caller using manual acks.
+func (r RedisStream) handleGroupMessages(ctx context.Context, stream string, msgs []redis.XMessage, redelivered bool, handleMessage domain.HandleMessageFn) error {
+	for _, msg := range msgs {
+		if redelivered {
+			deliveries, err := r.deliveries(ctx, stream, msg.ID)
+			if err != nil {
+				return fmt.Errorf("RedisStream: %w: %s", ErrSubscribing, err)
+			}
+
+			if deliveries > r.maxDeliveries {
+				if err := r.deadLetter(ctx, stream, msg, deliveries, errors.New("delivered too many times without being acked")); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if err := handleMessage(msg.ID, parseRedisMessage(msg.Values)); err != nil {
+			// If we get an EOF error then we'll want to bubble this up since this
+			// signals that there's been a disconnect
+			if errors.Is(err, io.EOF) {
+				return err
+			}
+
+			// If we're shutting down the message wasn't really handled so we leave
+			// it for another consumer without counting it against the message
+			if ctx.Err() != nil {
+				continue
+			}
+
+			deliveries, pErr := r.deliveries(ctx, stream, msg.ID)
+			if pErr != nil {
+				return fmt.Errorf("RedisStream: %w: %s", ErrSubscribing, pErr)
+			}
+
+			if deliveries >= r.maxDeliveries {
+				if err := r.deadLetter(ctx, stream, msg, deliveries, err); err != nil {
+					return err
 				}
 			}
+			continue
+		}
+
+		if r.manualAck {
+			continue
 		}
+
+		// The message has been handled so we still want to ack it if the caller's
+		// context has been cancelled, otherwise it'd be handled again
+		if err := r.Ack(context.WithoutCancel(ctx), stream, msg.ID); err != nil {
+			return fmt.Errorf("RedisStream: %w: %s", ErrSubscribing, err)
+		}
+	}
+	return nil
+}
+
+// Ack acks messages in the RedisStream's consumer group so that they aren't delivered again. It does
+// nothing if the RedisStream isn't using a consumer group.
+func (r RedisStream) Ack(ctx context.Context, stream string, ids ...string) error {
+	if r.group == "" || len(ids) == 0 {
+		return nil
+	}
+
+	if err := r.client.XAck(ctx, stream, r.group, ids...).Err(); err != nil {
+		return fmt.Errorf("failed to ack messages %v: %s", ids, err)
+	}
+	return nil
+}
+
+// deadLetter moves a message to the stream's dead letter stream, along with the number of
+// times it was delivered and the last error handling it, and acks it
+func (r RedisStream) deadLetter(ctx context.Context, stream string, msg redis.XMessage, deliveries int64, reason error) error {
+	ctx = context.WithoutCancel(ctx)
+	deadLetterStream := NewDeadLetterStream(stream)
+
+	values := make(map[string]interface{}, len(msg.Values)+3)
+	for k, v := range msg.Values {
+		values[k] = v
+	}
+	values["id"] = msg.ID
+	values["deliveries"] = deliveries
+	values["error"] = reason.Error()
+
+	if err := r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: deadLetterStream,
+		ID:     "*",
+		Values: values,
+		MaxLen: r.maxLen,
+	}).Err(); err != nil {
+		return fmt.Errorf("RedisStream: %w: failed to move message %s to dead letter stream: %s", ErrSubscribing, msg.ID, err)
+	}
+
+	if err := r.Ack(ctx, stream, msg.ID); err != nil {
+		return fmt.Errorf("RedisStream: %w: %s", ErrSubscribing, err)
+	}
+
+	r.log.Error("moved message to dead letter stream", "stream", stream, "dead_letter_stream", deadLetterStream, "group", r.group, "id", msg.ID, "deliveries", deliveries, "err", reason)
+	return nil
+}
+
+// NewDeadLetterStream returns the name of the stream that messages a consumer group fails to handle
+// on the given stream are moved to
+func NewDeadLetterStream(stream string) string {
+	return stream + ":dead-letter"
+}
+
+// deliveries returns the number of times a pending message has been delivered to consumers in the group
+func (r RedisStream) deliveries(ctx context.Context, stream string, id string) (int64, error) {
+	pending, err := r.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  r.group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending message %s: %s", id, err)
+	}
+
+	if len(pending) == 0 {
+		return 0, nil
 	}
+	return pending[0].RetryCount, nil
 }
 
 func (r RedisStream) Close(_ string) error {
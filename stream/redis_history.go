@@ -0,0 +1,165 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+// addEventScript increments the channel's event ID and adds the event to the
+// channel's redis stream with it in one step so that IDs are added in order even
+// when more than one Proxy is adding events. If the ID key has been lost while the
+// stream still exists, e.g. because it was evicted, the ID carries on from the last
+// event in the stream since redis rejects IDs that aren't greater than it.
+var addEventScript = redis.NewScript(`
+local id = redis.call('INCR', KEYS[2])
+local last = redis.call('XREVRANGE', KEYS[1], '+', '-', 'COUNT', 1)
+if #last > 0 then
+  local lastID = tonumber(string.match(last[1][1], '^(%d+)-'))
+  if id <= lastID then
+    id = lastID + 1
+    redis.call('SET', KEYS[2], id)
+  end
+end
+redis.call('XADD', KEYS[1], 'MAXLEN', ARGV[1], id .. '-0', 'data', ARGV[2])
+return id
+`)
+
+// RedisEventHistory is an EventHistory that keeps the events for each channel in a
+// capped redis stream. The history is shared by every Proxy using the same redis
+// so SDKs can reconnect to a different Proxy and still have their events replayed.
+type RedisEventHistory struct {
+	client    redis.UniversalClient
+	namespace string
+	size      int64
+}
+
+// NewRedisEventHistory creates a RedisEventHistory that keeps up to size events for each channel
+func NewRedisEventHistory(client redis.UniversalClient, namespace string, size int64) RedisEventHistory {
+	return RedisEventHistory{
+		client:    client,
+		namespace: namespace,
+		size:      size,
+	}
+}
+
+// Add makes RedisEventHistory implement the EventHistory interface
+func (r RedisEventHistory) Add(ctx context.Context, channel string, msg domain.SSEMessage) (int64, error) {
+	msg.EventID = 0
+	b, err := jsoniter.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	streamKey, idKey := r.keys(channel)
+	id, err := addEventScript.Run(ctx, r.client, []string{streamKey, idKey}, r.size, b).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to add event to history: %s", err)
+	}
+	return id, nil
+}
+
+// Since makes RedisEventHistory implement the EventHistory interface
+func (r RedisEventHistory) Since(ctx context.Context, channel string, id int64) ([]domain.SSEMessage, error) {
+	latest, err := r.Latest(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == latest {
+		return []domain.SSEMessage{}, nil
+	}
+	if id > latest {
+		return nil, fmt.Errorf("%w: channel=%s, id=%d, latest=%d", ErrEventHistoryGap, channel, id, latest)
+	}
+
+	streamKey, _ := r.keys(channel)
+	xs, err := r.client.XRange(ctx, streamKey, fmt.Sprintf("%d-0", id+1), "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event history: %s", err)
+	}
+
+	msgs := make([]domain.SSEMessage, 0, len(xs))
+	for i, x := range xs {
+		eventID, err := parseEventID(x.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		// If the first event isn't the one after the SDK's then the ones in between
+		// have been trimmed from the stream
+		if i == 0 && eventID != id+1 {
+			return nil, fmt.Errorf("%w: channel=%s, id=%d, oldest=%d", ErrEventHistoryGap, channel, id, eventID)
+		}
+
+		data, _ := x.Values["data"].(string)
+
+		msg := domain.SSEMessage{}
+		if err := jsoniter.UnmarshalFromString(data, &msg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event %s: %s", x.ID, err)
+		}
+		msg.EventID = eventID
+		msgs = append(msgs, msg)
+	}
+
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("%w: channel=%s, id=%d, latest=%d", ErrEventHistoryGap, channel, id, latest)
+	}
+	return msgs, nil
+}
+
+// Latest makes RedisEventHistory implement the EventHistory interface
+func (r RedisEventHistory) Latest(ctx context.Context, channel string) (int64, error) {
+	_, idKey := r.keys(channel)
+
+	latest, err := r.client.Get(ctx, idKey).Int64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get latest event id: %s", err)
+	}
+	return latest, nil
+}
+
+// EventHistoryKeyPattern matches the keys that a RedisEventHistory without a
+// namespace writes to redis
+const EventHistoryKeyPattern = "{" + eventHistoryKeyPrefix + "*"
+
+const eventHistoryKeyPrefix = "sdk-events-"
+
+// NamespaceEventHistoryKey returns the key that one written by a RedisEventHistory
+// without a namespace has in the namespace. The namespace goes inside the hash tag
+// so that a channel's stream and its ID key stay in the same slot.
+func NamespaceEventHistoryKey(namespace string, key string) string {
+	end := strings.LastIndex(key, "}")
+	if !strings.HasPrefix(key, "{") || end < 0 {
+		return domain.NewNamespacedKey(namespace, key)
+	}
+	return fmt.Sprintf("{%s}%s", domain.NewNamespacedKey(namespace, key[1:end]), key[end+1:])
+}
+
+// keys returns the key of the channel's stream and the key of its latest event
+// ID. They share a hash tag so that they're in the same slot in a redis cluster.
+func (r RedisEventHistory) keys(channel string) (string, string) {
+	streamKey := fmt.Sprintf("{%s}", domain.NewNamespacedKey(r.namespace, eventHistoryKeyPrefix+channel))
+	return streamKey, fmt.Sprintf("%s-id", streamKey)
+}
+
+// parseEventID parses the event ID from a redis stream ID in the form <id>-0
+func parseEventID(streamID string) (int64, error) {
+	id, _, _ := strings.Cut(streamID, "-")
+
+	eventID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse event id from %q: %s", streamID, err)
+	}
+	return eventID, nil
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRedisStream_Pub(t *testing.T) {
@@ -207,3 +210,310 @@ func TestRedisStream_Sub(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisStream_SubResume(t *testing.T) {
+	m := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{
+		Addr: m.Addr(),
+	})
+
+	redisStream := NewRedisStream(rc)
+	ctx := context.Background()
+
+	// Messages published before we first subscribe shouldn't be handled
+	assert.Nil(t, redisStream.Pub(ctx, "test-stream", "foo"))
+
+	sub := func(expected int) []interface{} {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		actual := []interface{}{}
+		err := redisStream.Sub(ctx, "test-stream", "", func(id string, v interface{}) error {
+			actual = append(actual, v)
+			if len(actual) == expected {
+				cancel()
+			}
+			return nil
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		return actual
+	}
+
+	done := make(chan []interface{})
+	go func() {
+		done <- sub(1)
+	}()
+
+	// Wait until we've subscribed before publishing
+	assert.Eventually(t, func() bool {
+		_, ok := redisStream.positions.Load("test-stream")
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, redisStream.Pub(ctx, "test-stream", "bar"))
+	assert.Equal(t, []interface{}{"bar"}, <-done)
+
+	// Messages published while we're disconnected should be handled when we resubscribe
+	assert.Nil(t, redisStream.Pub(ctx, "test-stream", "baz"))
+	assert.Nil(t, redisStream.Pub(ctx, "test-stream", "qux"))
+
+	assert.Equal(t, []interface{}{"baz", "qux"}, sub(2))
+}
+
+func TestRedisStream_SubConsumerGroup(t *testing.T) {
+	const (
+		stream = "test-stream"
+		group  = "test-group"
+	)
+
+	// readWithoutAck reads the messages in the stream as the consumer without acking
+	// them, which is what happens if a consumer dies while it's handling them
+	readWithoutAck := func(consumer string) func(t *testing.T, rc redis.UniversalClient) {
+		return func(t *testing.T, rc redis.UniversalClient) {
+			_, err := rc.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+				Group:    group,
+				Consumer: consumer,
+				Streams:  []string{stream, ">"},
+				Block:    -1,
+			}).Result()
+			require.NoError(t, err)
+		}
+	}
+
+	testCases := map[string]struct {
+		messages     []string
+		setup        func(t *testing.T, rc redis.UniversalClient)
+		claimMinIdle time.Duration
+		callbackErr  error
+
+		expected        []interface{}
+		expectedPending int64
+	}{
+		"Given I have two new messages": {
+			messages:     []string{"foo", "bar"},
+			claimMinIdle: time.Minute,
+
+			expected:        []interface{}{"foo", "bar"},
+			expectedPending: 0,
+		},
+		"Given I have two messages I didn't ack before I disconnected": {
+			messages:     []string{"foo", "bar"},
+			setup:        readWithoutAck("consumer-1"),
+			claimMinIdle: time.Minute,
+
+			expected:        []interface{}{"foo", "bar"},
+			expectedPending: 0,
+		},
+		"Given I have two messages another consumer didn't ack": {
+			messages:     []string{"foo", "bar"},
+			setup:        readWithoutAck("consumer-2"),
+			claimMinIdle: time.Millisecond,
+
+			expected:        []interface{}{"foo", "bar"},
+			expectedPending: 0,
+		},
+		"Given I have two new messages and the callback errors": {
+			messages:     []string{"foo", "bar"},
+			claimMinIdle: time.Minute,
+			callbackErr:  errors.New("callback error"),
+
+			expected:        []interface{}{"foo", "bar"},
+			expectedPending: 2,
+		},
+	}
+
+	for desc, tc := range testCases {
+		desc := desc
+		tc := tc
+
+		t.Run(desc, func(t *testing.T) {
+			m := miniredis.RunT(t)
+			rc := redis.NewClient(&redis.Options{
+				Addr: m.Addr(),
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// Create the group before publishing so that the messages are new to it
+			require.NoError(t, rc.XGroupCreateMkStream(ctx, stream, group, "$").Err())
+
+			redisStream := NewRedisStream(rc, WithConsumerGroup(group, "consumer-1"), WithClaimMinIdle(tc.claimMinIdle))
+			for _, msg := range tc.messages {
+				assert.Nil(t, redisStream.Pub(ctx, stream, msg))
+			}
+
+			if tc.setup != nil {
+				tc.setup(t, rc)
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			actual := []interface{}{}
+			err := redisStream.Sub(ctx, stream, "", func(id string, v interface{}) error {
+				actual = append(actual, v)
+				if len(actual) == len(tc.expected) {
+					cancel()
+				}
+				return tc.callbackErr
+			})
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Equal(t, tc.expected, actual)
+
+			pending, err := rc.XPending(context.Background(), stream, group).Result()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPending, pending.Count)
+		})
+	}
+}
+
+func TestRedisStream_SubConsumerGroupSharesMessages(t *testing.T) {
+	m := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{
+		Addr: m.Addr(),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, rc.XGroupCreateMkStream(ctx, "test-stream", "test-group", "$").Err())
+
+	expected := []interface{}{}
+	for _, msg := range []string{"foo", "bar", "baz", "qux"} {
+		assert.Nil(t, NewRedisStream(rc).Pub(ctx, "test-stream", msg))
+		expected = append(expected, msg)
+	}
+
+	mx := &sync.Mutex{}
+	actual := []interface{}{}
+
+	wg := &sync.WaitGroup{}
+	for _, consumer := range []string{"consumer-1", "consumer-2"} {
+		redisStream := NewRedisStream(rc, WithConsumerGroup("test-group", consumer), WithClaimMinIdle(50*time.Millisecond))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_ = redisStream.Sub(ctx, "test-stream", "", func(id string, v interface{}) error {
+				mx.Lock()
+				defer mx.Unlock()
+
+				actual = append(actual, v)
+				if len(actual) == len(expected) {
+					cancel()
+				}
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	// Each message should only have been handled by one of the consumers
+	assert.ElementsMatch(t, expected, actual)
+}
+
+func TestRedisStream_SubConsumerGroupMaxDeliveries(t *testing.T) {
+	m := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{
+		Addr: m.Addr(),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, rc.XGroupCreateMkStream(ctx, "test-stream", "test-group", "$").Err())
+
+	redisStream := NewRedisStream(rc, WithConsumerGroup("test-group", "consumer-1"), WithClaimMinIdle(10*time.Millisecond), WithMaxDeliveries(3))
+	assert.Nil(t, redisStream.Pub(ctx, "test-stream", "foo"))
+
+	mx := &sync.Mutex{}
+	attempts := 0
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		_ = redisStream.Sub(ctx, "test-stream", "", func(id string, v interface{}) error {
+			mx.Lock()
+			defer mx.Unlock()
+
+			attempts++
+			return errors.New("callback error")
+		})
+	}()
+
+	// The message should be acked once it's failed to be handled on each delivery
+	assert.Eventually(t, func() bool {
+		pending, err := rc.XPending(context.Background(), "test-stream", "test-group").Result()
+		return err == nil && pending.Count == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+
+	mx.Lock()
+	defer mx.Unlock()
+	assert.Equal(t, 3, attempts)
+
+	// The message is moved to the dead letter stream rather than dropped
+	deadLetters, err := rc.XRange(context.Background(), NewDeadLetterStream("test-stream"), "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "foo", deadLetters[0].Values["event"])
+	assert.Equal(t, "3", deadLetters[0].Values["deliveries"])
+	assert.Equal(t, "callback error", deadLetters[0].Values["error"])
+}
+
+func TestRedisStream_SubConsumerGroupManualAck(t *testing.T) {
+	m := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{
+		Addr: m.Addr(),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, rc.XGroupCreateMkStream(ctx, "test-stream", "test-group", "$").Err())
+
+	redisStream := NewRedisStream(rc, WithConsumerGroup("test-group", "consumer-1"), WithManualAck(), WithClaimMinIdle(10*time.Millisecond), WithMaxDeliveries(2))
+	assert.Nil(t, redisStream.Pub(ctx, "test-stream", "foo"))
+	assert.Nil(t, redisStream.Pub(ctx, "test-stream", "bar"))
+
+	mx := &sync.Mutex{}
+	received := map[interface{}]int{}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		_ = redisStream.Sub(ctx, "test-stream", "", func(id string, v interface{}) error {
+			mx.Lock()
+			defer mx.Unlock()
+
+			received[v]++
+
+			// Only ack bar, foo is handled successfully but never acked
+			if v == "bar" {
+				return redisStream.Ack(ctx, "test-stream", id)
+			}
+			return nil
+		})
+	}()
+
+	// foo is delivered again until it's moved to the dead letter stream
+	assert.Eventually(t, func() bool {
+		n, err := rc.XLen(context.Background(), NewDeadLetterStream("test-stream")).Result()
+		return err == nil && n == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+
+	pending, err := rc.XPending(context.Background(), "test-stream", "test-group").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pending.Count)
+
+	mx.Lock()
+	defer mx.Unlock()
+	assert.Equal(t, map[interface{}]int{"foo": 2, "bar": 1}, received)
+}
@@ -8,10 +8,14 @@ import (
 
 	"github.com/harness-community/sse/v3"
 	jsoniter "github.com/json-iterator/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"gopkg.in/cenkalti/backoff.v1"
 
 	"github.com/harness/ff-proxy/v2/domain"
 	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/tracing"
 )
 
 var (
@@ -43,6 +47,14 @@ func WithBackoff(b backoff.BackOff) func(s *Stream) {
 	}
 }
 
+// WithTracerProvider is an optional func for setting the TracerProvider used to
+// create spans for each message that's handled
+func WithTracerProvider(tp trace.TracerProvider) func(s *Stream) {
+	return func(s *Stream) {
+		s.tracer = tp.Tracer(tracing.ScopeName)
+	}
+}
+
 // Stream defines a type that can subscribe to a stream and handle events that come off it
 type Stream struct {
 	log            log.Logger
@@ -52,6 +64,7 @@ type Stream struct {
 	onDisconnect   func()
 	onConnect      func()
 	backoff        backoff.BackOff
+	tracer         trace.Tracer
 }
 
 // NewStream opens a subscription to the client service's stream endpoint
@@ -71,6 +84,10 @@ func NewStream(l log.Logger, topic string, s domain.Stream, m domain.MessageHand
 	if stream.backoff == nil {
 		stream.backoff = backoff.NewConstantBackOff(1 * time.Minute)
 	}
+
+	if stream.tracer == nil {
+		stream.tracer = noop.NewTracerProvider().Tracer(tracing.ScopeName)
+	}
 	return *stream
 }
 
@@ -99,6 +116,9 @@ func (s Stream) subscribe(ctx context.Context) {
 		s.onConnect()
 	}
 
+	// We don't pass the ID of the last message we handled when we resubscribe, it's up to
+	// the domain.Stream to pick up where it left off, e.g. the RedisStream keeps track of
+	// the last message it handled on each stream
 	msgID := ""
 	err := s.stream.Sub(ctx, s.topic, msgID, func(id string, v interface{}) (err error) {
 		msg, err := parseMessage(v)
@@ -108,7 +128,21 @@ func (s Stream) subscribe(ctx context.Context) {
 
 		msgID = id
 
-		return s.messageHandler.HandleMessage(ctx, msg)
+		msgCtx, span := s.tracer.Start(ctx, "Stream.HandleMessage",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("stream.topic", s.topic),
+				attribute.String("message.event", msg.Event),
+				attribute.String("message.domain", msg.Domain),
+				attribute.String("message.identifier", msg.Identifier),
+				attribute.String("environment.id", msg.Environment),
+			),
+		)
+		defer span.End()
+
+		err = s.messageHandler.HandleMessage(msgCtx, msg)
+		tracing.RecordError(span, err)
+		return err
 	})
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
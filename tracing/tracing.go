@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/harness/ff-proxy/v2/build"
+)
+
+const (
+	// ScopeName is the instrumentation scope that all of the Proxy's spans are created under
+	ScopeName = "github.com/harness/ff-proxy/v2"
+
+	serviceName = "ff-proxy"
+)
+
+// NewTracerProvider creates a TracerProvider that batches spans and exports them
+// using OTLP over http. The exporter is configured using the standard OTEL_EXPORTER_OTLP_*
+// environment variables e.g. OTEL_EXPORTER_OTLP_ENDPOINT. The sampleRatio is the
+// fraction of root spans that are sampled, child spans follow their parent's decision.
+func NewTracerProvider(ctx context.Context, sampleRatio float64) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %s", err)
+	}
+
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(build.Version),
+	)
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	), nil
+}
+
+// Propagator returns the propagator used to pass trace context between the
+// Proxy, SDKs and Harness SaaS. We use the W3C trace context format.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.TraceContext{}
+}
+
+// RecordError records the error on the span and marks it as failed
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Transport is a http.RoundTripper that creates a client span for each outbound
+// request and propagates the trace context in the request headers
+type Transport struct {
+	next       http.RoundTripper
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTransport creates a Transport that decorates the passed RoundTripper. If next
+// is nil the http.DefaultTransport is used.
+func NewTransport(next http.RoundTripper, tp trace.TracerProvider) Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return Transport{
+		next:       next,
+		tracer:     tp.Tracer(ScopeName),
+		propagator: Propagator(),
+	}
+}
+
+// RoundTrip makes Transport implement the http.RoundTripper interface
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(), fmt.Sprintf("HTTP %s", req.Method),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", req.Method),
+			attribute.String("http.url", req.URL.Redacted()),
+		),
+	)
+	defer span.End()
+
+	// RoundTrippers shouldn't modify the request they're given so we
+	// clone it before adding the trace headers
+	req = req.Clone(ctx)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		RecordError(span, err)
+		return resp, err
+	}
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTransport_RoundTrip(t *testing.T) {
+	testCases := map[string]struct {
+		status         int
+		expectedStatus codes.Code
+	}{
+		"Given the server returns a 200": {
+			status:         http.StatusOK,
+			expectedStatus: codes.Unset,
+		},
+		"Given the server returns a 500": {
+			status:         http.StatusInternalServerError,
+			expectedStatus: codes.Error,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+			var traceParent string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				traceParent = r.Header.Get("traceparent")
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			// Start a parent span so we can check the outbound request is part of the same trace
+			ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+
+			client := &http.Client{Transport: NewTransport(nil, tp)}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/client/auth", nil)
+			assert.Nil(t, err)
+
+			resp, err := client.Do(req)
+			assert.Nil(t, err)
+			resp.Body.Close()
+			parent.End()
+
+			assert.Empty(t, req.Header.Get("traceparent"), "the original request shouldn't be modified")
+
+			spans := exporter.GetSpans()
+			assert.Len(t, spans, 2)
+
+			span := spans[0]
+			assert.Equal(t, "HTTP GET", span.Name)
+			assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+			assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext.TraceID())
+			assert.Contains(t, span.Attributes, attribute.Int("http.status_code", tc.status))
+			assert.Equal(t, tc.expectedStatus, span.Status.Code)
+
+			expectedTraceParent := "00-" + span.SpanContext.TraceID().String() + "-" + span.SpanContext.SpanID().String() + "-01"
+			assert.Equal(t, expectedTraceParent, traceParent)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package transport
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/harness/ff-proxy/v2/domain"
+)
+
+// adminService is the interface for the service that backs the admin API
+type adminService interface {
+	Environments(ctx context.Context) (domain.AdminEnvironmentsResponse, error)
+	Environment(ctx context.Context, req domain.AdminEnvironmentRequest) (domain.AdminEnvironmentResponse, error)
+	Inventory(ctx context.Context) (domain.AdminInventoryResponse, error)
+	Streams(ctx context.Context) (domain.AdminStreamsResponse, error)
+	Health(ctx context.Context) (domain.HealthResponse, error)
+	FlagOverrides(ctx context.Context, req domain.AdminEnvironmentRequest) (domain.AdminFlagOverridesResponse, error)
+	SetFlagOverride(ctx context.Context, req domain.FlagOverrideRequest) (domain.FlagOverride, error)
+	DeleteFlagOverride(ctx context.Context, req domain.AdminDeleteFlagOverrideRequest) error
+	FlagOverrideAudit(ctx context.Context, req domain.AdminEnvironmentRequest) (domain.AdminFlagOverrideAuditResponse, error)
+}
+
+// AdminEndpoints collects all of the endpoints that make up the admin API
+type AdminEndpoints struct {
+	GetEnvironments endpoint.Endpoint
+	GetEnvironment  endpoint.Endpoint
+	GetInventory    endpoint.Endpoint
+	GetStreams      endpoint.Endpoint
+	GetHealth       endpoint.Endpoint
+
+	GetFlagOverrides     endpoint.Endpoint
+	PutFlagOverride      endpoint.Endpoint
+	DeleteFlagOverride   endpoint.Endpoint
+	GetFlagOverrideAudit endpoint.Endpoint
+}
+
+// NewAdminEndpoints returns an initialised AdminEndpoints where each endpoint
+// invokes the corresponding method on the passed adminService
+func NewAdminEndpoints(s adminService) *AdminEndpoints {
+	return &AdminEndpoints{
+		GetEnvironments: makeAdminGetEnvironmentsEndpoint(s),
+		GetEnvironment:  makeAdminGetEnvironmentEndpoint(s),
+		GetInventory:    makeAdminGetInventoryEndpoint(s),
+		GetStreams:      makeAdminGetStreamsEndpoint(s),
+		GetHealth:       makeAdminGetHealthEndpoint(s),
+
+		GetFlagOverrides:     makeAdminGetFlagOverridesEndpoint(s),
+		PutFlagOverride:      makeAdminPutFlagOverrideEndpoint(s),
+		DeleteFlagOverride:   makeAdminDeleteFlagOverrideEndpoint(s),
+		GetFlagOverrideAudit: makeAdminGetFlagOverrideAuditEndpoint(s),
+	}
+}
+
+// makeAdminGetEnvironmentsEndpoint is a function to convert an adminService's
+// Environments method to an endpoint
+func makeAdminGetEnvironmentsEndpoint(s adminService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (response interface{}, err error) {
+		resp, err := s.Environments(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// makeAdminGetEnvironmentEndpoint is a function to convert an adminService's
+// Environment method to an endpoint
+func makeAdminGetEnvironmentEndpoint(s adminService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(domain.AdminEnvironmentRequest)
+		resp, err := s.Environment(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// makeAdminGetInventoryEndpoint is a function to convert an adminService's
+// Inventory method to an endpoint
+func makeAdminGetInventoryEndpoint(s adminService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (response interface{}, err error) {
+		resp, err := s.Inventory(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// makeAdminGetStreamsEndpoint is a function to convert an adminService's
+// Streams method to an endpoint
+func makeAdminGetStreamsEndpoint(s adminService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (response interface{}, err error) {
+		resp, err := s.Streams(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// makeAdminGetHealthEndpoint is a function to convert an adminService's
+// Health method to an endpoint
+func makeAdminGetHealthEndpoint(s adminService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (response interface{}, err error) {
+		resp, err := s.Health(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// makeAdminGetFlagOverridesEndpoint is a function to convert an adminService's
+// FlagOverrides method to an endpoint
+func makeAdminGetFlagOverridesEndpoint(s adminService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(domain.AdminEnvironmentRequest)
+		resp, err := s.FlagOverrides(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// makeAdminPutFlagOverrideEndpoint is a function to convert an adminService's
+// SetFlagOverride method to an endpoint
+func makeAdminPutFlagOverrideEndpoint(s adminService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(domain.FlagOverrideRequest)
+		resp, err := s.SetFlagOverride(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// makeAdminDeleteFlagOverrideEndpoint is a function to convert an adminService's
+// DeleteFlagOverride method to an endpoint
+func makeAdminDeleteFlagOverrideEndpoint(s adminService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(domain.AdminDeleteFlagOverrideRequest)
+		if err := s.DeleteFlagOverride(ctx, req); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+}
+
+// makeAdminGetFlagOverrideAuditEndpoint is a function to convert an adminService's
+// FlagOverrideAudit method to an endpoint
+func makeAdminGetFlagOverrideAuditEndpoint(s adminService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(domain.AdminEnvironmentRequest)
+		resp, err := s.FlagOverrideAudit(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
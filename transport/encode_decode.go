@@ -8,7 +8,9 @@ import (
 	"net/http"
 
 	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/log"
 	proxyservice "github.com/harness/ff-proxy/v2/proxy-service"
+	"github.com/harness/ff-proxy/v2/stream"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/labstack/echo/v4"
 )
@@ -19,6 +21,10 @@ var (
 	rulesQueryParam = "rules"
 )
 
+// maxBatchEvaluationTargets is the maximum number of targets that can be
+// evaluated in a single batch evaluations request
+const maxBatchEvaluationTargets = 1000
+
 // encodeResponse is the common method to encode all the non error response types
 // to the client. If we need to we can write specific encodeResponse functions
 // for endpoints that require one.
@@ -27,6 +33,48 @@ func encodeResponse(_ context.Context, w http.ResponseWriter, response interface
 	return jsoniter.NewEncoder(w).Encode(response)
 }
 
+// encodeFeatureConfigsResponse encodes the FeatureConfigs in a domain.FeatureConfigResponse and sets the
+// ETag header. If the client already has the latest FeatureConfigs it returns a 304 with no body.
+func encodeFeatureConfigsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	r, ok := response.(domain.FeatureConfigResponse)
+	if !ok {
+		return fmt.Errorf("internal error encoding feature configs response")
+	}
+
+	if notModified := writeETag(w, r.ETag, r.NotModified); notModified {
+		return nil
+	}
+	return encodeResponse(ctx, w, r.FeatureConfigs)
+}
+
+// encodeTargetSegmentsResponse encodes the Segments in a domain.TargetSegmentsResponse and sets the
+// ETag header. If the client already has the latest Segments it returns a 304 with no body.
+func encodeTargetSegmentsResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	r, ok := response.(domain.TargetSegmentsResponse)
+	if !ok {
+		return fmt.Errorf("internal error encoding target segments response")
+	}
+
+	if notModified := writeETag(w, r.ETag, r.NotModified); notModified {
+		return nil
+	}
+	return encodeResponse(ctx, w, r.Segments)
+}
+
+// writeETag sets the ETag header if there is one and writes a 304 status code
+// if the resource hasn't been modified. It returns true if it wrote the 304.
+func writeETag(w http.ResponseWriter, etag string, notModified bool) bool {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+
+	if notModified {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
 func encodeStreamResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
 	r, ok := response.(domain.StreamResponse)
 	if !ok {
@@ -40,6 +88,51 @@ func encodeStreamResponse(_ context.Context, w http.ResponseWriter, response int
 	return nil
 }
 
+// encodeReplayStreamResponse returns an encoder that hands the stream off to Pushpin
+// like encodeStreamResponse and writes any events the SDK missed in the body of
+// the response, which Pushpin sends to the SDK before holding the stream open
+func encodeReplayStreamResponse(l log.Logger, h domain.EventHistory) encodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		r, ok := response.(domain.StreamResponse)
+		if !ok {
+			return fmt.Errorf("internal error encoding stream response")
+		}
+
+		msgs, err := stream.Replay(ctx, h, r.GripChannel, r.LastEventID)
+		if err != nil {
+			l.Warn("failed to replay events to sdk stream", "channel", r.GripChannel, "last_event_id", r.LastEventID, "err", err)
+		}
+
+		if err := encodeStreamResponse(ctx, w, response); err != nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			data, err := jsoniter.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(stream.FormatEvent(msg.EventID, data)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// encodeSSEStreamResponse returns an encoder that holds the stream open using
+// the sseServer instead of handing it off to Pushpin
+func encodeSSEStreamResponse(s sseServer) encodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		r, ok := response.(domain.StreamResponse)
+		if !ok {
+			return fmt.Errorf("internal error encoding stream response")
+		}
+
+		return s.ServeStream(ctx, w, r.GripChannel, r.LastEventID)
+	}
+}
+
 func encodeEchoError(c echo.Context, err error) error {
 	code := codeFrom(err)
 	return c.JSON(code, map[string]interface{}{
@@ -53,6 +146,10 @@ func codeFrom(err error) int {
 		return http.StatusBadRequest
 	}
 
+	if errors.Is(err, proxyservice.ErrBadRequest) {
+		return http.StatusBadRequest
+	}
+
 	if errors.Is(err, proxyservice.ErrNotFound) {
 		return http.StatusNotFound
 	}
@@ -110,6 +207,66 @@ func decodeHealthRequest(_ echo.Context) (interface{}, error) {
 	return nil, nil
 }
 
+// decodeAdminRequest decodes admin requests that don't have any parameters
+func decodeAdminRequest(_ echo.Context) (interface{}, error) {
+	return nil, nil
+}
+
+// decodeAdminEnvironmentRequest decodes GET /admin/environments/{environmentUUID} requests
+// into a domain.AdminEnvironmentRequest that can be passed to the AdminService
+func decodeAdminEnvironmentRequest(c echo.Context) (interface{}, error) {
+	envID := c.Param("environment_uuid")
+	if envID == "" {
+		return nil, errBadRouting
+	}
+
+	return domain.AdminEnvironmentRequest{EnvironmentID: envID}, nil
+}
+
+// decodeAdminPutFlagOverrideRequest decodes PUT /admin/environments/{environmentUUID}/overrides/{feature}
+// requests into a domain.FlagOverrideRequest that can be passed to the AdminService
+func decodeAdminPutFlagOverrideRequest(c echo.Context) (interface{}, error) {
+	//#nosec G307
+	defer c.Request().Body.Close()
+
+	envID := c.Param("environment_uuid")
+	feature := c.Param("feature")
+	if envID == "" || feature == "" {
+		return nil, errBadRouting
+	}
+
+	b, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) == 0 {
+		return nil, fmt.Errorf("%w: request body cannot be empty", errBadRequest)
+	}
+
+	req := domain.FlagOverrideRequest{}
+	if err := jsoniter.Unmarshal(b, &req); err != nil {
+		return nil, fmt.Errorf("%w: %s", errBadRequest, err)
+	}
+
+	// The environment and feature always come from the path
+	req.EnvironmentID = envID
+	req.Feature = feature
+	return req, nil
+}
+
+// decodeAdminDeleteFlagOverrideRequest decodes DELETE /admin/environments/{environmentUUID}/overrides/{feature}
+// requests into a domain.AdminDeleteFlagOverrideRequest that can be passed to the AdminService
+func decodeAdminDeleteFlagOverrideRequest(c echo.Context) (interface{}, error) {
+	envID := c.Param("environment_uuid")
+	feature := c.Param("feature")
+	if envID == "" || feature == "" {
+		return nil, errBadRouting
+	}
+
+	return domain.AdminDeleteFlagOverrideRequest{EnvironmentID: envID, Feature: feature}, nil
+}
+
 // decodeGetFeatureConfigisRequest decodes GET /client/env/{environmentUUID}/feature-configs requests
 // into a domain.FeatureConfigRequest that can be passed to the ProxyService
 func decodeGetFeatureConfigsRequest(c echo.Context) (interface{}, error) {
@@ -120,6 +277,7 @@ func decodeGetFeatureConfigsRequest(c echo.Context) (interface{}, error) {
 
 	req := domain.FeatureConfigRequest{
 		EnvironmentID: envID,
+		IfNoneMatch:   c.Request().Header.Get("If-None-Match"),
 	}
 	return req, nil
 }
@@ -153,6 +311,7 @@ func decodeGetTargetSegmentsRequest(c echo.Context) (interface{}, error) {
 	req := domain.TargetSegmentsRequest{
 		EnvironmentID: envID,
 		Rules:         rules,
+		IfNoneMatch:   c.Request().Header.Get("If-None-Match"),
 	}
 	return req, nil
 }
@@ -209,13 +368,72 @@ func decodeGetEvaluationsByFeatureRequest(c echo.Context) (interface{}, error) {
 	return req, nil
 }
 
+// decodeBatchEvaluationsRequest decodes POST /client/env/{environmentUUID}/target-evaluations requests
+// into a domain.BatchEvaluationsRequest that can be passed to the ProxyService. It returns a wrapped bad
+// request error if the body is empty, if there are no targets, too many targets or a target without an identifier
+func decodeBatchEvaluationsRequest(c echo.Context) (interface{}, error) {
+	//#nosec G307
+	defer c.Request().Body.Close()
+
+	envID := c.Param("environment_uuid")
+	if envID == "" {
+		return nil, errBadRouting
+	}
+
+	b, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) == 0 {
+		return nil, fmt.Errorf("%w: request body cannot be empty", errBadRequest)
+	}
+
+	req := domain.BatchEvaluationsRequest{}
+	if err := jsoniter.Unmarshal(b, &req); err != nil {
+		return nil, fmt.Errorf("%w: %s", errBadRequest, err)
+	}
+	req.EnvironmentID = envID
+
+	if len(req.Targets) == 0 {
+		return nil, fmt.Errorf("%w: targets cannot be empty", errBadRequest)
+	}
+
+	if len(req.Targets) > maxBatchEvaluationTargets {
+		return nil, fmt.Errorf("%w: a maximum of %d targets can be evaluated per request", errBadRequest, maxBatchEvaluationTargets)
+	}
+
+	identifiers := make(map[string]struct{}, len(req.Targets))
+	for i, t := range req.Targets {
+		if t.Identifier == "" {
+			return nil, fmt.Errorf("%w: target identifier cannot be empty", errBadRequest)
+		}
+
+		// The response is keyed by target identifier so a duplicate would
+		// overwrite the evaluations of the target before it
+		if _, ok := identifiers[t.Identifier]; ok {
+			return nil, fmt.Errorf("%w: duplicate target identifier %q", errBadRequest, t.Identifier)
+		}
+		identifiers[t.Identifier] = struct{}{}
+
+		// Mimic what we do for auth requests and set the identifier as
+		// the name if it hasn't been provided
+		if t.Name == "" {
+			req.Targets[i].Name = t.Identifier
+		}
+	}
+
+	return req, nil
+}
+
 // decodeGetStreamRequest decodes GET /stream requests into a domain.StreamRequest that
 // can be passed to the ProxyService
 func decodeGetStreamRequest(c echo.Context) (interface{}, error) {
 	apiKey := c.Request().Header.Get("API-Key")
 
 	req := domain.StreamRequest{
-		APIKey: apiKey,
+		APIKey:      apiKey,
+		LastEventID: c.Request().Header.Get("Last-Event-ID"),
 	}
 
 	if req.APIKey == "" {
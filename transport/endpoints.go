@@ -17,6 +17,7 @@ type Endpoints struct {
 	GetTargetSegmentsByIdentifier endpoint.Endpoint
 	GetEvaluations                endpoint.Endpoint
 	GetEvaluationsByFeature       endpoint.Endpoint
+	PostBatchEvaluations          endpoint.Endpoint
 	GetStream                     endpoint.Endpoint
 	PostMetrics                   endpoint.Endpoint
 	Health                        endpoint.Endpoint
@@ -33,6 +34,7 @@ func NewEndpoints(p proxyservice.ProxyService) *Endpoints {
 		GetTargetSegmentsByIdentifier: makeGetTargetSegmentsByIdentifierEndpoint(p),
 		GetEvaluations:                makeGetEvaluationsEndpoint(p),
 		GetEvaluationsByFeature:       makeGetEvaluationsByFeatureEndpoint(p),
+		PostBatchEvaluations:          makePostBatchEvaluationsEndpoint(p),
 		GetStream:                     makeGetStreamEndpoint(p),
 		PostMetrics:                   makePostMetricsEndpoint(p),
 		Health:                        makeHealthEndpoint(p),
@@ -130,6 +132,19 @@ func makeGetEvaluationsByFeatureEndpoint(s proxyservice.ProxyService) endpoint.E
 	}
 }
 
+// makePostBatchEvaluationsEndpoint is a function to convert a clients
+// BatchEvaluations method to an endpoint
+func makePostBatchEvaluationsEndpoint(s proxyservice.ProxyService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(domain.BatchEvaluationsRequest)
+		evaluations, err := s.BatchEvaluations(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return evaluations, nil
+	}
+}
+
 // makeGetStreamEndpoint is a function to convert a clients Stream method
 // to an endpoint
 func makeGetStreamEndpoint(s proxyservice.ProxyService) endpoint.Endpoint {
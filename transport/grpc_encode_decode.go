@@ -0,0 +1,319 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	clientgen "github.com/harness/ff-proxy/v2/gen/client"
+	"github.com/harness/ff-proxy/v2/gen/proxypb"
+	proxyservice "github.com/harness/ff-proxy/v2/proxy-service"
+)
+
+// grpcError converts a service error to a gRPC status error
+func grpcError(err error) error {
+	return status.Error(grpcCodeFrom(err), err.Error())
+}
+
+// grpcCodeFrom casts a service error to a gRPC status code
+func grpcCodeFrom(err error) codes.Code {
+	if errors.Is(err, errBadRequest) || errors.Is(err, errBadRouting) {
+		return codes.InvalidArgument
+	}
+
+	if errors.Is(err, proxyservice.ErrNotFound) {
+		return codes.NotFound
+	}
+
+	if errors.Is(err, proxyservice.ErrUnauthorised) {
+		return codes.Unauthenticated
+	}
+
+	if errors.Is(err, proxyservice.ErrNotImplemented) {
+		return codes.Unimplemented
+	}
+
+	if errors.Is(err, proxyservice.ErrStreamDisconnected) {
+		return codes.Unavailable
+	}
+
+	return codes.Internal
+}
+
+// decodeGRPCAuthRequest decodes an AuthenticateRequest into a domain.AuthRequest.
+// It returns a wrapped bad request error if the apiKey is empty.
+func decodeGRPCAuthRequest(req *proxypb.AuthenticateRequest) (domain.AuthRequest, error) {
+	if req.GetApiKey() == "" {
+		return domain.AuthRequest{}, fmt.Errorf("%w: api_key cannot be empty", errBadRequest)
+	}
+
+	target := fromProtoTarget(req.GetTarget())
+
+	// Mimic what the client service does and set identifier value as the
+	// name if it hasn't been provided.
+	if target.Name == "" {
+		target.Name = target.Identifier
+	}
+
+	return domain.AuthRequest{
+		APIKey: req.GetApiKey(),
+		Target: domain.Target{Target: target},
+	}, nil
+}
+
+// decodeGRPCMetricsRequest decodes a PostMetricsRequest into a domain.MetricsRequest.
+// It returns a wrapped bad request error if the environment_id is empty.
+func decodeGRPCMetricsRequest(req *proxypb.PostMetricsRequest) (domain.MetricsRequest, error) {
+	if req.GetEnvironmentId() == "" {
+		return domain.MetricsRequest{}, fmt.Errorf("%w: environment_id cannot be empty", errBadRequest)
+	}
+
+	metricsData := make([]clientgen.MetricsData, 0, len(req.GetMetricsData()))
+	for _, md := range req.GetMetricsData() {
+		metricsData = append(metricsData, clientgen.MetricsData{
+			Attributes:  fromProtoKeyValues(md.GetAttributes()),
+			Count:       int(md.GetCount()),
+			MetricsType: clientgen.MetricsDataMetricsType(md.GetMetricsType()),
+			Timestamp:   md.GetTimestamp(),
+		})
+	}
+
+	targetData := make([]clientgen.TargetData, 0, len(req.GetTargetData()))
+	for _, td := range req.GetTargetData() {
+		targetData = append(targetData, clientgen.TargetData{
+			Attributes: fromProtoKeyValues(td.GetAttributes()),
+			Identifier: td.GetIdentifier(),
+			Name:       td.GetName(),
+		})
+	}
+
+	return domain.MetricsRequest{
+		EnvironmentID: req.GetEnvironmentId(),
+		Metrics: clientgen.Metrics{
+			MetricsData: &metricsData,
+			TargetData:  &targetData,
+		},
+	}, nil
+}
+
+func fromProtoKeyValues(kvs []*proxypb.KeyValue) []clientgen.KeyValue {
+	result := make([]clientgen.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		result = append(result, clientgen.KeyValue{Key: kv.GetKey(), Value: kv.GetValue()})
+	}
+	return result
+}
+
+func fromProtoTarget(t *proxypb.Target) clientgen.Target {
+	target := clientgen.Target{
+		Identifier: t.GetIdentifier(),
+		Name:       t.GetName(),
+	}
+
+	if t.GetAnonymous() {
+		target.Anonymous = domain.ToPtr(true)
+	}
+
+	if t.GetAttributes() != nil {
+		target.Attributes = domain.ToPtr(t.GetAttributes().AsMap())
+	}
+	return target
+}
+
+func toProtoTarget(t clientgen.Target) (*proxypb.Target, error) {
+	target := &proxypb.Target{
+		Identifier: t.Identifier,
+		Name:       t.Name,
+		Anonymous:  domain.SafePtrDereference(t.Anonymous),
+	}
+
+	if t.Attributes != nil {
+		attributes, err := structpb.NewStruct(*t.Attributes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert attributes for target %q: %s", t.Identifier, err)
+		}
+		target.Attributes = attributes
+	}
+	return target, nil
+}
+
+func toProtoFeatureConfig(fc domain.FeatureConfig) *proxypb.FeatureConfig {
+	variations := make([]*proxypb.Variation, 0, len(fc.Variations))
+	for _, v := range fc.Variations {
+		variations = append(variations, &proxypb.Variation{
+			Identifier:  v.Identifier,
+			Name:        domain.SafePtrDereference(v.Name),
+			Value:       v.Value,
+			Description: domain.SafePtrDereference(v.Description),
+		})
+	}
+
+	rules := []*proxypb.ServingRule{}
+	for _, r := range domain.SafePtrDereference(fc.Rules) {
+		rules = append(rules, &proxypb.ServingRule{
+			RuleId:   domain.SafePtrDereference(r.RuleId),
+			Priority: int32(r.Priority),
+			Clauses:  toProtoClauses(r.Clauses),
+			Serve:    toProtoServe(r.Serve),
+		})
+	}
+
+	prerequisites := []*proxypb.Prerequisite{}
+	for _, p := range domain.SafePtrDereference(fc.Prerequisites) {
+		prerequisites = append(prerequisites, &proxypb.Prerequisite{
+			Feature:    p.Feature,
+			Variations: p.Variations,
+		})
+	}
+
+	variationMaps := []*proxypb.VariationMap{}
+	for _, vm := range domain.SafePtrDereference(fc.VariationToTargetMap) {
+		targets := []*proxypb.TargetMap{}
+		for _, t := range domain.SafePtrDereference(vm.Targets) {
+			targets = append(targets, &proxypb.TargetMap{Identifier: t.Identifier, Name: t.Name})
+		}
+
+		variationMaps = append(variationMaps, &proxypb.VariationMap{
+			Variation:      vm.Variation,
+			Targets:        targets,
+			TargetSegments: domain.SafePtrDereference(vm.TargetSegments),
+		})
+	}
+
+	return &proxypb.FeatureConfig{
+		Feature:              fc.Feature,
+		Environment:          fc.Environment,
+		Project:              fc.Project,
+		Kind:                 string(fc.Kind),
+		State:                string(fc.State),
+		Version:              domain.SafePtrDereference(fc.Version),
+		OffVariation:         fc.OffVariation,
+		DefaultServe:         toProtoServe(fc.DefaultServe),
+		Variations:           variations,
+		Rules:                rules,
+		Prerequisites:        prerequisites,
+		VariationToTargetMap: variationMaps,
+	}
+}
+
+func toProtoServe(s clientgen.Serve) *proxypb.Serve {
+	serve := &proxypb.Serve{
+		Variation: domain.SafePtrDereference(s.Variation),
+	}
+
+	if s.Distribution != nil {
+		variations := make([]*proxypb.WeightedVariation, 0, len(s.Distribution.Variations))
+		for _, wv := range s.Distribution.Variations {
+			variations = append(variations, &proxypb.WeightedVariation{Variation: wv.Variation, Weight: int32(wv.Weight)})
+		}
+
+		serve.Distribution = &proxypb.Distribution{
+			BucketBy:   s.Distribution.BucketBy,
+			Variations: variations,
+		}
+	}
+	return serve
+}
+
+func toProtoClauses(clauses []clientgen.Clause) []*proxypb.Clause {
+	result := make([]*proxypb.Clause, 0, len(clauses))
+	for _, c := range clauses {
+		result = append(result, &proxypb.Clause{
+			Id:        domain.SafePtrDereference(c.Id),
+			Attribute: c.Attribute,
+			Op:        c.Op,
+			Values:    c.Values,
+			Negate:    c.Negate,
+		})
+	}
+	return result
+}
+
+func toProtoSegment(s domain.Segment) (*proxypb.Segment, error) {
+	toTargets := func(targets *[]clientgen.Target) ([]*proxypb.Target, error) {
+		result := []*proxypb.Target{}
+		for _, t := range domain.SafePtrDereference(targets) {
+			target, err := toProtoTarget(t)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, target)
+		}
+		return result, nil
+	}
+
+	included, err := toTargets(s.Included)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded, err := toTargets(s.Excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	servingRules := []*proxypb.GroupServingRule{}
+	for _, r := range domain.SafePtrDereference(s.ServingRules) {
+		servingRules = append(servingRules, &proxypb.GroupServingRule{
+			RuleId:   r.RuleId,
+			Priority: int32(r.Priority),
+			Clauses:  toProtoClauses(r.Clauses),
+		})
+	}
+
+	tags := []*proxypb.Tag{}
+	for _, t := range domain.SafePtrDereference(s.Tags) {
+		tags = append(tags, &proxypb.Tag{Identifier: t.Identifier, Name: t.Name})
+	}
+
+	return &proxypb.Segment{
+		Identifier:   s.Identifier,
+		Name:         s.Name,
+		Environment:  domain.SafePtrDereference(s.Environment),
+		Version:      domain.SafePtrDereference(s.Version),
+		Included:     included,
+		Excluded:     excluded,
+		Rules:        toProtoClauses(domain.SafePtrDereference(s.Rules)),
+		ServingRules: servingRules,
+		Tags:         tags,
+		CreatedAt:    domain.SafePtrDereference(s.CreatedAt),
+		ModifiedAt:   domain.SafePtrDereference(s.ModifiedAt),
+	}, nil
+}
+
+func toProtoEvaluation(e clientgen.Evaluation) *proxypb.Evaluation {
+	return &proxypb.Evaluation{
+		Flag:       e.Flag,
+		Identifier: domain.SafePtrDereference(e.Identifier),
+		Kind:       e.Kind,
+		Value:      e.Value,
+	}
+}
+
+func toProtoHealthResponse(h domain.HealthResponse) *proxypb.HealthResponse {
+	return &proxypb.HealthResponse{
+		ConfigStatus: &proxypb.ComponentStatus{
+			State: string(h.ConfigStatus.State),
+			Since: h.ConfigStatus.Since,
+		},
+		StreamStatus: &proxypb.ComponentStatus{
+			State: string(h.StreamStatus.State),
+			Since: h.StreamStatus.Since,
+		},
+		CacheStatus: h.CacheStatus,
+	}
+}
+
+func toProtoFlagChangeEvent(msg domain.SSEMessage) *proxypb.FlagChangeEvent {
+	return &proxypb.FlagChangeEvent{
+		Event:       msg.Event,
+		Domain:      msg.Domain,
+		Identifier:  msg.Identifier,
+		Version:     int64(msg.Version),
+		Environment: msg.Environment,
+	}
+}
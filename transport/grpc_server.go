@@ -0,0 +1,313 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	jsoniter "github.com/json-iterator/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	clientgen "github.com/harness/ff-proxy/v2/gen/client"
+	"github.com/harness/ff-proxy/v2/gen/proxypb"
+	"github.com/harness/ff-proxy/v2/log"
+)
+
+// eventListener is the interface for a type that can be used to listen for the
+// events that are published to SDK streams
+type eventListener interface {
+	Listen(ctx context.Context, channel string, fn func(data []byte) error) error
+}
+
+// GRPCServer is a gRPC server that serves the same endpoints as the HTTPServer
+type GRPCServer struct {
+	proxypb.UnimplementedProxyServiceServer
+
+	server    *grpc.Server
+	addr      string
+	log       log.Logger
+	endpoints *Endpoints
+	listener  eventListener
+}
+
+// NewGRPCServer registers the passed endpoints against the ProxyService and returns
+// a GRPCServer that's ready to use. If the eventListener is nil the Subscribe rpc
+// will return an Unimplemented error.
+func NewGRPCServer(port int, e *Endpoints, l log.Logger, listener eventListener, opts ...grpc.ServerOption) *GRPCServer {
+	l = l.With("component", "GRPCServer")
+
+	g := &GRPCServer{
+		server:    grpc.NewServer(opts...),
+		addr:      fmt.Sprintf(":%d", port),
+		log:       l,
+		endpoints: e,
+		listener:  listener,
+	}
+	proxypb.RegisterProxyServiceServer(g.server, g)
+	return g
+}
+
+// Serve listens on the GRPCServers addr and handles requests
+func (g *GRPCServer) Serve() error {
+	lis, err := net.Listen("tcp", g.addr)
+	if err != nil {
+		return err
+	}
+	return g.ServeListener(lis)
+}
+
+// ServeListener handles requests on the passed listener
+func (g *GRPCServer) ServeListener(lis net.Listener) error {
+	g.log.Info("starting grpc server", "addr", lis.Addr().String())
+	return g.server.Serve(lis)
+}
+
+// Shutdown gracefully shuts down the server, if the ctx is cancelled before all
+// requests have finished it forcefully stops the server
+func (g *GRPCServer) Shutdown(ctx context.Context) {
+	g.log.Info("shutting down grpc server", "addr", g.addr)
+
+	done := make(chan struct{})
+	go func() {
+		g.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		g.server.Stop()
+	}
+}
+
+// Authenticate makes GRPCServer implement the ProxyServiceServer interface
+func (g *GRPCServer) Authenticate(ctx context.Context, req *proxypb.AuthenticateRequest) (*proxypb.AuthenticateResponse, error) {
+	authReq, err := decodeGRPCAuthRequest(req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp, err := g.endpoints.PostAuthenticate(ctx, authReq)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	r, ok := resp.(domain.AuthResponse)
+	if !ok {
+		return nil, status.Error(codes.Internal, "internal error encoding auth response")
+	}
+	return &proxypb.AuthenticateResponse{AuthToken: r.AuthToken}, nil
+}
+
+// GetFeatureConfigs makes GRPCServer implement the ProxyServiceServer interface
+func (g *GRPCServer) GetFeatureConfigs(ctx context.Context, req *proxypb.GetFeatureConfigsRequest) (*proxypb.GetFeatureConfigsResponse, error) {
+	if req.GetEnvironmentId() == "" {
+		return nil, grpcError(fmt.Errorf("%w: environment_id cannot be empty", errBadRequest))
+	}
+
+	resp, err := g.endpoints.GetFeatureConfigs(ctx, domain.FeatureConfigRequest{EnvironmentID: req.GetEnvironmentId()})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	r, ok := resp.(domain.FeatureConfigResponse)
+	if !ok {
+		return nil, status.Error(codes.Internal, "internal error encoding feature configs response")
+	}
+
+	featureConfigs := make([]*proxypb.FeatureConfig, 0, len(r.FeatureConfigs))
+	for _, fc := range r.FeatureConfigs {
+		featureConfigs = append(featureConfigs, toProtoFeatureConfig(fc))
+	}
+	return &proxypb.GetFeatureConfigsResponse{FeatureConfigs: featureConfigs}, nil
+}
+
+// GetFeatureConfig makes GRPCServer implement the ProxyServiceServer interface
+func (g *GRPCServer) GetFeatureConfig(ctx context.Context, req *proxypb.GetFeatureConfigRequest) (*proxypb.FeatureConfig, error) {
+	if req.GetEnvironmentId() == "" || req.GetIdentifier() == "" {
+		return nil, grpcError(fmt.Errorf("%w: environment_id and identifier cannot be empty", errBadRequest))
+	}
+
+	resp, err := g.endpoints.GetFeatureConfigsByIdentifier(ctx, domain.FeatureConfigByIdentifierRequest{
+		EnvironmentID: req.GetEnvironmentId(),
+		Identifier:    req.GetIdentifier(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	r, ok := resp.(domain.FeatureConfig)
+	if !ok {
+		return nil, status.Error(codes.Internal, "internal error encoding feature config response")
+	}
+	return toProtoFeatureConfig(r), nil
+}
+
+// GetTargetSegments makes GRPCServer implement the ProxyServiceServer interface
+func (g *GRPCServer) GetTargetSegments(ctx context.Context, req *proxypb.GetTargetSegmentsRequest) (*proxypb.GetTargetSegmentsResponse, error) {
+	if req.GetEnvironmentId() == "" {
+		return nil, grpcError(fmt.Errorf("%w: environment_id cannot be empty", errBadRequest))
+	}
+
+	resp, err := g.endpoints.GetTargetSegments(ctx, domain.TargetSegmentsRequest{
+		EnvironmentID: req.GetEnvironmentId(),
+		Rules:         req.GetRules(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	r, ok := resp.(domain.TargetSegmentsResponse)
+	if !ok {
+		return nil, status.Error(codes.Internal, "internal error encoding target segments response")
+	}
+
+	segments := make([]*proxypb.Segment, 0, len(r.Segments))
+	for _, s := range r.Segments {
+		segment, err := toProtoSegment(s)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "internal error encoding target segments response: %s", err)
+		}
+		segments = append(segments, segment)
+	}
+	return &proxypb.GetTargetSegmentsResponse{Segments: segments}, nil
+}
+
+// GetTargetSegment makes GRPCServer implement the ProxyServiceServer interface
+func (g *GRPCServer) GetTargetSegment(ctx context.Context, req *proxypb.GetTargetSegmentRequest) (*proxypb.Segment, error) {
+	if req.GetEnvironmentId() == "" || req.GetIdentifier() == "" {
+		return nil, grpcError(fmt.Errorf("%w: environment_id and identifier cannot be empty", errBadRequest))
+	}
+
+	resp, err := g.endpoints.GetTargetSegmentsByIdentifier(ctx, domain.TargetSegmentsByIdentifierRequest{
+		EnvironmentID: req.GetEnvironmentId(),
+		Identifier:    req.GetIdentifier(),
+		Rules:         req.GetRules(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	r, ok := resp.(domain.Segment)
+	if !ok {
+		return nil, status.Error(codes.Internal, "internal error encoding target segment response")
+	}
+
+	segment, err := toProtoSegment(r)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "internal error encoding target segment response: %s", err)
+	}
+	return segment, nil
+}
+
+// GetEvaluations makes GRPCServer implement the ProxyServiceServer interface
+func (g *GRPCServer) GetEvaluations(ctx context.Context, req *proxypb.GetEvaluationsRequest) (*proxypb.GetEvaluationsResponse, error) {
+	if req.GetEnvironmentId() == "" || req.GetTargetIdentifier() == "" {
+		return nil, grpcError(fmt.Errorf("%w: environment_id and target_identifier cannot be empty", errBadRequest))
+	}
+
+	resp, err := g.endpoints.GetEvaluations(ctx, domain.EvaluationsRequest{
+		EnvironmentID:    req.GetEnvironmentId(),
+		TargetIdentifier: req.GetTargetIdentifier(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	r, ok := resp.([]clientgen.Evaluation)
+	if !ok {
+		return nil, status.Error(codes.Internal, "internal error encoding evaluations response")
+	}
+
+	evaluations := make([]*proxypb.Evaluation, 0, len(r))
+	for _, e := range r {
+		evaluations = append(evaluations, toProtoEvaluation(e))
+	}
+	return &proxypb.GetEvaluationsResponse{Evaluations: evaluations}, nil
+}
+
+// GetEvaluation makes GRPCServer implement the ProxyServiceServer interface
+func (g *GRPCServer) GetEvaluation(ctx context.Context, req *proxypb.GetEvaluationRequest) (*proxypb.Evaluation, error) {
+	if req.GetEnvironmentId() == "" || req.GetTargetIdentifier() == "" || req.GetFeatureIdentifier() == "" {
+		return nil, grpcError(fmt.Errorf("%w: environment_id, target_identifier and feature_identifier cannot be empty", errBadRequest))
+	}
+
+	resp, err := g.endpoints.GetEvaluationsByFeature(ctx, domain.EvaluationsByFeatureRequest{
+		EnvironmentID:     req.GetEnvironmentId(),
+		TargetIdentifier:  req.GetTargetIdentifier(),
+		FeatureIdentifier: req.GetFeatureIdentifier(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	r, ok := resp.(clientgen.Evaluation)
+	if !ok {
+		return nil, status.Error(codes.Internal, "internal error encoding evaluation response")
+	}
+	return toProtoEvaluation(r), nil
+}
+
+// PostMetrics makes GRPCServer implement the ProxyServiceServer interface
+func (g *GRPCServer) PostMetrics(ctx context.Context, req *proxypb.PostMetricsRequest) (*proxypb.PostMetricsResponse, error) {
+	metricsReq, err := decodeGRPCMetricsRequest(req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	if _, err := g.endpoints.PostMetrics(ctx, metricsReq); err != nil {
+		return nil, grpcError(err)
+	}
+	return &proxypb.PostMetricsResponse{}, nil
+}
+
+// Health makes GRPCServer implement the ProxyServiceServer interface
+func (g *GRPCServer) Health(ctx context.Context, _ *proxypb.HealthRequest) (*proxypb.HealthResponse, error) {
+	resp, err := g.endpoints.Health(ctx, nil)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	r, ok := resp.(domain.HealthResponse)
+	if !ok {
+		return nil, status.Error(codes.Internal, "internal error encoding health response")
+	}
+	return toProtoHealthResponse(r), nil
+}
+
+// Subscribe makes GRPCServer implement the ProxyServiceServer interface. It holds
+// the stream open and sends any flag or segment changes for the environment that
+// the api key belongs to until the client disconnects or the stream is closed.
+func (g *GRPCServer) Subscribe(req *proxypb.SubscribeRequest, srv proxypb.ProxyService_SubscribeServer) error {
+	if g.listener == nil {
+		return status.Error(codes.Unimplemented, "streaming is not enabled")
+	}
+
+	if req.GetApiKey() == "" {
+		return grpcError(fmt.Errorf("%w: api_key cannot be empty", errBadRequest))
+	}
+
+	ctx := srv.Context()
+
+	resp, err := g.endpoints.GetStream(ctx, domain.StreamRequest{APIKey: req.GetApiKey()})
+	if err != nil {
+		return grpcError(err)
+	}
+
+	r, ok := resp.(domain.StreamResponse)
+	if !ok {
+		return status.Error(codes.Internal, "internal error encoding stream response")
+	}
+
+	return g.listener.Listen(ctx, r.GripChannel, func(data []byte) error {
+		msg := domain.SSEMessage{}
+		if err := jsoniter.Unmarshal(data, &msg); err != nil {
+			g.log.Error("failed to unmarshal stream event", "channel", r.GripChannel, "err", err)
+			return nil
+		}
+		return srv.Send(toProtoFlagChangeEvent(msg))
+	})
+}
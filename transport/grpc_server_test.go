@@ -0,0 +1,324 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/harness/ff-proxy/v2/domain"
+	"github.com/harness/ff-proxy/v2/gen/proxypb"
+	"github.com/harness/ff-proxy/v2/log"
+	"github.com/harness/ff-proxy/v2/middleware"
+	"github.com/harness/ff-proxy/v2/stream"
+)
+
+// setupGRPCServer is a helper that starts a GRPCServer on an in memory listener
+// and returns a client that's connected to it
+func setupGRPCServer(t *testing.T, bypassAuth bool, listener eventListener, opts ...setupOpts) proxypb.ProxyServiceClient {
+	endpoints, _ := setupEndpoints(t, opts...)
+	logger := log.NoOpLogger{}
+
+	repo := mockRepo{
+		getFn: func(context context.Context, key domain.AuthAPIKey) (string, bool, error) {
+			return "", true, nil
+		},
+	}
+
+	unaryRequestID, streamRequestID := middleware.NewGRPCRequestIDInterceptors()
+	unaryLogging, streamLogging := middleware.NewGRPCLoggingInterceptors(logger)
+	unaryAuth, streamAuth := middleware.NewGRPCAuthInterceptors(logger, repo, []byte(`secret`), bypassAuth)
+	server := NewGRPCServer(0, endpoints, logger, listener,
+		grpc.ChainUnaryInterceptor(unaryRequestID, unaryLogging, unaryAuth),
+		grpc.ChainStreamInterceptor(streamRequestID, streamLogging, streamAuth),
+	)
+
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.ServeListener(lis)
+	}()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Shutdown(context.Background())
+	})
+
+	return proxypb.NewProxyServiceClient(conn)
+}
+
+func TestGRPCServer_Authenticate(t *testing.T) {
+	attributes, err := structpb.NewStruct(map[string]interface{}{"hello": "world", "age": 27})
+	assert.Nil(t, err)
+
+	testCases := map[string]struct {
+		req          *proxypb.AuthenticateRequest
+		expectedCode codes.Code
+	}{
+		"Given I make an auth request without an APIKey": {
+			req:          &proxypb.AuthenticateRequest{},
+			expectedCode: codes.InvalidArgument,
+		},
+		"Given I make an auth request with an APIKey that doesn't exist": {
+			req:          &proxypb.AuthenticateRequest{ApiKey: "hello"},
+			expectedCode: codes.Unauthenticated,
+		},
+		"Given I make an auth request with an APIKey that does exist": {
+			req:          &proxypb.AuthenticateRequest{ApiKey: apiKey1},
+			expectedCode: codes.OK,
+		},
+		"Given I include a Target in my Auth request": {
+			req: &proxypb.AuthenticateRequest{
+				ApiKey: apiKey1,
+				Target: &proxypb.Target{Identifier: "foo", Name: "bar", Anonymous: true, Attributes: attributes},
+			},
+			expectedCode: codes.OK,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			client := setupGRPCServer(t, false, nil)
+
+			resp, err := client.Authenticate(context.Background(), tc.req)
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+
+			if tc.expectedCode == codes.OK {
+				assert.NotEmpty(t, resp.GetAuthToken())
+			}
+		})
+	}
+}
+
+func TestGRPCServer_Auth(t *testing.T) {
+	client := setupGRPCServer(t, false, nil)
+
+	// Health doesn't require a token
+	health, err := client.Health(context.Background(), &proxypb.HealthRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, "healthy", health.GetCacheStatus())
+	assert.Equal(t, string(domain.ConfigStateSynced), health.GetConfigStatus().GetState())
+
+	req := &proxypb.GetFeatureConfigsRequest{EnvironmentId: envID123}
+
+	_, err = client.GetFeatureConfigs(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer foo")
+	_, err = client.GetFeatureConfigs(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	auth, err := client.Authenticate(context.Background(), &proxypb.AuthenticateRequest{ApiKey: apiKey1})
+	assert.Nil(t, err)
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+auth.GetAuthToken())
+	_, err = client.GetFeatureConfigs(ctx, req)
+	assert.Nil(t, err)
+}
+
+func TestGRPCServer_GetFeatureConfigs(t *testing.T) {
+	client := setupGRPCServer(t, true, nil)
+
+	testCases := map[string]struct {
+		envID            string
+		expectedCode     codes.Code
+		expectedFeatures []string
+	}{
+		"Given I make a request without an environment": {
+			envID:        "",
+			expectedCode: codes.InvalidArgument,
+		},
+		"Given I make a request for an environment that doesn't exist": {
+			envID:            "abcd",
+			expectedCode:     codes.OK,
+			expectedFeatures: []string{},
+		},
+		"Given I make a request for an environment that does exist": {
+			envID:            envID123,
+			expectedCode:     codes.OK,
+			expectedFeatures: []string{"harnessappdemodarkmode", "yet_another_flag"},
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			resp, err := client.GetFeatureConfigs(context.Background(), &proxypb.GetFeatureConfigsRequest{EnvironmentId: tc.envID})
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+
+			if tc.expectedCode != codes.OK {
+				return
+			}
+
+			actual := []string{}
+			for _, fc := range resp.GetFeatureConfigs() {
+				actual = append(actual, fc.GetFeature())
+			}
+			assert.ElementsMatch(t, tc.expectedFeatures, actual)
+		})
+	}
+}
+
+func TestGRPCServer_GetFeatureConfig(t *testing.T) {
+	client := setupGRPCServer(t, true, nil)
+
+	fc, err := client.GetFeatureConfig(context.Background(), &proxypb.GetFeatureConfigRequest{EnvironmentId: envID123, Identifier: "harnessappdemodarkmode"})
+	assert.Nil(t, err)
+
+	assert.Equal(t, "boolean", fc.GetKind())
+	assert.Equal(t, "on", fc.GetState())
+	assert.Equal(t, int64(568), fc.GetVersion())
+	assert.Equal(t, "true", fc.GetDefaultServe().GetVariation())
+	assert.Len(t, fc.GetVariations(), 2)
+	assert.Len(t, fc.GetRules(), 1)
+	assert.Equal(t, []string{"55"}, fc.GetRules()[0].GetClauses()[0].GetValues())
+	assert.Equal(t, []string{"flagsTeam"}, fc.GetVariationToTargetMap()[0].GetTargetSegments())
+
+	_, err = client.GetFeatureConfig(context.Background(), &proxypb.GetFeatureConfigRequest{EnvironmentId: envID123, Identifier: "foo"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCServer_GetTargetSegments(t *testing.T) {
+	client := setupGRPCServer(t, true, nil)
+
+	resp, err := client.GetTargetSegments(context.Background(), &proxypb.GetTargetSegmentsRequest{EnvironmentId: envID123})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, resp.GetSegments())
+
+	segment, err := client.GetTargetSegment(context.Background(), &proxypb.GetTargetSegmentRequest{EnvironmentId: envID123, Identifier: resp.GetSegments()[0].GetIdentifier()})
+	assert.Nil(t, err)
+	assert.Equal(t, resp.GetSegments()[0].GetName(), segment.GetName())
+
+	_, err = client.GetTargetSegment(context.Background(), &proxypb.GetTargetSegmentRequest{EnvironmentId: envID123, Identifier: "foo"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCServer_GetEvaluations(t *testing.T) {
+	client := setupGRPCServer(t, true, nil)
+
+	resp, err := client.GetEvaluations(context.Background(), &proxypb.GetEvaluationsRequest{EnvironmentId: envID123, TargetIdentifier: "foo"})
+	assert.Nil(t, err)
+
+	expected := []*proxypb.Evaluation{
+		{Flag: "harnessappdemodarkmode", Identifier: "true", Kind: "boolean", Value: "true"},
+		{Flag: "yet_another_flag", Identifier: "1", Kind: "string", Value: "1"},
+	}
+	// Evaluations aren't returned in any particular order
+	actual := resp.GetEvaluations()
+	sort.Slice(actual, func(i, j int) bool { return actual[i].GetFlag() < actual[j].GetFlag() })
+
+	assert.Len(t, actual, len(expected))
+	for i, e := range expected {
+		assert.Equal(t, e.GetFlag(), actual[i].GetFlag())
+		assert.Equal(t, e.GetIdentifier(), actual[i].GetIdentifier())
+		assert.Equal(t, e.GetKind(), actual[i].GetKind())
+		assert.Equal(t, e.GetValue(), actual[i].GetValue())
+	}
+
+	evaluation, err := client.GetEvaluation(context.Background(), &proxypb.GetEvaluationRequest{EnvironmentId: envID123, TargetIdentifier: "foo", FeatureIdentifier: "yet_another_flag"})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", evaluation.GetValue())
+}
+
+func TestGRPCServer_PostMetrics(t *testing.T) {
+	var stored domain.MetricsRequest
+
+	client := setupGRPCServer(t, true, nil, setupWithMetricService(&mockMetricService{storeMetrics: func(req domain.MetricsRequest) error {
+		stored = req
+		return nil
+	}}))
+
+	_, err := client.PostMetrics(context.Background(), &proxypb.PostMetricsRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.PostMetrics(context.Background(), &proxypb.PostMetricsRequest{
+		EnvironmentId: envID123,
+		MetricsData: []*proxypb.MetricsData{
+			{Timestamp: 1, Count: 2, MetricsType: "FFMETRICS", Attributes: []*proxypb.KeyValue{{Key: "featureName", Value: "foo"}}},
+		},
+		TargetData: []*proxypb.TargetData{
+			{Identifier: "bar", Name: "bar"},
+		},
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, envID123, stored.EnvironmentID)
+	assert.Equal(t, 2, (*stored.MetricsData)[0].Count)
+	assert.Equal(t, "foo", (*stored.MetricsData)[0].Attributes[0].Value)
+	assert.Equal(t, "bar", (*stored.TargetData)[0].Identifier)
+}
+
+func TestGRPCServer_Subscribe(t *testing.T) {
+	broadcaster := stream.NewBroadcaster(log.NoOpLogger{})
+
+	t.Run("Given streaming isn't enabled", func(t *testing.T) {
+		client := setupGRPCServer(t, true, nil)
+
+		sub, err := client.Subscribe(context.Background(), &proxypb.SubscribeRequest{ApiKey: apiKey1})
+		assert.Nil(t, err)
+
+		_, err = sub.Recv()
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+
+	t.Run("Given the Saas stream is unhealthy", func(t *testing.T) {
+		client := setupGRPCServer(t, true, broadcaster, setupWithHealthySaasStream(func(string) bool { return false }))
+
+		sub, err := client.Subscribe(context.Background(), &proxypb.SubscribeRequest{ApiKey: apiKey1})
+		assert.Nil(t, err)
+
+		_, err = sub.Recv()
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+
+	t.Run("Given I subscribe with a valid api key", func(t *testing.T) {
+		client := setupGRPCServer(t, true, broadcaster)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", "req-123")
+		sub, err := client.Subscribe(ctx, &proxypb.SubscribeRequest{ApiKey: apiKey1})
+		assert.Nil(t, err)
+
+		assert.Eventually(t, func() bool {
+			return broadcaster.Connections()[envID123] == 1
+		}, time.Second, 10*time.Millisecond)
+
+		msg := domain.SSEMessage{Event: "patch", Domain: "flag", Identifier: "foo", Version: 3, Environment: envID123}
+		assert.Nil(t, broadcaster.Pub(context.Background(), envID123, msg))
+
+		event, err := sub.Recv()
+		assert.Nil(t, err)
+		assert.Equal(t, "patch", event.GetEvent())
+		assert.Equal(t, "flag", event.GetDomain())
+		assert.Equal(t, "foo", event.GetIdentifier())
+		assert.Equal(t, int64(3), event.GetVersion())
+		assert.Equal(t, envID123, event.GetEnvironment())
+
+		// The request ID is sent back on streams like it is for unary requests
+		header, err := sub.Header()
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"req-123"}, header.Get("x-request-id"))
+	})
+}
@@ -15,25 +15,39 @@ import (
 const (
 	authRoute                     = "/client/auth"
 	healthRoute                   = "/health"
+	readyRoute                    = "/ready"
 	featureConfigsRoute           = "/client/env/:environment_uuid/feature-configs"
 	featureConfigsIdentifierRoute = "/client/env/:environment_uuid/feature-configs/:identifier"
 	segmentsRoute                 = "/client/env/:environment_uuid/target-segments"
 	segmentsIdentifierRoute       = "/client/env/:environment_uuid/target-segments/:identifier"
 	evaluationsRoute              = "/client/env/:environment_uuid/target/:target/evaluations"
 	evaluationsFlagRoute          = "/client/env/:environment_uuid/target/:target/evaluations/:feature"
+	batchEvaluationsRoute         = "/client/env/:environment_uuid/target-evaluations"
 	streamRoute                   = "/stream"
 	metricsRoute                  = "/metrics/:environment_uuid"
+
+	adminPrefix             = "/admin"
+	adminEnvironmentsRoute  = "/environments"
+	adminEnvironmentRoute   = "/environments/:environment_uuid"
+	adminInventoryRoute     = "/inventory"
+	adminStreamsRoute       = "/streams"
+	adminHealthRoute        = "/health"
+	adminOverridesRoute     = "/environments/:environment_uuid/overrides"
+	adminOverrideRoute      = "/environments/:environment_uuid/overrides/:feature"
+	adminOverrideAuditRoute = "/environments/:environment_uuid/overrides/audit"
 )
 
 var proxyRoutes = domain.NewImmutableSet(map[string]struct{}{
 	authRoute:                     {},
 	healthRoute:                   {},
+	readyRoute:                    {},
 	featureConfigsRoute:           {},
 	featureConfigsIdentifierRoute: {},
 	segmentsRoute:                 {},
 	segmentsIdentifierRoute:       {},
 	evaluationsRoute:              {},
 	evaluationsFlagRoute:          {},
+	batchEvaluationsRoute:         {},
 	streamRoute:                   {},
 	metricsRoute:                  {},
 })
@@ -43,6 +57,17 @@ type prometheusRegister interface {
 	prometheus.Gatherer
 }
 
+// sseServer is the interface for a type that can hold open SSE streams with SDKs
+type sseServer interface {
+	ServeStream(ctx context.Context, w http.ResponseWriter, channel string, lastEventID string) error
+}
+
+// readiness is the interface for a type that knows whether the Proxy is ready to serve SDK requests
+type readiness interface {
+	Ready() bool
+	Status() domain.ReadinessResponse
+}
+
 // HTTPServer is an http server that handles http requests
 type HTTPServer struct {
 	router     *echo.Echo
@@ -51,11 +76,55 @@ type HTTPServer struct {
 	tlsEnabled bool
 	tlsCert    string
 	tlsKey     string
+	sseServer  sseServer
+	readiness  readiness
+	history    domain.EventHistory
+
+	adminEndpoints  *AdminEndpoints
+	adminMiddleware []echo.MiddlewareFunc
+}
+
+// HTTPServerOption is type for passing optional config to the HTTPServer
+type HTTPServerOption func(h *HTTPServer)
+
+// WithSSEServer configures the HTTPServer to serve /stream requests itself using
+// the passed sseServer rather than handing them off to Pushpin via GRIP headers
+func WithSSEServer(s sseServer) HTTPServerOption {
+	return func(h *HTTPServer) {
+		h.sseServer = s
+	}
+}
+
+// WithEventHistory configures the HTTPServer to replay the events that SDKs missed
+// when they reconnect to streams that are handed off to Pushpin. Streams served
+// by an sseServer are replayed by the sseServer itself.
+func WithEventHistory(eh domain.EventHistory) HTTPServerOption {
+	return func(h *HTTPServer) {
+		h.history = eh
+	}
+}
+
+// WithReadiness configures the HTTPServer to serve the readiness of the Proxy on
+// /ready and to reject SDK requests with a 503 until the Proxy is ready
+func WithReadiness(r readiness) HTTPServerOption {
+	return func(h *HTTPServer) {
+		h.readiness = r
+	}
+}
+
+// WithAdminEndpoints registers the admin API under /admin. The passed
+// middleware is only applied to the admin routes and should be used to authenticate
+// admin requests since they aren't covered by the SDK auth middleware.
+func WithAdminEndpoints(e *AdminEndpoints, mw ...echo.MiddlewareFunc) HTTPServerOption {
+	return func(h *HTTPServer) {
+		h.adminEndpoints = e
+		h.adminMiddleware = mw
+	}
 }
 
 // NewHTTPServer registers the passed endpoints against routes and returns an
 // HTTPServer that's ready to use
-func NewHTTPServer(port int, e *Endpoints, l log.Logger, tlsEnabled bool, tlsCert string, tlsKey string) *HTTPServer {
+func NewHTTPServer(port int, e *Endpoints, l log.Logger, tlsEnabled bool, tlsCert string, tlsKey string, opts ...HTTPServerOption) *HTTPServer {
 	l = l.With("component", "HTTPServer")
 
 	router := echo.New()
@@ -76,7 +145,15 @@ func NewHTTPServer(port int, e *Endpoints, l log.Logger, tlsEnabled bool, tlsCer
 		tlsCert:    tlsCert,
 		tlsKey:     tlsKey,
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
 	h.registerEndpoints(e)
+	if h.adminEndpoints != nil {
+		h.registerAdminEndpoints(h.adminEndpoints)
+	}
 	return h
 }
 
@@ -109,12 +186,28 @@ func (h *HTTPServer) Use(mw ...echo.MiddlewareFunc) {
 }
 
 func (h *HTTPServer) registerEndpoints(e *Endpoints) {
+	streamEncoder := encodeStreamResponse
+	if h.history != nil {
+		streamEncoder = encodeReplayStreamResponse(h.log, h.history)
+	}
+	if h.sseServer != nil {
+		streamEncoder = encodeSSEStreamResponse(h.sseServer)
+	}
+
+	// Routes that SDKs use to fetch config are rejected until the Proxy is ready
+	// so that they don't get served empty or partial config
+	clientMiddleware := []echo.MiddlewareFunc{}
+	if h.readiness != nil {
+		clientMiddleware = append(clientMiddleware, readinessMiddleware(h.readiness))
+		h.router.GET(readyRoute, readinessHandler(h.readiness))
+	}
+
 	h.router.POST(authRoute, NewUnaryHandler(
 		e.PostAuthenticate,
 		decodeAuthRequest,
 		encodeResponse,
 		encodeEchoError,
-	))
+	), clientMiddleware...)
 
 	h.router.GET(healthRoute, NewUnaryHandler(
 		e.Health,
@@ -126,51 +219,58 @@ func (h *HTTPServer) registerEndpoints(e *Endpoints) {
 	h.router.GET(featureConfigsRoute, NewUnaryHandler(
 		e.GetFeatureConfigs,
 		decodeGetFeatureConfigsRequest,
-		encodeResponse,
+		encodeFeatureConfigsResponse,
 		encodeEchoError,
-	))
+	), clientMiddleware...)
 
 	h.router.GET(featureConfigsIdentifierRoute, NewUnaryHandler(
 		e.GetFeatureConfigsByIdentifier,
 		decodeGetFeatureConfigsByIdentifierRequest,
 		encodeResponse,
 		encodeEchoError,
-	))
+	), clientMiddleware...)
 
 	h.router.GET(segmentsRoute, NewUnaryHandler(
 		e.GetTargetSegments,
 		decodeGetTargetSegmentsRequest,
-		encodeResponse,
+		encodeTargetSegmentsResponse,
 		encodeEchoError,
-	))
+	), clientMiddleware...)
 
 	h.router.GET(segmentsIdentifierRoute, NewUnaryHandler(
 		e.GetTargetSegmentsByIdentifier,
 		decodeGetTargetSegmentsByIdentifierRequest,
 		encodeResponse,
 		encodeEchoError,
-	))
+	), clientMiddleware...)
 
 	h.router.GET(evaluationsRoute, NewUnaryHandler(
 		e.GetEvaluations,
 		decodeGetEvaluationsRequest,
 		encodeResponse,
 		encodeEchoError,
-	))
+	), clientMiddleware...)
 
 	h.router.GET(evaluationsFlagRoute, NewUnaryHandler(
 		e.GetEvaluationsByFeature,
 		decodeGetEvaluationsByFeatureRequest,
 		encodeResponse,
 		encodeEchoError,
-	))
+	), clientMiddleware...)
+
+	h.router.POST(batchEvaluationsRoute, NewUnaryHandler(
+		e.PostBatchEvaluations,
+		decodeBatchEvaluationsRequest,
+		encodeResponse,
+		encodeEchoError,
+	), clientMiddleware...)
 
 	h.router.GET(streamRoute, NewUnaryHandler(
 		e.GetStream,
 		decodeGetStreamRequest,
-		encodeStreamResponse,
+		streamEncoder,
 		encodeEchoError,
-	))
+	), clientMiddleware...)
 
 	h.router.POST(metricsRoute, NewUnaryHandler(
 		e.PostMetrics,
@@ -180,6 +280,98 @@ func (h *HTTPServer) registerEndpoints(e *Endpoints) {
 	))
 }
 
+// readinessHandler returns a 200 if the Proxy is ready and a 503 if it isn't
+func readinessHandler(r readiness) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status := r.Status()
+		if !status.Ready {
+			return c.JSON(http.StatusServiceUnavailable, status)
+		}
+		return c.JSON(http.StatusOK, status)
+	}
+}
+
+// readinessMiddleware rejects requests with a 503 until the Proxy is ready
+func readinessMiddleware(r readiness) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if r.Ready() {
+				return next(c)
+			}
+
+			c.Response().Header().Set("Retry-After", "5")
+			return c.JSON(http.StatusServiceUnavailable, r.Status())
+		}
+	}
+}
+
+func (h *HTTPServer) registerAdminEndpoints(e *AdminEndpoints) {
+	admin := h.router.Group(adminPrefix, h.adminMiddleware...)
+
+	admin.GET(adminEnvironmentsRoute, NewUnaryHandler(
+		e.GetEnvironments,
+		decodeAdminRequest,
+		encodeResponse,
+		encodeEchoError,
+	))
+
+	admin.GET(adminEnvironmentRoute, NewUnaryHandler(
+		e.GetEnvironment,
+		decodeAdminEnvironmentRequest,
+		encodeResponse,
+		encodeEchoError,
+	))
+
+	admin.GET(adminInventoryRoute, NewUnaryHandler(
+		e.GetInventory,
+		decodeAdminRequest,
+		encodeResponse,
+		encodeEchoError,
+	))
+
+	admin.GET(adminStreamsRoute, NewUnaryHandler(
+		e.GetStreams,
+		decodeAdminRequest,
+		encodeResponse,
+		encodeEchoError,
+	))
+
+	admin.GET(adminHealthRoute, NewUnaryHandler(
+		e.GetHealth,
+		decodeAdminRequest,
+		encodeResponse,
+		encodeEchoError,
+	))
+
+	admin.GET(adminOverridesRoute, NewUnaryHandler(
+		e.GetFlagOverrides,
+		decodeAdminEnvironmentRequest,
+		encodeResponse,
+		encodeEchoError,
+	))
+
+	admin.PUT(adminOverrideRoute, NewUnaryHandler(
+		e.PutFlagOverride,
+		decodeAdminPutFlagOverrideRequest,
+		encodeResponse,
+		encodeEchoError,
+	))
+
+	admin.DELETE(adminOverrideRoute, NewUnaryHandler(
+		e.DeleteFlagOverride,
+		decodeAdminDeleteFlagOverrideRequest,
+		encodeResponse,
+		encodeEchoError,
+	))
+
+	admin.GET(adminOverrideAuditRoute, NewUnaryHandler(
+		e.GetFlagOverrideAudit,
+		decodeAdminEnvironmentRequest,
+		encodeResponse,
+		encodeEchoError,
+	))
+}
+
 // WithCustomHandler lets you register a custom handler with the HTTPServer
 // It will error if you try to register a handler for a route that's already defined.
 func (h *HTTPServer) WithCustomHandler(method string, route string, handler http.Handler) error {
@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -10,6 +11,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -20,6 +22,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/harness/ff-proxy/v2/cache"
 	"github.com/harness/ff-proxy/v2/config/local"
@@ -28,8 +31,10 @@ import (
 	"github.com/harness/ff-proxy/v2/hash"
 	"github.com/harness/ff-proxy/v2/log"
 	"github.com/harness/ff-proxy/v2/middleware"
+	"github.com/harness/ff-proxy/v2/overrides"
 	proxyservice "github.com/harness/ff-proxy/v2/proxy-service"
 	"github.com/harness/ff-proxy/v2/repository"
+	"github.com/harness/ff-proxy/v2/stream"
 	"github.com/harness/ff-proxy/v2/token"
 )
 
@@ -103,9 +108,15 @@ type setupConfig struct {
 	promReg           prometheusRegister
 	sdkClients        *mockSDKClient
 	healthFn          func(ctx context.Context) domain.HealthResponse
-	healthySaasStream func() bool
+	healthySaasStream func(envID string) bool
 	andRulesEnabled   bool
 	port              int
+	sseServer         sseServer
+	adminToken        string
+	readiness         readiness
+	offlineConfigDir  string
+	flagOverrides     *repository.FlagOverrideRepo
+	eventHistory      domain.EventHistory
 }
 
 type setupOpts func(s *setupConfig)
@@ -146,6 +157,12 @@ func setupWithClientService(c *mockClientService) setupOpts {
 	}
 }
 
+func setupWithMetricService(m *mockMetricService) setupOpts {
+	return func(s *setupConfig) {
+		s.metricService = m
+	}
+}
+
 func setupHealthFn(fn func(ctx context.Context) domain.HealthResponse) setupOpts {
 	return func(s *setupConfig) {
 		s.healthFn = fn
@@ -158,7 +175,7 @@ func setupWithCache(c cache.Cache) setupOpts {
 	}
 }
 
-func setupWithHealthySaasStream(fn func() bool) setupOpts {
+func setupWithHealthySaasStream(fn func(envID string) bool) setupOpts {
 	return func(s *setupConfig) {
 		s.healthySaasStream = fn
 	}
@@ -176,15 +193,108 @@ func setupWithPort(port int) setupOpts {
 	}
 }
 
+func setupWithSSEServer(sse sseServer) setupOpts {
+	return func(s *setupConfig) {
+		s.sseServer = sse
+	}
+}
+
+func setupWithAdminToken(token string) setupOpts {
+	return func(s *setupConfig) {
+		s.adminToken = token
+	}
+}
+
+func setupWithReadiness(r readiness) setupOpts {
+	return func(s *setupConfig) {
+		s.readiness = r
+	}
+}
+
+// setupWithOfflineConfig runs the service in offline mode with the repos, including
+// the TargetRepo, populated from the config in dir
+func setupWithOfflineConfig(dir string) setupOpts {
+	return func(s *setupConfig) {
+		s.offlineConfigDir = dir
+	}
+}
+
+// setupWithEventHistory sets the EventHistory that streams handed off to Pushpin are replayed from
+func setupWithEventHistory(h domain.EventHistory) setupOpts {
+	return func(s *setupConfig) {
+		s.eventHistory = h
+	}
+}
+
+// setupWithFlagOverrides enables flag overrides in the FeatureFlagRepo and the admin API
+func setupWithFlagOverrides() setupOpts {
+	return func(s *setupConfig) {
+		s.flagOverrides = &repository.FlagOverrideRepo{}
+	}
+}
+
 // setupHTTPServer is a helper that loads test config for populating the repos
 // and injects all the required dependencies into the proxy service and http server
 func setupHTTPServer(t *testing.T, bypassAuth bool, opts ...setupOpts) *HTTPServer {
-	fileSystem := os.DirFS("../config/local/test")
-	config, err := local.NewConfig(fileSystem)
-	if err != nil {
-		t.Fatal(err)
+	endpoints, setupConfig := setupEndpoints(t, opts...)
+	logger := log.NoOpLogger{}
+
+	repo := mockRepo{
+		getFn: func(context context.Context, key domain.AuthAPIKey) (string, bool, error) {
+			return "", true, nil
+		},
+	}
+
+	serverOpts := []HTTPServerOption{}
+	if setupConfig.sseServer != nil {
+		serverOpts = append(serverOpts, WithSSEServer(setupConfig.sseServer))
+	}
+
+	if setupConfig.readiness != nil {
+		serverOpts = append(serverOpts, WithReadiness(setupConfig.readiness))
+	}
+
+	if setupConfig.eventHistory != nil {
+		serverOpts = append(serverOpts, WithEventHistory(setupConfig.eventHistory))
+	}
+
+	if setupConfig.adminToken != "" {
+		adminConfig := proxyservice.AdminConfig{
+			Logger:        logger,
+			AuthRepo:      *setupConfig.authRepo,
+			FeatureRepo:   *setupConfig.featureRepo,
+			SegmentRepo:   *setupConfig.segmentRepo,
+			InventoryRepo: repository.NewInventoryRepo(setupConfig.cache, logger),
+			ProxyKeys:     []string{"proxy-key"},
+			ConnectedStreams: func() map[string]interface{} {
+				return map[string]interface{}{"1234": ""}
+			},
+			Health: setupConfig.healthFn,
+		}
+
+		if setupConfig.flagOverrides != nil {
+			adminConfig.Overrides = overrides.NewService(logger, *setupConfig.flagOverrides, *setupConfig.featureRepo, *setupConfig.authRepo, domain.NoOpMessageHandler{})
+		}
+
+		adminService := proxyservice.NewAdminService(adminConfig)
+		serverOpts = append(serverOpts, WithAdminEndpoints(NewAdminEndpoints(adminService), middleware.NewEchoAdminAuthMiddleware(setupConfig.adminToken)))
 	}
 
+	server := NewHTTPServer(setupConfig.port, endpoints, logger, false, "", "", serverOpts...)
+	server.Use(
+		middleware.NewCorsMiddleware(),
+		middleware.AllowQuerySemicolons(),
+		middleware.NewEchoRequestIDMiddleware(),
+		middleware.NewEchoLoggingMiddleware(logger),
+		middleware.NewEchoAuthMiddleware(logger, repo, []byte(`secret`), bypassAuth),
+		middleware.NewPrometheusMiddleware(prometheus.NewRegistry()),
+	)
+	return server
+}
+
+// setupEndpoints is a helper that loads test config for populating the repos
+// and injects all the required dependencies into the proxy service and endpoints
+func setupEndpoints(t *testing.T, opts ...setupOpts) (*Endpoints, *setupConfig) {
 	setupConfig := &setupConfig{}
 	for _, opt := range opts {
 		opt(setupConfig)
@@ -203,8 +313,16 @@ func setupHTTPServer(t *testing.T, bypassAuth bool, opts ...setupOpts) *HTTPServ
 		setupConfig.cache = cache.NewMemoizeCache(redisClient, 1*time.Minute, 2*time.Minute, nil)
 	}
 
+	if setupConfig.flagOverrides != nil {
+		or := repository.NewFlagOverrideRepo(setupConfig.cache)
+		setupConfig.flagOverrides = &or
+	}
+
 	if setupConfig.featureRepo == nil {
 		fr := repository.NewFeatureFlagRepo(setupConfig.cache)
+		if setupConfig.flagOverrides != nil {
+			fr = repository.NewFeatureFlagRepo(setupConfig.cache, repository.WithFlagOverrides(*setupConfig.flagOverrides))
+		}
 
 		setupConfig.featureRepo = &fr
 	}
@@ -259,7 +377,7 @@ func setupHTTPServer(t *testing.T, bypassAuth bool, opts ...setupOpts) *HTTPServ
 	}
 
 	if setupConfig.healthySaasStream == nil {
-		setupConfig.healthySaasStream = func() bool { return true }
+		setupConfig.healthySaasStream = func(string) bool { return true }
 	}
 
 	if setupConfig.port == 0 {
@@ -270,6 +388,18 @@ func setupHTTPServer(t *testing.T, bypassAuth bool, opts ...setupOpts) *HTTPServ
 
 	tokenSource := token.NewSource(logger, setupConfig.authRepo, hash.NewSha256(), []byte(`secret`))
 
+	configDir := "../config/local/test"
+	configOpts := []func(c *local.Config){}
+	if setupConfig.offlineConfigDir != "" {
+		configDir = setupConfig.offlineConfigDir
+		configOpts = append(configOpts, local.WithTargetRepo(*setupConfig.targetRepo))
+	}
+
+	config, err := local.NewConfig(os.DirFS(configDir), configOpts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	err = config.Populate(context.Background(), setupConfig.authRepo, setupConfig.featureRepo, setupConfig.segmentRepo)
 	assert.Nil(t, err)
 
@@ -284,31 +414,14 @@ func setupHTTPServer(t *testing.T, bypassAuth bool, opts ...setupOpts) *HTTPServ
 		AuthFn:             tokenSource.GenerateToken,
 		ClientService:      setupConfig.clientService,
 		MetricStore:        setupConfig.metricService,
-		Offline:            false,
+		Offline:            setupConfig.offlineConfigDir != "",
 		Hasher:             hash.NewSha256(),
 		HealthySaasStream:  setupConfig.healthySaasStream,
 		AndRulesEnabled:    setupConfig.andRulesEnabled,
 		SDKStreamConnected: func(envID string) {},
 		ForwardTargets:     true,
 	})
-	endpoints := NewEndpoints(service)
-
-	repo := mockRepo{
-		getFn: func(context context.Context, key domain.AuthAPIKey) (string, bool, error) {
-			return "", true, nil
-		},
-	}
-
-	server := NewHTTPServer(setupConfig.port, endpoints, logger, false, "", "")
-	server.Use(
-		middleware.NewCorsMiddleware(),
-		middleware.AllowQuerySemicolons(),
-		middleware.NewEchoRequestIDMiddleware(),
-		middleware.NewEchoLoggingMiddleware(logger),
-		middleware.NewEchoAuthMiddleware(logger, repo, []byte(`secret`), bypassAuth),
-		middleware.NewPrometheusMiddleware(prometheus.NewRegistry()),
-	)
-	return server
+	return NewEndpoints(service), setupConfig
 }
 
 // variationToTargetMap:null is intentional here - refer to FFM-3246 before removing
@@ -395,6 +508,124 @@ func TestHTTPServer_GetFeatureConfig(t *testing.T) {
 	}
 }
 
+// TestHTTPServer_ConditionalGets sets up a service with repositories backed by a HashCache and
+// checks that the feature-configs and target-segments endpoints return an ETag and a 304 when
+// the If-None-Match header matches it
+func TestHTTPServer_ConditionalGets(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	hashCache := cache.NewHashCache(cache.NewKeyValCache(redisClient), 1*time.Minute, 2*time.Minute)
+
+	// setup HTTPServer & service with auth bypassed
+	server := setupHTTPServer(t, true, setupWithCache(hashCache), setupWithAndRulesEnabled(true))
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	getETag := func(t *testing.T, url string) string {
+		resp, err := testServer.Client().Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp.Header.Get("ETag")
+	}
+
+	featureConfigsURL := fmt.Sprintf("%s/client/env/1234/feature-configs", testServer.URL)
+	segmentsURL := fmt.Sprintf("%s/client/env/1234/target-segments", testServer.URL)
+	segmentsV2URL := fmt.Sprintf("%s/client/env/1234/target-segments?rules=v2", testServer.URL)
+
+	featureConfigsETag := getETag(t, featureConfigsURL)
+	segmentsETag := getETag(t, segmentsURL)
+	segmentsV2ETag := getETag(t, segmentsV2URL)
+
+	assert.NotEmpty(t, featureConfigsETag)
+	assert.NotEmpty(t, segmentsETag)
+	assert.NotEqual(t, segmentsETag, segmentsV2ETag)
+
+	testCases := map[string]struct {
+		url                string
+		ifNoneMatch        string
+		expectedStatusCode int
+		expectedETag       string
+		expectedEmptyBody  bool
+	}{
+		"Given I make a feature-configs request with no If-None-Match header": {
+			url:                featureConfigsURL,
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       featureConfigsETag,
+		},
+		"Given I make a feature-configs request with an If-None-Match header that matches": {
+			url:                featureConfigsURL,
+			ifNoneMatch:        featureConfigsETag,
+			expectedStatusCode: http.StatusNotModified,
+			expectedETag:       featureConfigsETag,
+			expectedEmptyBody:  true,
+		},
+		"Given I make a feature-configs request with a weak If-None-Match header that matches": {
+			url:                featureConfigsURL,
+			ifNoneMatch:        fmt.Sprintf(`"foo", W/%s`, featureConfigsETag),
+			expectedStatusCode: http.StatusNotModified,
+			expectedETag:       featureConfigsETag,
+			expectedEmptyBody:  true,
+		},
+		"Given I make a feature-configs request with an If-None-Match header that doesn't match": {
+			url:                featureConfigsURL,
+			ifNoneMatch:        `"foo"`,
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       featureConfigsETag,
+		},
+		"Given I make a target-segments request with an If-None-Match header that matches": {
+			url:                segmentsURL,
+			ifNoneMatch:        segmentsETag,
+			expectedStatusCode: http.StatusNotModified,
+			expectedETag:       segmentsETag,
+			expectedEmptyBody:  true,
+		},
+		"Given I make a v2 target-segments request with the v1 ETag": {
+			url:                segmentsV2URL,
+			ifNoneMatch:        segmentsETag,
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       segmentsV2ETag,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+
+			resp, err := testServer.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			assert.Equal(t, tc.expectedETag, resp.Header.Get("ETag"))
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("(%s): failed to read response body: %s", desc, err)
+			}
+			assert.Equal(t, tc.expectedEmptyBody, len(body) == 0)
+		})
+	}
+}
+
 // harnessAppDemoDarkMode is the expected response body for a FeatureConfigsByIdentifier request where identifier='harnessappdemodarkmode' - the newline at the end is intentional
 var harnessAppDemoDarkMode = []byte(`{"defaultServe":{"variation":"true"},"environment":"featureflagsqa","feature":"harnessappdemodarkmode","kind":"boolean","offVariation":"false","prerequisites":[],"project":"FeatureFlagsQADemo","rules":[{"clauses":[{"attribute":"age","id":"79f5bca0-17ca-42c2-8934-5cee840fe2e0","negate":false,"op":"equal","values":["55"]}],"priority":1,"ruleId":"8756c207-abf8-4202-83fd-dedf5d27e2c2","serve":{"variation":"false"}}],"state":"on","variationToTargetMap":[{"targetSegments":["flagsTeam"],"targets":[{"identifier":"davej","name":"Dave Johnston"}],"variation":"false"}],"variations":[{"identifier":"true","name":"True","value":"true"},{"identifier":"false","name":"False","value":"false"}],"version":568}
 `)
@@ -794,6 +1025,134 @@ func TestHTTPServer_GetEvaluations(t *testing.T) {
 	}
 }
 
+const (
+	offlineFeatureConfig = `[
+  {
+    "feature": "beta-banner",
+    "environment": "offline",
+    "kind": "boolean",
+    "state": "on",
+    "defaultServe": {"variation": "false"},
+    "offVariation": "false",
+    "prerequisites": [],
+    "rules": [
+      {
+        "clauses": [{"attribute": "", "id": "1", "negate": false, "op": "segmentMatch", "values": ["beta-testers"]}],
+        "priority": 1,
+        "ruleId": "beta-testers-rule",
+        "serve": {"variation": "true"}
+      }
+    ],
+    "variationToTargetMap": [],
+    "variations": [
+      {"identifier": "true", "name": "True", "value": "true"},
+      {"identifier": "false", "name": "False", "value": "false"}
+    ],
+    "version": 1
+  },
+  {
+    "feature": "checkout-region",
+    "environment": "offline",
+    "kind": "string",
+    "state": "on",
+    "defaultServe": {"variation": "global"},
+    "offVariation": "global",
+    "prerequisites": [],
+    "rules": [
+      {
+        "clauses": [{"attribute": "region", "id": "2", "negate": false, "op": "in", "values": ["eu", "uk"]}],
+        "priority": 1,
+        "ruleId": "europe-rule",
+        "serve": {"variation": "europe"}
+      }
+    ],
+    "variationToTargetMap": [],
+    "variations": [
+      {"identifier": "global", "name": "Global", "value": "global"},
+      {"identifier": "europe", "name": "Europe", "value": "europe"}
+    ],
+    "version": 1
+  }
+]`
+
+	offlineSegments = `[
+  {
+    "identifier": "beta-testers",
+    "name": "beta-testers",
+    "environment": "offline",
+    "excluded": [],
+    "included": [],
+    "rules": [{"attribute": "plan", "id": "3", "negate": false, "op": "equal", "values": ["enterprise"]}],
+    "version": 1
+  }
+]`
+
+	offlineTargets = `[
+  {"identifier": "alice", "name": "alice", "environment": "offline", "attributes": {"plan": "enterprise", "region": "eu"}},
+  {"identifier": "bob", "name": "bob", "environment": "offline", "attributes": {"plan": "free", "region": "us"}}
+]`
+)
+
+// TestHTTPServer_OfflineEvaluations loads config that has attribute based rules from an
+// offline config directory and checks the targets from it are used to evaluate them
+func TestHTTPServer_OfflineEvaluations(t *testing.T) {
+	dir := t.TempDir()
+	envDir := filepath.Join(dir, "env-5678")
+	require.NoError(t, os.Mkdir(envDir, 0o755))
+
+	files := map[string]string{
+		"feature_config.json": offlineFeatureConfig,
+		"segments.json":       offlineSegments,
+		"targets.json":        offlineTargets,
+		"auth_config.json":    `["5678"]`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(envDir, name), []byte(content), 0o600))
+	}
+
+	server := setupHTTPServer(t, true, setupWithOfflineConfig(dir), setupWithAndRulesEnabled(true))
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	testCases := map[string]struct {
+		target   string
+		expected map[string]string
+	}{
+		"Given I request evaluations for a target whose attributes match the rules": {
+			target:   "alice",
+			expected: map[string]string{"beta-banner": "true", "checkout-region": "europe"},
+		},
+		"Given I request evaluations for a target whose attributes don't match the rules": {
+			target:   "bob",
+			expected: map[string]string{"beta-banner": "false", "checkout-region": "global"},
+		},
+		"Given I request evaluations for a target that isn't in the offline config": {
+			target:   "carol",
+			expected: map[string]string{"beta-banner": "false", "checkout-region": "global"},
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			resp, err := testServer.Client().Get(fmt.Sprintf("%s/client/env/5678/target/%s/evaluations", testServer.URL, tc.target))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			evaluations := []clientgen.Evaluation{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&evaluations))
+
+			actual := map[string]string{}
+			for _, e := range evaluations {
+				actual[e.Flag] = e.Value
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
 var (
 	// darkModeEvaluationFalse is the expected response body for a EvaluationsByFeature request when identifer='james' and feature='harnessappdemodarkmode '- the newline at the end is intentional
 	darkModeEvaluationFalse = []byte(`{"flag":"harnessappdemodarkmode","identifier":"false","kind":"boolean","value":"false"}
@@ -893,6 +1252,127 @@ func TestHTTPServer_GetEvaluationsByFeature(t *testing.T) {
 	}
 }
 
+// TestHTTPServer_PostBatchEvaluations sets up a service with repositories
+// populated from config/test, injects it into the HTTPServer and makes HTTP
+// requests to the /client/env/{environmentUUID}/target-evaluations endpoint
+func TestHTTPServer_PostBatchEvaluations(t *testing.T) {
+	// setup HTTPServer & service with auth bypassed
+	server := setupHTTPServer(t, true)
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	darkModeTrue := clientgen.Evaluation{Flag: "harnessappdemodarkmode", Identifier: domain.ToPtr("true"), Kind: "boolean", Value: "true"}
+	yetAnotherFlag := clientgen.Evaluation{Flag: "yet_another_flag", Identifier: domain.ToPtr("1"), Kind: "string", Value: "1"}
+
+	testCases := map[string]struct {
+		method             string
+		url                string
+		body               []byte
+		expectedStatusCode int
+		expectedResponse   domain.BatchEvaluationsResponse
+	}{
+		"Given I make a request that isn't a POST request": {
+			method:             http.MethodGet,
+			url:                fmt.Sprintf("%s/client/env/1234/target-evaluations", testServer.URL),
+			expectedStatusCode: http.StatusMethodNotAllowed,
+		},
+		"Given I make a POST request with an empty body": {
+			method:             http.MethodPost,
+			url:                fmt.Sprintf("%s/client/env/1234/target-evaluations", testServer.URL),
+			body:               []byte{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"Given I make a POST request with no targets": {
+			method:             http.MethodPost,
+			url:                fmt.Sprintf("%s/client/env/1234/target-evaluations", testServer.URL),
+			body:               []byte(`{"targets": []}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"Given I make a POST request with a target that has no identifier": {
+			method:             http.MethodPost,
+			url:                fmt.Sprintf("%s/client/env/1234/target-evaluations", testServer.URL),
+			body:               []byte(`{"targets": [{"name": "foo"}]}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"Given I make a POST request with duplicate target identifiers": {
+			method:             http.MethodPost,
+			url:                fmt.Sprintf("%s/client/env/1234/target-evaluations", testServer.URL),
+			body:               []byte(`{"targets": [{"identifier": "foo"}, {"identifier": "foo"}]}`),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		"Given I make a POST request for an environment that doesn't exist": {
+			method:             http.MethodPost,
+			url:                fmt.Sprintf("%s/client/env/abcd/target-evaluations", testServer.URL),
+			body:               []byte(`{"targets": [{"identifier": "foo"}]}`),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: domain.BatchEvaluationsResponse{
+				"foo": {},
+			},
+		},
+		"Given I make a POST request for multiple targets": {
+			method:             http.MethodPost,
+			url:                fmt.Sprintf("%s/client/env/1234/target-evaluations", testServer.URL),
+			body:               []byte(`{"targets": [{"identifier": "foo"}, {"identifier": "bar", "attributes": {"age": "21"}}]}`),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: domain.BatchEvaluationsResponse{
+				"foo": {darkModeTrue, yetAnotherFlag},
+				"bar": {darkModeTrue, yetAnotherFlag},
+			},
+		},
+		"Given I make a POST request for multiple targets with a flag filter": {
+			method:             http.MethodPost,
+			url:                fmt.Sprintf("%s/client/env/1234/target-evaluations", testServer.URL),
+			body:               []byte(`{"targets": [{"identifier": "foo"}, {"identifier": "bar"}], "flags": ["harnessappdemodarkmode", "does-not-exist"]}`),
+			expectedStatusCode: http.StatusOK,
+			expectedResponse: domain.BatchEvaluationsResponse{
+				"foo": {darkModeTrue},
+				"bar": {darkModeTrue},
+			},
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			var req *http.Request
+			var err error
+
+			switch tc.method {
+			case http.MethodPost:
+				req, err = http.NewRequest(http.MethodPost, tc.url, bytes.NewBuffer(tc.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+			case http.MethodGet:
+				req, err = http.NewRequest(http.MethodGet, tc.url, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			resp, err := testServer.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+
+			if tc.expectedResponse != nil {
+				actual := domain.BatchEvaluationsResponse{}
+				if err := json.NewDecoder(resp.Body).Decode(&actual); err != nil {
+					t.Fatalf("(%s): failed to decode response body: %s", desc, err)
+				}
+
+				assert.Equal(t, len(tc.expectedResponse), len(actual))
+				for target, evaluations := range tc.expectedResponse {
+					assert.ElementsMatch(t, evaluations, actual[target])
+				}
+			}
+		})
+	}
+}
+
 func TestHTTPServer_PostMetrics(t *testing.T) {
 	// setup HTTPServer & service with auth bypassed
 	server := setupHTTPServer(t, true)
@@ -1311,18 +1791,24 @@ func TestHTTPServer_Stream(t *testing.T) {
 
 	authRepo := repository.NewAuthRepo(cache.NewMemCache())
 
-	healthySaasStream := func() bool {
+	healthySaasStream := func(string) bool {
 		return true
 	}
 
-	unhealthySaasStream := func() bool {
+	unhealthySaasStream := func(string) bool {
 		return false
 	}
 
+	// otherEnvUnhealthySaasStream is for when the Saas stream for a key that
+	// another environment belongs to is unhealthy
+	otherEnvUnhealthySaasStream := func(env string) bool {
+		return env != env2
+	}
+
 	testCases := map[string]struct {
 		method                  string
 		headers                 http.Header
-		healthySaasStream       func() bool
+		healthySaasStream       func(envID string) bool
 		expectedStatusCode      int
 		expectedResponseHeaders http.Header
 	}{
@@ -1372,10 +1858,23 @@ func TestHTTPServer_Stream(t *testing.T) {
 			},
 		},
 
+		"Given I make a GET request with a valid API Key Header while the stream for another environment isn't connected": {
+			method: http.MethodGet,
+			headers: http.Header{
+				"API-Key": []string{apiKey},
+			},
+			healthySaasStream:  otherEnvUnhealthySaasStream,
+			expectedStatusCode: http.StatusOK,
+			expectedResponseHeaders: http.Header{
+				"Content-Type": []string{"text/event-stream"},
+				"Grip-Channel": []string{envID},
+			},
+		},
+
 		"Given I make a GET request with an API Key Header for a stream that isn't connected": {
 			method: http.MethodGet,
 			headers: http.Header{
-				"API-Key": []string{"apiKey2"},
+				"API-Key": []string{apiKey},
 			},
 			healthySaasStream:  unhealthySaasStream,
 			expectedStatusCode: http.StatusServiceUnavailable,
@@ -1441,6 +1940,441 @@ func TestHTTPServer_Stream(t *testing.T) {
 	}
 }
 
+// TestHTTPServer_StreamWithSSEServer checks that when the HTTPServer is configured with
+// an SSE server it holds the stream open itself rather than handing it off to Pushpin
+func TestHTTPServer_StreamWithSSEServer(t *testing.T) {
+	const (
+		apiKey = "apikey1"
+		envID  = "1234"
+	)
+
+	broadcaster := stream.NewBroadcaster(log.NoOpLogger{})
+
+	server := setupHTTPServer(t, true,
+		setupWithAuthRepo(repository.NewAuthRepo(cache.NewMemCache())),
+		setupWithSSEServer(broadcaster),
+	)
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/stream", testServer.URL), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("API-Key", apiKey)
+
+	resp, err := testServer.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "", resp.Header.Get("Grip-Hold"))
+
+	assert.Eventually(t, func() bool {
+		return broadcaster.Connections()[envID] == 1
+	}, time.Second, 10*time.Millisecond)
+
+	msg := domain.SSEMessage{Event: "patch", Domain: "flag", Identifier: "foo", Version: 1, Environment: envID}
+	assert.Nil(t, broadcaster.Pub(context.Background(), envID, msg))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "event: *\n", line)
+
+	line, err = reader.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Contains(t, line, `"identifier":"foo"`)
+
+	assert.Nil(t, broadcaster.Close(envID))
+	_, err = io.ReadAll(reader)
+	assert.Nil(t, err)
+}
+
+// TestHTTPServer_StreamReplay checks that streams handed off to Pushpin are sent the
+// events the SDK missed in the body of the response
+func TestHTTPServer_StreamReplay(t *testing.T) {
+	const (
+		apiKey = "apikey1"
+		envID  = "1234"
+	)
+
+	history := stream.NewMemoryEventHistory(2)
+	for _, identifier := range []string{"foo", "bar", "baz"} {
+		_, err := history.Add(context.Background(), envID, domain.SSEMessage{Event: "patch", Domain: "flag", Identifier: identifier, Environment: envID})
+		require.NoError(t, err)
+	}
+
+	server := setupHTTPServer(t, true,
+		setupWithAuthRepo(repository.NewAuthRepo(cache.NewMemCache())),
+		setupWithEventHistory(history),
+	)
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	testCases := map[string]struct {
+		lastEventID  string
+		expectedBody string
+	}{
+		"Given the SDK didn't send a Last-Event-ID": {
+			lastEventID:  "",
+			expectedBody: "",
+		},
+		"Given the SDK has seen every event": {
+			lastEventID:  "3",
+			expectedBody: "",
+		},
+		"Given the SDK missed events that are in the history": {
+			lastEventID: "1",
+			expectedBody: `id: 2
+event: *
+data: {"event":"patch","domain":"flag","identifier":"bar","version":0,"environment":"1234","apiKey":"","eventId":2}
+
+id: 3
+event: *
+data: {"event":"patch","domain":"flag","identifier":"baz","version":0,"environment":"1234","apiKey":"","eventId":3}
+
+`,
+		},
+		"Given the SDK missed events that are no longer in the history": {
+			lastEventID: "0",
+			expectedBody: `id: 3
+event: *
+data: {"event":"refetchAll","domain":"proxy","identifier":"","version":0,"environment":"1234","apiKey":"","eventId":3}
+
+`,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/stream", testServer.URL), nil)
+			require.NoError(t, err)
+			req.Header.Set("API-Key", apiKey)
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+
+			resp, err := testServer.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "stream", resp.Header.Get("Grip-Hold"))
+			assert.Equal(t, envID, resp.Header.Get("Grip-Channel"))
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedBody, string(body))
+		})
+	}
+}
+
+// TestHTTPServer_Admin sets up an HTTPServer with the admin API enabled and makes
+// requests to the /admin endpoints
+func TestHTTPServer_Admin(t *testing.T) {
+	server := setupHTTPServer(t, false, setupWithAdminToken("admin-token"))
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	testCases := map[string]struct {
+		path               string
+		token              string
+		expectedStatusCode int
+		expectedBody       []byte
+	}{
+		"Given I make a request without the admin token": {
+			path:               "/admin/environments",
+			token:              "",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		"Given I make a request with an SDK auth token": {
+			path:               "/admin/environments",
+			token:              apiKey123Token,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		"Given I make a GET request to /admin/environments": {
+			path:               "/admin/environments",
+			token:              "admin-token",
+			expectedStatusCode: http.StatusOK,
+			expectedBody: []byte(`{"environments":["1234"]}
+`),
+		},
+		"Given I make a GET request to /admin/environments/{environmentUUID}": {
+			path:               "/admin/environments/1234",
+			token:              "admin-token",
+			expectedStatusCode: http.StatusOK,
+			expectedBody: []byte(`{"environmentId":"1234","apiKeys":["d4f79b313f8106f5af108ad96ff516222dbfd5a0ab52f4308e4b1ad1d740de60","15fac8fa1c99022568b008b9df07b04b45354ac5ca4740041d904cd3cf2b39e3","35ab1e0411c4cc6ecaaa676a4c7fef259798799ed40ad09fb07adae902bd0c7a"],"flags":[{"identifier":"harnessappdemodarkmode","version":568},{"identifier":"yet_another_flag","version":6}],"segments":[{"identifier":"flagsTeam","version":1}]}
+`),
+		},
+		"Given I make a GET request to /admin/environments/{environmentUUID} for an environment that doesn't exist": {
+			path:               "/admin/environments/foo",
+			token:              "admin-token",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		"Given I make a GET request to /admin/inventory": {
+			path:               "/admin/inventory",
+			token:              "admin-token",
+			expectedStatusCode: http.StatusOK,
+			expectedBody: []byte(`{"assets":[]}
+`),
+		},
+		"Given I make a GET request to /admin/streams": {
+			path:               "/admin/streams",
+			token:              "admin-token",
+			expectedStatusCode: http.StatusOK,
+			expectedBody: []byte(`{"channels":["1234"]}
+`),
+		},
+		"Given I make a GET request to /admin/health": {
+			path:               "/admin/health",
+			token:              "admin-token",
+			expectedStatusCode: http.StatusOK,
+			expectedBody: []byte(`{"configStatus":{"state":"SYNCED","since":1699877509155},"streamStatus":{"state":"CONNECTED","since":1699877509155},"cacheStatus":"healthy"}
+`),
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, tc.path), nil)
+			assert.Nil(t, err)
+
+			if tc.token != "" {
+				req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tc.token))
+			}
+
+			resp, err := testServer.Client().Do(req)
+			assert.Nil(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+
+			if tc.expectedBody != nil {
+				body, err := io.ReadAll(resp.Body)
+				assert.Nil(t, err)
+				assert.Equal(t, string(tc.expectedBody), string(body))
+			}
+		})
+	}
+}
+
+// TestHTTPServer_AdminDisabled checks that the admin routes aren't registered
+// unless the HTTPServer is configured with them
+func TestHTTPServer_AdminDisabled(t *testing.T) {
+	server := setupHTTPServer(t, false)
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/admin/environments", testServer.URL), nil)
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer admin-token")
+
+	resp, err := testServer.Client().Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// TestHTTPServer_AdminFlagOverrides sets up an HTTPServer with flag overrides enabled and
+// checks that overrides set through the admin API change the flags that SDKs are served
+func TestHTTPServer_AdminFlagOverrides(t *testing.T) {
+	server := setupHTTPServer(t, true, setupWithAdminToken("admin-token"), setupWithFlagOverrides())
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	do := func(method string, path string, body string) (int, []byte) {
+		req, err := http.NewRequest(method, fmt.Sprintf("%s%s", testServer.URL, path), bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer admin-token")
+
+		resp, err := testServer.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, b
+	}
+
+	getFlag := func() domain.FeatureFlag {
+		code, b := do(http.MethodGet, "/client/env/1234/feature-configs/harnessappdemodarkmode", "")
+		require.Equal(t, http.StatusOK, code)
+
+		flag := domain.FeatureFlag{}
+		require.NoError(t, json.Unmarshal(b, &flag))
+		return flag
+	}
+
+	code, _ := do(http.MethodPut, "/admin/environments/1234/overrides/harnessappdemodarkmode", `{"state": "paused"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = do(http.MethodPut, "/admin/environments/1234/overrides/foo", `{"state": "off"}`)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, b := do(http.MethodPut, "/admin/environments/1234/overrides/harnessappdemodarkmode", `{"state": "off", "reason": "incident"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, string(b), `"state":"off"`)
+
+	flag := getFlag()
+	assert.Equal(t, clientgen.Off, flag.State)
+	assert.Equal(t, int64(569), *flag.Version)
+
+	code, b = do(http.MethodGet, "/admin/environments/1234/overrides", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, string(b), `"feature":"harnessappdemodarkmode","state":"off","reason":"incident"`)
+
+	code, _ = do(http.MethodDelete, "/admin/environments/1234/overrides/harnessappdemodarkmode", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = do(http.MethodDelete, "/admin/environments/1234/overrides/harnessappdemodarkmode", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	flag = getFlag()
+	assert.Equal(t, clientgen.On, flag.State)
+	assert.Equal(t, int64(570), *flag.Version)
+
+	code, b = do(http.MethodGet, "/admin/environments/1234/overrides/audit", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	audit := domain.AdminFlagOverrideAuditResponse{}
+	require.NoError(t, json.Unmarshal(b, &audit))
+	require.Len(t, audit.Entries, 2)
+	assert.Equal(t, domain.FlagOverrideActionSet, audit.Entries[0].Action)
+	assert.Equal(t, domain.FlagOverrideActionDelete, audit.Entries[1].Action)
+}
+
+// TestHTTPServer_AdminFlagOverridesDisabled checks that the override routes return a 501
+// unless flag overrides are enabled
+func TestHTTPServer_AdminFlagOverridesDisabled(t *testing.T) {
+	server := setupHTTPServer(t, false, setupWithAdminToken("admin-token"))
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/admin/environments/1234/overrides", testServer.URL), nil)
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer admin-token")
+
+	resp, err := testServer.Client().Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+}
+
+// TestHTTPServer_AdminFlagOverridesCORS checks that browsers aren't allowed to make
+// cross-origin PUT and DELETE requests to change overrides
+func TestHTTPServer_AdminFlagOverridesCORS(t *testing.T) {
+	server := setupHTTPServer(t, true, setupWithAdminToken("admin-token"), setupWithFlagOverrides())
+	testServer := httptest.NewServer(server)
+	defer testServer.Close()
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		req, err := http.NewRequest(http.MethodOptions, fmt.Sprintf("%s/admin/environments/1234/overrides/harnessappdemodarkmode", testServer.URL), nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("Access-Control-Request-Method", method)
+
+		resp, err := testServer.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.NotContains(t, resp.Header.Get("Access-Control-Allow-Methods"), method)
+	}
+}
+
+type mockReadiness struct {
+	ready bool
+}
+
+func (m mockReadiness) Ready() bool {
+	return m.ready
+}
+
+func (m mockReadiness) Status() domain.ReadinessResponse {
+	if !m.ready {
+		return domain.ReadinessResponse{Ready: false, Reason: "key inventory doesn't exist yet"}
+	}
+	return domain.ReadinessResponse{Ready: true}
+}
+
+// TestHTTPServer_Readiness checks that SDK requests are rejected until the
+// Proxy is ready and that the readiness is served on /ready
+func TestHTTPServer_Readiness(t *testing.T) {
+	testCases := map[string]struct {
+		ready              bool
+		path               string
+		expectedStatusCode int
+		expectedBody       []byte
+		expectRetryAfter   bool
+	}{
+		"Given I make a GET request to /ready and the Proxy isn't ready": {
+			ready:              false,
+			path:               "/ready",
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBody: []byte(`{"ready":false,"reason":"key inventory doesn't exist yet"}
+`),
+		},
+		"Given I make a GET request to /ready and the Proxy is ready": {
+			ready:              true,
+			path:               "/ready",
+			expectedStatusCode: http.StatusOK,
+			expectedBody: []byte(`{"ready":true}
+`),
+		},
+		"Given I make a GET request for feature-configs and the Proxy isn't ready": {
+			ready:              false,
+			path:               "/client/env/1234/feature-configs",
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectRetryAfter:   true,
+		},
+		"Given I make a GET request for feature-configs and the Proxy is ready": {
+			ready:              true,
+			path:               "/client/env/1234/feature-configs",
+			expectedStatusCode: http.StatusOK,
+		},
+		"Given I make a GET request to /health and the Proxy isn't ready": {
+			ready:              false,
+			path:               "/health",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for desc, tc := range testCases {
+		tc := tc
+		t.Run(desc, func(t *testing.T) {
+			server := setupHTTPServer(t, true, setupWithReadiness(mockReadiness{ready: tc.ready}))
+			testServer := httptest.NewServer(server)
+			defer testServer.Close()
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, tc.path), nil)
+			assert.Nil(t, err)
+
+			resp, err := testServer.Client().Do(req)
+			assert.Nil(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expectedStatusCode, resp.StatusCode)
+			assert.Equal(t, tc.expectRetryAfter, resp.Header.Get("Retry-After") != "")
+
+			if tc.expectedBody != nil {
+				body, err := io.ReadAll(resp.Body)
+				assert.Nil(t, err)
+				assert.Equal(t, string(tc.expectedBody), string(body))
+			}
+		})
+	}
+}
+
 func TestHTTPServer_WithCustomHandler(t *testing.T) {
 	type args struct {
 		method  string